@@ -4,6 +4,7 @@
 package xmldot
 
 import (
+	"errors"
 	"strings"
 	"testing"
 )
@@ -737,3 +738,90 @@ func TestSet_AppendToEmptyArrayEdgeCases(t *testing.T) {
 		}
 	})
 }
+
+// TestSet_AppendViaCount tests that a trailing # appends like -1, for Set and SetRaw
+func TestSet_AppendViaCount(t *testing.T) {
+	tests := []struct {
+		name     string
+		xml      string
+		path     string
+		value    interface{}
+		raw      bool
+		expected string
+	}{
+		{"set appends escaped text", `<list><entry>a</entry></list>`, "list.entry.#", "b<c", false, `<list><entry>a</entry><entry>b&lt;c</entry></list>`},
+		{"set raw appends markup", `<list><entry>a</entry></list>`, "list.entry.#", "<t>b</t>", true, `<list><entry>a</entry><entry><t>b</t></entry></list>`},
+		{"first element in empty parent", `<list></list>`, "list.entry.#", "a", false, `<list><entry>a</entry></list>`},
+		{"self-closing parent", `<list/>`, "list.entry.#", "a", false, `<list><entry>a</entry></list>`},
+		{"missing parents", `<rss></rss>`, "rss.channel.item.#", "<title>t</title>", true, `<rss><channel><item><title>t</title></item></channel></rss>`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var result string
+			var err error
+			if tt.raw {
+				result, err = SetRaw(tt.xml, tt.path, tt.value.(string))
+			} else {
+				result, err = Set(tt.xml, tt.path, tt.value)
+			}
+			if err != nil {
+				t.Fatalf("error = %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("result = %q, want %q", result, tt.expected)
+			}
+			if minus, _ := Set(tt.xml, strings.TrimSuffix(tt.path, "#")+"-1", tt.value); !tt.raw && minus != result {
+				t.Errorf("-1 append = %q, want same result as # (%q)", minus, result)
+			}
+		})
+	}
+
+	if _, err := Set(`<list><entry>a</entry></list>`, "list.entry.#|@reverse", "b"); !errors.Is(err, ErrInvalidPath) {
+		t.Errorf("# with modifier error = %v, want ErrInvalidPath", err)
+	}
+}
+
+// TestSet_CreateAtIndex tests writes to the index one past the last sibling
+func TestSet_CreateAtIndex(t *testing.T) {
+	one := `<rss><channel><item><title>a</title></item></channel></rss>`
+
+	tests := []struct {
+		name     string
+		xml      string
+		path     string
+		expected string
+	}{
+		{"first element of missing array", `<rss><channel/></rss>`, "rss.channel.item.0", `<rss><channel><item>v</item></channel></rss>`},
+		{"missing parents", `<rss></rss>`, "rss.channel.item.0", `<rss><channel><item>v</item></channel></rss>`},
+		{"next element", one, "rss.channel.item.1", `<rss><channel><item><title>a</title></item><item>v</item></channel></rss>`},
+		{"child of next element", one, "rss.channel.item.1.title", `<rss><channel><item><title>a</title></item><item><title>v</title></item></channel></rss>`},
+		{"attribute of next element", one, "rss.channel.item.1.@id", `<rss><channel><item><title>a</title></item><item id="v"></item></channel></rss>`},
+		{"nested new indices", `<rss></rss>`, "rss.channel.0.item.0", `<rss><channel><item>v</item></channel></rss>`},
+		{"existing element replaced", one, "rss.channel.item.0.title", `<rss><channel><item><title>v</title></item></channel></rss>`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := CanSet(tt.xml, tt.path, "v"); err != nil {
+				t.Errorf("CanSet() error = %v", err)
+			}
+			result, err := Set(tt.xml, tt.path, "v")
+			if err != nil {
+				t.Fatalf("Set() error = %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Set() = %q, want %q", result, tt.expected)
+			}
+		})
+	}
+
+	for _, path := range []string{"rss.channel.item.2", "rss.channel.item.1.sub.1"} {
+		if _, err := Set(one, path, "v"); !errors.Is(err, ErrInvalidPath) {
+			t.Errorf("Set(%q) error = %v, want ErrInvalidPath", path, err)
+		}
+		if err := CanSet(one, path, "v"); !errors.Is(err, ErrInvalidPath) {
+			t.Errorf("CanSet(%q) error = %v, want ErrInvalidPath", path, err)
+		}
+	}
+}
@@ -5,6 +5,7 @@ package xmldot
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 )
 
@@ -120,6 +121,20 @@ func BenchmarkGet_SimpleElement(b *testing.B) {
 	}
 }
 
+func BenchmarkExists_LargeSubtree(b *testing.B) {
+	xml := "<root><big>" + strings.Repeat(`<item id="x"><name>value</name></item>`, 10000) + "</big></root>"
+	b.Run("Get", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_ = Get(xml, "root.big").Exists()
+		}
+	})
+	b.Run("Exists", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_ = Exists(xml, "root.big")
+		}
+	})
+}
+
 func BenchmarkGet_DeeplyNested(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		_ = Get(deepXML, "root.level0.level1.level2.level3.level4.level5.level6.level7.level8.level9.level10.level11.level12.level13.level14.level15.level16.level17.level18.level19.value")
@@ -361,7 +376,7 @@ func BenchmarkFilter_EvaluateNumeric(b *testing.B) {
 	content := "<age>30</age>"
 	attrs := make(map[string]string)
 	for i := 0; i < b.N; i++ {
-		_ = evaluateFilterWithDepth(filter, content, attrs, 0)
+		_ = evaluateFilterWithDepth(filter, content, attrs, 0, 0)
 	}
 }
 
@@ -370,7 +385,7 @@ func BenchmarkFilter_EvaluateString(b *testing.B) {
 	content := "<name>John</name>"
 	attrs := make(map[string]string)
 	for i := 0; i < b.N; i++ {
-		_ = evaluateFilterWithDepth(filter, content, attrs, 0)
+		_ = evaluateFilterWithDepth(filter, content, attrs, 0, 0)
 	}
 }
 
@@ -379,7 +394,7 @@ func BenchmarkFilter_EvaluateAttribute(b *testing.B) {
 	content := ""
 	attrs := map[string]string{"id": "123"}
 	for i := 0; i < b.N; i++ {
-		_ = evaluateFilterWithDepth(filter, content, attrs, 0)
+		_ = evaluateFilterWithDepth(filter, content, attrs, 0, 0)
 	}
 }
 
@@ -4,9 +4,13 @@
 package xmldot
 
 import (
+	"bytes"
+	"encoding/xml"
 	"fmt"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 )
 
 const (
@@ -21,6 +25,11 @@ type xmlBuilder struct {
 	result strings.Builder
 	pos    int
 	opts   *Options
+
+	// declarations holds xmlns attributes (Options.AutoDeclareNamespaces)
+	// still to be written on the first element created, or on the element
+	// receiving an attribute
+	declarations string
 }
 
 // newXMLBuilder creates a new XML builder with default options
@@ -48,6 +57,13 @@ func newXMLBuilderWithOptions(data []byte, opts *Options) *xmlBuilder {
 // valueToXML converts various value types to XML string representation
 // Returns the XML string and whether the value should be treated as raw XML
 func valueToXML(value interface{}) (string, bool, error) {
+	return valueToXMLWithOptions(value, nil)
+}
+
+// valueToXMLWithOptions is like valueToXML but renders time.Time and float
+// values using opts.TimeLayout and opts.FloatFormat when they are set.
+// nil options use the defaults (RFC 3339 and %g).
+func valueToXMLWithOptions(value interface{}, opts *Options) (string, bool, error) {
 	if value == nil {
 		return "", false, nil
 	}
@@ -57,13 +73,37 @@ func valueToXML(value interface{}) (string, bool, error) {
 		// Regular string - needs escaping
 		return escapeXML(v), false, nil
 	case int:
-		return fmt.Sprintf("%d", v), false, nil
+		return strconv.FormatInt(int64(v), 10), false, nil
 	case int64:
-		return fmt.Sprintf("%d", v), false, nil
+		// Formatted directly rather than through float64, so large IDs and
+		// counters are written exactly
+		return strconv.FormatInt(v, 10), false, nil
+	case int32:
+		return strconv.FormatInt(int64(v), 10), false, nil
+	case int16:
+		return strconv.FormatInt(int64(v), 10), false, nil
+	case int8:
+		return strconv.FormatInt(int64(v), 10), false, nil
+	case uint:
+		return strconv.FormatUint(uint64(v), 10), false, nil
+	case uint64:
+		return strconv.FormatUint(v, 10), false, nil
+	case uint32:
+		return strconv.FormatUint(uint64(v), 10), false, nil
+	case uint16:
+		return strconv.FormatUint(uint64(v), 10), false, nil
+	case uint8:
+		return strconv.FormatUint(uint64(v), 10), false, nil
 	case float64:
-		return fmt.Sprintf("%g", v), false, nil
+		return formatFloat(v, opts)
 	case float32:
-		return fmt.Sprintf("%g", v), false, nil
+		return formatFloat(v, opts)
+	case time.Time:
+		layout := time.RFC3339
+		if opts != nil && opts.TimeLayout != "" {
+			layout = opts.TimeLayout
+		}
+		return escapeXML(v.Format(layout)), false, nil
 	case bool:
 		if v {
 			return "true", false, nil
@@ -72,32 +112,191 @@ func valueToXML(value interface{}) (string, bool, error) {
 	case []byte:
 		// Byte slice - treat as raw XML (no escaping)
 		return string(v), true, nil
+	case Result:
+		// Query results are written back verbatim, so numeric text such as
+		// "01.50" or "1e2" is not reformatted
+		switch v.Type {
+		case Element:
+			return v.Raw, true, nil
+		case Null:
+			return "", false, fmt.Errorf("%w: cannot write a Null result", ErrInvalidValue)
+		case Array:
+			return "", false, fmt.Errorf("%w: cannot write an Array result", ErrInvalidValue)
+		default:
+			return escapeXML(v.Str), false, nil
+		}
 	default:
 		// Unsupported type
 		return "", false, fmt.Errorf("%w: unsupported type %T", ErrInvalidValue, value)
 	}
 }
 
+// formatFloat renders a float32 or float64 with opts.FloatFormat, falling
+// back to %g.
+func formatFloat(f interface{}, opts *Options) (string, bool, error) {
+	if opts == nil || opts.FloatFormat == "" {
+		return fmt.Sprintf("%g", f), false, nil
+	}
+	formatted := fmt.Sprintf(opts.FloatFormat, f)
+	if strings.Contains(formatted, "%!") {
+		return "", false, fmt.Errorf("%w: invalid FloatFormat %q", ErrInvalidValue, opts.FloatFormat)
+	}
+	return escapeXML(formatted), false, nil
+}
+
+// hasRootSegment reports whether the path uses the read-only #root accessor.
+func hasRootSegment(path []PathSegment) bool {
+	for _, seg := range path {
+		if seg.Type == SegmentRoot {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeAppendCount returns path with a trailing # after an element name
+// replaced by the -1 append index, so "list.entry.#" appends like
+// "list.entry.-1". Other paths are returned unchanged.
+// IMPORTANT: Copies the path to avoid mutating cached paths
+func normalizeAppendCount(path []PathSegment) []PathSegment {
+	n := len(path)
+	if n < 2 || path[n-1].Type != SegmentCount || path[n-2].Type != SegmentElement || len(path[n-1].Modifiers) > 0 {
+		return path
+	}
+	normalized := make([]PathSegment, n)
+	copy(normalized, path)
+	normalized[n-1] = PathSegment{Type: SegmentIndex, Index: -1}
+	return normalized
+}
+
+// countSiblings returns how many elements match prefix, a path ending in an
+// element name.
+func countSiblings(data []byte, prefix []PathSegment, opts *Options) int {
+	countPath := make([]PathSegment, len(prefix)+1)
+	copy(countPath, prefix)
+	countPath[len(prefix)] = PathSegment{Type: SegmentCount}
+	return int(executeQueryWithOptions(newXMLParser(data), countPath, 0, opts).Int())
+}
+
+// indexPastEndError is the error for a write to an index beyond the next
+// free position among count existing siblings.
+func indexPastEndError(seg PathSegment, count int, name string) error {
+	return fmt.Errorf("%w: index %d is past the end of %d existing %s elements; use %d or -1 to append",
+		ErrInvalidPath, seg.Index, count, name, count)
+}
+
+// createAtIndex handles a write to a missing element whose path contains an
+// index one past the last existing sibling (e.g. "channel.item.0" when there
+// are no items): the element is appended, and a write below it continues in
+// the new element. An index further out is rejected, since elements cannot
+// be created with gaps. It reports false if path needs no such handling.
+func (b *xmlBuilder) createAtIndex(path []PathSegment, value interface{}) (bool, error) {
+	i, err := b.missingIndex(path)
+	if err != nil {
+		return true, err
+	}
+	if i < 0 {
+		return false, nil
+	}
+
+	appendPath := make([]PathSegment, i+1)
+	copy(appendPath, path[:i])
+	appendPath[i] = PathSegment{Type: SegmentIndex, Index: -1, Intent: IntentAppend}
+	if i == len(path)-1 {
+		return true, b.appendElement(appendPath, value)
+	}
+
+	// Append an empty element, then write into it at the same index
+	if err := b.appendElement(appendPath, ""); err != nil {
+		return true, err
+	}
+	b.data = []byte(b.result.String())
+	b.result.Reset()
+	return true, b.setElement(path, value)
+}
+
+// missingIndex returns the position in path of the first index that selects
+// the next free position among its existing siblings, or -1 if every index
+// selects an existing element. An index further out is an error.
+func (b *xmlBuilder) missingIndex(path []PathSegment) (int, error) {
+	for i := 1; i < len(path); i++ {
+		seg := path[i]
+		if seg.Type != SegmentIndex || seg.Index < 0 || path[i-1].Type != SegmentElement {
+			continue
+		}
+
+		count := countSiblings(b.data, path[:i], b.opts)
+		if seg.Index < count {
+			continue
+		}
+		if seg.Index > count {
+			return 0, indexPastEndError(seg, count, path[i-1].Value)
+		}
+		return i, nil
+	}
+	return -1, nil
+}
+
+// setPlan is a write that checkSet has accepted, ready for applySet.
+type setPlan struct {
+	path     []PathSegment // with index intents resolved (a copy if any were)
+	xmlValue string        // the value as XML; empty for fan-out paths
+	isRaw    bool
+	fanOut   bool // path selects several elements, see applyFanOut
+	appends  bool // path ends in the -1 append index
+}
+
 // setElement replaces or creates an element at the specified path
 func (b *xmlBuilder) setElement(path []PathSegment, value interface{}) error {
+	plan, err := b.checkSet(path, value)
+	if err != nil {
+		return err
+	}
+	return b.applySet(plan, value)
+}
+
+// checkSet makes the checks of setElement that need only the path and the
+// value: the path must be writable and the value convertible to XML, both
+// within the size limits. Set and CanSet share it; what can only be checked
+// against the document is left to applySet, or to checkSetTargets.
+func (b *xmlBuilder) checkSet(path []PathSegment, value interface{}) (setPlan, error) {
 	if len(path) == 0 {
-		return ErrInvalidPath
+		return setPlan{}, ErrInvalidPath
+	}
+	if hasRootSegment(path) {
+		return setPlan{}, fmt.Errorf("%w: #root is read-only", ErrInvalidPath)
+	}
+	if positionalAttribute(path) >= 0 {
+		return setPlan{}, fmt.Errorf("%w: positional attributes are read-only", ErrInvalidPath)
+	}
+	if err := checkNamespaceDeclaration(path, value); err != nil {
+		return setPlan{}, err
+	}
+	path = normalizeAppendCount(path)
+	if isFanOutPath(path) {
+		// Each selected element checks the rest of the path itself
+		return setPlan{path: path, fanOut: true}, nil
+	}
+	for _, seg := range path {
+		if seg.Type == SegmentCount {
+			return setPlan{}, fmt.Errorf("%w: # can only end a write path, to append", ErrInvalidPath)
+		}
 	}
 
 	// Convert value to XML string
-	xmlValue, isRaw, err := valueToXML(value)
+	xmlValue, isRaw, err := valueToXMLWithOptions(value, b.opts)
 	if err != nil {
-		return err
+		return setPlan{}, err
 	}
 
 	// Security check: reject values that are too large
 	if len(xmlValue) > MaxValueSize {
-		return fmt.Errorf("%w: value exceeds maximum size of %d bytes", ErrInvalidValue, MaxValueSize)
+		return setPlan{}, fmt.Errorf("%w: value exceeds maximum size of %d bytes", ErrInvalidValue, MaxValueSize)
 	}
 
 	// Security check: reject documents that are too large
 	if len(b.data) > MaxDocumentSize {
-		return ErrMalformedXML
+		return setPlan{}, ErrMalformedXML
 	}
 
 	// Check for append operation (-1 index) and resolve intent
@@ -117,14 +316,17 @@ func (b *xmlBuilder) setElement(path []PathSegment, value interface{}) error {
 
 			intent, err := resolveIndexIntent(seg, i, pathCopy, "set")
 			if err != nil {
-				return err
+				return setPlan{}, err
 			}
 			// Update the segment with resolved intent (safe because we copied)
 			pathCopy[i].Intent = intent
 
-			// If this is an append operation, delegate to appendElement
+			// An append operation is delegated to appendElement
 			if intent == IntentAppend {
-				return b.appendElement(pathCopy, value)
+				if err := checkAppendPath(pathCopy); err != nil {
+					return setPlan{}, err
+				}
+				return setPlan{path: pathCopy, xmlValue: xmlValue, isRaw: isRaw, appends: true}, nil
 			}
 		}
 	}
@@ -133,6 +335,20 @@ func (b *xmlBuilder) setElement(path []PathSegment, value interface{}) error {
 	if needsCopy {
 		path = pathCopy
 	}
+	return setPlan{path: path, xmlValue: xmlValue, isRaw: isRaw}, nil
+}
+
+// applySet makes the write described by plan, for the value checkSet
+// accepted.
+func (b *xmlBuilder) applySet(plan setPlan, value interface{}) error {
+	if plan.fanOut {
+		_, err := b.applyFanOut(plan.path, value)
+		return err
+	}
+	if plan.appends {
+		return b.appendElement(plan.path, value)
+	}
+	path, xmlValue, isRaw := plan.path, plan.xmlValue, plan.isRaw
 
 	// Check if this is actually an attribute operation
 	if len(path) > 0 && path[len(path)-1].Type == SegmentAttribute {
@@ -144,6 +360,9 @@ func (b *xmlBuilder) setElement(path []PathSegment, value interface{}) error {
 		parser := newXMLParser(b.data)
 		location, found := b.findElementLocation(parser, elementPath, 0, 0)
 		if !found {
+			if handled, err := b.createAtIndex(path, value); handled {
+				return err
+			}
 			// Parent element doesn't exist - create it with the attribute
 			return b.createElementForAttribute(elementPath, path[len(path)-1], xmlValue)
 		}
@@ -160,6 +379,9 @@ func (b *xmlBuilder) setElement(path []PathSegment, value interface{}) error {
 		// Element exists - replace it
 		return b.replaceElement(location, path[len(path)-1], xmlValue)
 	}
+	if handled, err := b.createAtIndex(path, value); handled {
+		return err
+	}
 
 	// Element doesn't exist - create it
 	return b.createElement(path, xmlValue, isRaw)
@@ -173,6 +395,7 @@ type elementLocation struct {
 	contentEnd    int    // Position of '<' in closing tag
 	elementName   string // Name of the element
 	attrs         map[string]string
+	attrOrder     []string // Attribute names in document order
 	isSelfClosing bool
 }
 
@@ -199,7 +422,7 @@ func (b *xmlBuilder) findElementLocation(parser *xmlParser, segments []PathSegme
 		elemStartPos := parser.pos
 		parser.next() // skip '<'
 
-		elemName, attrs, isSelfClosing := parser.parseElementName()
+		elemName, attrs, attrOrder, isSelfClosing := parser.parseElementTag()
 
 		// Check if element matches current segment (with options support)
 		if !currentSeg.matchesWithOptions(elemName, b.opts) {
@@ -235,18 +458,18 @@ func (b *xmlBuilder) findElementLocation(parser *xmlParser, segments []PathSegme
 						contentEnd:    contentEnd + baseOffset,
 						elementName:   elemName,
 						attrs:         attrs,
+						attrOrder:     attrOrder,
 						isSelfClosing: isSelfClosing,
 					}, true
 				}
 				// Continue searching within this element
-				var content string
 				contentStartPos := parser.pos
-				if isSelfClosing {
-					content = ""
-				} else {
-					content = parser.parseElementContent(elemName)
+				var content []byte
+				if !isSelfClosing {
+					parser.parseElementContent(elemName)
+					content = rawElementContent(parser, contentStartPos)
 				}
-				contentParser := newXMLParser([]byte(content))
+				contentParser := newXMLParser(content)
 				// Pass the current content start position as the new base offset
 				// Check for overflow before recursing
 				newOffset := baseOffset + contentStartPos
@@ -288,19 +511,19 @@ func (b *xmlBuilder) findElementLocation(parser *xmlParser, segments []PathSegme
 				contentEnd:    contentEnd + baseOffset,
 				elementName:   elemName,
 				attrs:         attrs,
+				attrOrder:     attrOrder,
 				isSelfClosing: isSelfClosing,
 			}, true
 		}
 
 		// Continue searching within this element
-		var content string
 		contentStartPos := parser.pos
-		if isSelfClosing {
-			content = ""
-		} else {
-			content = parser.parseElementContent(elemName)
+		var content []byte
+		if !isSelfClosing {
+			parser.parseElementContent(elemName)
+			content = rawElementContent(parser, contentStartPos)
 		}
-		contentParser := newXMLParser([]byte(content))
+		contentParser := newXMLParser(content)
 		// Pass the current content start position as the new base offset
 		// Check for overflow before recursing
 		newOffset := baseOffset + contentStartPos
@@ -314,6 +537,18 @@ func (b *xmlBuilder) findElementLocation(parser *xmlParser, segments []PathSegme
 	return nil, false
 }
 
+// rawElementContent returns the original bytes between contentStart and the
+// closing tag the parser has just consumed. Recursing into these bytes rather
+// than the re-serialized content keeps nested offsets aligned with the source
+// document when tags use non-canonical formatting.
+func rawElementContent(parser *xmlParser, contentStart int) []byte {
+	content := parser.data[contentStart:parser.pos]
+	if end := bytes.LastIndexByte(content, '<'); end >= 0 {
+		return content[:end]
+	}
+	return content
+}
+
 // replaceElement replaces an element's content in the XML
 func (b *xmlBuilder) replaceElement(location *elementLocation, segment PathSegment, xmlValue string) error {
 	// Check if this is an attribute operation
@@ -321,9 +556,65 @@ func (b *xmlBuilder) replaceElement(location *elementLocation, segment PathSegme
 		return b.replaceAttribute(location, segment.Value, xmlValue)
 	}
 
+	if b.opts.SortAttributes {
+		// Rewrite the start tag first, keeping the location consistent
+		tag := b.data[location.startPos:location.contentStart]
+		if sorted := sortedStartTag(tag); sorted != string(tag) {
+			delta := len(sorted) - len(tag)
+			data := make([]byte, 0, len(b.data)+delta)
+			data = append(data, b.data[:location.startPos]...)
+			data = append(data, sorted...)
+			data = append(data, b.data[location.contentStart:]...)
+			b.data = data
+			moved := *location
+			moved.contentStart += delta
+			moved.contentEnd += delta
+			moved.endTagPos += delta
+			location = &moved
+		}
+	}
+
 	// Build the result XML
 	b.result.Reset()
 
+	if b.opts.TextOnlySet && !location.isSelfClosing && !strings.Contains(xmlValue, "<") {
+		// Only the direct text changes; children, comments and the
+		// whitespace between them are kept
+		xmlValue = replaceDirectText(b.data[location.contentStart:location.contentEnd], xmlValue)
+	} else if b.opts.PreserveInnerComments && !location.isSelfClosing {
+		leading, trailing := innerComments(b.data[location.contentStart:location.contentEnd])
+		xmlValue = leading + xmlValue + trailing
+	}
+
+	// A self-closing element is expanded to hold the new content
+	if location.isSelfClosing {
+		if xmlValue == "" {
+			b.result.Write(b.data)
+			return nil
+		}
+		// contentStart points just past "/>"
+		tag := bytes.TrimRight(b.data[location.startPos:location.contentStart-2], " \t\r\n")
+		b.result.Write(b.data[:location.startPos])
+		b.result.Write(tag)
+		b.result.WriteString(">")
+		b.result.WriteString(xmlValue)
+		b.result.WriteString("</")
+		b.result.WriteString(location.elementName)
+		b.result.WriteString(">")
+		b.result.Write(b.data[location.contentStart:])
+		return nil
+	}
+
+	// Emptying an element collapses it to a self-closing tag
+	if xmlValue == "" && b.opts.SelfCloseEmpty {
+		tag := bytes.TrimRight(b.data[location.startPos:location.contentStart-1], " \t\r\n")
+		b.result.Write(b.data[:location.startPos])
+		b.result.Write(tag)
+		b.result.WriteString("/>")
+		b.result.Write(b.data[elementEnd(location):])
+		return nil
+	}
+
 	// Write everything up to and including the opening tag (up to contentStart)
 	b.result.Write(b.data[:location.contentStart])
 
@@ -337,6 +628,205 @@ func (b *xmlBuilder) replaceElement(location *elementLocation, segment PathSegme
 	return nil
 }
 
+// innerComments returns the comments and processing instructions that are
+// direct children of an element with the given content, split into those
+// before any text, CDATA or child element (leading) and the rest (trailing).
+// It is used by Options.PreserveInnerComments.
+func innerComments(content []byte) (leading, trailing string) {
+	var before, after strings.Builder
+	seenContent := false
+	depth := 0
+	keep := func(markup []byte) {
+		if depth != 0 {
+			return
+		}
+		if seenContent {
+			after.Write(markup)
+		} else {
+			before.Write(markup)
+		}
+	}
+
+	for i := 0; i < len(content); {
+		if content[i] != '<' {
+			if depth == 0 && !isWhitespace(content[i]) {
+				seenContent = true
+			}
+			i++
+			continue
+		}
+
+		rest := content[i:]
+		end := 0
+		switch {
+		case bytes.HasPrefix(rest, []byte("<!--")):
+			end = markupEnd(rest, "-->")
+			if end > 0 {
+				keep(rest[:end])
+			}
+		case bytes.HasPrefix(rest, []byte("<?")):
+			end = markupEnd(rest, "?>")
+			if end > 0 {
+				keep(rest[:end])
+			}
+		case bytes.HasPrefix(rest, []byte("<![CDATA[")):
+			end = markupEnd(rest, "]]>")
+			if depth == 0 {
+				seenContent = true
+			}
+		case bytes.HasPrefix(rest, []byte("</")):
+			end = markupEnd(rest, ">")
+			depth--
+		default:
+			end = tagEnd(rest)
+			if depth == 0 {
+				seenContent = true
+			}
+			if end > 1 && rest[end-2] != '/' {
+				depth++
+			}
+		}
+		if end == 0 {
+			// Unterminated markup; the document was checked already
+			break
+		}
+		i += end
+	}
+	return before.String(), after.String()
+}
+
+// replaceDirectText returns element content with its direct text replaced
+// by text (already escaped), for Options.TextOnlySet. The first run of
+// direct text that is not just whitespace takes the new text, keeping the
+// whitespace around it; later runs are dropped except for their trailing
+// whitespace, which usually indents the next child. Without such a run the
+// text is inserted before the existing content. CDATA sections directly in
+// the element count as text; child elements, comments and processing
+// instructions are copied unchanged.
+func replaceDirectText(content []byte, text string) string {
+	var sb strings.Builder
+	var run []byte // direct text since the last markup
+	written := false
+	flush := func() {
+		trimmed := bytes.TrimLeft(run, " \t\r\n")
+		switch {
+		case len(trimmed) == 0:
+			sb.Write(run)
+		case !written && text != "":
+			sb.Write(run[:len(run)-len(trimmed)])
+			sb.WriteString(text)
+			sb.Write(run[len(bytes.TrimRight(run, " \t\r\n")):])
+			written = true
+		default:
+			sb.Write(run[len(bytes.TrimRight(run, " \t\r\n")):])
+		}
+		run = run[:0]
+	}
+
+	depth := 0
+	for i := 0; i < len(content); {
+		if content[i] != '<' {
+			if depth == 0 {
+				run = append(run, content[i])
+			} else {
+				sb.WriteByte(content[i])
+			}
+			i++
+			continue
+		}
+
+		rest := content[i:]
+		if depth == 0 && bytes.HasPrefix(rest, []byte("<![CDATA[")) {
+			if end := markupEnd(rest, "]]>"); end > 0 {
+				run = append(run, rest[:end]...)
+				i += end
+				continue
+			}
+		}
+		flush()
+
+		end := 0
+		switch {
+		case bytes.HasPrefix(rest, []byte("<![CDATA[")):
+			end = markupEnd(rest, "]]>")
+		case bytes.HasPrefix(rest, []byte("<!--")):
+			end = markupEnd(rest, "-->")
+		case bytes.HasPrefix(rest, []byte("<?")):
+			end = markupEnd(rest, "?>")
+		case bytes.HasPrefix(rest, []byte("</")):
+			end = markupEnd(rest, ">")
+			depth--
+		default:
+			end = tagEnd(rest)
+			if end > 1 && rest[end-2] != '/' {
+				depth++
+			}
+		}
+		if end == 0 {
+			// Unterminated markup; the document was checked already
+			end = len(rest)
+		}
+		sb.Write(rest[:end])
+		i += end
+	}
+	flush()
+
+	if !written && text != "" {
+		return text + sb.String()
+	}
+	return sb.String()
+}
+
+// markupEnd returns the length of markup up to and including the first
+// terminator, or 0 if it is unterminated.
+func markupEnd(markup []byte, terminator string) int {
+	if i := bytes.Index(markup, []byte(terminator)); i >= 0 {
+		return i + len(terminator)
+	}
+	return 0
+}
+
+// tagEnd returns the length of the start tag at the beginning of markup,
+// skipping '>' inside quoted attribute values, or 0 if it is unterminated.
+func tagEnd(markup []byte) int {
+	var quote byte
+	for i := 1; i < len(markup); i++ {
+		switch c := markup[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '>':
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// writeDeclarations writes the pending namespace declarations after an
+// element name. They are written once, on the first element that needs them.
+func (b *xmlBuilder) writeDeclarations() {
+	b.result.WriteString(b.declarations)
+	b.declarations = ""
+}
+
+// closeElement completes an element whose start tag has been written up to
+// its attributes: ">" + xmlValue + "</name>", or "/>" for an empty value when
+// Options.SelfCloseEmpty is set.
+func (b *xmlBuilder) closeElement(name, xmlValue string) {
+	if xmlValue == "" && b.opts.SelfCloseEmpty {
+		b.result.WriteString("/>")
+		return
+	}
+	b.result.WriteString(">")
+	b.result.WriteString(xmlValue)
+	b.result.WriteString("</")
+	b.result.WriteString(name)
+	b.result.WriteString(">")
+}
+
 // replaceAttribute replaces or adds an attribute to an element
 func (b *xmlBuilder) replaceAttribute(location *elementLocation, attrName string, attrValue string) error {
 	// Build new opening tag with updated attribute
@@ -348,12 +838,10 @@ func (b *xmlBuilder) replaceAttribute(location *elementLocation, attrName string
 	// Build new opening tag
 	b.result.WriteString("<")
 	b.result.WriteString(location.elementName)
+	b.writeDeclarations()
 
-	// Sort attribute names for deterministic output
-	attrNames := make([]string, 0, len(location.attrs)+1)
-	for name := range location.attrs {
-		attrNames = append(attrNames, name)
-	}
+	// Existing attributes keep their order; a new one is added last
+	attrNames := b.attributeNames(location, len(location.attrs)+1)
 
 	// Add new/modified attribute to list if not already present
 	// For case-insensitive matching, check existing attributes case-insensitively
@@ -377,10 +865,11 @@ func (b *xmlBuilder) replaceAttribute(location *elementLocation, attrName string
 	if !attrExists {
 		attrNames = append(attrNames, attrName)
 	}
+	if b.opts.SortAttributes {
+		sort.Strings(attrNames)
+	}
 
-	sort.Strings(attrNames)
-
-	// Write attributes in sorted order
+	// Write attributes
 	for _, name := range attrNames {
 		b.result.WriteString(" ")
 		b.result.WriteString(name)
@@ -447,7 +936,7 @@ func (b *xmlBuilder) createElement(path []PathSegment, xmlValue string, isRaw bo
 	}
 
 	// Create missing elements from parentDepth+1 to end
-	return b.createInParent(parentLocation, path[parentDepth+1:], xmlValue, isRaw)
+	return b.createInParent(parentLocation, parentDepth, path[parentDepth+1:], xmlValue, isRaw)
 }
 
 // createInRoot creates element path starting from root
@@ -505,7 +994,7 @@ func (b *xmlBuilder) createInRoot(path []PathSegment, xmlValue string, isRaw boo
 		b.result.WriteString(">")
 
 		// Build the path
-		b.buildElementPath(pathToCreate, xmlValue, isRaw)
+		b.writeIntoEmpty(elemStartPos, 0, pathToCreate, xmlValue, isRaw)
 
 		b.result.WriteString("</")
 		b.result.WriteString(elemName)
@@ -518,10 +1007,16 @@ func (b *xmlBuilder) createInRoot(path []PathSegment, xmlValue string, isRaw boo
 	}
 
 	// Find where root element ends
+	contentStart := parser.pos
 	_ = parser.parseElementContent(elemName)
 	contentEnd := parser.pos - len(elemName) - 3
 
 	b.result.Reset()
+	root := &elementLocation{startPos: elemStartPos, contentStart: contentStart, contentEnd: contentEnd}
+	if layout, ok := b.inferLayout(contentEnd, root, 0); ok {
+		b.writeLaidOut(layout, pathToCreate, xmlValue)
+		return nil
+	}
 	b.result.Write(b.data[:contentEnd])
 
 	// Build the path
@@ -590,8 +1085,9 @@ func (b *xmlBuilder) createSiblingRoot(path []PathSegment, xmlValue string, isRa
 	return nil
 }
 
-// createInParent creates missing elements within a parent element
-func (b *xmlBuilder) createInParent(parentLocation *elementLocation, remainingPath []PathSegment, xmlValue string, isRaw bool) error {
+// createInParent creates missing elements within a parent element, which is
+// depth levels below the root
+func (b *xmlBuilder) createInParent(parentLocation *elementLocation, depth int, remainingPath []PathSegment, xmlValue string, isRaw bool) error {
 	b.result.Reset()
 
 	if parentLocation.isSelfClosing {
@@ -617,7 +1113,7 @@ func (b *xmlBuilder) createInParent(parentLocation *elementLocation, remainingPa
 		b.result.WriteString(">")
 
 		// Build the missing path
-		b.buildElementPath(remainingPath, xmlValue, isRaw)
+		b.writeIntoEmpty(parentLocation.startPos, depth, remainingPath, xmlValue, isRaw)
 
 		// Close the parent element
 		b.result.WriteString("</")
@@ -626,6 +1122,9 @@ func (b *xmlBuilder) createInParent(parentLocation *elementLocation, remainingPa
 
 		// Write everything after the original self-closing tag
 		b.result.Write(b.data[parentLocation.contentEnd:])
+	} else if layout, ok := b.inferLayout(parentLocation.contentEnd, parentLocation, depth); ok {
+		// Formatted document - line the new elements up with their siblings
+		b.writeLaidOut(layout, remainingPath, xmlValue)
 	} else {
 		// Parent is a regular element - insert before closing tag
 		b.result.Write(b.data[:parentLocation.contentEnd])
@@ -649,13 +1148,16 @@ func (b *xmlBuilder) createInParent(parentLocation *elementLocation, remainingPa
 // Example:
 //
 //	Path: "root.user.@id" with value "123"
-//	Result: <root><user id="123"></user></root>
+//	Result: <root><user id="123"/></root>
+//
+// The element owning the attribute is written self-closing; a later write of
+// child content expands it.
 //
 // Security Considerations:
 //
-// This function reuses createElement() and replaceAttribute(), inheriting their
-// security protections including MaxPathSegments, MaxDocumentSize, MaxAttributes,
-// and proper XML escaping.
+// This function reuses createElement(), inheriting its security protections
+// including MaxPathSegments and MaxDocumentSize. The attribute value is escaped
+// by the caller.
 func (b *xmlBuilder) createElementForAttribute(elementPath []PathSegment, attrSeg PathSegment, attrValue string) error {
 	// Security check: Validate we're creating an attribute
 	if attrSeg.Type != SegmentAttribute {
@@ -688,8 +1190,54 @@ func (b *xmlBuilder) createElementForAttribute(elementPath []PathSegment, attrSe
 		return fmt.Errorf("%w: failed to locate created element for attribute", ErrInvalidPath)
 	}
 
-	// Step 5: Set the attribute on the created element
-	return b.replaceAttribute(location, attrSeg.Value, attrValue)
+	// Step 5: Replace the new, empty element with a self-closing tag
+	// carrying the attribute (attrValue is already escaped). Attributes
+	// written in step 1 (namespace declarations) are kept.
+	b.result.Reset()
+	b.result.Write(b.data[:location.startPos])
+	b.result.WriteString("<")
+	b.result.WriteString(location.elementName)
+	b.writeDeclarations()
+	attrNames := make([]string, 0, len(location.attrs))
+	for name := range location.attrs {
+		attrNames = append(attrNames, name)
+	}
+	sort.Strings(attrNames)
+	for _, name := range attrNames {
+		b.result.WriteString(" ")
+		b.result.WriteString(name)
+		b.result.WriteString(`="`)
+		b.result.WriteString(escapeXML(location.attrs[name]))
+		b.result.WriteString(`"`)
+	}
+	b.result.WriteString(" ")
+	b.result.WriteString(attrSeg.Value)
+	b.result.WriteString(`="`)
+	b.result.WriteString(attrValue)
+	b.result.WriteString(`"/>`)
+	b.result.Write(b.data[elementEnd(location):])
+	return nil
+}
+
+// checkAppendPath checks the shape of an append path: parent segments, the
+// element to append, and the -1 index.
+func checkAppendPath(path []PathSegment) error {
+	// Path structure: parent segments + element segment + index segment
+	// Example: "items.item.-1" → ["items", "item", "-1"]
+	if len(path) < 2 {
+		return fmt.Errorf("%w: append requires at least element.-1", ErrInvalidPath)
+	}
+
+	// Validate last segment is Index with -1
+	lastSeg := path[len(path)-1]
+	if lastSeg.Type != SegmentIndex || lastSeg.Index != -1 {
+		return fmt.Errorf("%w: expected -1 index for append operation", ErrInvalidPath)
+	}
+
+	if path[len(path)-2].Type != SegmentElement {
+		return fmt.Errorf("%w: can only append elements, not attributes or other types", ErrInvalidPath)
+	}
+	return nil
 }
 
 // appendElement creates a NEW element at the end of an array.
@@ -709,7 +1257,7 @@ func (b *xmlBuilder) createElementForAttribute(elementPath []PathSegment, attrSe
 // Security: Inherits all limits from createElement (MaxPathSegments, MaxDocumentSize)
 func (b *xmlBuilder) appendElement(path []PathSegment, value interface{}) error {
 	// Convert value to XML
-	xmlValue, isRaw, err := valueToXML(value)
+	xmlValue, isRaw, err := valueToXMLWithOptions(value, b.opts)
 	if err != nil {
 		return err
 	}
@@ -719,26 +1267,14 @@ func (b *xmlBuilder) appendElement(path []PathSegment, value interface{}) error
 		return fmt.Errorf("%w: value exceeds maximum size", ErrInvalidValue)
 	}
 
-	// Path structure: parent segments + element segment + index segment
-	// Example: "items.item.-1" → ["items", "item", "-1"]
-	if len(path) < 2 {
-		return fmt.Errorf("%w: append requires at least element.-1", ErrInvalidPath)
-	}
-
-	// Validate last segment is Index with -1
-	lastSeg := path[len(path)-1]
-	if lastSeg.Type != SegmentIndex || lastSeg.Index != -1 {
-		return fmt.Errorf("%w: expected -1 index for append operation", ErrInvalidPath)
+	if err := checkAppendPath(path); err != nil {
+		return err
 	}
 
 	// Split into parent path and element name
 	parentPath := path[:len(path)-2] // All segments before the element name
 	elementSeg := path[len(path)-2]  // The element to append
 
-	if elementSeg.Type != SegmentElement {
-		return fmt.Errorf("%w: can only append elements, not attributes or other types", ErrInvalidPath)
-	}
-
 	// Find parent element location
 	parser := newXMLParser(b.data)
 	var parentLoc *elementLocation
@@ -789,13 +1325,7 @@ func (b *xmlBuilder) appendElement(path []PathSegment, value interface{}) error
 		b.result.WriteString(">")
 
 		// Add new element
-		b.result.WriteString("<")
-		b.result.WriteString(elementSeg.Value)
-		b.result.WriteString(">")
-		b.result.WriteString(xmlValue)
-		b.result.WriteString("</")
-		b.result.WriteString(elementSeg.Value)
-		b.result.WriteString(">")
+		b.writeIntoEmpty(parentLoc.startPos, len(parentPath)-1, []PathSegment{elementSeg}, xmlValue, isRaw)
 
 		// Close parent
 		b.result.WriteString("</")
@@ -813,6 +1343,13 @@ func (b *xmlBuilder) appendElement(path []PathSegment, value interface{}) error
 
 	// Build result XML
 	b.result.Reset()
+	if layout, ok := b.inferLayout(insertPos, parentLoc, len(parentPath)-1); ok {
+		b.writeLaidOut(layout, []PathSegment{elementSeg}, xmlValue)
+		if b.result.Len() > MaxDocumentSize {
+			return fmt.Errorf("%w: resulting document exceeds maximum size", ErrInvalidValue)
+		}
+		return nil
+	}
 	b.result.Write(b.data[:insertPos])
 
 	// Build new element with proper indentation
@@ -832,12 +1369,9 @@ func (b *xmlBuilder) appendElement(path []PathSegment, value interface{}) error
 	// Write new element
 	b.result.WriteString("<")
 	b.result.WriteString(elementSeg.Value)
-	b.result.WriteString(">")
+	b.writeDeclarations()
 	// xmlValue already properly escaped by valueToXML (or raw if isRaw flag was set)
-	b.result.WriteString(xmlValue)
-	b.result.WriteString("</")
-	b.result.WriteString(elementSeg.Value)
-	b.result.WriteString(">")
+	b.closeElement(elementSeg.Value, xmlValue)
 
 	// Write rest of document
 	b.result.Write(b.data[insertPos:])
@@ -863,11 +1397,8 @@ func (b *xmlBuilder) appendRootElement(elementSeg PathSegment, xmlValue string,
 		b.result.Reset()
 		b.result.WriteString("<")
 		b.result.WriteString(elementSeg.Value)
-		b.result.WriteString(">")
-		b.result.WriteString(xmlValue)
-		b.result.WriteString("</")
-		b.result.WriteString(elementSeg.Value)
-		b.result.WriteString(">")
+		b.writeDeclarations()
+		b.closeElement(elementSeg.Value, xmlValue)
 		return nil
 	}
 
@@ -900,11 +1431,8 @@ func (b *xmlBuilder) appendRootElement(elementSeg PathSegment, xmlValue string,
 	b.result.Write(b.data[:insertPos])
 	b.result.WriteString("<")
 	b.result.WriteString(elementSeg.Value)
-	b.result.WriteString(">")
-	b.result.WriteString(xmlValue)
-	b.result.WriteString("</")
-	b.result.WriteString(elementSeg.Value)
-	b.result.WriteString(">")
+	b.writeDeclarations()
+	b.closeElement(elementSeg.Value, xmlValue)
 	b.result.Write(b.data[insertPos:])
 
 	// Security check: validate final document size doesn't exceed limit
@@ -983,20 +1511,18 @@ func (b *xmlBuilder) buildElementPathWithDepth(path []PathSegment, xmlValue stri
 
 		b.result.WriteString("<")
 		b.result.WriteString(seg.Value)
-		b.result.WriteString(">")
+		b.writeDeclarations()
 
 		if i == len(path)-1 {
-			// Last element - add the value
-			if isRaw {
-				b.result.WriteString(xmlValue)
-			} else {
-				b.result.WriteString(xmlValue)
-			}
+			// Last element - add the value and close it
+			b.closeElement(seg.Value, xmlValue)
+		} else {
+			b.result.WriteString(">")
 		}
 	}
 
-	// Close all elements in reverse order
-	for i := len(path) - 1; i >= 0; i-- {
+	// Close the enclosing elements in reverse order
+	for i := len(path) - 2; i >= 0; i-- {
 		if path[i].Type == SegmentAttribute {
 			continue
 		}
@@ -1014,11 +1540,32 @@ func (b *xmlBuilder) buildElementPathWithDepth(path []PathSegment, xmlValue stri
 	}
 }
 
-// deleteElement removes an element or attribute at the specified path
-func (b *xmlBuilder) deleteElement(path []PathSegment) error {
+// checkDelete makes the checks of deleteElement that need only the path.
+func checkDelete(path []PathSegment) error {
 	if len(path) == 0 {
 		return ErrInvalidPath
 	}
+	if hasRootSegment(path) {
+		return fmt.Errorf("%w: #root is read-only", ErrInvalidPath)
+	}
+	if positionalAttribute(path) >= 0 {
+		return fmt.Errorf("%w: positional attributes are read-only", ErrInvalidPath)
+	}
+	if len(path) < 2 && path[0].Type == SegmentAttribute {
+		return ErrInvalidPath
+	}
+	return nil
+}
+
+// deleteElement removes an element or attribute at the specified path
+func (b *xmlBuilder) deleteElement(path []PathSegment) error {
+	if err := checkDelete(path); err != nil {
+		return err
+	}
+	if isFanOutPath(path) {
+		_, err := b.applyFanOut(path, nil)
+		return err
+	}
 
 	// Security check
 	if len(b.data) > MaxDocumentSize {
@@ -1029,9 +1576,6 @@ func (b *xmlBuilder) deleteElement(path []PathSegment) error {
 	lastSeg := path[len(path)-1]
 	if lastSeg.Type == SegmentAttribute {
 		// Find the parent element
-		if len(path) < 2 {
-			return ErrInvalidPath
-		}
 		parentPath := path[:len(path)-1]
 		parser := newXMLParser(b.data)
 		location, found := b.findElementLocation(parser, parentPath, 0, 0)
@@ -1105,9 +1649,9 @@ func (b *xmlBuilder) deleteAttribute(location *elementLocation, attrName string)
 	b.result.WriteString("<")
 	b.result.WriteString(location.elementName)
 
-	// Sort attribute names for deterministic output
+	// The remaining attributes keep their order
 	attrNames := make([]string, 0, len(location.attrs))
-	for name := range location.attrs {
+	for _, name := range b.attributeNames(location, len(location.attrs)) {
 		// Skip the attribute to be deleted (case-sensitive or insensitive)
 		shouldSkip := false
 		if b.opts.CaseSensitive {
@@ -1119,8 +1663,9 @@ func (b *xmlBuilder) deleteAttribute(location *elementLocation, attrName string)
 			attrNames = append(attrNames, name)
 		}
 	}
-
-	sort.Strings(attrNames)
+	if b.opts.SortAttributes {
+		sort.Strings(attrNames)
+	}
 
 	// Copy all attributes except the one being deleted
 	for _, name := range attrNames {
@@ -1143,10 +1688,1380 @@ func (b *xmlBuilder) deleteAttribute(location *elementLocation, attrName string)
 	return nil
 }
 
-// getResult returns the built XML string
+// attributeNames returns the names of location's attributes in document
+// order, in a new slice with room for capacity names.
+func (b *xmlBuilder) attributeNames(location *elementLocation, capacity int) []string {
+	names := make([]string, 0, capacity)
+	if len(location.attrOrder) == len(location.attrs) {
+		return append(names, location.attrOrder...)
+	}
+	// Order unknown: fall back to sorted names for deterministic output
+	for name := range location.attrs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// sortedStartTag rewrites the start tag tag ("<name ...>" or "<name .../>")
+// with its attributes in sorted order, for Options.SortAttributes. Values are
+// re-escaped and double-quoted.
+func sortedStartTag(tag []byte) string {
+	parser := newXMLParser(tag)
+	parser.next() // skip '<'
+	name, attrs, order, isSelfClosing := parser.parseElementTag()
+	if sort.StringsAreSorted(order) {
+		return string(tag)
+	}
+
+	names := append([]string(nil), order...)
+	sort.Strings(names)
+	var sb strings.Builder
+	sb.WriteString("<")
+	sb.WriteString(name)
+	for _, attrName := range names {
+		sb.WriteString(" ")
+		sb.WriteString(attrName)
+		sb.WriteString("=\"")
+		sb.WriteString(escapeXML(attrs[attrName]))
+		sb.WriteString("\"")
+	}
+	if isSelfClosing {
+		sb.WriteString("/>")
+	} else {
+		sb.WriteString(">")
+	}
+	return sb.String()
+}
+
+// buildElementMarkup renders a complete element with escaped attribute values
+// and text content. Attributes are written in sorted order for deterministic
+// output, matching replaceAttribute.
+func buildElementMarkup(name string, attrs map[string]string, text string) string {
+	var sb strings.Builder
+	sb.WriteString("<")
+	sb.WriteString(name)
+
+	attrNames := make([]string, 0, len(attrs))
+	for attrName := range attrs {
+		attrNames = append(attrNames, attrName)
+	}
+	sort.Strings(attrNames)
+
+	for _, attrName := range attrNames {
+		sb.WriteString(" ")
+		sb.WriteString(attrName)
+		sb.WriteString(`="`)
+		sb.WriteString(escapeXML(attrs[attrName]))
+		sb.WriteString(`"`)
+	}
+
+	sb.WriteString(">")
+	sb.WriteString(escapeXML(text))
+	sb.WriteString("</")
+	sb.WriteString(name)
+	sb.WriteString(">")
+	return sb.String()
+}
+
+// setChildElement replaces the first child element matching name under the
+// element at parentPath with markup, or appends markup as the last child of
+// the parent when no such child exists. Missing parents are created.
+func (b *xmlBuilder) setChildElement(parentPath []PathSegment, name string, markup string) error {
+	// IMPORTANT: Copy path to avoid mutating cached paths
+	targetPath := make([]PathSegment, len(parentPath), len(parentPath)+1)
+	copy(targetPath, parentPath)
+	targetPath = append(targetPath, PathSegment{Type: SegmentElement, Value: name})
+
+	// Replace the whole element (opening tag, content and closing tag)
+	parser := newXMLParser(b.data)
+	if location, found := b.findElementLocation(parser, targetPath, 0, 0); found {
+		elementEnd := location.contentStart
+		if !location.isSelfClosing {
+			elementEnd = location.endTagPos + len(location.elementName) + 3 // </name>
+		}
+
+		b.result.Reset()
+		b.result.Write(b.data[:location.startPos])
+		b.result.WriteString(markup)
+		b.result.Write(b.data[elementEnd:])
+		return nil
+	}
+
+	// Parent doesn't exist - create the chain with the element as content
+	parser = newXMLParser(b.data)
+	parentLocation, found := b.findElementLocation(parser, parentPath, 0, 0)
+	if !found {
+		return b.createElement(parentPath, markup, true)
+	}
+
+	b.result.Reset()
+	if parentLocation.isSelfClosing {
+		// Expand self-closing parent: <parent/> -> <parent>markup</parent>
+		// contentStart is just after "/>", so strip it to reuse the opening tag
+		b.result.Write(b.data[:parentLocation.contentStart-2])
+		b.result.WriteString(">")
+		b.result.WriteString(markup)
+		b.result.WriteString("</")
+		b.result.WriteString(parentLocation.elementName)
+		b.result.WriteString(">")
+		b.result.Write(b.data[parentLocation.contentStart:])
+		return nil
+	}
+
+	// Append as last child of the parent
+	b.result.Write(b.data[:parentLocation.contentEnd])
+	b.result.WriteString(markup)
+	b.result.Write(b.data[parentLocation.contentEnd:])
+	return nil
+}
+
+// prependChild inserts markup as the first child of the element at
+// parentPath. When the parent's content starts with a line break and
+// indentation before its first child, markup is written on that line and the
+// child moves to a new line with the same indentation. Missing parents are
+// created.
+func (b *xmlBuilder) prependChild(parentPath []PathSegment, markup string) error {
+	parser := newXMLParser(b.data)
+	location, found := b.findElementLocation(parser, parentPath, 0, 0)
+	if !found {
+		return b.createElement(parentPath, markup, true)
+	}
+
+	b.result.Reset()
+	if location.isSelfClosing {
+		// <parent/> -> <parent>markup</parent>
+		b.result.Write(b.data[:location.contentStart-2])
+		b.result.WriteString(">")
+		b.result.WriteString(markup)
+		b.result.WriteString("</")
+		b.result.WriteString(location.elementName)
+		b.result.WriteString(">")
+		b.result.Write(b.data[location.contentStart:])
+		return nil
+	}
+
+	pos := location.contentStart
+	for pos < location.contentEnd && isWhitespace(b.data[pos]) {
+		pos++
+	}
+	leading := b.data[location.contentStart:pos]
+	if pos == location.contentEnd || !bytes.Contains(leading, []byte("\n")) {
+		// No indented first child to line up with
+		pos, leading = location.contentStart, nil
+	}
+
+	b.result.Write(b.data[:pos])
+	b.result.WriteString(markup)
+	b.result.Write(leading)
+	b.result.Write(b.data[pos:])
+	return nil
+}
+
+// isFanOutPath reports whether a write path addresses every match of a
+// wildcard (*), field extraction (#.child) or filter (#(cond) / #(cond)#)
+// segment rather than a single element. Paths with a recursive wildcard (**)
+// keep their single-target behavior.
+func isFanOutPath(path []PathSegment) bool {
+	fanOut := false
+	for _, seg := range path {
+		switch seg.Type {
+		case SegmentWildcard:
+			if seg.Wildcard {
+				return false
+			}
+			fanOut = true
+		case SegmentFieldExtraction, SegmentFilter:
+			fanOut = true
+		}
+	}
+	return fanOut
+}
+
+// fanOutTarget is an element selected by the fan-out part of a write path.
+type fanOutTarget struct {
+	start int    // Position of '<' in the opening tag
+	end   int    // Position just after the element
+	name  string // Element name
+}
+
+// applyFanOut applies a write to every element selected by the fan-out part
+// of path (up to and including its last wildcard, #.child or filter segment).
+// The remainder of the path is applied to each selected element as a regular
+// Set, so it updates existing nodes and creates missing ones per element;
+// fan-out segments themselves never create elements. A nil value deletes:
+// the remainder in each selected element, or, without a remainder, the
+// selected elements themselves.
+//
+// A * wildcard selects children of any name, so the elements of the
+// remainder below it must already exist in every match: creating them would
+// write into unrelated elements (catalog.product.*.currency would add a
+// currency to the product's name). Such a write returns ErrInvalidPath;
+// name.#.child creates a child in every name element. Attributes are still
+// created on existing elements, so root.*.@id marks every child.
+//
+// Each selected element is rewritten independently and the results are
+// spliced into the document in a single pass. Returns the number of selected
+// elements that changed.
+func (b *xmlBuilder) applyFanOut(path []PathSegment, value interface{}) (int, error) {
+	fanOut, err := b.fanOutTargets(path, value)
+	if err != nil {
+		return 0, err
+	}
+
+	changed := 0
+	b.result.Reset()
+	prev := 0
+	for _, target := range fanOut.targets {
+		original := b.data[target.start:target.end]
+		subPath := fanOut.subPath(target)
+
+		var updated string
+		sub := newXMLBuilderWithOptions(original, b.opts)
+		switch {
+		case value == nil && len(fanOut.rest) == 0:
+			// The selected element itself is deleted
+		case value == nil:
+			err = sub.deleteElement(subPath)
+			updated = sub.getResult()
+		case fanOut.updateOnly && !sub.elementExists(subPath):
+			return 0, fanOut.missingError(target)
+		case sub.attributeEquals(subPath, value):
+			updated = string(original)
+		default:
+			err = sub.setElement(subPath, value)
+			updated = sub.getResult()
+		}
+		if err != nil {
+			return 0, err
+		}
+
+		if updated != string(original) {
+			changed++
+		}
+		b.result.Write(b.data[prev:target.start])
+		b.result.WriteString(updated)
+		prev = target.end
+	}
+	b.result.Write(b.data[prev:])
+
+	if b.result.Len() > MaxDocumentSize {
+		return 0, fmt.Errorf("%w: resulting document exceeds maximum size", ErrInvalidValue)
+	}
+	return changed, nil
+}
+
+// fanOutWrite holds the elements a fan-out write path selects, and the rest
+// of the path to apply to each of them.
+type fanOutWrite struct {
+	targets    []fanOutTarget
+	rest       []PathSegment
+	updateOnly bool // * selected the targets, so rest must already exist
+}
+
+// fanOutTargets collects the elements path selects for a write of value
+// (nil to delete), as applyFanOut and checkFanOut see them.
+func (b *xmlBuilder) fanOutTargets(path []PathSegment, value interface{}) (fanOutWrite, error) {
+	if len(b.data) > MaxDocumentSize {
+		return fanOutWrite{}, ErrMalformedXML
+	}
+
+	segments, lastFanOut, err := normalizeFanOutPath(path)
+	if err != nil {
+		return fanOutWrite{}, err
+	}
+	rest := segments[lastFanOut+1:]
+	fanOut := fanOutWrite{
+		rest: rest,
+		updateOnly: segments[lastFanOut].Type == SegmentWildcard && value != nil &&
+			len(rest) > 0 && rest[0].Type != SegmentAttribute,
+	}
+	if err := b.collectFanOutTargets(b.data, 0, segments, 0, lastFanOut, &fanOut.targets); err != nil {
+		return fanOutWrite{}, err
+	}
+	return fanOut, nil
+}
+
+// subPath returns the path to write within target: its own name followed
+// by the rest of the fan-out path.
+func (w fanOutWrite) subPath(target fanOutTarget) []PathSegment {
+	// IMPORTANT: Build a fresh path to avoid mutating cached paths
+	subPath := make([]PathSegment, 0, len(w.rest)+1)
+	subPath = append(subPath, PathSegment{Type: SegmentElement, Value: target.name})
+	return append(subPath, w.rest...)
+}
+
+// missingError is the error for a * match that lacks the rest of the path.
+func (w fanOutWrite) missingError(target fanOutTarget) error {
+	return fmt.Errorf("%w: <%s> matched by * lacks the rest of the path; use name.#.child to create it in every match",
+		ErrInvalidPath, target.name)
+}
+
+// checkFanOut makes the checks of applyFanOut without writing: the path
+// must select its elements within the limits, and the rest of the path must
+// be writable in each of them.
+func (b *xmlBuilder) checkFanOut(path []PathSegment, value interface{}) error {
+	fanOut, err := b.fanOutTargets(path, value)
+	if err != nil {
+		return err
+	}
+	for _, target := range fanOut.targets {
+		subPath := fanOut.subPath(target)
+		sub := newXMLBuilderWithOptions(b.data[target.start:target.end], b.opts)
+		switch {
+		case value == nil:
+			err = checkDelete(subPath)
+		case fanOut.updateOnly && !sub.elementExists(subPath):
+			err = fanOut.missingError(target)
+		default:
+			var plan setPlan
+			if plan, err = sub.checkSet(subPath, value); err == nil {
+				err = sub.checkSetTargets(plan, value)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkSetTargets makes the checks of applySet that depend on the document,
+// without writing: an index must not leave a gap after the existing
+// siblings, and a fan-out path must be writable in every element it selects.
+func (b *xmlBuilder) checkSetTargets(plan setPlan, value interface{}) error {
+	if plan.fanOut {
+		return b.checkFanOut(plan.path, value)
+	}
+	if plan.appends {
+		return nil
+	}
+	i, err := b.missingIndex(plan.path)
+	if err != nil || i < 0 {
+		return err
+	}
+	// Below the element created at i there are no siblings yet
+	for j := i + 1; j < len(plan.path); j++ {
+		if seg := plan.path[j]; seg.Type == SegmentIndex && seg.Index > 0 && plan.path[j-1].Type == SegmentElement {
+			return indexPastEndError(seg, 0, plan.path[j-1].Value)
+		}
+	}
+	return nil
+}
+
+// elementExists reports whether the element path addresses (its parent, for
+// an attribute path) exists.
+func (b *xmlBuilder) elementExists(path []PathSegment) bool {
+	if path[len(path)-1].Type == SegmentAttribute {
+		path = path[:len(path)-1]
+	}
+	_, found := b.findElementLocation(newXMLParser(b.data), path, 0, 0)
+	return found
+}
+
+// attributeEquals reports whether path addresses an existing attribute whose
+// value already equals value, so that setting it would only re-serialize the tag.
+func (b *xmlBuilder) attributeEquals(path []PathSegment, value interface{}) bool {
+	if len(path) < 2 || path[len(path)-1].Type != SegmentAttribute {
+		return false
+	}
+	xmlValue, _, err := valueToXMLWithOptions(value, b.opts)
+	if err != nil {
+		return false
+	}
+	location, found := b.findElementLocation(newXMLParser(b.data), path[:len(path)-1], 0, 0)
+	if !found {
+		return false
+	}
+	current, ok := location.attrs[path[len(path)-1].Value]
+	return ok && escapeXML(current) == xmlValue
+}
+
+// normalizeFanOutPath rewrites #.child and #.@attr field extraction segments
+// into a # (all matches of the preceding element) followed by the child
+// segment, and returns the index of the last fan-out segment.
+func normalizeFanOutPath(path []PathSegment) ([]PathSegment, int, error) {
+	segments := make([]PathSegment, 0, len(path)+1)
+	for _, seg := range path {
+		if seg.Type != SegmentFieldExtraction {
+			segments = append(segments, seg)
+			continue
+		}
+		if seg.Field == "%" {
+			return nil, 0, fmt.Errorf("%w: #.%% cannot be used in write paths", ErrInvalidPath)
+		}
+		segments = append(segments, PathSegment{Type: SegmentCount})
+		switch {
+		case strings.HasPrefix(seg.Field, "@"):
+			segments = append(segments, PathSegment{Type: SegmentAttribute, Value: seg.Field[1:], Modifiers: seg.Modifiers})
+		default:
+			segments = append(segments, PathSegment{Type: SegmentElement, Value: seg.Field, Modifiers: seg.Modifiers})
+		}
+	}
+
+	lastFanOut := -1
+	for i, seg := range segments {
+		if len(seg.Modifiers) > 0 {
+			return nil, 0, fmt.Errorf("%w: write paths cannot contain modifiers", ErrInvalidPath)
+		}
+		prevIsElement := i > 0 && (segments[i-1].Type == SegmentElement || segments[i-1].Type == SegmentWildcard)
+		switch seg.Type {
+		case SegmentWildcard:
+			lastFanOut = i
+		case SegmentFilter:
+			if !prevIsElement || seg.Filter == nil {
+				return nil, 0, fmt.Errorf("%w: filter must follow an element", ErrInvalidPath)
+			}
+			lastFanOut = i
+		case SegmentCount:
+			if !prevIsElement || i == len(segments)-1 {
+				return nil, 0, fmt.Errorf("%w: # must follow an element and precede a child", ErrInvalidPath)
+			}
+			lastFanOut = i
+		}
+	}
+	if lastFanOut < 0 {
+		return nil, 0, ErrInvalidPath
+	}
+	for _, seg := range segments[:lastFanOut] {
+		if seg.Type != SegmentElement && seg.Type != SegmentIndex && seg.Type != SegmentWildcard &&
+			seg.Type != SegmentFilter && seg.Type != SegmentCount {
+			return nil, 0, fmt.Errorf("%w: unsupported segment before a fan-out segment", ErrInvalidPath)
+		}
+	}
+	return segments, lastFanOut, nil
+}
+
+// collectFanOutTargets walks data (located at baseOffset in the document)
+// along segments and records every element selected once segment lastFanOut
+// has been consumed. Plain element segments select the first match, as in Get.
+//
+// Security: At most MaxWildcardResults targets are collected. A further
+// target returns ErrLimitExceeded, so that a write never silently updates
+// only part of its matches; targets then holds the first MaxWildcardResults.
+func (b *xmlBuilder) collectFanOutTargets(data []byte, baseOffset int, segments []PathSegment, segIndex, lastFanOut int, targets *[]fanOutTarget) error {
+	seg := segments[segIndex]
+	var next *PathSegment
+	if segIndex+1 < len(segments) {
+		next = &segments[segIndex+1]
+	}
+
+	// Determine how many segments this step consumes and how candidates are chosen
+	consumed := 1
+	selectAll := seg.Type == SegmentWildcard
+	index := 0
+	var filter *PathSegment
+	if next != nil {
+		switch next.Type {
+		case SegmentIndex:
+			consumed, selectAll, index = 2, false, next.Index
+		case SegmentCount:
+			consumed, selectAll = 2, true
+		case SegmentFilter:
+			consumed, filter = 2, next
+			selectAll = next.FilterAll
+		}
+	}
+	if index < 0 {
+		return nil
+	}
+
+	parser := newXMLParser(data)
+	matchCount := 0
+	siblings := 0
+	filterHits := 0
+	for parser.skipToNextElement() {
+		start := parser.pos
+		parser.next() // skip '<'
+		name, attrs, _, isSelfClosing := parser.parseElementTag()
+		contentStart := parser.pos
+		var content string
+		if !isSelfClosing {
+			content = parser.parseElementContent(name)
+		}
+		end := parser.pos
+
+		if !seg.matchesWithOptions(name, b.opts) {
+			continue
+		}
+		position := siblings
+		siblings++
+		if filter != nil && !evaluateFilterOnMatch(filter.Filter, elementMatch{name: name, attrs: attrs, content: content}, position) {
+			continue
+		}
+		if filter != nil && filter.Limit > 0 {
+			if filterHits == filter.Limit {
+				return nil
+			}
+			filterHits++
+		}
+		if !selectAll && filter == nil && matchCount < index {
+			matchCount++
+			continue
+		}
+
+		if segIndex+consumed > lastFanOut {
+			if len(*targets) >= MaxWildcardResults {
+				return fmt.Errorf("%w: write matches more than %d elements", ErrLimitExceeded, MaxWildcardResults)
+			}
+			*targets = append(*targets, fanOutTarget{start: baseOffset + start, end: baseOffset + end, name: name})
+		} else if !isSelfClosing {
+			if err := b.collectFanOutTargets(rawElementContent(parser, contentStart), baseOffset+contentStart, segments, segIndex+consumed, lastFanOut, targets); err != nil {
+				return err
+			}
+		}
+
+		if !selectAll {
+			return nil
+		}
+	}
+	return nil
+}
+
+// transplantElement copies the whole element at from (tags, attributes and
+// children) to to, removing the original unless keepSource is set. Both paths
+// are resolved against the unmodified document:
+//   - to without a trailing index replaces the element at to, or creates it
+//     (and any missing parents)
+//   - to ending in a non-negative index inserts before the element currently
+//     at that index, or follows Set's index rules if there is none
+//   - to ending in -1 appends after the last sibling of that name
+//
+// The element is renamed when the final name in to does not match it, and
+// its continuation lines are re-indented to the destination's indentation.
+//
+// The source and destination are tracked by byte offset: the document is
+// edited once at the destination, and the source's offsets are then moved
+// past that edit to remove it.
+func (b *xmlBuilder) transplantElement(from, to []PathSegment, keepSource bool) error {
+	if len(b.data) > MaxDocumentSize {
+		return ErrMalformedXML
+	}
+
+	parser := newXMLParser(b.data)
+	source, found := b.findElementLocation(parser, from, 0, 0)
+	if !found {
+		return fmt.Errorf("%w: source element not found", ErrInvalidPath)
+	}
+	sourceStart, sourceEnd := source.startPos, elementEnd(source)
+	sourceIndent, sourceOnOwnLine := lineIndent(b.data, sourceStart)
+
+	// Rename the element if the destination name doesn't match it
+	name := source.elementName
+	last := to[len(to)-1]
+	nameSeg := last
+	if last.Type == SegmentIndex {
+		nameSeg = to[len(to)-2]
+	}
+	if !nameSeg.matchesWithOptions(name, b.opts) {
+		name = nameSeg.Value
+	}
+	markup := renameElementMarkup(string(b.data[sourceStart:sourceEnd]), source.elementName, name)
+
+	// Write the element at the destination. base is the document the edit
+	// is made on: base[:keepBefore] and base[keepAfter:] survive unchanged in
+	// doc, so source offsets outside that window can be carried over.
+	base := string(b.data)
+	var doc string
+	var keepBefore, keepAfter int
+	var dest *elementLocation
+	if last.Type != SegmentIndex || last.Index >= 0 {
+		dest, found = b.findElementLocation(newXMLParser(b.data), to, 0, 0)
+	}
+	if found && dest != nil {
+		destIndent, destOnOwnLine := lineIndent(b.data, dest.startPos)
+		if sourceOnOwnLine && destOnOwnLine {
+			markup = reindentMarkup(markup, sourceIndent, destIndent)
+		}
+		keepBefore = dest.startPos
+		if last.Type == SegmentIndex {
+			// Insert before the element currently at the index, keeping its
+			// leading whitespace for the inserted element
+			ws := dest.startPos
+			for ws > 0 && isWhitespace(base[ws-1]) {
+				ws--
+			}
+			keepAfter = ws
+		} else {
+			keepAfter = elementEnd(dest)
+		}
+		doc = base[:keepBefore] + markup + base[keepAfter:]
+	} else {
+		// Create an empty destination element, then swap in the markup
+		created, start, end, err := b.createTransplantDestination(to)
+		if err != nil {
+			return err
+		}
+		if !keepSource {
+			// Creating the destination only inserts markup after existing
+			// elements, so the source is found again at its path
+			source, found = b.findElementLocation(newXMLParser([]byte(created)), from, 0, 0)
+			if !found {
+				return fmt.Errorf("%w: source element not found", ErrInvalidPath)
+			}
+			sourceStart, sourceEnd = source.startPos, elementEnd(source)
+		}
+		base = created
+		keepBefore, keepAfter = start, end
+		if indent, ownLine := lineIndent([]byte(created), start); ownLine && start > 0 {
+			// The new element was already lined up with its siblings
+			if sourceOnOwnLine {
+				markup = reindentMarkup(markup, sourceIndent, indent)
+			}
+			doc = created[:start] + markup + created[end:]
+		} else if indent, ok := siblingIndent(created, start); ok {
+			// Put the element on its own line, aligned with its previous sibling
+			ws := start
+			for ws > 0 && isWhitespace(created[ws-1]) {
+				ws--
+			}
+			if sourceOnOwnLine {
+				markup = reindentMarkup(markup, sourceIndent, indent)
+			}
+			keepBefore = ws
+			doc = created[:ws] + "\n" + indent + markup + created[ws:start] + created[end:]
+		} else {
+			doc = created[:start] + markup + created[end:]
+		}
+	}
+
+	if !keepSource {
+		switch {
+		case sourceEnd <= keepBefore:
+			// The source precedes the destination and kept its offsets
+		case sourceStart >= keepAfter:
+			// The source follows the destination and moved with the edit
+			shift := len(doc) - len(base)
+			sourceStart += shift
+			sourceEnd += shift
+		case dest != nil && last.Type != SegmentIndex && sourceStart >= dest.startPos && sourceEnd <= elementEnd(dest):
+			// The destination replaced the source itself (or an element
+			// containing it), so there is nothing left to remove
+			sourceStart = -1
+		default:
+			return fmt.Errorf("%w: cannot move an element into itself", ErrInvalidPath)
+		}
+
+		if sourceStart >= 0 {
+			// Drop the line the source occupied if nothing else is on it
+			start, end := sourceStart, sourceEnd
+			rest := strings.TrimLeft(doc[end:], " \t")
+			restOfLineEmpty := rest == "" || rest[0] == '\n' || rest[0] == '\r'
+			if _, ownLine := lineIndent([]byte(doc), start); ownLine && restOfLineEmpty {
+				if nl := strings.LastIndexByte(doc[:start], '\n'); nl >= 0 {
+					start = nl
+				}
+			}
+			doc = doc[:start] + doc[end:]
+		}
+	}
+
+	if len(doc) > MaxDocumentSize {
+		return fmt.Errorf("%w: resulting document exceeds maximum size", ErrInvalidValue)
+	}
+	if !ValidBytes([]byte(doc)) {
+		return ErrMalformedXML
+	}
+
+	b.result.Reset()
+	b.result.WriteString(doc)
+	return nil
+}
+
+// createTransplantDestination creates the missing element at to with empty
+// content, following Set's rules, and returns the resulting document with
+// the start and end offsets of the new element. An element created by a
+// trailing index is the last sibling of its name; otherwise it is the first
+// match of to, since it did not exist before.
+func (b *xmlBuilder) createTransplantDestination(to []PathSegment) (string, int, int, error) {
+	sub := newXMLBuilderWithOptions(b.data, b.opts)
+	if err := sub.setElement(to, ""); err != nil {
+		return "", 0, 0, err
+	}
+	created := sub.getResult()
+
+	locate := to
+	if n := len(to); to[n-1].Type == SegmentIndex {
+		count := countSiblings([]byte(created), to[:n-1], b.opts)
+		locate = make([]PathSegment, n)
+		copy(locate, to)
+		locate[n-1] = PathSegment{Type: SegmentIndex, Index: count - 1}
+	}
+	dest, found := b.findElementLocation(newXMLParser([]byte(created)), locate, 0, 0)
+	if !found {
+		return "", 0, 0, fmt.Errorf("%w: failed to create destination", ErrInvalidPath)
+	}
+	return created, dest.startPos, elementEnd(dest), nil
+}
+
+// elementEnd returns the position just after the element's closing tag
+// (or after "/>" for self-closing elements).
+func elementEnd(location *elementLocation) int {
+	if location.isSelfClosing {
+		return location.contentStart
+	}
+	return location.endTagPos + len(location.elementName) + 3 // </name>
+}
+
+// lineIndent returns the whitespace between the start of the line and pos,
+// and whether pos is preceded only by whitespace on its line.
+func lineIndent(data []byte, pos int) (string, bool) {
+	start := pos
+	for start > 0 && (data[start-1] == ' ' || data[start-1] == '\t') {
+		start--
+	}
+	if start > 0 && data[start-1] != '\n' && data[start-1] != '\r' {
+		return "", false
+	}
+	return string(data[start:pos]), true
+}
+
+// siblingIndent returns the indentation of the previous sibling of an element
+// written at pos, if that sibling sits on its own line. It reports false when
+// the element directly follows its parent's opening tag or the document is
+// not formatted line by line.
+func siblingIndent(doc string, pos int) (string, bool) {
+	before := strings.TrimRight(doc[:pos], " \t\r\n")
+	if !strings.HasSuffix(before, ">") {
+		return "", false
+	}
+	lineStart := strings.LastIndexByte(before, '\n') + 1
+	if lineStart == 0 {
+		return "", false
+	}
+	line := before[lineStart:]
+	trimmed := strings.TrimLeft(line, " \t")
+	if !strings.HasPrefix(trimmed, "<") {
+		return "", false
+	}
+	// A line holding only an opening tag belongs to the parent, not a sibling
+	if !strings.HasPrefix(trimmed, "</") && !strings.HasSuffix(trimmed, "/>") && !strings.Contains(trimmed, "</") {
+		return "", false
+	}
+	return line[:len(line)-len(trimmed)], true
+}
+
+// insertLayout describes where a new element is written so that it lines up
+// with its siblings in a document formatted one element per line.
+type insertLayout struct {
+	pos    int    // insert position in b.data
+	indent string // indentation of the new element
+	unit   string // extra indentation per nesting level; "" keeps a chain on one line
+	before string // written before the new element's indentation
+	after  string // written after the new element
+}
+
+// inferLayout works out how to indent an element inserted at pos inside
+// parent, an element depth levels below the root. The indentation is copied
+// from the previous sibling; without one, Options.Indent is added to the
+// indentation of the parent's closing tag, or for an empty parent to that of
+// the parent itself, whose closing tag then moves to a line of its own. It
+// reports false when the surrounding markup is not formatted line by line,
+// so the caller keeps its compact output.
+func (b *xmlBuilder) inferLayout(pos int, parent *elementLocation, depth int) (insertLayout, bool) {
+	contentEnd := parent.contentEnd
+	closeIndent, closeOwnLine := lineIndent(b.data, contentEnd)
+	indent, ok := siblingIndent(string(b.data[:pos]), pos)
+	if !ok {
+		if pos != contentEnd || b.opts.Indent == "" {
+			return insertLayout{}, false
+		}
+		if !closeOwnLine {
+			if parent.contentStart != contentEnd {
+				return insertLayout{}, false
+			}
+			parentIndent := b.elementIndent(parent.startPos, depth)
+			return insertLayout{
+				pos:    pos,
+				indent: parentIndent + b.opts.Indent,
+				unit:   b.opts.Indent,
+				before: "\n",
+				after:  "\n" + parentIndent,
+			}, true
+		}
+		indent = closeIndent + b.opts.Indent
+	}
+
+	unit := b.opts.Indent
+	if unit == "" && closeOwnLine && len(indent) > len(closeIndent) && strings.HasPrefix(indent, closeIndent) {
+		unit = indent[len(closeIndent):]
+	}
+
+	if pos == contentEnd && closeOwnLine {
+		return insertLayout{pos: contentEnd - len(closeIndent), indent: indent, unit: unit, after: "\n"}, true
+	}
+	return insertLayout{pos: pos, indent: indent, unit: unit, before: "\n"}, true
+}
+
+// elementIndent returns the indentation of the element whose tag starts at
+// pos, depth levels below the root: the whitespace before it when the tag
+// starts a line, or else one Options.Indent per level.
+func (b *xmlBuilder) elementIndent(pos, depth int) string {
+	if indent, ok := lineIndent(b.data, pos); ok {
+		return indent
+	}
+	return strings.Repeat(b.opts.Indent, depth)
+}
+
+// writeLaidOut writes b.data with the element chain for path inserted as
+// described by layout.
+func (b *xmlBuilder) writeLaidOut(layout insertLayout, path []PathSegment, xmlValue string) {
+	b.result.Write(b.data[:layout.pos])
+	b.writeChain(layout, path, xmlValue)
+	b.result.Write(b.data[layout.pos:])
+}
+
+// writeIntoEmpty writes the element chain for path as the content of an
+// element that had none, such as an expanded self-closing tag starting at
+// pos, depth levels below the root. With Options.Indent the chain is laid
+// out one element per line, one level deeper than the parent, and the
+// parent's closing tag goes on a line of its own.
+func (b *xmlBuilder) writeIntoEmpty(pos, depth int, path []PathSegment, xmlValue string, isRaw bool) {
+	if b.opts.Indent == "" {
+		b.buildElementPath(path, xmlValue, isRaw)
+		return
+	}
+	parentIndent := b.elementIndent(pos, depth)
+	b.writeChain(insertLayout{
+		indent: parentIndent + b.opts.Indent,
+		unit:   b.opts.Indent,
+		before: "\n",
+		after:  "\n" + parentIndent,
+	}, path, xmlValue)
+}
+
+// writeChain writes the element chain for path with the indentation of
+// layout, ignoring its position. Attribute segments are skipped, as in
+// buildElementPath.
+func (b *xmlBuilder) writeChain(layout insertLayout, path []PathSegment, xmlValue string) {
+	elements := make([]string, 0, len(path))
+	for _, seg := range path {
+		if seg.Type != SegmentAttribute {
+			elements = append(elements, seg.Value)
+		}
+	}
+
+	b.result.WriteString(layout.before)
+	b.result.WriteString(layout.indent)
+	for i, name := range elements {
+		if i > 0 && layout.unit != "" {
+			b.result.WriteString("\n")
+			b.result.WriteString(layout.indent)
+			b.result.WriteString(strings.Repeat(layout.unit, i))
+		}
+		b.result.WriteString("<")
+		b.result.WriteString(name)
+		b.writeDeclarations()
+		if i < len(elements)-1 {
+			b.result.WriteString(">")
+		}
+	}
+	if len(elements) > 0 {
+		b.closeElement(elements[len(elements)-1], xmlValue)
+	} else {
+		b.result.WriteString(xmlValue)
+	}
+	for i := len(elements) - 2; i >= 0; i-- {
+		if layout.unit != "" {
+			b.result.WriteString("\n")
+			b.result.WriteString(layout.indent)
+			b.result.WriteString(strings.Repeat(layout.unit, i))
+		}
+		b.result.WriteString("</")
+		b.result.WriteString(elements[i])
+		b.result.WriteString(">")
+	}
+	b.result.WriteString(layout.after)
+}
+
+// reindentMarkup replaces the indentation prefix from with to on every line
+// of markup after the first.
+func reindentMarkup(markup, from, to string) string {
+	if from == to || !strings.Contains(markup, "\n") {
+		return markup
+	}
+	lines := strings.Split(markup, "\n")
+	for i := 1; i < len(lines); i++ {
+		if strings.HasPrefix(lines[i], from) {
+			lines[i] = to + lines[i][len(from):]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// subtreeNode is an element, comment, processing instruction or other markup
+// in a subtree being indented by indentSubtree. start and end delimit its
+// markup; tagEnd, children and hasText are only set for elements.
+type subtreeNode struct {
+	start, end int
+	tagEnd     int // end of the opening tag
+	children   []*subtreeNode
+	hasText    bool
+}
+
+// indentSubtree pretty-prints the element in markup for PrettySubtree. Each
+// child of an element that holds no text goes on its own line, prefixed with
+// base and one indent per level; everything else is copied verbatim.
+func indentSubtree(markup, base, indent string) string {
+	decoder := xml.NewDecoder(strings.NewReader(markup))
+	decoder.Strict = false
+
+	var root *subtreeNode
+	var open []*subtreeNode
+	for {
+		start := int(decoder.InputOffset())
+		token, err := decoder.RawToken()
+		if err != nil {
+			break
+		}
+		end := int(decoder.InputOffset())
+
+		var parent *subtreeNode
+		if len(open) > 0 {
+			parent = open[len(open)-1]
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			node := &subtreeNode{start: start, tagEnd: end}
+			if parent != nil {
+				parent.children = append(parent.children, node)
+			} else if root == nil {
+				root = node
+			}
+			open = append(open, node)
+		case xml.EndElement:
+			// A self-closing tag yields an end token without consuming input
+			if parent != nil {
+				parent.end = end
+				open = open[:len(open)-1]
+			}
+		case xml.CharData:
+			if parent != nil && len(bytes.TrimSpace(t)) > 0 {
+				parent.hasText = true
+			}
+		default:
+			if parent != nil {
+				parent.children = append(parent.children, &subtreeNode{start: start, end: end})
+			}
+		}
+		if root != nil && len(open) == 0 {
+			break
+		}
+	}
+	if root == nil || root.end == 0 {
+		return markup
+	}
+
+	var sb strings.Builder
+	var write func(node *subtreeNode, depth int)
+	write = func(node *subtreeNode, depth int) {
+		if node.hasText || len(node.children) == 0 {
+			sb.WriteString(markup[node.start:node.end])
+			return
+		}
+		// Opening tag, then each child on its own line
+		sb.WriteString(markup[node.start:node.tagEnd])
+		for _, child := range node.children {
+			sb.WriteString("\n")
+			sb.WriteString(base)
+			sb.WriteString(strings.Repeat(indent, depth+1))
+			write(child, depth+1)
+		}
+		sb.WriteString("\n")
+		sb.WriteString(base)
+		sb.WriteString(strings.Repeat(indent, depth))
+		last := node.children[len(node.children)-1]
+		sb.WriteString(strings.TrimLeft(markup[last.end:node.end], " \t\r\n"))
+	}
+	write(root, 0)
+	return sb.String()
+}
+
+// elementStarts returns the positions of the opening tags of the elements
+// path selects, in document order: every match of a wildcard, filter or
+// trailing # path, or the single element of a plain path (none if it is
+// missing).
+func (b *xmlBuilder) elementStarts(path []PathSegment) ([]int, error) {
+	if len(path) == 0 {
+		return nil, ErrInvalidPath
+	}
+
+	var segments, rest []PathSegment
+	lastFanOut := len(path) - 1
+	switch {
+	case path[lastFanOut].Type == SegmentCount:
+		// name.# selects every sibling called name
+		segments = path
+		for i, seg := range path[:lastFanOut] {
+			if len(seg.Modifiers) > 0 || seg.Type == SegmentAttribute || seg.Type == SegmentText ||
+				seg.Type == SegmentRoot || (seg.Type == SegmentWildcard && seg.Wildcard) {
+				return nil, fmt.Errorf("%w: path must address an element", ErrInvalidPath)
+			}
+			if i == lastFanOut-1 && seg.Type != SegmentElement && seg.Type != SegmentWildcard {
+				return nil, fmt.Errorf("%w: # must follow an element", ErrInvalidPath)
+			}
+		}
+		if len(path[lastFanOut].Modifiers) > 0 || lastFanOut == 0 {
+			return nil, fmt.Errorf("%w: path must address an element", ErrInvalidPath)
+		}
+	case isFanOutPath(path):
+		var err error
+		segments, lastFanOut, err = normalizeFanOutPath(path)
+		if err != nil {
+			return nil, err
+		}
+		rest = segments[lastFanOut+1:]
+		for _, seg := range rest {
+			if seg.Type != SegmentElement && (seg.Type != SegmentIndex || seg.Index < 0) {
+				return nil, fmt.Errorf("%w: path must address an element", ErrInvalidPath)
+			}
+		}
+	default:
+		if err := validateElementPath(path, false); err != nil {
+			return nil, err
+		}
+		location, found := b.findElementLocation(newXMLParser(b.data), path, 0, 0)
+		if !found {
+			return nil, nil
+		}
+		return []int{location.startPos}, nil
+	}
+
+	// Like Get, spans cover at most MaxWildcardResults elements
+	var targets []fanOutTarget
+	_ = b.collectFanOutTargets(b.data, 0, segments, 0, lastFanOut, &targets)
+	starts := make([]int, 0, len(targets))
+	for _, target := range targets {
+		if len(rest) == 0 {
+			starts = append(starts, target.start)
+			continue
+		}
+		subPath := make([]PathSegment, 0, len(rest)+1)
+		subPath = append(subPath, PathSegment{Type: SegmentElement, Value: target.name})
+		subPath = append(subPath, rest...)
+		original := b.data[target.start:target.end]
+		if location, found := b.findElementLocation(newXMLParser(original), subPath, 0, 0); found {
+			starts = append(starts, target.start+location.startPos)
+		}
+	}
+	return starts, nil
+}
+
+// renameAttribute renames attribute oldName to newName in the opening tag of
+// every element path selects and returns how many tags changed. Tags are
+// edited in place, so attribute order, quoting and spacing are kept.
+func (b *xmlBuilder) renameAttribute(path []PathSegment, oldName, newName string) (int, error) {
+	if len(b.data) > MaxDocumentSize {
+		return 0, ErrMalformedXML
+	}
+	starts, err := b.elementStarts(path)
+	if err != nil {
+		return 0, err
+	}
+
+	b.result.Reset()
+	prev, renamed := 0, 0
+	for _, start := range starts {
+		end := start + tagEnd(b.data[start:])
+		if end == start {
+			continue
+		}
+		tag, ok, err := renameAttributeInTag(b.data[start:end], oldName, newName, b.opts)
+		if err != nil {
+			b.result.Reset()
+			return 0, err
+		}
+		if !ok {
+			continue
+		}
+		if b.opts.SortAttributes {
+			tag = sortedStartTag([]byte(tag))
+		}
+		b.result.Write(b.data[prev:start])
+		b.result.WriteString(tag)
+		prev = end
+		renamed++
+	}
+	if renamed == 0 {
+		b.result.Reset()
+		return 0, nil
+	}
+	b.result.Write(b.data[prev:])
+	return renamed, nil
+}
+
+// renameAttributeInTag renames attribute oldName to newName in the opening
+// tag tag. It reports false if the tag has no oldName attribute. If the tag
+// already has a newName attribute, that one is removed when
+// opts.RenameOverwrite is set, and ErrInvalidValue is returned otherwise.
+func renameAttributeInTag(tag []byte, oldName, newName string, opts *Options) (string, bool, error) {
+	// Each attribute spans from the whitespace before its name to the end of its value
+	type attrSpan struct {
+		start, nameStart, nameEnd, end int
+	}
+	isSpace := func(i int) bool { return i < len(tag) && isWhitespace(tag[i]) }
+	isNameEnd := func(c byte) bool { return isWhitespace(c) || c == '=' || c == '/' || c == '>' }
+
+	i := 1
+	for i < len(tag) && !isNameEnd(tag[i]) {
+		i++
+	}
+	elementName := string(tag[1:i])
+
+	var attrs []attrSpan
+	for i < len(tag) {
+		start := i
+		for isSpace(i) {
+			i++
+		}
+		if i >= len(tag) || tag[i] == '/' || tag[i] == '>' {
+			break
+		}
+		nameStart := i
+		for i < len(tag) && !isNameEnd(tag[i]) {
+			i++
+		}
+		nameEnd := i
+		for isSpace(i) {
+			i++
+		}
+		if i < len(tag) && tag[i] == '=' {
+			i++
+			for isSpace(i) {
+				i++
+			}
+			if i < len(tag) && (tag[i] == '"' || tag[i] == '\'') {
+				if q := bytes.IndexByte(tag[i+1:], tag[i]); q >= 0 {
+					i += q + 2
+				} else {
+					i = len(tag)
+				}
+			}
+		}
+		attrs = append(attrs, attrSpan{start: start, nameStart: nameStart, nameEnd: nameEnd, end: i})
+	}
+
+	matches := func(a attrSpan, name string) bool {
+		attrName := string(tag[a.nameStart:a.nameEnd])
+		if opts.CaseSensitive {
+			return attrName == name
+		}
+		return toLowerASCII(attrName) == toLowerASCII(name)
+	}
+
+	old, existing := -1, -1
+	for n, a := range attrs {
+		if old < 0 && matches(a, oldName) {
+			old = n
+		}
+	}
+	if old < 0 || string(tag[attrs[old].nameStart:attrs[old].nameEnd]) == newName {
+		return "", false, nil
+	}
+	for n, a := range attrs {
+		if n != old && matches(a, newName) {
+			existing = n
+			break
+		}
+	}
+	if existing >= 0 && !opts.RenameOverwrite {
+		return "", false, fmt.Errorf("%w: attribute %q already exists on <%s>", ErrInvalidValue, newName, elementName)
+	}
+
+	var sb strings.Builder
+	sb.Grow(len(tag) + len(newName))
+	prev := 0
+	for n, a := range attrs {
+		switch n {
+		case old:
+			sb.Write(tag[prev:a.nameStart])
+			sb.WriteString(newName)
+			prev = a.nameEnd
+		case existing:
+			sb.Write(tag[prev:a.start])
+			prev = a.end
+		}
+	}
+	sb.Write(tag[prev:])
+	return sb.String(), true, nil
+}
+
+// editEach calls fn with every element path selects, in document order, and
+// replaces the element's content with the returned text or removes the
+// element. An element whose returned text equals its current text is left
+// untouched. Returns the number of elements that changed.
+func (b *xmlBuilder) editEach(path []PathSegment, fn func(Result) (string, bool)) (int, error) {
+	if len(b.data) > MaxDocumentSize {
+		return 0, ErrMalformedXML
+	}
+	starts, err := b.elementStarts(path)
+	if err != nil {
+		return 0, err
+	}
+
+	b.result.Reset()
+	prev, changed := 0, 0
+	for _, start := range starts {
+		if start < prev {
+			// Nested in an element that was already edited
+			continue
+		}
+		parser := newXMLParser(b.data)
+		parser.pos = start + 1
+		name, attrs, attrOrder, isSelfClosing := parser.parseElementTag()
+		tag := parser.tag
+		var content string
+		if !isSelfClosing {
+			content = parser.parseElementContent(name)
+		}
+		end := parser.pos
+
+		current := newElementResult(elementMatch{name: name, attrs: attrs, attrOrder: attrOrder, content: content, isSelfClosing: isSelfClosing, tag: tag})
+		value, keep := fn(current)
+		if keep && value == current.Str {
+			continue
+		}
+
+		b.result.Write(b.data[prev:start])
+		if keep {
+			sub := newXMLBuilderWithOptions(b.data[start:end], b.opts)
+			if err := sub.setElement([]PathSegment{{Type: SegmentElement, Value: name}}, value); err != nil {
+				b.result.Reset()
+				return 0, err
+			}
+			b.result.WriteString(sub.getResult())
+		}
+		prev = end
+		changed++
+	}
+	if changed == 0 {
+		b.result.Reset()
+		return 0, nil
+	}
+	b.result.Write(b.data[prev:])
+
+	if strings.TrimSpace(b.result.String()) == "" {
+		b.result.Reset()
+		return 0, fmt.Errorf("%w: cannot remove the root element", ErrInvalidPath)
+	}
+	if b.result.Len() > MaxDocumentSize {
+		b.result.Reset()
+		return 0, fmt.Errorf("%w: resulting document exceeds maximum size", ErrInvalidValue)
+	}
+	return changed, nil
+}
+
+// update passes the current value at path to fn and writes the returned
+// text back. An existing element or attribute is replaced through the
+// location found when reading it; a missing one is created by setElement.
+func (b *xmlBuilder) update(path []PathSegment, fn func(Result) string) error {
+	if hasRootSegment(path) {
+		return fmt.Errorf("%w: #root is read-only", ErrInvalidPath)
+	}
+	if positionalAttribute(path) >= 0 {
+		return fmt.Errorf("%w: positional attributes are read-only", ErrInvalidPath)
+	}
+	last := path[len(path)-1]
+	elementPath := path
+	if last.Type == SegmentAttribute {
+		elementPath = path[:len(path)-1]
+	}
+
+	var location *elementLocation
+	found := false
+	if len(elementPath) > 0 {
+		location, found = b.findElementLocation(newXMLParser(b.data), elementPath, 0, 0)
+	}
+	if !found {
+		return b.setElement(path, fn(Result{Type: Null}))
+	}
+
+	old := Result{Type: Null}
+	tag := b.data[location.startPos:location.contentStart]
+	if last.Type == SegmentAttribute {
+		if value, ok := location.attrs[last.Value]; ok {
+			old = newAttributeResult(tag, last.Value, value)
+		}
+	} else {
+		var content string
+		if !location.isSelfClosing {
+			parser := newXMLParser(b.data)
+			parser.pos = location.contentStart
+			content = parser.parseElementContent(location.elementName)
+		}
+		old = newElementResult(elementMatch{
+			name:          location.elementName,
+			attrs:         location.attrs,
+			attrOrder:     location.attrOrder,
+			content:       content,
+			isSelfClosing: location.isSelfClosing,
+			tag:           tag,
+		})
+	}
+
+	xmlValue := escapeXML(fn(old))
+	if len(xmlValue) > MaxValueSize {
+		return fmt.Errorf("%w: value exceeds maximum size of %d bytes", ErrInvalidValue, MaxValueSize)
+	}
+	if last.Type == SegmentAttribute {
+		return b.replaceAttribute(location, last.Value, xmlValue)
+	}
+	return b.replaceElement(location, last, xmlValue)
+}
+
+// renameElementMarkup renames the outermost element of markup from oldName to newName.
+func renameElementMarkup(markup, oldName, newName string) string {
+	if oldName == newName {
+		return markup
+	}
+	markup = "<" + newName + markup[1+len(oldName):]
+	if closing := "</" + oldName + ">"; strings.HasSuffix(markup, closing) {
+		markup = markup[:len(markup)-len(closing)] + "</" + newName + ">"
+	}
+	return markup
+}
+
+// getResult returns the built XML string
 func (b *xmlBuilder) getResult() string {
+	result := b.result.String()
 	if b.result.Len() == 0 {
-		return string(b.data)
+		result = string(b.data)
+	}
+	if b.opts != nil && b.opts.NormalizeNewlines {
+		result = normalizeNewlines(result)
+	}
+	return result
+}
+
+// normalizeNewlines converts CRLF and lone CR line endings to LF.
+// CDATA sections are copied verbatim since their content is literal.
+func normalizeNewlines(s string) string {
+	if !strings.Contains(s, "\r") {
+		return s
+	}
+
+	var buf strings.Builder
+	buf.Grow(len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] == '<' && strings.HasPrefix(s[i:], "<![CDATA[") {
+			end := strings.Index(s[i:], "]]>")
+			if end < 0 {
+				buf.WriteString(s[i:])
+				break
+			}
+			end += i + 3
+			buf.WriteString(s[i:end])
+			i = end - 1
+			continue
+		}
+		if s[i] == '\r' {
+			buf.WriteByte('\n')
+			if i+1 < len(s) && s[i+1] == '\n' {
+				i++
+			}
+			continue
+		}
+		buf.WriteByte(s[i])
 	}
-	return b.result.String()
+	return buf.String()
 }
@@ -349,7 +349,7 @@ func TestBuilder_ReplaceAttribute(t *testing.T) {
 			path:     "user",
 			attrName: "active",
 			value:    "true",
-			expected: `<user active="true" id="123"><name>John</name></user>`, // Attributes are sorted alphabetically
+			expected: `<user id="123" active="true"><name>John</name></user>`, // New attributes are added last
 		},
 	}
 
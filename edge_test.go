@@ -4,7 +4,9 @@
 package xmldot
 
 import (
+	"errors"
 	"fmt"
+	"math"
 	"strings"
 	"testing"
 	"unicode/utf8"
@@ -284,6 +286,53 @@ func TestEdgeBoundaries_MaxDepth(t *testing.T) {
 	}
 }
 
+// TestEdgeBoundaries_ConfiguredMaxDepth tests a per-call nesting limit above
+// MaxNestingDepth
+func TestEdgeBoundaries_ConfiguredMaxDepth(t *testing.T) {
+	nested := func(depth int) (string, string) {
+		xml := "<root>" + strings.Repeat("<level>", depth) + "value" + strings.Repeat("</level>", depth) + "</root>"
+		return xml, "root" + strings.Repeat(".level", depth)
+	}
+	opts := &Options{CaseSensitive: true, MaxNestingDepth: 500}
+	strict := &Options{CaseSensitive: true, MaxNestingDepth: 500, Strict: true}
+
+	xml, path := nested(300)
+	if got := GetWithOptions(xml, path, opts).String(); got != "value" {
+		t.Errorf("GetWithOptions() at depth 300 = %q, want value", got)
+	}
+	if got := GetWithOptions(xml, "root.**.level.#", opts).Int(); got != 300 {
+		t.Errorf("GetWithOptions(**) count = %d, want 300", got)
+	}
+	result, err := QueryWithOptions(xml, path, strict)
+	if err != nil || result.String() != "value" {
+		t.Errorf("QueryWithOptions(Strict) at depth 300 = %q, %v", result.String(), err)
+	}
+	if err := ValidWithOptions(xml, opts); err != nil {
+		t.Errorf("ValidWithOptions() at depth 300 error = %v", err)
+	}
+
+	// The default limit still applies without the option
+	if Get(xml, path).Exists() {
+		t.Errorf("Get() at depth 300 exists without a raised limit")
+	}
+	if _, err := QueryWithOptions(xml, path, &Options{CaseSensitive: true, Strict: true}); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("QueryWithOptions(Strict) default limit error = %v, want ErrLimitExceeded", err)
+	}
+
+	// Exceeding the configured limit is reported in Strict mode
+	xml, path = nested(600)
+	result, err = QueryWithOptions(xml, path, strict)
+	if !errors.Is(err, ErrLimitExceeded) || !strings.Contains(err.Error(), "maximum of 500") {
+		t.Errorf("QueryWithOptions(Strict) at depth 600 error = %v, want ErrLimitExceeded", err)
+	}
+	if result.Exists() {
+		t.Errorf("QueryWithOptions(Strict) at depth 600 = %q, want Null", result.String())
+	}
+	if err := ValidWithOptions(xml, opts); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("ValidWithOptions() at depth 600 error = %v, want ErrLimitExceeded", err)
+	}
+}
+
 // TestEdgeBoundaries_MaxAttributes tests attribute count limits
 func TestEdgeBoundaries_MaxAttributes(t *testing.T) {
 	tests := []struct {
@@ -344,6 +393,61 @@ func TestEdgeBoundaries_MaxAttributes(t *testing.T) {
 	}
 }
 
+// TestEdgeBoundaries_AttributeOverflowError tests that excess attributes are
+// reported instead of silently ignored when requested
+func TestEdgeBoundaries_AttributeOverflowError(t *testing.T) {
+	withAttrs := func(n int) string {
+		var sb strings.Builder
+		sb.WriteString("<root><item ")
+		for i := 0; i < n; i++ {
+			sb.WriteString(fmt.Sprintf("attr%d=\"val%d\" ", i, i))
+		}
+		sb.WriteString(">content</item></root>")
+		return sb.String()
+	}
+
+	tests := []struct {
+		name      string
+		xml       string
+		opts      *Options
+		overflows bool
+	}{
+		{"at package limit", withAttrs(MaxAttributes), &Options{CaseSensitive: true, AttributeOverflowError: true}, false},
+		{"over package limit", withAttrs(MaxAttributes + 50), &Options{CaseSensitive: true, AttributeOverflowError: true}, true},
+		{"at custom limit", withAttrs(3), &Options{CaseSensitive: true, MaxAttributes: 3, AttributeOverflowError: true}, false},
+		{"over custom limit", withAttrs(4), &Options{CaseSensitive: true, MaxAttributes: 3, AttributeOverflowError: true}, true},
+		{"custom limit clamped", withAttrs(MaxAttributes + 1), &Options{CaseSensitive: true, MaxAttributes: 500, AttributeOverflowError: true}, true},
+		{"limit without error flag", withAttrs(4), &Options{CaseSensitive: true, MaxAttributes: 3}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := GetWithOptions(tt.xml, "root.item.@attr0", tt.opts)
+			if result.Exists() == tt.overflows {
+				t.Errorf("GetWithOptions().Exists() = %v, want %v", result.Exists(), !tt.overflows)
+			}
+
+			_, err := SetWithOptions(tt.xml, "root.item", "x", tt.opts)
+			if tt.overflows && !errors.Is(err, ErrLimitExceeded) {
+				t.Errorf("SetWithOptions() error = %v, want ErrLimitExceeded", err)
+			}
+			if !tt.overflows && errors.Is(err, ErrLimitExceeded) {
+				t.Errorf("SetWithOptions() unexpected error = %v", err)
+			}
+
+			_, err = DeleteBytesWithOptions([]byte(tt.xml), "root.item", tt.opts)
+			if tt.overflows && !errors.Is(err, ErrLimitExceeded) {
+				t.Errorf("DeleteBytesWithOptions() error = %v, want ErrLimitExceeded", err)
+			}
+		})
+	}
+
+	// Without the option, excess attributes are still silently ignored
+	if Get(withAttrs(MaxAttributes+1), fmt.Sprintf("root.item.@attr%d", MaxAttributes)).Exists() {
+		t.Error("Expected attribute beyond MaxAttributes to be ignored by default")
+	}
+}
+
 // TestEdgeBoundaries_ArrayBoundaries tests array index boundary conditions
 func TestEdgeBoundaries_ArrayBoundaries(t *testing.T) {
 	xml := "<root><item>first</item><item>second</item><item>third</item><item>fourth</item><item>fifth</item></root>"
@@ -603,55 +707,46 @@ func TestEdgeBoundaries_SpecialNumbers(t *testing.T) {
 	tests := []struct {
 		name  string
 		value any
-		desc  string
+		want  string
 	}{
-		{
-			name:  "Zero",
-			value: 0,
-			desc:  "Should handle zero",
-		},
-		{
-			name:  "Negative number",
-			value: -42,
-			desc:  "Should handle negative",
-		},
-		{
-			name:  "Large integer",
-			value: 9223372036854775807, // max int64
-			desc:  "Should handle max int64",
-		},
-		{
-			name:  "Float",
-			value: 3.14159,
-			desc:  "Should handle float",
-		},
-		{
-			name:  "Boolean true",
-			value: true,
-			desc:  "Should handle boolean",
-		},
-		{
-			name:  "Boolean false",
-			value: false,
-			desc:  "Should handle boolean",
-		},
+		{name: "Zero", value: 0, want: "0"},
+		{name: "Negative number", value: -42, want: "-42"},
+		{name: "Large integer", value: 9223372036854775807, want: "9223372036854775807"}, // max int
+		{name: "Max int64", value: int64(math.MaxInt64), want: "9223372036854775807"},
+		{name: "Min int64", value: int64(math.MinInt64), want: "-9223372036854775808"},
+		{name: "Large ID", value: int64(9007199254740993), want: "9007199254740993"}, // 2^53+1, not exact as float64
+		{name: "int32", value: int32(-2147483648), want: "-2147483648"},
+		{name: "int8", value: int8(-7), want: "-7"},
+		{name: "Max uint64", value: uint64(math.MaxUint64), want: "18446744073709551615"},
+		{name: "uint", value: uint(42), want: "42"},
+		{name: "uint8", value: uint8(255), want: "255"},
+		{name: "Float", value: 3.14159, want: "3.14159"},
+		{name: "Boolean true", value: true, want: "true"},
+		{name: "Boolean false", value: false, want: "false"},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			modified, err := Set(xml, "root.item", tt.value)
 			if err != nil {
-				t.Errorf("Set with %v failed: %v", tt.value, err)
+				t.Fatalf("Set with %v failed: %v", tt.value, err)
+			}
+			if want := "<root><item>" + tt.want + "</item></root>"; modified != want {
+				t.Errorf("Set(%v) = %s, want %s", tt.value, modified, want)
 			}
 
 			result := Get(modified, "root.item")
-			if !result.Exists() {
-				t.Errorf("Result should exist after setting %v", tt.value)
+			if got := result.String(); got != tt.want {
+				t.Errorf("Get() = %q, want %q", got, tt.want)
 			}
-			// Just verify it was set (exact string representation may vary)
-			_ = result.String()
 		})
 	}
+
+	// Integers read back exactly as int64
+	modified, _ := Set(xml, "root.item.@id", int64(math.MaxInt64))
+	if got := Get(modified, "root.item.@id").Int(); got != math.MaxInt64 {
+		t.Errorf("Int() = %d, want %d", got, int64(math.MaxInt64))
+	}
 }
 
 // TestEdgeBoundaries_WildcardResultLimit tests wildcard result limits
@@ -689,18 +784,16 @@ func TestEdgeBoundaries_WildcardResultLimit(t *testing.T) {
 			sb.WriteString("</root>")
 			xml := sb.String()
 
-			// Test wildcard count
+			// Counting streams without collecting, so it is not capped
 			countResult := Get(xml, "root.item.#")
-			count := countResult.Int()
+			if count := countResult.Int(); count != int64(tt.elementCount) {
+				t.Errorf("Count mismatch: got %d, want %d", count, tt.elementCount)
+			}
 
-			if tt.expectLimit {
-				if count > int64(MaxWildcardResults) {
-					t.Errorf("Count exceeded MaxWildcardResults: got %d, limit %d", count, MaxWildcardResults)
-				}
-			} else {
-				if count != int64(tt.elementCount) {
-					t.Errorf("Count mismatch: got %d, want %d", count, tt.elementCount)
-				}
+			// Collecting wildcard results is still capped
+			wildcardResult := Get(xml, "root.*")
+			if tt.expectLimit && len(wildcardResult.Array()) > MaxWildcardResults {
+				t.Errorf("Wildcard exceeded MaxWildcardResults: got %d, limit %d", len(wildcardResult.Array()), MaxWildcardResults)
 			}
 		})
 	}
@@ -740,6 +833,82 @@ func TestEdgeBoundaries_RecursiveOperationLimit(t *testing.T) {
 	_ = result.String()
 }
 
+func TestEdgeBoundaries_RecursiveOverflowError(t *testing.T) {
+	// Every <a> and <b> with content costs one recursive operation
+	wide := "<root>" + strings.Repeat("<a><b>1</b></a>", MaxRecursiveOperations) + "</root>"
+	small := "<root>" + strings.Repeat("<a><b>1</b></a>", 50) + "</root>"
+	strict := &Options{CaseSensitive: true, RecursiveOverflowError: true}
+
+	// Without the option, the truncated search returns partial results
+	partial := GetWithOptions(wide, "root.**.b", &Options{CaseSensitive: true})
+	if !partial.Exists() {
+		t.Fatal("Expected partial results without RecursiveOverflowError")
+	}
+
+	for _, order := range []RecursiveOrder{DepthFirst, BreadthFirst} {
+		opts := *strict
+		opts.RecursiveOrder = order
+
+		result, err := QueryWithOptions(wide, "root.**.b", &opts)
+		if !errors.Is(err, ErrLimitExceeded) {
+			t.Errorf("order %d: QueryWithOptions() error = %v, want ErrLimitExceeded", order, err)
+		}
+		if result.Exists() {
+			t.Errorf("order %d: expected Null result on overflow, got %v", order, result.Type)
+		}
+		if GetWithOptions(wide, "root.**.b", &opts).Exists() {
+			t.Errorf("order %d: GetWithOptions() should return Null on overflow", order)
+		}
+
+		result, err = QueryWithOptions(small, "root.**.b", &opts)
+		if err != nil {
+			t.Fatalf("order %d: QueryWithOptions() unexpected error = %v", order, err)
+		}
+		if n := len(result.Array()); n != 50 {
+			t.Errorf("order %d: got %d results, want 50", order, n)
+		}
+	}
+
+	// Per-query state must not leak into the caller's Options
+	if strict.state != nil {
+		t.Error("QueryWithOptions modified the caller's Options")
+	}
+}
+
+func TestQueryWithOptions_Errors(t *testing.T) {
+	xml := `<root><item a="1" a="2">x</item></root>`
+
+	tests := []struct {
+		name    string
+		xml     string
+		path    string
+		opts    *Options
+		wantErr error
+		exists  bool
+	}{
+		{"found", xml, "root.item", nil, nil, true},
+		{"not found", xml, "root.missing", nil, nil, false},
+		{"empty path", xml, "", nil, ErrInvalidPath, false},
+		{"duplicate attributes", xml, "root.item", &Options{CaseSensitive: true, RejectDuplicateAttributes: true}, ErrMalformedXML, false},
+		{"attribute overflow", xml, "root.item", &Options{CaseSensitive: true, MaxAttributes: 1, AttributeOverflowError: true}, ErrLimitExceeded, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := QueryWithOptions(tt.xml, tt.path, tt.opts)
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("QueryWithOptions() unexpected error = %v", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("QueryWithOptions() error = %v, want %v", err, tt.wantErr)
+			}
+			if result.Exists() != tt.exists {
+				t.Errorf("QueryWithOptions().Exists() = %v, want %v", result.Exists(), tt.exists)
+			}
+		})
+	}
+}
+
 // ============================================================================
 // Edge Case Tests - Errors
 // ============================================================================
@@ -1117,6 +1286,50 @@ func TestEdgeErrors_InvalidModifierSyntax(t *testing.T) {
 	}
 }
 
+// TestEdgeErrors_UnknownModifierStrict tests that Strict queries report an
+// unknown modifier by name instead of returning an empty result
+func TestEdgeErrors_UnknownModifierStrict(t *testing.T) {
+	xml := "<root><item>b</item><item>a</item></root>"
+	strict := &Options{CaseSensitive: true, Strict: true}
+
+	tests := []struct {
+		name    string
+		path    string
+		want    string
+		wantErr error
+		wantMsg string
+	}{
+		{"known modifiers", "root.item|@sort|@first", "a", nil, ""},
+		{"unknown modifier", "root.item|@nonsense", "", ErrUnknownModifier, "@nonsense"},
+		{"typo after known modifier", "root.item|@sort|@frist", "", ErrUnknownModifier, "@frist"},
+		{"unknown modifier mid-path", "root|@thsi.item", "", ErrUnknownModifier, "@thsi"},
+		{"argument not accepted", "root.item|@first:2", "", ErrInvalidPath, "@first"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := QueryWithOptions(xml, tt.path, strict)
+			if tt.wantErr == nil {
+				if err != nil || result.String() != tt.want {
+					t.Errorf("QueryWithOptions() = %q, %v, want %q", result.String(), err, tt.want)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) || !strings.Contains(err.Error(), tt.wantMsg) {
+				t.Errorf("QueryWithOptions() error = %v, want %v naming %q", err, tt.wantErr, tt.wantMsg)
+			}
+			if result.Exists() || GetWithOptions(xml, tt.path, strict).Exists() {
+				t.Errorf("Strict result exists, want Null")
+			}
+
+			// Without Strict the unknown modifier yields Null silently
+			if _, err := QueryWithOptions(xml, tt.path, &Options{CaseSensitive: true}); err != nil {
+				t.Errorf("QueryWithOptions() without Strict error = %v, want nil", err)
+			}
+		})
+	}
+}
+
 // TestEdgeErrors_PathTooLong tests extremely long paths
 func TestEdgeErrors_PathTooLong(t *testing.T) {
 	xml := "<root><item>value</item></root>"
@@ -1825,11 +2038,10 @@ func TestEdgeLarge_ManyElements(t *testing.T) {
 				t.Errorf("Expected to access first item")
 			}
 
-			// Test counting elements
+			// Test counting elements (streamed, not capped by MaxWildcardResults)
 			countResult := Get(xml, "root.item.#")
-			expectedCount := min(tt.count, MaxWildcardResults)
-			if countResult.Int() != int64(expectedCount) {
-				t.Errorf("Expected count %d, got %d", expectedCount, countResult.Int())
+			if countResult.Int() != int64(tt.count) {
+				t.Errorf("Expected count %d, got %d", tt.count, countResult.Int())
 			}
 
 			// Test wildcard access
@@ -2321,6 +2533,78 @@ func TestEdgeNamespace_WildcardWithNamespaces(t *testing.T) {
 	}
 }
 
+// TestEdgeNamespace_WildcardDocumentOrder tests that a wildcard over mixed
+// prefixed and unprefixed siblings returns them in document order, with
+// prefixes in Name() and stable indexes
+func TestEdgeNamespace_WildcardDocumentOrder(t *testing.T) {
+	xml := `<root xmlns:ns="urn:ns" xmlns:x="urn:x">
+	<item>a</item>
+	<ns:item id="n1">b</ns:item>
+	<!-- comment -->
+	<x:other>c</x:other>
+	<item>d</item>
+	<ns:item id="n2"/>
+</root>`
+
+	wantNames := []string{"item", "ns:item", "x:other", "item", "ns:item"}
+	wantValues := []string{"a", "b", "c", "d", ""}
+
+	for _, opts := range []*Options{nil, {CaseSensitive: true}} {
+		result := GetWithOptions(xml, "root.*", opts)
+		items := result.Array()
+		if len(items) != len(wantNames) {
+			t.Fatalf("root.* returned %d items, want %d", len(items), len(wantNames))
+		}
+		for i, item := range items {
+			if item.Name() != wantNames[i] || item.String() != wantValues[i] {
+				t.Errorf("item %d = %s %q, want %s %q", i, item.Name(), item.String(), wantNames[i], wantValues[i])
+			}
+		}
+
+		var named []string
+		result.ForEachNamed(func(_ int, name string, _ Result) bool {
+			named = append(named, name)
+			return true
+		})
+		if strings.Join(named, ",") != strings.Join(wantNames, ",") {
+			t.Errorf("ForEachNamed() names = %v, want %v", named, wantNames)
+		}
+
+		// Indexing and counting select from the wildcard's matches
+		for i := range wantNames {
+			indexed := GetWithOptions(xml, "root.*."+itoa(i), opts)
+			if indexed.Name() != wantNames[i] || indexed.String() != wantValues[i] {
+				t.Errorf("root.*.%d = %s %q, want %s %q", i, indexed.Name(), indexed.String(), wantNames[i], wantValues[i])
+			}
+		}
+		if got := GetWithOptions(xml, "root.*.4.@id", opts).String(); got != "n2" {
+			t.Errorf("root.*.4.@id = %q, want n2", got)
+		}
+		if got := GetWithOptions(xml, "root.*.#", opts).Int(); got != 5 {
+			t.Errorf("root.*.# = %d, want 5", got)
+		}
+		if r := GetWithOptions(xml, "root.*.5", opts); r.Exists() {
+			t.Errorf("root.*.5 = %v, want Null", r)
+		}
+	}
+
+	// Repeated queries give the same order
+	first := Get(xml, "root.*").String()
+	for i := 0; i < 10; i++ {
+		if got := Get(xml, "root.*").String(); got != first {
+			t.Fatalf("root.* order changed: %s, then %s", first, got)
+		}
+	}
+
+	// Prefixed patterns keep document order among their matches
+	if got := Get(xml, "root.ns:*.1.@id").String(); got != "n2" {
+		t.Errorf("root.ns:*.1.@id = %q, want n2", got)
+	}
+	if got := Get(xml, "root").Get("*.1").Name(); got != "ns:item" {
+		t.Errorf("Result.Get(*.1).Name() = %q, want ns:item", got)
+	}
+}
+
 // TestEdgeNamespace_SetWithNamespaces tests Set operations on namespaced elements
 func TestEdgeNamespace_SetWithNamespaces(t *testing.T) {
 	tests := []struct {
@@ -2641,6 +2925,61 @@ func TestEdgeStructure_ProcessingInstructions(t *testing.T) {
 	}
 }
 
+// TestEdgeStructure_MalformedProcessingInstructions tests that PIs end at
+// "?>", and that an unterminated PI neither hides the elements after it nor
+// passes validation
+func TestEdgeStructure_MalformedProcessingInstructions(t *testing.T) {
+	tests := []struct {
+		name     string
+		xml      string
+		path     string
+		expected string
+		offset   int // offset of the unterminated PI, or -1 if well-formed
+	}{
+		{"'>' inside PI", `<root><?t a>b?><item>v</item></root>`, "root.item", "v", -1},
+		{"'<' inside PI", `<root><?php echo "<b>"; ?><item>v</item></root>`, "root.item", "v", -1},
+		{"unterminated before sibling", `<root><?t data <item>v</item><other>o</other></root>`, "root.other", "o", 6},
+		{"unterminated inside element", `<root><item>v<?t x</item><other>o</other></root>`, "root.item", "v", 13},
+		{"unterminated before root", `<?t data <root><item>v</item></root>`, "root.item", "v", 0},
+		{"unterminated at end", `<root><item>v</item></root><?t`, "root.item", "v", 27},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Get(tt.xml, tt.path).String(); got != tt.expected {
+				t.Errorf("Get(%q) = %q, want %q", tt.path, got, tt.expected)
+			}
+			if got := GetWithOptions(tt.xml, tt.path, &Options{CaseSensitive: false}).String(); got != tt.expected {
+				t.Errorf("GetWithOptions(%q) = %q, want %q", tt.path, got, tt.expected)
+			}
+
+			err := ValidateWithError(tt.xml)
+			if tt.offset < 0 {
+				if err != nil {
+					t.Errorf("ValidateWithError() = %v, want nil", err)
+				}
+				return
+			}
+			want := fmt.Sprintf("unterminated processing instruction at offset %d", tt.offset)
+			if err == nil || err.Message != want {
+				t.Errorf("ValidateWithError() = %v, want %q", err, want)
+			}
+
+			// Strict queries report the problem instead of a best-effort result
+			result, qerr := QueryWithOptions(tt.xml, tt.path, &Options{CaseSensitive: true, Strict: true})
+			if !errors.Is(qerr, ErrMalformedXML) || !strings.Contains(qerr.Error(), want) {
+				t.Errorf("QueryWithOptions(Strict) error = %v, want ErrMalformedXML with %q", qerr, want)
+			}
+			if result.Exists() {
+				t.Errorf("QueryWithOptions(Strict) = %q, want Null", result.String())
+			}
+			if _, err := Set(tt.xml, tt.path, "x"); !errors.Is(err, ErrMalformedXML) {
+				t.Errorf("Set() error = %v, want ErrMalformedXML", err)
+			}
+		})
+	}
+}
+
 // TestEdgeStructure_MixedContent tests handling of mixed content (text + elements)
 func TestEdgeStructure_MixedContent(t *testing.T) {
 	tests := []struct {
@@ -2936,6 +3275,57 @@ func TestEdgeStructure_AttributeEdgeCases(t *testing.T) {
 	}
 }
 
+// TestEdgeStructure_DuplicateAttributes tests that a repeated attribute
+// resolves to its first declaration, and is rejected with RejectDuplicateAttributes
+func TestEdgeStructure_DuplicateAttributes(t *testing.T) {
+	xml := `<root><item a="1" b="x" a="2">text</item></root>`
+
+	t.Run("first wins", func(t *testing.T) {
+		if got := Get(xml, "root.item.@a").String(); got != "1" {
+			t.Errorf("Get(@a) = %q, want %q", got, "1")
+		}
+		if got := Get(xml, "root.item.#(@a==2)").Exists(); got {
+			t.Error("Filter should not match the shadowed duplicate value")
+		}
+		attrs := Get(xml, "root.item").Attributes()
+		if len(attrs) != 2 || attrs[0] != (Attr{Name: "a", Value: "1"}) {
+			t.Errorf("Attributes() = %v, want [{a 1} {b x}]", attrs)
+		}
+	})
+
+	t.Run("rejected", func(t *testing.T) {
+		opts := &Options{CaseSensitive: true, RejectDuplicateAttributes: true}
+
+		if r := GetWithOptions(xml, "root.item.@b", opts); r.Exists() {
+			t.Errorf("GetWithOptions() = %v, want Null", r)
+		}
+		if _, err := SetWithOptions(xml, "root.item.@b", "y", opts); !errors.Is(err, ErrMalformedXML) {
+			t.Errorf("SetWithOptions() error = %v, want ErrMalformedXML", err)
+		}
+		if _, err := DeleteBytesWithOptions([]byte(xml), "root.item", opts); !errors.Is(err, ErrMalformedXML) {
+			t.Errorf("DeleteBytesWithOptions() error = %v, want ErrMalformedXML", err)
+		}
+
+		// The check is independent of Strict in both directions
+		if _, err := QueryWithOptions(xml, "root.item.@b", opts); !errors.Is(err, ErrMalformedXML) {
+			t.Errorf("QueryWithOptions() without Strict error = %v, want ErrMalformedXML", err)
+		}
+		strict := &Options{CaseSensitive: true, Strict: true}
+		if r, err := QueryWithOptions(xml, "root.item.@a", strict); err != nil || r.String() != "1" {
+			t.Errorf("QueryWithOptions(Strict) = %q, %v, want first value and nil", r.String(), err)
+		}
+
+		// Same names on different elements, in comments or in values are fine
+		clean := `<root a="1"><!-- <x a="1" a="2"/> --><item a="1" b="a=&quot;2&quot;"/><item a="2"/></root>`
+		if got := GetWithOptions(clean, "root.item.1.@a", opts).String(); got != "2" {
+			t.Errorf("GetWithOptions() = %q, want %q", got, "2")
+		}
+		if _, err := SetWithOptions(clean, "root.@b", "y", opts); err != nil {
+			t.Errorf("SetWithOptions() error = %v", err)
+		}
+	})
+}
+
 // TestEdgeStructure_DuplicateElements tests handling of duplicate element names
 func TestEdgeStructure_DuplicateElements(t *testing.T) {
 	xml := "<root><item>first</item><item>second</item><item>third</item></root>"
@@ -18,4 +18,13 @@ var (
 	// ErrInvalidValue is returned when the value cannot be converted to XML
 	// or is inappropriate for the operation.
 	ErrInvalidValue = errors.New("invalid value for XML")
+
+	// ErrLimitExceeded is returned when the document exceeds a configured
+	// limit and strict handling was requested (e.g. Options.AttributeOverflowError)
+	// instead of silently ignoring the excess.
+	ErrLimitExceeded = errors.New("limit exceeded")
+
+	// ErrUnknownModifier is returned when a modifier chain names a modifier
+	// that is not registered. The error names the modifier.
+	ErrUnknownModifier = errors.New("unknown modifier")
 )
@@ -133,6 +133,15 @@ func TestErrorMessages(t *testing.T) {
 			contains:    []string{"malformed"},
 			notContains: []string{"panic", "internal", "nil pointer"},
 		},
+		{
+			name: "mismatched closing tag message",
+			fn: func() error {
+				_, err := Set("<root><item>value</wrong></root>", "root.item", "value")
+				return err
+			},
+			contains:    []string{"malformed", "expected </item>", "found </wrong>", "at offset 17"},
+			notContains: []string{"panic"},
+		},
 		{
 			name: "invalid path message",
 			fn: func() error {
@@ -43,32 +43,20 @@ func (m *lowercaseModifier) Apply(r xmldot.Result) xmldot.Result {
 		return r
 	}
 
-	// Handle arrays: apply to all elements
+	// Handle arrays: apply to all elements. Changing a copy of each item
+	// keeps its Type, Raw, name and attributes.
 	if r.Type == xmldot.Array {
 		results := make([]xmldot.Result, len(r.Results))
 		for i, elem := range r.Results {
-			results[i] = xmldot.Result{
-				Type:  elem.Type,
-				Str:   strings.ToLower(elem.Str),
-				Raw:   elem.Raw,
-				Num:   elem.Num,
-				Index: elem.Index,
-			}
-		}
-		return xmldot.Result{
-			Type:    xmldot.Array,
-			Results: results,
+			elem.Str = strings.ToLower(elem.Str)
+			results[i] = elem
 		}
+		return xmldot.NewArrayResult(results)
 	}
 
 	// Convert single element to lowercase
-	return xmldot.Result{
-		Type:  r.Type,
-		Str:   strings.ToLower(r.Str),
-		Raw:   r.Raw,
-		Num:   r.Num,
-		Index: r.Index,
-	}
+	r.Str = strings.ToLower(r.Str)
+	return r
 }
 
 // countModifier counts array elements and returns a Number Result
@@ -106,14 +94,14 @@ func (m *countModifier) Apply(r xmldot.Result) xmldot.Result {
 	}
 }
 
-// joinModifier joins array elements with a separator (default: ", ")
-type joinModifier struct{}
+// listModifier joins array elements into a comma-separated list
+type listModifier struct{}
 
-func (m *joinModifier) Name() string {
-	return "join"
+func (m *listModifier) Name() string {
+	return "list"
 }
 
-func (m *joinModifier) Apply(r xmldot.Result) xmldot.Result {
+func (m *listModifier) Apply(r xmldot.Result) xmldot.Result {
 	// Return empty for Null
 	if r.Type == xmldot.Null {
 		return r
@@ -125,16 +113,7 @@ func (m *joinModifier) Apply(r xmldot.Result) xmldot.Result {
 	}
 
 	// Join array elements with ", "
-	var parts []string
-	for _, elem := range r.Results {
-		parts = append(parts, elem.String())
-	}
-
-	joined := strings.Join(parts, ", ")
-	return xmldot.Result{
-		Type: xmldot.String,
-		Str:  joined,
-	}
+	return xmldot.NewStringResult(strings.Join(r.Strings(), ", "))
 }
 
 func init() {
@@ -148,8 +127,8 @@ func init() {
 	if err := xmldot.RegisterModifier("count", &countModifier{}); err != nil {
 		panic(fmt.Sprintf("Failed to register count modifier: %v", err))
 	}
-	if err := xmldot.RegisterModifier("join", &joinModifier{}); err != nil {
-		panic(fmt.Sprintf("Failed to register join modifier: %v", err))
+	if err := xmldot.RegisterModifier("list", &listModifier{}); err != nil {
+		panic(fmt.Sprintf("Failed to register list modifier: %v", err))
 	}
 }
 
@@ -182,7 +161,7 @@ func main() {
 
 	// Example 4: Join titles
 	fmt.Println("Example 4: Join titles")
-	result = xmldot.Get(xml, "catalog.books.*.title|@join")
+	result = xmldot.Get(xml, "catalog.books.*.title|@list")
 	fmt.Printf("All titles: %s\n\n", result.String())
 
 	// Example 5: Chain custom and built-in modifiers
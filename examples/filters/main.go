@@ -136,6 +136,6 @@ func main() {
 
 	// Example 10: Count filtered results
 	fmt.Println("Example 10: Count active employees")
-	result = xmldot.Get(employeesXML, "company.employees.employee.#(@status==active)#")
-	fmt.Printf("Active employees: %d\n", len(result.Array()))
+	result = xmldot.Get(employeesXML, "company.employees.employee.#(@status==active)#.#")
+	fmt.Printf("Active employees: %d\n", result.Int())
 }
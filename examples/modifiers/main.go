@@ -51,7 +51,7 @@ func main() {
 		<group><item>A</item><item>B</item></group>
 		<group><item>C</item><item>D</item></group>
 	</data>`
-	result = xmldot.Get(nestedXML, "data.group.item|@flatten")
+	result = xmldot.Get(nestedXML, "data.group.#.item|@flatten")
 	fmt.Printf("Flattened: %v\n\n", result.Array())
 
 	// Example 6: @pretty - Format XML with indentation
@@ -102,13 +102,22 @@ func main() {
 	}
 	fmt.Println("\n")
 
-	// Example 11: Chaining modifiers
-	fmt.Println("Example 11: Chaining - @sort|@reverse|@first")
+	// Example 11: @entries - Name/value pairs
+	fmt.Println("Example 11: @entries - Name/value pairs")
+	result = xmldot.Get(blogXML, "blog.posts.post.0|@entries")
+	result.ForEachNamed(func(_ int, name string, value xmldot.Result) bool {
+		fmt.Printf("  %s = %s\n", name, value.String())
+		return true
+	})
+	fmt.Println()
+
+	// Example 12: Chaining modifiers
+	fmt.Println("Example 12: Chaining - @sort|@reverse|@first")
 	result = xmldot.Get(blogXML, "blog.posts.post.title|@sort|@reverse|@first")
 	fmt.Printf("Last title alphabetically: %s\n\n", result.String())
 
-	// Example 12: Modifiers with filters
-	fmt.Println("Example 12: Combining filters and modifiers")
+	// Example 13: Modifiers with filters
+	fmt.Println("Example 13: Combining filters and modifiers")
 	result = xmldot.Get(blogXML, "blog.posts.post.#(views>200)#.title|@sort")
 	fmt.Println("Popular posts (sorted):")
 	for _, title := range result.Array() {
@@ -113,22 +113,23 @@ func main() {
 	fmt.Println("Example 5: Path caching benefits (automatic)")
 	const iterations = 10000
 
-	// First run (cold cache)
+	// Parse the path on every query (no cache)
+	uncached := &xmldot.Options{CaseSensitive: true, DisableCache: true}
 	start = time.Now()
 	for i := 0; i < iterations; i++ {
-		_ = xmldot.Get(smallXML, "catalog.product.0.name")
+		_ = xmldot.GetWithOptions(smallXML, "catalog.product.0.name", uncached)
 	}
 	coldTime := time.Since(start)
 
-	// Second run (warm cache)
+	// Reuse the cached parse
 	start = time.Now()
 	for i := 0; i < iterations; i++ {
 		_ = xmldot.Get(smallXML, "catalog.product.0.name")
 	}
 	warmTime := time.Since(start)
 
-	fmt.Printf("First %d queries: %v\n", iterations, coldTime)
-	fmt.Printf("Repeat %d queries: %v\n", iterations, warmTime)
+	fmt.Printf("Uncached %d queries: %v\n", iterations, coldTime)
+	fmt.Printf("Cached %d queries: %v\n", iterations, warmTime)
 	fmt.Printf("Cache benefit: %.1f%% faster\n\n", (1.0-float64(warmTime)/float64(coldTime))*100)
 
 	// Example 6: Avoid recursive wildcards on large documents
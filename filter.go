@@ -4,9 +4,12 @@
 package xmldot
 
 import (
+	"fmt"
 	"math"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/netascode/xmldot/internal/pattern"
 )
@@ -24,6 +27,9 @@ const (
 	// This prevents ReDoS (Regular Expression Denial of Service) attacks with
 	// exponential backtracking patterns. Value of 10000 matches GJSON's default.
 	MaxPatternIterations = 10000
+
+	// MaxFilterOpLength is the maximum length of a custom filter operator token.
+	MaxFilterOpLength = 4
 )
 
 // FilterOp represents a filter comparison operator.
@@ -48,8 +54,173 @@ const (
 	OpPatternNotMatch
 	// OpExists checks if an attribute/element exists.
 	OpExists
+	// OpCustom represents an operator registered with RegisterFilterOp.
+	OpCustom
+	// OpPrefix represents the ^= operator (value starts with).
+	OpPrefix
 )
 
+// FilterOpFunc implements a custom filter operator. It receives the value found
+// at the filter's path (left) and the filter's comparison value (right, with
+// surrounding quotes removed) and reports whether the candidate matches.
+//
+// Thread Safety: FilterOpFunc implementations must be safe for concurrent use.
+type FilterOpFunc func(left, right string) bool
+
+// filterOpRegistry holds custom filter operators keyed by operator token.
+// Thread-safe for concurrent registration and lookup.
+var (
+	filterOpRegistry = make(map[string]FilterOpFunc)
+	filterOpMu       sync.RWMutex
+)
+
+// builtinFilterOps lists the operator tokens recognized by the filter parser.
+var builtinFilterOps = []string{"==", "!=", "<=", ">=", "!%", "^=", "<", ">", "%"}
+
+// RegisterFilterOp registers a custom filter operator globally, making it
+// usable in filter expressions like any built-in operator.
+// Can be called during init() for package-level operators.
+//
+// The operator must be 1 to MaxFilterOpLength characters long, must not be a
+// built-in operator, and may not contain whitespace, element name characters
+// (letters, digits, _ - :) or path syntax characters (. @ # ( ) [ ] | \ ' ").
+// Returns error if the operator is invalid or already registered.
+//
+// Example:
+//
+//	func init() {
+//	    xmldot.RegisterFilterOp("~~", func(left, right string) bool {
+//	        return strings.EqualFold(left, right)
+//	    })
+//	}
+//
+//	result := xmldot.Get(xml, "users.user.#(name~~ALICE)#")
+func RegisterFilterOp(op string, fn FilterOpFunc) error {
+	if err := validateFilterOp(op); err != nil {
+		return err
+	}
+	if fn == nil {
+		return fmt.Errorf("filter operator %q function cannot be nil", op)
+	}
+
+	filterOpMu.Lock()
+	if _, exists := filterOpRegistry[op]; exists {
+		filterOpMu.Unlock()
+		return fmt.Errorf("filter operator %q already registered", op)
+	}
+	filterOpRegistry[op] = fn
+	filterOpMu.Unlock()
+
+	// Cached paths may have parsed this operator as part of a path or value
+	resetPathCache()
+	return nil
+}
+
+// GetFilterOp retrieves a registered filter operator by token.
+// Returns nil if not found.
+func GetFilterOp(op string) FilterOpFunc {
+	filterOpMu.RLock()
+	defer filterOpMu.RUnlock()
+	return filterOpRegistry[op]
+}
+
+// UnregisterFilterOp removes a custom filter operator from the registry.
+// Built-in operators cannot be unregistered (returns error).
+func UnregisterFilterOp(op string) error {
+	if isBuiltinFilterOp(op) {
+		return fmt.Errorf("cannot unregister built-in filter operator %q", op)
+	}
+
+	filterOpMu.Lock()
+	delete(filterOpRegistry, op)
+	filterOpMu.Unlock()
+
+	resetPathCache()
+	return nil
+}
+
+// FilterOps returns the tokens of all registered custom filter operators in sorted order.
+func FilterOps() []string {
+	filterOpMu.RLock()
+	ops := make([]string, 0, len(filterOpRegistry))
+	for op := range filterOpRegistry {
+		ops = append(ops, op)
+	}
+	filterOpMu.RUnlock()
+
+	sort.Strings(ops)
+	return ops
+}
+
+// isBuiltinFilterOp checks if an operator token is built-in
+func isBuiltinFilterOp(op string) bool {
+	for _, b := range builtinFilterOps {
+		if op == b {
+			return true
+		}
+	}
+	return false
+}
+
+// validateFilterOp checks that a custom operator token can be parsed unambiguously.
+func validateFilterOp(op string) error {
+	if op == "" {
+		return fmt.Errorf("filter operator cannot be empty")
+	}
+	if len(op) > MaxFilterOpLength {
+		return fmt.Errorf("filter operator %q exceeds maximum length of %d", op, MaxFilterOpLength)
+	}
+	if isBuiltinFilterOp(op) {
+		return fmt.Errorf("filter operator %q is built-in", op)
+	}
+	for i := 0; i < len(op); i++ {
+		c := op[i]
+		if c <= ' ' || c >= 0x7f ||
+			(c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') ||
+			strings.IndexByte("_-:.@#()[]|\\'\"", c) >= 0 {
+			return fmt.Errorf("filter operator %q contains invalid character %q", op, c)
+		}
+	}
+	return nil
+}
+
+// findCustomFilterOp locates the first registered custom operator in expr.
+// At each position the longest custom operator wins, unless a built-in
+// operator of at least the same length starts there first.
+// Returns the operator and its position, or ("", -1) if none applies.
+func findCustomFilterOp(expr string) (string, int) {
+	filterOpMu.RLock()
+	defer filterOpMu.RUnlock()
+
+	if len(filterOpRegistry) == 0 {
+		return "", -1
+	}
+
+	for i := 0; i < len(expr); i++ {
+		best := ""
+		for op := range filterOpRegistry {
+			if len(op) > len(best) && strings.HasPrefix(expr[i:], op) {
+				best = op
+			}
+		}
+
+		builtinLen := 0
+		for _, b := range builtinFilterOps {
+			if len(b) > builtinLen && strings.HasPrefix(expr[i:], b) {
+				builtinLen = len(b)
+			}
+		}
+
+		if len(best) > builtinLen {
+			return best, i
+		}
+		if builtinLen > 0 {
+			return "", -1
+		}
+	}
+	return "", -1
+}
+
 // parseFilter parses a filter expression like "[age>21]" into a Filter.
 // Supported operators: ==, !=, <, >, <=, >=, %, !%
 // Supported operands: element paths, attribute paths (@attr), numeric values, string values
@@ -89,8 +260,10 @@ func parseFilter(expr string) (*Filter, error) {
 
 // parseFilterCondition parses a filter condition (without brackets) into a Filter.
 // This is the GJSON-style filter parser that doesn't expect bracket markers.
-// Supported operators: ==, !=, <, >, <=, >=, %, !%
+// Supported operators: ==, !=, <, >, <=, >=, %, !%, ^=
 // Supported operands: element paths, attribute paths (@attr), numeric values, string values
+// Conditions joined with && must all match; the first becomes the returned
+// Filter and the rest are stored in its And field.
 //
 // Examples:
 //   - "age>21" → {Path: "age", Op: OpGreaterThan, Value: "21"}
@@ -99,6 +272,9 @@ func parseFilter(expr string) (*Filter, error) {
 //   - "@active" → {Path: "@active", Op: OpExists, Value: ""}
 //   - "name%'*Go*'" → {Path: "name", Op: OpPatternMatch, Value: "*Go*"}
 //   - "status!%'temp*'" → {Path: "status", Op: OpPatternNotMatch, Value: "temp*"}
+//   - "@name^=.Main" → {Path: "@name", Op: OpPrefix, Value: ".Main"}
+//   - "%==foo" → {Path: "%", Op: OpEqual, Value: "foo"}
+//   - "@a==1 && b>2" → {Path: "@a", Op: OpEqual, Value: "1", And: [{Path: "b", ...}]}
 //
 // Security: Expressions longer than MaxFilterExpressionLength are rejected.
 // Security: Null bytes and operator characters in paths are rejected.
@@ -119,18 +295,47 @@ func parseFilterCondition(expr string) (*Filter, error) {
 		return nil, ErrInvalidPath
 	}
 
+	// Conditions joined with && must all match
+	if conditions := splitFilterConditions(expr); len(conditions) > 1 {
+		first, err := parseFilterCondition(conditions[0])
+		if err != nil {
+			return nil, err
+		}
+		for _, cond := range conditions[1:] {
+			next, err := parseFilterCondition(cond)
+			if err != nil {
+				return nil, err
+			}
+			first.And = append(first.And, next)
+		}
+		return first, nil
+	}
+
+	// A leading % is the element's own text (%==foo), not the pattern
+	// operator, so operator detection starts after it
+	start := 0
+	if expr[0] == '%' {
+		start = 1
+	}
+
+	// Registered custom operators take part in operator detection
+	customOp, customPos := findCustomFilterOp(expr[start:])
+	if customPos >= 0 {
+		customPos += start
+	}
+
 	// Check for existence filter (just a path with no operator)
 	// e.g., [@active] or [name]
-	if !strings.ContainsAny(expr, "=!<>%") {
+	if customPos < 0 && !strings.ContainsAny(expr, "=!<>%") {
 		// Security check: validate path doesn't contain null bytes
 		if strings.ContainsRune(expr, '\x00') {
 			return nil, ErrInvalidPath
 		}
-		return &Filter{
+		return withPathSegments(&Filter{
 			Path:  expr,
 			Op:    OpExists,
 			Value: "",
-		}, nil
+		}), nil
 	}
 
 	// Find the operator
@@ -138,8 +343,14 @@ func parseFilterCondition(expr string) (*Filter, error) {
 	var opStr string
 	var opPos = -1
 
-	// Check for two-character operators first (==, <=, >=, !=, !%)
-	for i := 0; i < len(expr)-1; i++ {
+	if customPos >= 0 {
+		op = OpCustom
+		opStr = customOp
+		opPos = customPos
+	}
+
+	// Check for two-character operators first (==, <=, >=, !=, !%, ^=)
+	for i := start; opPos < 0 && i < len(expr)-1; i++ {
 		twoChar := expr[i : i+2]
 		switch twoChar {
 		case "==":
@@ -162,6 +373,10 @@ func parseFilterCondition(expr string) (*Filter, error) {
 			op = OpPatternNotMatch
 			opStr = "!%"
 			opPos = i
+		case "^=":
+			op = OpPrefix
+			opStr = "^="
+			opPos = i
 		}
 		if opPos >= 0 {
 			break
@@ -170,7 +385,7 @@ func parseFilterCondition(expr string) (*Filter, error) {
 
 	// If no two-character operator found, check for single-character operators
 	if opPos < 0 {
-		for i := 0; i < len(expr); i++ {
+		for i := start; i < len(expr); i++ {
 			c := expr[i]
 			switch c {
 			case '<':
@@ -216,40 +431,172 @@ func parseFilterCondition(expr string) (*Filter, error) {
 
 	// Security check: validate path doesn't contain operator characters
 	// Path should only contain element names, dots, and @ for attributes
-	if strings.ContainsAny(path, "=!<>%") {
+	if path != "%" && strings.ContainsAny(path, "=!<>%^") {
 		return nil, ErrInvalidPath
 	}
 
+	// An unquoted @name refers to another attribute of the same element;
+	// quote it ("@name") to compare against the literal text
+	var valuePath string
+	if value[0] == '@' {
+		if len(value) == 1 || strings.ContainsAny(value[1:], "@. ") {
+			return nil, ErrInvalidPath
+		}
+		valuePath = value
+	}
+
 	// Remove quotes from string values
-	if (strings.HasPrefix(value, "'") && strings.HasSuffix(value, "'")) ||
-		(strings.HasPrefix(value, "\"") && strings.HasSuffix(value, "\"")) {
-		value = value[1 : len(value)-1]
+	if value[0] == '\'' || value[0] == '"' {
+		unquoted, ok := unquoteFilterValue(value)
+		if !ok {
+			return nil, ErrInvalidPath
+		}
+		value = unquoted
 	}
 
+	// Element and attribute values are compared decoded, so entity references
+	// in the literal are decoded too: Tom &amp; Jerry matches Tom & Jerry
+	value = unescapeXML(value)
+
 	// Security check: validate value doesn't contain control characters AFTER quote removal
 	if strings.ContainsAny(value, "\x00\n\r\t") {
 		return nil, ErrInvalidPath
 	}
 
-	return &Filter{
+	filter := &Filter{
 		Path:  path,
 		Op:    op,
 		Value: value,
-	}, nil
+	}
+	if valuePath != "" {
+		filter.Value = ""
+		filter.ValuePath = valuePath
+	}
+	if op == OpCustom {
+		filter.CustomOp = opStr
+	}
+	return withPathSegments(filter), nil
 }
 
-// evaluateFilterWithDepth evaluates a filter with recursion depth tracking.
-// Optimized: Fast paths for common filter patterns to avoid parsing overhead.
-func evaluateFilterWithDepth(filter *Filter, content string, attrs map[string]string, depth int) bool {
+// withPathSegments parses the element path of f once, for evaluation on many
+// elements. Attribute (@id), position (#) and text (%) paths are resolved
+// without segments.
+func withPathSegments(f *Filter) *Filter {
+	if f.Path == "" || strings.HasPrefix(f.Path, "@") || f.Path == "#" || f.Path == "%" {
+		return f
+	}
+	f.segments = parsePathInternal(f.Path)
+	f.parsed = true
+	return f
+}
+
+// pathSegments returns the parsed element path of f. Filters built without
+// the filter parser have their path parsed on each call.
+func (f *Filter) pathSegments() []PathSegment {
+	if f.parsed {
+		return f.segments
+	}
+	return parsePath(f.Path)
+}
+
+// splitFilterConditions splits a filter expression on && outside quoted
+// values. An expression without && is returned as a single condition.
+func splitFilterConditions(expr string) []string {
+	if !strings.Contains(expr, "&&") {
+		return []string{expr}
+	}
+
+	var conditions []string
+	var quote byte
+	start := 0
+	for i := 0; i < len(expr); i++ {
+		c := expr[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '&' && i+1 < len(expr) && expr[i+1] == '&':
+			conditions = append(conditions, expr[start:i])
+			start = i + 2
+			i++
+		}
+	}
+	return append(conditions, expr[start:])
+}
+
+// unquoteFilterValue strips the quotes from a single- or double-quoted filter
+// value and resolves backslash escapes (\", \' and \\). Inside quotes a value
+// may contain spaces, dots, parentheses, '#', '|' and operators. A value that
+// only starts with a quote is taken literally, as before quoting was supported.
+// Returns false if the closing quote is escaped or followed by other text.
+func unquoteFilterValue(value string) (string, bool) {
+	quote := value[0]
+	if len(value) < 2 || value[len(value)-1] != quote {
+		return value, true
+	}
+
+	var sb strings.Builder
+	for i := 1; i < len(value); i++ {
+		c := value[i]
+		if c == '\\' && i+1 < len(value) {
+			i++
+			sb.WriteByte(value[i])
+			continue
+		}
+		if c == quote {
+			// The closing quote must end the value
+			return sb.String(), i == len(value)-1
+		}
+		sb.WriteByte(c)
+	}
+	return "", false
+}
+
+// evaluateFilterWithDepth evaluates a filter, including any conditions joined
+// with &&, with recursion depth tracking. position is the element's zero-based
+// position among its candidate siblings, which a "#" filter path compares.
+func evaluateFilterWithDepth(filter *Filter, content string, attrs map[string]string, position, depth int) bool {
 	if filter == nil {
 		return true
 	}
+	if !evaluateConditionWithDepth(filter, content, attrs, position, depth) {
+		return false
+	}
+	for _, cond := range filter.And {
+		if !evaluateConditionWithDepth(cond, content, attrs, position, depth) {
+			return false
+		}
+	}
+	return true
+}
+
+// evaluateConditionWithDepth evaluates a single filter condition, ignoring
+// filter.And.
+// Optimized: Fast paths for common filter patterns to avoid parsing overhead.
+func evaluateConditionWithDepth(filter *Filter, content string, attrs map[string]string, position, depth int) bool {
 
 	// Security check: enforce maximum filter recursion depth
 	if depth >= MaxFilterDepth {
 		return false
 	}
 
+	// Resolve an attribute reference on the right-hand side
+	if filter.ValuePath != "" {
+		value, ok := attrs[filter.ValuePath[1:]]
+		if !ok {
+			return false
+		}
+		resolved := *filter
+		resolved.Value = value
+		resolved.ValuePath = ""
+		filter = &resolved
+	}
+
 	// Get the value to compare
 	var actualValue string
 	var exists bool
@@ -258,11 +605,27 @@ func evaluateFilterWithDepth(filter *Filter, content string, attrs map[string]st
 		// Fast path: Attribute filter - direct map lookup, no parsing
 		attrName := filter.Path[1:]
 		actualValue, exists = attrs[attrName]
+	} else if filter.Path == "#" {
+		// Position of the element among its siblings (#(#>=3))
+		actualValue, exists = strconv.Itoa(position), true
+	} else if filter.Path == "%" {
+		// The element's own direct text (#(%==foo)), as element.% reads it
+		actualValue, exists = unescapeXML(extractDirectTextOnly(content)), true
+	} else if segments := filter.pathSegments(); isNestedFilterPath(segments) {
+		// Nested path (e.g., item.@sku): matches if any element reached by
+		// the path satisfies the condition, not just the first one
+		matched := false
+		visited := 0
+		forEachFilterValue([]byte(content), segments, 0, &visited, func(value string) bool {
+			matched = filter.Op == OpExists || compareFilterValue(filter, value)
+			return !matched
+		})
+		return matched
 	} else {
 		// Element filter - extract text from specific child element
 		parser := newXMLParser([]byte(content))
 		parser.filterDepth = depth + 1
-		result := executeQuery(parser, parsePath(filter.Path), 0)
+		result := executeQuery(parser, segments, 0)
 		exists = result.Exists()
 		actualValue = result.String()
 	}
@@ -277,6 +640,11 @@ func evaluateFilterWithDepth(filter *Filter, content string, attrs map[string]st
 		return false
 	}
 
+	return compareFilterValue(filter, actualValue)
+}
+
+// compareFilterValue compares an actual value against the filter's operator and value.
+func compareFilterValue(filter *Filter, actualValue string) bool {
 	// Perform comparison based on operator
 	switch filter.Op {
 	case OpEqual:
@@ -351,14 +719,93 @@ func evaluateFilterWithDepth(filter *Filter, content string, attrs map[string]st
 			return matched
 		}
 		return !matched
+
+	case OpPrefix:
+		return strings.HasPrefix(actualValue, filter.Value)
+
+	case OpCustom:
+		fn := GetFilterOp(filter.CustomOp)
+		if fn == nil {
+			// Operator was unregistered after the path was parsed
+			return false
+		}
+		return fn(actualValue, filter.Value)
 	}
 
 	return false
 }
 
-// evaluateFilterOnMatch evaluates a filter against an elementMatch.
-func evaluateFilterOnMatch(filter *Filter, match elementMatch) bool {
-	return evaluateFilterWithDepth(filter, match.content, match.attrs, 0)
+// isNestedFilterPath reports whether a filter path is a dotted path of plain
+// element names, optionally ending in an attribute (e.g., item.@sku, a.b.c).
+// Such paths are evaluated with "any match" semantics across repeated elements.
+func isNestedFilterPath(segments []PathSegment) bool {
+	if len(segments) < 2 {
+		return false
+	}
+	for i, seg := range segments {
+		if len(seg.Modifiers) > 0 {
+			return false
+		}
+		switch {
+		case seg.Type == SegmentElement && seg.Filter == nil:
+		case seg.Type == SegmentAttribute && i == len(segments)-1:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// forEachFilterValue calls fn with the value of every element (or attribute)
+// reached by segments within data, stopping early when fn returns false.
+// Returns false if iteration was stopped.
+//
+// Security: At most MaxWildcardResults elements are visited in total.
+func forEachFilterValue(data []byte, segments []PathSegment, segIndex int, visited *int, fn func(string) bool) bool {
+	seg := segments[segIndex]
+	parser := newXMLParser(data)
+	for parser.skipToNextElement() {
+		if *visited >= MaxWildcardResults {
+			return false
+		}
+		parser.next() // skip '<'
+		name, attrs, _, isSelfClosing := parser.parseElementTag()
+
+		var content string
+		if !isSelfClosing {
+			content = parser.parseElementContent(name)
+		}
+		if !seg.matches(name) {
+			continue
+		}
+		*visited++
+
+		if segIndex == len(segments)-1 {
+			if !fn(unescapeXML(extractTextContent(content))) {
+				return false
+			}
+			continue
+		}
+
+		next := segments[segIndex+1]
+		if next.Type == SegmentAttribute {
+			if value, ok := attrs[next.Value]; ok && !fn(value) {
+				return false
+			}
+			continue
+		}
+
+		if content != "" && !forEachFilterValue([]byte(content), segments, segIndex+1, visited, fn) {
+			return false
+		}
+	}
+	return true
+}
+
+// evaluateFilterOnMatch evaluates a filter against an elementMatch at the
+// given zero-based position among the candidates for the filter.
+func evaluateFilterOnMatch(filter *Filter, match elementMatch, position int) bool {
+	return evaluateFilterWithDepth(filter, match.content, match.attrs, position, 0)
 }
 
 // isNumericValue checks if a string contains a valid numeric value (int or float).
@@ -764,6 +764,74 @@ func TestGJSONFilterAllMatches(t *testing.T) {
 	}
 }
 
+// TestGJSONFilterLimit tests #(condition)#:N, which keeps the first N matches
+func TestGJSONFilterLimit(t *testing.T) {
+	xml := `<catalog>
+		<product sku="a"><stock>0</stock><name>A</name></product>
+		<product sku="b"><stock>2</stock><name>B</name></product>
+		<product sku="c"><stock>1</stock><name>C</name></product>
+		<product sku="d"><stock>5</stock><name>D</name></product>
+		<product sku="e"><stock>3</stock><name>E</name></product>
+	</catalog>`
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"catalog.product.#(stock>0)#:3.name", `["B","C","D"]`},
+		{"catalog.product.#(stock>0)#:3.@sku", `["b","c","d"]`},
+		{"catalog.product.#(stock>0)#:3.#.name", `["B","C","D"]`},
+		{"catalog.product.#(stock>0)#:2.name|@reverse", `["C","B"]`},
+		{"catalog.product.#(stock>0)#:3.#", "3"},
+		{"catalog.product.#(stock>0)#:10.name", `["B","C","D","E"]`},
+		{"catalog.product.#(stock>0)#:10.#", "4"},
+		{"catalog.product.#(stock>0)#:1.name", "B"},
+		{"catalog.product.#(stock>9)#:3.name", ""},
+		{"catalog.product.#(stock>0)#:0.name", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := Get(xml, tt.path).String(); got != tt.want {
+				t.Errorf("Get(%q) = %s, want %s", tt.path, got, tt.want)
+			}
+			if got := GetWithOptions(xml, tt.path, &Options{CaseSensitive: true}).String(); got != tt.want {
+				t.Errorf("GetWithOptions(%q) = %s, want %s", tt.path, got, tt.want)
+			}
+		})
+	}
+
+	// Writes through a limited filter touch only the first N matches
+	got, err := Set(xml, "catalog.product.#(stock>0)#:2.name", "X")
+	if err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if names := Get(got, "catalog.product.#.name").String(); names != `["A","X","X","D","E"]` {
+		t.Errorf("Set() names = %s", names)
+	}
+}
+
+// TestGJSONFilterLimit_ShortCircuit checks that a limited filter stops
+// evaluating candidates once it has N matches.
+func TestGJSONFilterLimit_ShortCircuit(t *testing.T) {
+	evaluated := 0
+	if err := RegisterFilterOp("~>", func(left, right string) bool {
+		evaluated++
+		return left > right
+	}); err != nil {
+		t.Fatalf("RegisterFilterOp() error = %v", err)
+	}
+	defer func() { _ = UnregisterFilterOp("~>") }()
+
+	xml := `<list><v>1</v><v>2</v><v>3</v><v>4</v><v>5</v></list>`
+	if got := Get(xml, "list.v.#(%~>1)#:2").String(); got != `["2","3"]` {
+		t.Errorf("Get() = %s, want [\"2\",\"3\"]", got)
+	}
+	if evaluated != 3 {
+		t.Errorf("filter evaluated %d elements, want 3", evaluated)
+	}
+}
+
 // TestGJSONFilterAttributeFilters tests attribute filters with # syntax
 func TestGJSONFilterAttributeFilters(t *testing.T) {
 	xml := `<users>
@@ -1025,6 +1093,60 @@ func TestGJSONFilterEmptyResults(t *testing.T) {
 	}
 }
 
+// TestGJSONFilterSingleElement tests that a filter treats a single,
+// non-repeating element as a one-element set: it matches or excludes it
+// exactly as it would one element among many.
+func TestGJSONFilterSingleElement(t *testing.T) {
+	xml := `<root><item id="1"><name>a</name></item></root>`
+
+	tests := []struct {
+		name   string
+		path   string
+		exists bool
+		want   string
+	}{
+		{name: "first match attribute", path: "root.item.#(@id==1).name", exists: true, want: "a"},
+		{name: "first match element", path: "root.item.#(name==a).@id", exists: true, want: "1"},
+		{name: "first match excluded", path: "root.item.#(@id==2)", exists: false},
+		{name: "all matches", path: "root.item.#(@id==1)#.name", exists: true, want: "a"},
+		{name: "all matches count", path: "root.item.#(@id==1)#.#", exists: true, want: "1"},
+		{name: "all matches excluded", path: "root.item.#(@id==2)#", exists: false},
+		{name: "all matches excluded count", path: "root.item.#(@id==2)#.#", exists: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Get(xml, tt.path)
+			if result.Exists() != tt.exists {
+				t.Fatalf("Get(%q).Exists() = %v, want %v", tt.path, result.Exists(), tt.exists)
+			}
+			if tt.exists && result.String() != tt.want {
+				t.Errorf("Get(%q) = %q, want %q", tt.path, result.String(), tt.want)
+			}
+		})
+	}
+
+	// The same filters on a repeated element give the same answers.
+	repeated := `<root><item id="1"><name>a</name></item><item id="3"><name>b</name></item></root>`
+	for _, tt := range tests {
+		single := Get(xml, tt.path)
+		many := Get(repeated, tt.path)
+		if single.Exists() != many.Exists() || single.String() != many.String() {
+			t.Errorf("Get(%q): single element %q, repeated %q", tt.path, single.String(), many.String())
+		}
+	}
+
+	// A write through a filter updates the single element only on a match.
+	got, err := Set(xml, "root.item.#(@id==1).name", "z")
+	if err != nil || Get(got, "root.item.name").String() != "z" {
+		t.Errorf("Set through matching filter = %q, %v", got, err)
+	}
+	got, err = Set(xml, "root.item.#(@id==2).name", "z")
+	if err != nil || got != xml {
+		t.Errorf("Set through non-matching filter = %q, %v; want document unchanged", got, err)
+	}
+}
+
 // TestGJSONFilterWithModifiers tests filters combined with modifiers
 func TestGJSONFilterWithModifiers(t *testing.T) {
 	xml := `<items>
@@ -1151,8 +1273,8 @@ func TestFilterPathologicalInputs(t *testing.T) {
 		{
 			name:        "filter_with_escaped_chars",
 			path:        "root.item.#(name=='Test\\')",
-			shouldExist: true, // Backslash is treated as literal character
-			comment:     "Escaped characters in filter - backslash treated as literal",
+			shouldExist: false, // \' escapes the closing quote
+			comment:     "Escaped closing quote in filter - unterminated value is rejected",
 		},
 		{
 			name:        "filter_recursive_structure",
@@ -2106,3 +2228,515 @@ func TestPatternMatchLongExpression(t *testing.T) {
 		t.Error("Expected error for overly long filter expression")
 	}
 }
+
+// TestFilterNestedPath tests filters whose left operand is a dotted path,
+// optionally ending in an attribute of a nested child
+func TestFilterNestedPath(t *testing.T) {
+	xml := `<orders>
+		<order id="1"><item sku="XYZ"><qty>1</qty></item><item sku="ABC"><qty>5</qty></item></order>
+		<order id="2"><item sku="ABC"><qty>2</qty></item></order>
+		<order id="3"><item sku="DEF"><meta><tag k="gift"/></meta></item></order>
+		<order id="4"/>
+	</orders>`
+
+	tests := []struct {
+		name     string
+		path     string
+		expected string
+	}{
+		{"nested attribute any item", "orders.order.#(item.@sku==ABC)#.@id", `["1","2"]`},
+		{"nested attribute first match", "orders.order.#(item.@sku==ABC).@id", "1"},
+		{"nested attribute non-first item", "orders.order.#(item.@sku==DEF).@id", "3"},
+		{"deeply nested attribute", "orders.order.#(item.meta.tag.@k==gift).@id", "3"},
+		{"nested attribute existence", "orders.order.#(item.@sku)#.@id", `["1","2","3"]`},
+		{"nested element numeric", "orders.order.#(item.qty>4).@id", "1"},
+		{"nested attribute pattern", "orders.order.#(item.@sku%A*)#.@id", `["1","2"]`},
+		{"nested attribute no match", "orders.order.#(item.@sku==NOPE).@id", ""},
+		{"nested path continues after filter", "orders.order.#(item.@sku==DEF).item.meta.tag.@k", "gift"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Get(xml, tt.path)
+			if result.String() != tt.expected {
+				t.Errorf("Get(%q) = %q, want %q", tt.path, result.String(), tt.expected)
+			}
+		})
+	}
+}
+
+// TestFilterNestedPathParsedOnce tests that a filter's nested path is parsed
+// with the filter, not looked up in the path cache for every element
+func TestFilterNestedPathParsedOnce(t *testing.T) {
+	t.Cleanup(ClearPathCache)
+	ClearPathCache()
+
+	filter, err := parseFilter("item.qty>4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !filter.parsed || len(filter.segments) != 2 {
+		t.Fatalf("nested path not parsed with the filter: %+v", filter)
+	}
+	if attr, _ := parseFilter("@id==1"); attr.parsed {
+		t.Error("attribute filter path should not be parsed into segments")
+	}
+
+	xml := `<orders><order id="1"><item><qty>5</qty></item></order><order id="2"><item><qty>1</qty></item></order></orders>`
+	if got := Get(xml, "orders.order.#(item.qty>4)#.@id").String(); got != "1" {
+		t.Errorf("Get() = %q, want 1", got)
+	}
+	pathCacheMu.Lock()
+	_, cached := pathCache["item.qty"]
+	pathCacheMu.Unlock()
+	if cached {
+		t.Error("nested filter path should not be parsed through the path cache")
+	}
+}
+
+// TestFilterAttributeReference tests filters whose right operand is an
+// unquoted @attribute of the same element
+func TestFilterAttributeReference(t *testing.T) {
+	xml := `<ranges>
+		<r min="1" max="5" n="a"/>
+		<r min="7" max="3" n="b"/>
+		<r min="2" n="c"/>
+		<r min="@max" max="9" n="d"/>
+		<r min="4" max="4" n="e"><lim lo="3"/></r>
+	</ranges>`
+
+	tests := []struct {
+		name     string
+		path     string
+		expected string
+	}{
+		{"less or equal", "ranges.r.#(@min<=@max)#.@n", `["a","e"]`},
+		{"greater", "ranges.r.#(@min>@max)#.@n", "b"},
+		{"equal", "ranges.r.#(@min==@max)#.@n", "e"},
+		{"missing reference never matches", "ranges.r.#(@min!=@max)#.@n", `["a","b","d"]`},
+		{"quoted literal", `ranges.r.#(@min=="@max")#.@n`, "d"},
+		{"nested left operand", "ranges.r.#(lim.@lo<@max)#.@n", "e"},
+		{"combined with &&", "ranges.r.#(@min<=@max && @n!=a)#.@n", "e"},
+		{"count", "ranges.r.#(@min<=@max)#.#", "2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Get(xml, tt.path)
+			if result.String() != tt.expected {
+				t.Errorf("Get(%q) = %q, want %q", tt.path, result.String(), tt.expected)
+			}
+		})
+	}
+
+	for _, expr := range []string{"@min<=@", "@min<=@a.b"} {
+		if _, err := parseFilterCondition(expr); err == nil {
+			t.Errorf("parseFilterCondition(%q) expected error", expr)
+		}
+	}
+}
+
+// TestFilterQuotedValues tests quoted filter values containing separators,
+// operators and escaped quotes
+func TestFilterQuotedValues(t *testing.T) {
+	xml := `<cats>
+		<cat path="a.b.c" id="1"><name>Category, 1)</name></cat>
+		<cat path="x" id="2"><name>A#1</name></cat>
+		<cat id="3"><name>it's "q"</name></cat>
+		<cat id="4"><name>a|b==c</name></cat>
+		<cat id="5"><name>back\slash</name></cat>
+		<cat id="6"><name>Category 1</name></cat>
+	</cats>`
+
+	tests := []struct {
+		name     string
+		path     string
+		expected string
+	}{
+		{"closing paren and comma", `cats.cat.#(name=="Category, 1)")#.@id`, "1"},
+		{"dots in attribute value", `cats.cat.#(@path=='a.b.c').@id`, "1"},
+		{"hash", `cats.cat.#(name=="A#1").@id`, "2"},
+		{"escaped single quote", `cats.cat.#(name=='it\'s "q"').@id`, "3"},
+		{"escaped double quote", `cats.cat.#(name=="it's \"q\"").@id`, "3"},
+		{"pipe and operator", `cats.cat.#(name=="a|b==c")#|@first`, "a|b==c"},
+		{"escaped backslash", `cats.cat.#(name=="back\\slash").@id`, "5"},
+		{"not equal", `cats.cat.#(name!="Category, 1)").@id`, "2"},
+		{"unquoted spaces", `cats.cat.#(name==Category 1).@id`, "6"},
+		{"path continues after quoted filter", `cats.cat.#(@path=='a.b.c').name`, "Category, 1)"},
+		{"escaped closing quote", `cats.cat.#(name=="A#1\").@id`, ""},
+		{"text after closing quote", `cats.cat.#(name=="A"#1").@id`, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Get(xml, tt.path)
+			if result.String() != tt.expected {
+				t.Errorf("Get(%q) = %q, want %q", tt.path, result.String(), tt.expected)
+			}
+		})
+	}
+}
+
+// TestFilterEntities tests that filters compare decoded values, with entity
+// references allowed in the source and in the literal
+func TestFilterEntities(t *testing.T) {
+	xml := `<shows>
+		<show id="1" title="Tom &amp; Jerry"><name>Tom &amp; Jerry</name><rule>a &lt; b</rule></show>
+		<show id="2" title="Plain"><name>Plain</name><rule>a &gt; b</rule></show>
+	</shows>`
+
+	tests := []struct {
+		name     string
+		path     string
+		expected string
+	}{
+		{"decoded literal", `shows.show.#(name==Tom & Jerry).@id`, "1"},
+		{"encoded literal", `shows.show.#(name==Tom &amp; Jerry).@id`, "1"},
+		{"quoted encoded literal", `shows.show.#(name=="Tom &amp; Jerry").@id`, "1"},
+		{"attribute with encoded literal", `shows.show.#(@title==Tom &amp; Jerry).@id`, "1"},
+		{"attribute with decoded literal", `shows.show.#(@title=="Tom & Jerry").@id`, "1"},
+		{"less than decoded", `shows.show.#(rule=="a < b").@id`, "1"},
+		{"less than encoded", `shows.show.#(rule==a &lt; b).@id`, "1"},
+		{"greater than encoded", `shows.show.#(rule==a &gt; b).@id`, "2"},
+		{"not equal encoded", `shows.show.#(name!=Tom &amp; Jerry)#.@id`, "2"},
+		{"prefix encoded", `shows.show.#(rule^=a &lt;).@id`, "1"},
+		{"pattern encoded", `shows.show.#(name%"Tom &amp;*").@id`, "1"},
+		{"nested path", `shows.#(show.name==Tom &amp; Jerry).show.@id`, "1"},
+		{"nested attribute path", `shows.#(show.@title=="Tom &amp; Jerry").show.@id`, "1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Get(xml, tt.path).String(); got != tt.expected {
+				t.Errorf("Get(%q) = %q, want %q", tt.path, got, tt.expected)
+			}
+		})
+	}
+
+	// Writes select the same elements
+	updated, err := Set(xml, `shows.show.#(name==Tom &amp; Jerry).@seen`, "yes")
+	if err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if got := Get(updated, "shows.show.#(@seen==yes).@id").String(); got != "1" {
+		t.Errorf("Set() updated show %q, want 1", got)
+	}
+}
+
+// TestFilterCombinedConditions tests && conditions, the ^= prefix operator and
+// namespace-prefixed attribute operands
+func TestFilterCombinedConditions(t *testing.T) {
+	xml := `<manifest xmlns:android="http://schemas.android.com/apk/res/android"><application>
+		<activity android:name=".MainActivity" android:exported="true"><label>Main</label></activity>
+		<activity android:name=".MainSettings" android:exported="false"><label>Settings</label></activity>
+		<activity android:name=".Other" android:exported="true"><label>a &amp;&amp; b</label></activity>
+		<activity android:name=".MainHelp" android:exported="true"><label>Help</label></activity>
+	</application></manifest>`
+	base := "manifest.application.activity."
+
+	tests := []struct {
+		name     string
+		path     string
+		expected string
+	}{
+		{"namespaced attributes with prefix", `#(@android:exported==true && @android:name^=.Main)#.@android:name`, `[".MainActivity",".MainHelp"]`},
+		{"element and attribute operands", `#(label^=Hel && @android:exported==true).@android:name`, ".MainHelp"},
+		{"no spaces around &&", `#(@android:exported==true&&label!=Main)#.label`, `["a && b","Help"]`},
+		{"&& inside quoted value", `#(label=='a && b' && @android:exported==true).@android:name`, ".Other"},
+		{"existence and comparison", `#(@android:exported && label==Settings).@android:name`, ".MainSettings"},
+		{"three conditions", `#(@android:exported==true && @android:name^=.Main && label!=Main)#.label`, "Help"},
+		{"no match", `#(@android:exported==false && @android:name^=.Other).label`, ""},
+		{"prefix alone", `#(@android:name^=.Main)#.label`, `["Main","Settings","Help"]`},
+		{"trailing &&", `#(@android:exported==true &&).label`, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Get(xml, base+tt.path)
+			if result.String() != tt.expected {
+				t.Errorf("Get(%q) = %q, want %q", tt.path, result.String(), tt.expected)
+			}
+		})
+	}
+
+	// Combined filters select write targets too
+	updated, err := Set(xml, base+`#(@android:exported==true && @android:name^=.Main)#.@enabled`, "false")
+	if err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if got := Get(updated, base+"#(@enabled==false)#.@android:name").String(); got != `[".MainActivity",".MainHelp"]` {
+		t.Errorf("Set() updated %s, want MainActivity and MainHelp", got)
+	}
+}
+
+// TestFilterCount tests counting filter matches with #(condition)#.#
+func TestFilterCount(t *testing.T) {
+	xml := `<company>
+		<employee status="active"><name>Ann</name></employee>
+		<employee status="inactive"><name>Bob</name></employee>
+		<employee status="active"><name>Cid</name></employee>
+		<contractor status="active"><name>Dee</name></contractor>
+	</company>`
+
+	tests := []struct {
+		name     string
+		path     string
+		expected Type
+		count    int
+	}{
+		{"active employees", "company.employee.#(@status==active)#.#", Number, 2},
+		{"child element condition", "company.employee.#(name!=Bob)#.#", Number, 2},
+		{"all employees", "company.employee.#(@status)#.#", Number, 3},
+		{"no match", "company.employee.#(@status==retired)#.#", Null, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Get(xml, tt.path)
+			if result.Type != tt.expected || result.Int() != int64(tt.count) {
+				t.Errorf("Get(%q) = %v %q, want %v %d", tt.path, result.Type, result.String(), tt.expected, tt.count)
+			}
+			if want := len(Get(xml, strings.TrimSuffix(tt.path, ".#")).Array()); result.Int() != int64(want) {
+				t.Errorf("Get(%q) = %d, want the length of the filtered array %d", tt.path, result.Int(), want)
+			}
+		})
+	}
+
+	opts := &Options{CaseSensitive: false}
+	if result := GetWithOptions(xml, "company.EMPLOYEE.#(@status==active)#.#", opts); result.Int() != 2 {
+		t.Errorf("GetWithOptions() = %q, want 2", result.String())
+	}
+
+	// The count is capped like the filtered array
+	var b strings.Builder
+	b.WriteString("<r>")
+	for i := 0; i < MaxWildcardResults+10; i++ {
+		b.WriteString(`<i ok="1"/>`)
+	}
+	b.WriteString("</r>")
+	if result := Get(b.String(), "r.i.#(@ok==1)#.#"); result.Int() != MaxWildcardResults {
+		t.Errorf("Get() over the limit = %q, want %d", result.String(), MaxWildcardResults)
+	}
+}
+
+// TestFilterText tests extracting direct text after a filter with #(condition)#.%
+func TestFilterText(t *testing.T) {
+	xml := `<users>
+		<user active="true">Ann</user>
+		<user active="false">Bob</user>
+		<user active="true">Cid<note>new</note></user>
+	</users>`
+
+	tests := []struct {
+		name     string
+		path     string
+		expected string
+	}{
+		{"all matches", "users.user.#(@active==true)#.%", `["Ann","Cid"]`},
+		{"first match", "users.user.#(@active==true).%", "Ann"},
+		{"single match", "users.user.#(@active==false)#.%", "Bob"},
+		{"with modifier", "users.user.#(@active==true)#.%|@reverse", `["Cid","Ann"]`},
+		{"joined", "users.user.#(@active==true)#.%|@join", "AnnCid"},
+		{"joined with separator", "users.user.#(@active==true)#.%|@join:,", "Ann,Cid"},
+		{"no match", "users.user.#(@active==maybe)#.%", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Get(xml, tt.path).String(); got != tt.expected {
+				t.Errorf("Get(%q) = %q, want %q", tt.path, got, tt.expected)
+			}
+			opts := &Options{CaseSensitive: false}
+			if got := GetWithOptions(xml, tt.path, opts).String(); got != tt.expected {
+				t.Errorf("GetWithOptions(%q) = %q, want %q", tt.path, got, tt.expected)
+			}
+		})
+	}
+}
+
+// TestFilterPosition tests filtering on an element's position with #(#>=3)
+func TestFilterPosition(t *testing.T) {
+	xml := `<r>
+		<item><price>5</price></item>
+		<note/>
+		<item><price>20</price></item>
+		<item><price>30</price></item>
+		<item><price>1</price></item>
+		<item><price>50</price></item>
+	</r>`
+
+	tests := []struct {
+		name     string
+		path     string
+		expected string
+	}{
+		{"from index", "r.item.#(#>=3)#.price", `["1","50"]`},
+		{"first from index", "r.item.#(#>=3).price", "1"},
+		{"exact position", "r.item.#(#==1).price", "20"},
+		{"other siblings are not counted", "r.item.#(#==2).price", "30"},
+		{"count", "r.item.#(#>=3)#.#", "2"},
+		{"combined with a condition", "r.item.#(#<3 && price>10)#.price", `["20","30"]`},
+		{"condition first", "r.item.#(price>10 && #<3)#.price", `["20","30"]`},
+		{"position of all children", "r.#(#==1)", ""},
+		{"past the end", "r.item.#(#>9)#.price", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Get(xml, tt.path).String(); got != tt.expected {
+				t.Errorf("Get(%q) = %q, want %q", tt.path, got, tt.expected)
+			}
+			opts := &Options{CaseSensitive: false}
+			if got := GetWithOptions(xml, tt.path, opts).String(); got != tt.expected {
+				t.Errorf("GetWithOptions(%q) = %q, want %q", tt.path, got, tt.expected)
+			}
+		})
+	}
+
+	// Positions select write targets too
+	updated, err := Set(xml, "r.item.#(#>=3)#.price", "0")
+	if err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if got := Get(updated, "r.item.#.price|@join:,").String(); got != "5,20,30,0,0" {
+		t.Errorf("Set() prices = %q, want 5,20,30,0,0", got)
+	}
+}
+
+// TestFilterOwnText tests filtering elements on their own text with %
+func TestFilterOwnText(t *testing.T) {
+	xml := `<r>
+		<value>foo</value>
+		<value>bar</value>
+		<value> foo <x>y</x></value>
+		<value>Tom &amp; Jerry</value>
+		<value>12</value>
+	</r>`
+
+	tests := []struct {
+		name     string
+		path     string
+		expected string
+	}{
+		{"equal", "r.value.#(%==foo)#.%", `["foo","foo"]`},
+		{"first match", "r.value.#(%==bar)", "bar"},
+		{"not equal", "r.value.#(%!=foo)#.%", `["bar","Tom & Jerry","12"]`},
+		{"spaces around operator", "r.value.#(% == bar)", "bar"},
+		{"pattern", "r.value.#(%%'b*')#.%", "bar"},
+		{"negated pattern", "r.value.#(%!%'*o*')#.%", `["bar","12"]`},
+		{"numeric", "r.value.#(%>10)", "12"},
+		{"decoded text", "r.value.#(%=='Tom & Jerry')", "Tom & Jerry"},
+		{"combined with position", "r.value.#(%==foo && #>0)#.x", "y"},
+		{"count", "r.value.#(%==foo)#.#", "2"},
+		{"no match", "r.value.#(%==baz)#.%", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Get(xml, tt.path).String(); got != tt.expected {
+				t.Errorf("Get(%q) = %q, want %q", tt.path, got, tt.expected)
+			}
+			opts := &Options{CaseSensitive: false}
+			if got := GetWithOptions(xml, tt.path, opts).String(); got != tt.expected {
+				t.Errorf("GetWithOptions(%q) = %q, want %q", tt.path, got, tt.expected)
+			}
+		})
+	}
+
+	for _, path := range []string{"r.value.#(%)", "r.value.#(%x==foo)"} {
+		if r := Get(xml, path); r.Exists() {
+			t.Errorf("Get(%q) = %q, want no result", path, r.String())
+		}
+	}
+
+	updated, err := Set(xml, "r.value.#(%==bar)#", "baz")
+	if err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if got := Get(updated, "r.value.#(%==baz)#.#").String(); got != "1" {
+		t.Errorf("Set() matches = %q, want 1", got)
+	}
+}
+
+// TestRegisterFilterOp tests registering, using, listing and unregistering custom filter operators
+func TestRegisterFilterOp(t *testing.T) {
+	xml := `<users>
+		<user id="1"><name>Alice</name><tags>a,b</tags></user>
+		<user id="2"><name>Bob</name><tags>c</tags></user>
+		<user id="3"><name>alice</name><tags>b,c</tags></user>
+	</users>`
+
+	equalFold := func(left, right string) bool { return strings.EqualFold(left, right) }
+	contains := func(left, right string) bool { return strings.Contains(left, right) }
+
+	// Parse before registration so the path cache holds the old interpretation
+	if r := Get(xml, "users.user.#(name~~ALICE)#.@id"); r.Exists() {
+		t.Fatalf("Expected no match before registration, got %q", r.String())
+	}
+
+	if err := RegisterFilterOp("~~", equalFold); err != nil {
+		t.Fatalf("RegisterFilterOp failed: %v", err)
+	}
+	if err := RegisterFilterOp("=~", contains); err != nil {
+		t.Fatalf("RegisterFilterOp failed: %v", err)
+	}
+	defer func() {
+		_ = UnregisterFilterOp("~~")
+		_ = UnregisterFilterOp("=~")
+	}()
+
+	tests := []struct {
+		name     string
+		path     string
+		expected string
+	}{
+		{"custom op all matches", "users.user.#(name~~ALICE)#.@id", `["1","3"]`},
+		{"custom op first match", "users.user.#(name~~BOB).@id", "2"},
+		{"custom op on attribute", "users.user.#(@id=~2).name", "Bob"},
+		{"custom op quoted value", `users.user.#(tags=~'b,').@id`, "3"},
+		{"custom op nested path", "users.#(user.name~~bob).user.@id", "1"},
+		{"built-in still works", "users.user.#(name==alice).@id", "3"},
+		{"built-in not shadowed", "users.user.#(@id>=2)#.@id", `["2","3"]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Get(xml, tt.path)
+			if result.String() != tt.expected {
+				t.Errorf("Get(%q) = %q, want %q", tt.path, result.String(), tt.expected)
+			}
+		})
+	}
+
+	if ops := FilterOps(); len(ops) != 2 || ops[0] != "=~" || ops[1] != "~~" {
+		t.Errorf("FilterOps() = %v, want [=~ ~~]", ops)
+	}
+	if GetFilterOp("~~") == nil {
+		t.Error("GetFilterOp(\"~~\") returned nil")
+	}
+
+	if err := RegisterFilterOp("~~", equalFold); err == nil {
+		t.Error("Expected error registering duplicate operator")
+	}
+	for _, op := range []string{"", "==", "%", "~~~~~", "a~", "~.", "~ ", "-", "~|"} {
+		if err := RegisterFilterOp(op, equalFold); err == nil {
+			_ = UnregisterFilterOp(op)
+			t.Errorf("Expected error registering invalid operator %q", op)
+		}
+	}
+	if err := RegisterFilterOp("^^", nil); err == nil {
+		t.Error("Expected error registering nil function")
+	}
+	if err := UnregisterFilterOp("=="); err == nil {
+		t.Error("Expected error unregistering built-in operator")
+	}
+
+	if err := UnregisterFilterOp("~~"); err != nil {
+		t.Fatalf("UnregisterFilterOp failed: %v", err)
+	}
+	if GetFilterOp("~~") != nil {
+		t.Error("Expected operator to be removed")
+	}
+	if r := Get(xml, "users.user.#(name~~ALICE)#.@id"); r.Exists() {
+		t.Errorf("Expected no match after unregistering, got %q", r.String())
+	}
+}
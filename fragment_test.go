@@ -4,6 +4,7 @@
 package xmldot
 
 import (
+	"errors"
 	"testing"
 )
 
@@ -786,3 +787,95 @@ func BenchmarkSetFragmentSiblingRoots(b *testing.B) {
 		}
 	})
 }
+
+// TestFragmentRootAccessor tests the synthetic #root accessor for top-level siblings
+func TestFragmentRootAccessor(t *testing.T) {
+	fragment := `<a id="1">first</a>
+<b><name>Bob</name></b>
+<!-- comment -->
+<a id="3">third</a>`
+
+	tests := []struct {
+		name     string
+		path     string
+		expected string
+	}{
+		{"first root", "#root.0", "first"},
+		{"second root child", "#root.1.name", "Bob"},
+		{"third root", "#root.2", "third"},
+		{"third root attribute", "#root.2.@id", "3"},
+		{"count roots", "#root.#", "3"},
+		{"field extraction", "#root.#.@id", `["1","3"]`},
+		{"out of bounds", "#root.3", ""},
+		{"negative index", "#root.-1", ""},
+		{"not at start", "a.#root", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Get(fragment, tt.path)
+			if result.String() != tt.expected {
+				t.Errorf("Get(%q) = %q, want %q", tt.path, result.String(), tt.expected)
+			}
+		})
+	}
+
+	t.Run("all roots", func(t *testing.T) {
+		result := Get(fragment, "#root")
+		if !result.IsArray() || len(result.Array()) != 3 {
+			t.Fatalf("Expected array of 3 roots, got %v", result)
+		}
+		if result.Array()[1].Get("name").String() != "Bob" {
+			t.Errorf("Expected second root to contain Bob, got %q", result.Array()[1].Raw)
+		}
+	})
+
+	t.Run("single root document", func(t *testing.T) {
+		xml := `<root><child>x</child></root>`
+		if got := Get(xml, "#root.0.child").String(); got != "x" {
+			t.Errorf("Expected 'x', got %q", got)
+		}
+		if got := Get(xml, "#root.#").Int(); got != 1 {
+			t.Errorf("Expected count 1, got %d", got)
+		}
+	})
+
+	t.Run("filter roots", func(t *testing.T) {
+		result := Get(fragment, "#root.#(@id==3)")
+		if result.String() != "third" {
+			t.Errorf("Expected 'third', got %q", result.String())
+		}
+	})
+
+	t.Run("with options", func(t *testing.T) {
+		opts := &Options{CaseSensitive: false}
+		result := GetWithOptions(fragment, "#root.1.NAME", opts)
+		if result.String() != "Bob" {
+			t.Errorf("Expected 'Bob', got %q", result.String())
+		}
+	})
+
+	t.Run("strict query", func(t *testing.T) {
+		strict := &Options{CaseSensitive: true, Strict: true}
+		if _, err := QueryWithOptions(fragment, "#root.1.name", strict); !errors.Is(err, ErrMalformedXML) {
+			t.Errorf("Expected ErrMalformedXML without AllowFragment, got %v", err)
+		}
+		strict.AllowFragment = true
+		result, err := QueryWithOptions(fragment, "#root.1.name", strict)
+		if err != nil || result.String() != "Bob" {
+			t.Errorf("Expected 'Bob' with AllowFragment, got %q, %v", result.String(), err)
+		}
+	})
+
+	t.Run("read-only", func(t *testing.T) {
+		if _, err := Set(fragment, "#root.0", "x"); !errors.Is(err, ErrInvalidPath) {
+			t.Errorf("Set: expected ErrInvalidPath, got %v", err)
+		}
+		if _, err := Delete(fragment, "#root.0"); !errors.Is(err, ErrInvalidPath) {
+			t.Errorf("Delete: expected ErrInvalidPath, got %v", err)
+		}
+		if err := CanSet(fragment, "#root.0", "x"); !errors.Is(err, ErrInvalidPath) {
+			t.Errorf("CanSet: expected ErrInvalidPath, got %v", err)
+		}
+	})
+}
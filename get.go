@@ -4,7 +4,11 @@
 package xmldot
 
 import (
+	"bytes"
+	"fmt"
+	"runtime"
 	"strings"
+	"sync"
 	"unsafe"
 )
 
@@ -26,6 +30,7 @@ import (
 //   - Document size limit: Documents larger than MaxDocumentSize (10MB) are rejected
 //   - Nesting depth limit: XML nesting deeper than MaxNestingDepth (100 levels) is truncated
 //   - Attribute limit: Elements with more than MaxAttributes (100) have excess attributes ignored
+//     (use GetWithOptions with Options.AttributeOverflowError to reject them instead)
 //   - Token size limit: Tokens larger than MaxTokenSize (1MB) are truncated
 //   - DOCTYPE skipping: DOCTYPE declarations are skipped to prevent XXE attacks
 //
@@ -59,7 +64,7 @@ import (
 //	name := Get(xml, "root.user.name")
 //	fmt.Println(name.String()) // "John"
 func Get(xml, path string) Result {
-	return GetBytes([]byte(xml), path)
+	return GetBytes(stringToBytes(xml), path)
 }
 
 // GetString is like Get but optimized for string input with zero-copy conversion.
@@ -91,7 +96,7 @@ func GetBytes(xml []byte, path string) Result {
 	parser := newXMLParser(xml)
 
 	// Execute the query
-	return executeQuery(parser, segments, 0)
+	return markMulti(executeQuery(parser, segments, 0), segments)
 }
 
 const (
@@ -104,18 +109,155 @@ const (
 	MaxRecursiveOperations = 10000
 )
 
+// docSpan holds the offsets of an element in the queried document: its
+// opening '<', the start of its content and the end of its closing tag (or
+// "/>"). The zero value is an unknown span.
+type docSpan struct {
+	start, content, end int
+}
+
+// known reports whether s locates an element.
+func (s docSpan) known() bool {
+	return s.end > 0
+}
+
 // elementMatch represents a matched element with its attributes and content
 type elementMatch struct {
 	name          string
 	attrs         map[string]string
+	attrOrder     []string // Attribute names in document order
 	content       string
 	isSelfClosing bool
+	tag           []byte  // Opening tag in the input, for attribute Raw
+	span          docSpan // Location in the input, for Result.Span
+}
+
+// decodeText decodes the entity references in direct text read with %,
+// unless opts.RawText asks for the text as written in the document.
+func decodeText(text string, opts *Options) string {
+	if opts != nil && opts.RawText {
+		return text
+	}
+	return unescapeXML(text)
+}
+
+// newElementResult builds an Element Result from a matched element.
+// The element's tag name and own attributes (in document order) are carried along.
+func newElementResult(match elementMatch) Result {
+	return Result{
+		Type:  Element,
+		Str:   unescapeXML(extractTextContent(match.content)),
+		Raw:   match.content,
+		attrs: orderedAttrs(match.attrs, match.attrOrder),
+		name:  match.name,
+		tag:   attributeTag(match),
+		span:  match.span,
+	}
+}
+
+// attributeTag copies the opening tag of match for a Result, so that
+// attributes read from the Result later keep their source text.
+func attributeTag(match elementMatch) string {
+	if len(match.attrs) == 0 {
+		return ""
+	}
+	return string(match.tag)
+}
+
+// newAttributeResult builds an Attribute Result for the attribute name of
+// the opening tag tag. Str is the unescaped value and Raw is the attribute's
+// declaration as written in tag, e.g. id='1'. Without a tag declaring name,
+// Raw is name="value" with the value escaped.
+func newAttributeResult(tag []byte, name, value string) Result {
+	raw, ok := attributeSource(tag, name)
+	if !ok {
+		raw = name + `="` + escapeXML(value) + `"`
+	}
+	return Result{Type: Attribute, Str: value, Raw: raw}
+}
+
+// countMatchingElements counts the remaining sibling elements whose names
+// satisfy match. Elements are skipped rather than captured, so counting uses
+// constant memory and is not bounded by MaxWildcardResults.
+func countMatchingElements(parser *xmlParser, match func(name string) bool) int {
+	count := 0
+	for parser.skipToNextElement() {
+		parser.next() // skip '<'
+		elemName, _, isSelfClosing := parser.parseElementName()
+		if match(elemName) {
+			count++
+		}
+		if !isSelfClosing {
+			parser.skipElementContent(elemName)
+		}
+	}
+	return count
+}
+
+// countFilterMatches streams through the elements at the parser's level and
+// counts those accepted by match and the filter of seg without collecting
+// them, for #(condition)#.# queries. Counting stops at the filter's limit,
+// the most elements #(condition)# or #(condition)#:N can return.
+func countFilterMatches(parser *xmlParser, match func(name string) bool, seg PathSegment) Result {
+	count := 0
+	position := 0
+	limit := filterLimit(seg)
+	for count < limit && parser.skipToNextElement() {
+		parser.next() // skip '<'
+		elemName, attrs, attrOrder, isSelfClosing := parser.parseElementTag()
+		tag := parser.tag
+		if !match(elemName) {
+			if !isSelfClosing {
+				parser.skipElementContent(elemName)
+			}
+			continue
+		}
+		position++
+
+		var content string
+		if !isSelfClosing {
+			content = parser.parseElementContent(elemName)
+		}
+		if evaluateFilterOnMatch(seg.Filter, elementMatch{
+			name:          elemName,
+			attrs:         attrs,
+			tag:           tag,
+			span:          parser.span(),
+			attrOrder:     attrOrder,
+			content:       content,
+			isSelfClosing: isSelfClosing,
+		}, position-1) {
+			count++
+		}
+	}
+	if count == 0 {
+		return Result{Type: Null}
+	}
+	return newCountResult(count)
+}
+
+// isFilterCount reports whether the filter segment at segIndex is a
+// #(condition)# followed only by the # count operator.
+func isFilterCount(segments []PathSegment, segIndex int) bool {
+	return segIndex+2 == len(segments) &&
+		segments[segIndex].FilterAll &&
+		segments[segIndex+1].Type == SegmentCount
+}
+
+// newCountResult builds the Number Result returned by the # operator.
+func newCountResult(count int) Result {
+	return Result{
+		Type: Number,
+		Num:  float64(count),
+		Str:  itoa(count),
+	}
 }
 
 // searchContext tracks recursive search operations to prevent DoS attacks
 type searchContext struct {
 	operations int
 	results    *[]Result
+	truncated  bool // a match was dropped because results hit MaxWildcardResults
 }
 
 // collectFragmentRoots collects all root elements matching the target name in a fragment.
@@ -131,7 +273,8 @@ func collectFragmentRoots(parser *xmlParser, targetName string) []elementMatch {
 		}
 
 		parser.next() // skip '<'
-		elemName, attrs, isSelfClosing := parser.parseElementName()
+		elemName, attrs, attrOrder, isSelfClosing := parser.parseElementTag()
+		tag := parser.tag
 
 		// Only collect roots with matching name
 		if elemName != targetName {
@@ -153,6 +296,9 @@ func collectFragmentRoots(parser *xmlParser, targetName string) []elementMatch {
 		matches = append(matches, elementMatch{
 			name:          elemName,
 			attrs:         attrs,
+			tag:           tag,
+			span:          parser.span(),
+			attrOrder:     attrOrder,
 			content:       content,
 			isSelfClosing: isSelfClosing,
 		})
@@ -161,6 +307,226 @@ func collectFragmentRoots(parser *xmlParser, targetName string) []elementMatch {
 	return matches
 }
 
+// handleRootQuery resolves a path starting with the synthetic #root accessor,
+// which treats the top-level elements of a document or fragment as an array:
+//   - "#root" returns all top-level elements
+//   - "#root.N" selects the Nth top-level element (and continues below it)
+//   - "#root.#" counts top-level elements
+//   - "#root.#.field" and "#root.#(cond)" extract from or filter them
+//
+// A nil opts selects the default (fast path) matching functions.
+// Security: Limited to MaxWildcardResults top-level elements (counting is streamed).
+func handleRootQuery(parser *xmlParser, segments []PathSegment, opts *Options) Result {
+	matchAll := func(string) bool { return true }
+
+	if len(segments) > 1 && segments[1].Type == SegmentCount {
+		count := countMatchingElements(parser, matchAll)
+		if count == 0 {
+			return Result{Type: Null}
+		}
+		result := newCountResult(count)
+		if len(segments[1].Modifiers) > 0 {
+			result = applyModifiersWithOptions(result, segments[1].Modifiers, opts)
+		}
+		return result
+	}
+
+	var matches []elementMatch
+	for parser.skipToNextElement() {
+		if len(matches) >= MaxWildcardResults {
+			break
+		}
+		parser.next() // skip '<'
+		elemName, attrs, attrOrder, isSelfClosing := parser.parseElementTag()
+		tag := parser.tag
+
+		var content string
+		if !isSelfClosing {
+			content = parser.parseElementContent(elemName)
+		}
+		matches = append(matches, elementMatch{
+			name:          elemName,
+			attrs:         attrs,
+			tag:           tag,
+			span:          parser.span(),
+			attrOrder:     attrOrder,
+			content:       content,
+			isSelfClosing: isSelfClosing,
+		})
+	}
+	if len(matches) == 0 {
+		return Result{Type: Null}
+	}
+
+	// Continue as if #root were a wildcard over the collected top-level elements
+	wildcardMatches := func(matches []elementMatch, segments []PathSegment) Result {
+		if opts != nil {
+			return handleWildcardMatchesWithOptions(matches, segments, 0, opts)
+		}
+		return handleWildcardMatches(matches, segments, 0)
+	}
+
+	if len(segments) == 1 {
+		result := wildcardMatches(matches, segments)
+		if len(segments[0].Modifiers) > 0 {
+			result = applyModifiersWithOptions(result, segments[0].Modifiers, opts)
+		}
+		return result
+	}
+
+	nextSeg := segments[1]
+	switch nextSeg.Type {
+	case SegmentIndex:
+		if nextSeg.Index < 0 || nextSeg.Index >= len(matches) {
+			return Result{Type: Null}
+		}
+		// Drop the index segment and resolve the rest against the selected element
+		rest := make([]PathSegment, 0, len(segments)-1)
+		rest = append(rest, PathSegment{Type: SegmentRoot})
+		rest = append(rest, segments[2:]...)
+		result := wildcardMatches(matches[nextSeg.Index:nextSeg.Index+1], rest)
+		if len(rest) == 1 && len(nextSeg.Modifiers) > 0 {
+			result = applyModifiersWithOptions(result, nextSeg.Modifiers, opts)
+		}
+		return result
+	case SegmentFieldExtraction:
+		if opts != nil {
+			return executeFieldExtractionWithOptions(matches, nextSeg, opts)
+		}
+		return executeFieldExtraction(matches, nextSeg)
+	case SegmentFilter:
+		if opts != nil {
+			return handleFilterQueryOnMatchesWithOptions(matches, segments, 1, opts)
+		}
+		return handleFilterQueryOnMatches(matches, segments, 1)
+	default:
+		return wildcardMatches(matches, segments)
+	}
+}
+
+// modifierBoundary returns the index of the first segment that carries
+// modifiers and is followed by further segments, or -1 if there is none.
+func modifierBoundary(segments []PathSegment) int {
+	for i := 0; i < len(segments)-1; i++ {
+		if len(segments[i].Modifiers) > 0 {
+			return i
+		}
+	}
+	return -1
+}
+
+// attributePosition reports whether seg addresses an attribute by position
+// (@0, @1, ...) in document order, or counts the attributes (@#). Attribute
+// names cannot start with a digit or '#', so neither form clashes with a name.
+func attributePosition(seg PathSegment) (index int, count, ok bool) {
+	if seg.Type != SegmentAttribute || seg.Value == "" {
+		return 0, false, false
+	}
+	if seg.Value == "#" {
+		return 0, true, true
+	}
+	for i := 0; i < len(seg.Value); i++ {
+		if seg.Value[i] < '0' || seg.Value[i] > '9' {
+			return 0, false, false
+		}
+		if index > MaxAttributes {
+			// Beyond any element's attributes; stop before overflowing
+			return MaxAttributes, false, true
+		}
+		index = index*10 + int(seg.Value[i]-'0')
+	}
+	return index, false, true
+}
+
+// positionalAttribute returns the index of the first segment that addresses
+// an attribute by position or counts attributes, or -1. Such a segment is
+// resolved against the element matched by the segments before it.
+func positionalAttribute(segments []PathSegment) int {
+	for i := 1; i < len(segments); i++ {
+		if _, _, ok := attributePosition(segments[i]); ok {
+			return i
+		}
+	}
+	return -1
+}
+
+// continueAfterModifier resolves the segments that follow a modifier chain
+// relative to the chain's Result, much like Result.Get: an Element is queried
+// through its Raw content (so the next segment names one of its children, or
+// one of its own attributes), and an Array is queried through its first item. Arrays also
+// accept an index ("|@reverse.0") or a count ("|@sort.#") as the next segment.
+// Primitive values cannot be navigated and yield Null.
+func continueAfterModifier(r Result, rest []PathSegment, opts *Options) Result {
+	switch r.Type {
+	case Array:
+		if len(r.Results) == 0 {
+			return Result{Type: Null}
+		}
+		switch rest[0].Type {
+		case SegmentIndex:
+			index := rest[0].Index
+			if index < 0 {
+				index += len(r.Results)
+			}
+			if index < 0 || index >= len(r.Results) {
+				return Result{Type: Null}
+			}
+			item := applyModifiersWithOptions(r.Results[index], rest[0].Modifiers, opts)
+			if len(rest) == 1 || item.Type == Null {
+				return item
+			}
+			return continueAfterModifier(item, rest[1:], opts)
+		case SegmentCount:
+			if len(rest) == 1 {
+				return applyModifiersWithOptions(newCountResult(len(r.Results)), rest[0].Modifiers, opts)
+			}
+		}
+		return continueAfterModifier(r.Results[0], rest, opts)
+
+	case Element:
+		// The element's own attributes are not part of Raw
+		if rest[0].Type == SegmentAttribute {
+			if len(rest) > 1 {
+				return Result{Type: Null}
+			}
+			if index, count, ok := attributePosition(rest[0]); ok {
+				if count {
+					return applyModifiersWithOptions(newCountResult(len(r.attrs)), rest[0].Modifiers, opts)
+				}
+				if index >= len(r.attrs) {
+					return Result{Type: Null}
+				}
+				attr := r.attrs[index]
+				return applyModifiersWithOptions(newAttributeResult(stringToBytes(r.tag), attr.Name, attr.Value), rest[0].Modifiers, opts)
+			}
+			for _, attr := range r.attrs {
+				if attr.Name == rest[0].Value || (opts != nil && !opts.CaseSensitive && toLowerASCII(attr.Name) == rest[0].Value) {
+					return applyModifiersWithOptions(newAttributeResult(stringToBytes(r.tag), attr.Name, attr.Value), rest[0].Modifiers, opts)
+				}
+			}
+			return Result{Type: Null}
+		}
+
+		data := r.Raw
+		segments := rest
+		if isMultiRootFragment(data) {
+			// Wrap sibling children so array operations see a single parent
+			data = "<_xmldot_root>" + data + "</_xmldot_root>"
+			segments = make([]PathSegment, 0, len(rest)+1)
+			segments = append(segments, PathSegment{Type: SegmentElement, Value: "_xmldot_root"})
+			segments = append(segments, rest...)
+		}
+		parser := newXMLParser(stringToBytes(data))
+		parser.base = -1 // offsets in modifier output are not spans
+		if opts == nil {
+			return executeQuery(parser, segments, 0)
+		}
+		return executeQueryWithOptions(parser, segments, 0, opts)
+	}
+
+	return Result{Type: Null}
+}
+
 // executeQuery recursively matches path segments against XML structure
 func executeQuery(parser *xmlParser, segments []PathSegment, segIndex int) Result {
 	// Base case: we've matched all segments
@@ -168,14 +534,45 @@ func executeQuery(parser *xmlParser, segments []PathSegment, segIndex int) Resul
 		return Result{Type: Null}
 	}
 
+	// A modifier followed by more segments ends the first stage of the path;
+	// the rest is resolved relative to the modified Result
+	if segIndex == 0 {
+		if boundary := modifierBoundary(segments); boundary >= 0 {
+			result := executeQuery(parser, segments[:boundary+1], 0)
+			return continueAfterModifier(result, segments[boundary+1:], nil)
+		}
+		// Positional attributes need the matched element's attribute order
+		if pos := positionalAttribute(segments); pos > 0 {
+			result := executeQuery(parser, segments[:pos], 0)
+			return continueAfterModifier(result, segments[pos:], nil)
+		}
+	}
+
 	currentSeg := segments[segIndex]
 	isLastSegment := segIndex == len(segments)-1
 
+	// Synthetic #root accessor for top-level elements
+	if currentSeg.Type == SegmentRoot {
+		if segIndex != 0 {
+			return Result{Type: Null}
+		}
+		return handleRootQuery(parser, segments, nil)
+	}
+
 	// Fragment root array support: Check if we're at root level (segIndex==0) with array operations
 	// This enables: <user>A</user><user>B</user> + query "user.#" → 2
 	if segIndex == 0 && !isLastSegment && currentSeg.Type == SegmentElement {
 		nextSeg := segments[1]
 		if nextSeg.Type == SegmentIndex || nextSeg.Type == SegmentCount || nextSeg.Type == SegmentFieldExtraction {
+			// Counting streams through the roots without collecting them
+			if nextSeg.Type == SegmentCount {
+				count := countMatchingElements(parser, func(name string) bool { return name == currentSeg.Value })
+				if count == 0 {
+					return Result{Type: Null}
+				}
+				return newCountResult(count)
+			}
+
 			// Array operation on fragment roots - collect all matching roots
 			matches := collectFragmentRoots(parser, currentSeg.Value)
 
@@ -199,11 +596,7 @@ func executeQuery(parser *xmlParser, segments []PathSegment, segIndex int) Resul
 						if segments[segIndex+2].Type == SegmentAttribute {
 							attrName := segments[segIndex+2].Value
 							if attrValue, ok := match.attrs[attrName]; ok {
-								return Result{
-									Type: Attribute,
-									Str:  attrValue,
-									Raw:  attrValue,
-								}
+								return newAttributeResult(match.tag, attrName, attrValue)
 							}
 							return Result{Type: Null}
 						}
@@ -219,16 +612,12 @@ func executeQuery(parser *xmlParser, segments []PathSegment, segIndex int) Resul
 						}
 
 						// Continue matching within selected root element
-						contentParser := newXMLParser([]byte(match.content))
+						contentParser := newContentParser(match.content, match.span)
 						return executeQuery(contentParser, segments, segIndex+2)
 					}
 
 					// No more segments - return the indexed root element
-					return Result{
-						Type: Element,
-						Str:  unescapeXML(extractTextContent(match.content)),
-						Raw:  match.content,
-					}
+					return newElementResult(match)
 				}
 				return Result{Type: Null} // Out of bounds
 
@@ -266,7 +655,8 @@ func executeQuery(parser *xmlParser, segments []PathSegment, segIndex int) Resul
 		var allMatches []elementMatch
 		for parser.skipToNextElement() {
 			parser.next()
-			elemName, attrs, isSelfClosing := parser.parseElementName()
+			elemName, attrs, attrOrder, isSelfClosing := parser.parseElementTag()
+			tag := parser.tag
 
 			if !currentSeg.matches(elemName) {
 				if !isSelfClosing {
@@ -285,6 +675,9 @@ func executeQuery(parser *xmlParser, segments []PathSegment, segIndex int) Resul
 			allMatches = append(allMatches, elementMatch{
 				name:          elemName,
 				attrs:         attrs,
+				tag:           tag,
+				span:          parser.span(),
+				attrOrder:     attrOrder,
 				content:       content,
 				isSelfClosing: isSelfClosing,
 			})
@@ -302,11 +695,18 @@ func executeQuery(parser *xmlParser, segments []PathSegment, segIndex int) Resul
 	// This is the GJSON pattern: element.#(condition)
 	hasFollowingFilter := !isLastSegment && segments[segIndex+1].Type == SegmentFilter
 	if hasFollowingFilter && currentSeg.Type == SegmentElement {
-		// Collect all elements matching current segment
-		var allMatches []elementMatch
-		for parser.skipToNextElement() {
+		if isFilterCount(segments, segIndex+1) {
+			return countFilterMatches(parser, currentSeg.matches, segments[segIndex+1])
+		}
+
+		// Filter the elements matching current segment as they are read,
+		// stopping once the filter has all the matches it keeps
+		filterSeg := segments[segIndex+1]
+		var filtered []elementMatch
+		for position := 0; parser.skipToNextElement(); {
 			parser.next()
-			elemName, attrs, isSelfClosing := parser.parseElementName()
+			elemName, attrs, attrOrder, isSelfClosing := parser.parseElementTag()
+			tag := parser.tag
 
 			if !currentSeg.matches(elemName) {
 				if !isSelfClosing {
@@ -322,20 +722,29 @@ func executeQuery(parser *xmlParser, segments []PathSegment, segIndex int) Resul
 				content = parser.parseElementContent(elemName)
 			}
 
-			allMatches = append(allMatches, elementMatch{
+			match := elementMatch{
 				name:          elemName,
 				attrs:         attrs,
+				tag:           tag,
+				span:          parser.span(),
+				attrOrder:     attrOrder,
 				content:       content,
 				isSelfClosing: isSelfClosing,
-			})
+			}
+			if evaluateFilterOnMatch(filterSeg.Filter, match, position) {
+				filtered = append(filtered, match)
+				if len(filtered) >= filterLimit(filterSeg) {
+					break
+				}
+			}
 
-			if len(allMatches) >= MaxWildcardResults {
+			position++
+			if position >= MaxWildcardResults {
 				break
 			}
 		}
 
-		// Now apply the filter segment to these matches
-		return handleFilterQueryOnMatches(allMatches, segments, segIndex+1)
+		return routeFilterMatches(filtered, segments, segIndex+1)
 	}
 
 	// Check if this is the last segment and it has modifiers to apply after resolution
@@ -357,19 +766,37 @@ func executeQuery(parser *xmlParser, segments []PathSegment, segIndex int) Resul
 		return handleRecursiveWildcard(parser, segments, segIndex)
 	}
 
+	// Counting (element.#) tallies matches without collecting them
+	if currentSeg.Type == SegmentElement && currentSeg.Filter == nil && !isLastSegment && segments[segIndex+1].Type == SegmentCount {
+		count := countMatchingElements(parser, currentSeg.matches)
+		if count == 0 {
+			return Result{Type: Null}
+		}
+		result := newCountResult(count)
+		// Apply modifiers from the count segment if present (Phase 6)
+		if len(segments[segIndex+1].Modifiers) > 0 {
+			result = applyModifiers(result, segments[segIndex+1].Modifiers)
+		}
+		return result
+	}
+
 	// Find matching elements - need to collect for array operations or wildcards or filters
 	var matches []elementMatch
 
-	// For single-level wildcards, we collect ALL matches
-	isWildcard := currentSeg.Type == SegmentWildcard && !currentSeg.Wildcard
+	// For single-level wildcards, we collect ALL matches, as we do for a final
+	// element segment whose modifiers work on the set of matches (name|@last)
+	isWildcard := (currentSeg.Type == SegmentWildcard && !currentSeg.Wildcard) ||
+		(isLastSegment && currentSeg.Type == SegmentElement && modifiesMatchSet(currentSeg.Modifiers))
 
 	// For filters, we collect ALL matches then filter them
 	hasFilter := currentSeg.Filter != nil
+	position := 0 // of the next match, for #(#...) filters
 
 	for parser.skipToNextElement() {
 		parser.next() // skip '<'
 
-		elemName, attrs, isSelfClosing := parser.parseElementName()
+		elemName, attrs, attrOrder, isSelfClosing := parser.parseElementTag()
+		tag := parser.tag
 
 		// Check if this segment is an attribute request
 		if currentSeg.Type == SegmentAttribute {
@@ -409,15 +836,19 @@ func executeQuery(parser *xmlParser, segments []PathSegment, segIndex int) Resul
 			match := elementMatch{
 				name:          elemName,
 				attrs:         attrs,
+				tag:           tag,
+				span:          parser.span(),
+				attrOrder:     attrOrder,
 				content:       content,
 				isSelfClosing: isSelfClosing,
 			}
 
 			// If there's a filter, only collect if it matches
 			if hasFilter {
-				if evaluateFilterOnMatch(currentSeg.Filter, match) {
+				if evaluateFilterOnMatch(currentSeg.Filter, match, position) {
 					matches = append(matches, match)
 				}
+				position++
 			} else {
 				matches = append(matches, match)
 			}
@@ -435,11 +866,7 @@ func executeQuery(parser *xmlParser, segments []PathSegment, segIndex int) Resul
 					// More segments - not supported for attributes
 					return Result{Type: Null}
 				}
-				result := Result{
-					Type: Attribute,
-					Str:  attrValue,
-					Raw:  attrValue,
-				}
+				result := newAttributeResult(tag, attrName, attrValue)
 				// Apply modifiers from the attribute segment if present (Phase 6)
 				if len(segments[segIndex+1].Modifiers) > 0 {
 					result = applyModifiers(result, segments[segIndex+1].Modifiers)
@@ -466,11 +893,14 @@ func executeQuery(parser *xmlParser, segments []PathSegment, segIndex int) Resul
 
 		// If this is the last segment, return the element content
 		if isLastSegment {
-			result := Result{
-				Type: Element,
-				Str:  unescapeXML(extractTextContent(content)),
-				Raw:  content,
-			}
+			result := newElementResult(elementMatch{
+				name:      elemName,
+				attrs:     attrs,
+				tag:       tag,
+				attrOrder: attrOrder,
+				content:   content,
+				span:      parser.span(),
+			})
 			// Apply modifiers if present (Phase 6)
 			if len(currentSeg.Modifiers) > 0 {
 				result = applyModifiers(result, currentSeg.Modifiers)
@@ -479,15 +909,17 @@ func executeQuery(parser *xmlParser, segments []PathSegment, segIndex int) Resul
 		}
 
 		// Otherwise, parse the content and continue matching
-		contentParser := newXMLParser([]byte(content))
+		contentParser := newContentParser(content, parser.span())
 		result := executeQuery(contentParser, segments, segIndex+1)
 		if result.Type != Null {
 			return result
 		}
 	}
 
-	// Handle wildcard or filter results
-	if (isWildcard || hasFilter) && len(matches) > 0 {
+	// Handle wildcard or filter results. An index or count after a
+	// single-level wildcard (root.*.0, root.*.#) selects from or counts its
+	// matches, like it does after an element name.
+	if (isWildcard || hasFilter) && len(matches) > 0 && !selectsWildcardMatch(segments, segIndex) {
 		result := handleWildcardMatches(matches, segments, segIndex)
 		// Apply modifiers if this is the last segment with modifiers (Phase 6)
 		if isLastSegment && len(currentSeg.Modifiers) > 0 {
@@ -511,11 +943,7 @@ func executeQuery(parser *xmlParser, segments []PathSegment, segIndex int) Resul
 					if segments[segIndex+2].Type == SegmentAttribute {
 						attrName := segments[segIndex+2].Value
 						if attrValue, ok := match.attrs[attrName]; ok {
-							result := Result{
-								Type: Attribute,
-								Str:  attrValue,
-								Raw:  attrValue,
-							}
+							result := newAttributeResult(match.tag, attrName, attrValue)
 							// Apply modifiers from the attribute segment if it's the last one (Phase 6)
 							if segIndex+3 >= len(segments) && len(segments[segIndex+2].Modifiers) > 0 {
 								result = applyModifiers(result, segments[segIndex+2].Modifiers)
@@ -526,16 +954,12 @@ func executeQuery(parser *xmlParser, segments []PathSegment, segIndex int) Resul
 					}
 
 					// Continue matching within this element
-					contentParser := newXMLParser([]byte(match.content))
+					contentParser := newContentParser(match.content, match.span)
 					return executeQuery(contentParser, segments, segIndex+2)
 				}
 
 				// No more segments - return the element
-				result := Result{
-					Type: Element,
-					Str:  unescapeXML(extractTextContent(match.content)),
-					Raw:  match.content,
-				}
+				result := newElementResult(match)
 				// Apply modifiers from the index segment if present (Phase 6)
 				if len(nextSeg.Modifiers) > 0 {
 					result = applyModifiers(result, nextSeg.Modifiers)
@@ -562,6 +986,18 @@ func executeQuery(parser *xmlParser, segments []PathSegment, segIndex int) Resul
 	return Result{Type: Null}
 }
 
+// selectsWildcardMatch reports whether the segment at segIndex is a
+// single-level wildcard followed by an index or count, which apply to the
+// wildcard's matches as a whole rather than to each match.
+func selectsWildcardMatch(segments []PathSegment, segIndex int) bool {
+	if segIndex+1 >= len(segments) {
+		return false
+	}
+	seg, next := segments[segIndex], segments[segIndex+1]
+	return seg.Type == SegmentWildcard && !seg.Wildcard && seg.Filter == nil &&
+		(next.Type == SegmentIndex || next.Type == SegmentCount)
+}
+
 // handleWildcardMatches processes matches from a single-level wildcard
 func handleWildcardMatches(matches []elementMatch, segments []PathSegment, segIndex int) Result {
 	isLastSegment := segIndex == len(segments)-1
@@ -570,22 +1006,14 @@ func handleWildcardMatches(matches []elementMatch, segments []PathSegment, segIn
 	if isLastSegment {
 		if len(matches) == 1 {
 			// Single match - return as single result
-			return Result{
-				Type: Element,
-				Str:  unescapeXML(extractTextContent(matches[0].content)),
-				Raw:  matches[0].content,
-			}
+			return newElementResult(matches[0])
 		}
 		// Multiple matches - return as array
 		// For Phase 3, we'll return the first match and mark it as Array type
 		// Full array support will come later
 		results := make([]Result, 0, len(matches))
 		for _, match := range matches {
-			results = append(results, Result{
-				Type: Element,
-				Str:  unescapeXML(extractTextContent(match.content)),
-				Raw:  match.content,
-			})
+			results = append(results, newElementResult(match))
 		}
 		return Result{
 			Type:    Array,
@@ -609,11 +1037,7 @@ func handleWildcardMatches(matches []elementMatch, segments []PathSegment, segIn
 					// More segments - not supported for attributes
 					continue
 				}
-				allResults = append(allResults, Result{
-					Type: Attribute,
-					Str:  attrValue,
-					Raw:  attrValue,
-				})
+				allResults = append(allResults, newAttributeResult(match.tag, attrName, attrValue))
 			}
 			continue
 		}
@@ -630,7 +1054,7 @@ func handleWildcardMatches(matches []elementMatch, segments []PathSegment, segIn
 		}
 
 		// Continue matching within this element's content
-		contentParser := newXMLParser([]byte(match.content))
+		contentParser := newContentParser(match.content, match.span)
 		result := executeQuery(contentParser, segments, segIndex+1)
 		if result.Type != Null {
 			// If we got an empty Array back, that means field extraction occurred
@@ -689,24 +1113,91 @@ func handleRecursiveWildcard(parser *xmlParser, segments []PathSegment, segIndex
 	nextSegIndex := segIndex + 1
 	targetSeg := segments[nextSegIndex]
 
+	// Modifiers on the final segment apply to the combined matches rather
+	// than to each match on its own
+	modifiers := segments[len(segments)-1].Modifiers
+	if len(modifiers) > 0 {
+		segments = withoutFinalModifiers(segments)
+		targetSeg = segments[nextSegIndex]
+	}
+
+	// An index or count after the target selects among all matches, like
+	// on a single-level array (root.**.price.0, .-1, .#)
+	var selection []PathSegment
+	segments, selection = splitRecursiveSelection(segments, nextSegIndex)
+
 	// Recursively search for matches at any depth
 	var allResults []Result
 	ctx := &searchContext{operations: 0, results: &allResults}
 	recursiveSearchWithContext(parser, targetSeg, segments, nextSegIndex, ctx, 0)
 
-	if len(allResults) == 0 {
-		return Result{Type: Null}
+	var result Result
+	switch {
+	case selection != nil:
+		result = continueAfterModifier(Result{Type: Array, Results: allResults}, selection, nil)
+	case len(allResults) == 0:
+		result = Result{Type: Null}
+	case len(allResults) == 1:
+		result = allResults[0]
+	default:
+		result = Result{
+			Type:    Array,
+			Results: allResults,
+		}
 	}
-	if len(allResults) == 1 {
-		return allResults[0]
+	if len(modifiers) > 0 {
+		result = applyModifiers(result, modifiers)
 	}
-	return Result{
-		Type:    Array,
-		Results: allResults,
+	return result
+}
+
+// splitRecursiveSelection splits the segments of a recursive wildcard query
+// before the index or count that follows its target (at targetIndex),
+// directly or after child element names (root.**.employee.name.1). The search
+// then collects the elements before the index, and the selection segments are
+// resolved against the combined matches.
+//
+// Child names between the target and the index select every matching child
+// rather than the first, so that the index counts all of them in document
+// order. A copy of the segments is returned in that case, as parsed paths are
+// cached and shared.
+func splitRecursiveSelection(segments []PathSegment, targetIndex int) ([]PathSegment, []PathSegment) {
+	end := targetIndex + 1
+	for end < len(segments) && segments[end].Type == SegmentElement && len(segments[end].Modifiers) == 0 {
+		end++
+	}
+	if end >= len(segments) {
+		return segments, nil
+	}
+	if next := segments[end].Type; next != SegmentIndex && next != SegmentCount {
+		return segments, nil
+	}
+	if end == targetIndex+1 {
+		return segments[:end], segments[end:]
+	}
+
+	// A glob without metacharacters matches the name like an element
+	// segment, but selects every match
+	search := make([]PathSegment, end)
+	copy(search, segments[:end])
+	for i := targetIndex + 1; i < end; i++ {
+		search[i] = PathSegment{Type: SegmentWildcard, Value: search[i].Value}
 	}
+	return search, segments[end:]
 }
 
-// recursiveSearchWithContext performs depth-first search with operation tracking
+// withoutFinalModifiers returns a copy of segments whose last segment carries
+// no modifiers. Parsed paths are cached and shared, so they are never
+// modified in place.
+func withoutFinalModifiers(segments []PathSegment) []PathSegment {
+	stripped := make([]PathSegment, len(segments))
+	copy(stripped, segments)
+	stripped[len(stripped)-1].Modifiers = nil
+	return stripped
+}
+
+// recursiveSearchWithContext performs a depth-first, pre-order search with
+// operation tracking, so matches are collected in document order
 // Security: limits recursion depth, result count, and total operations
 func recursiveSearchWithContext(parser *xmlParser, targetSeg PathSegment, segments []PathSegment, segIndex int, ctx *searchContext, depth int) {
 	// Security checks: limit recursion depth, result count, and total operations
@@ -719,7 +1210,8 @@ func recursiveSearchWithContext(parser *xmlParser, targetSeg PathSegment, segmen
 
 	for parser.skipToNextElement() {
 		parser.next() // skip '<'
-		elemName, attrs, isSelfClosing := parser.parseElementName()
+		elemName, attrs, attrOrder, isSelfClosing := parser.parseElementTag()
+		tag := parser.tag
 
 		var content string
 		if isSelfClosing {
@@ -728,33 +1220,26 @@ func recursiveSearchWithContext(parser *xmlParser, targetSeg PathSegment, segmen
 			content = parser.parseElementContent(elemName)
 		}
 
-		// Check if this element matches the target OR if we need to check within it
-		// First, recurse into content regardless of match (for deeper matches)
-		if !isSelfClosing && content != "" {
-			contentParser := newXMLParser([]byte(content))
-			recursiveSearchWithContext(contentParser, targetSeg, segments, segIndex, ctx, depth+1)
-		}
-
-		// Check operation limit again after recursion
-		if ctx.operations >= MaxRecursiveOperations {
-			return
-		}
-
-		// Then check if this element itself matches
+		// Check the element itself before its descendants so matches are
+		// collected in document order
 		if targetSeg.matches(elemName) {
 			// Security check: stop if we've reached the result limit
 			if len(*ctx.results) >= MaxWildcardResults {
+				ctx.truncated = true
 				return
 			}
 
 			// Found a match!
 			if isLastSegment {
 				// This is the final segment - add the result
-				*ctx.results = append(*ctx.results, Result{
-					Type: Element,
-					Str:  unescapeXML(extractTextContent(content)),
-					Raw:  content,
-				})
+				*ctx.results = append(*ctx.results, newElementResult(elementMatch{
+					name:      elemName,
+					attrs:     attrs,
+					tag:       tag,
+					span:      parser.span(),
+					attrOrder: attrOrder,
+					content:   content,
+				}))
 			} else {
 				// Continue matching with the next segment
 				nextSegment := segments[segIndex+1]
@@ -764,11 +1249,7 @@ func recursiveSearchWithContext(parser *xmlParser, targetSeg PathSegment, segmen
 					if attrValue, ok := attrs[attrName]; ok {
 						// Check if this is the final segment after attribute
 						if segIndex+2 >= len(segments) {
-							*ctx.results = append(*ctx.results, Result{
-								Type: Attribute,
-								Str:  attrValue,
-								Raw:  attrValue,
-							})
+							*ctx.results = append(*ctx.results, newAttributeResult(tag, attrName, attrValue))
 						}
 					}
 				case SegmentText:
@@ -784,6 +1265,9 @@ func recursiveSearchWithContext(parser *xmlParser, targetSeg PathSegment, segmen
 					match := elementMatch{
 						name:          elemName,
 						attrs:         attrs,
+						tag:           tag,
+						span:          parser.span(),
+						attrOrder:     attrOrder,
 						content:       content,
 						isSelfClosing: isSelfClosing,
 					}
@@ -793,7 +1277,7 @@ func recursiveSearchWithContext(parser *xmlParser, targetSeg PathSegment, segmen
 						*ctx.results = append(*ctx.results, result.Results...)
 					}
 				default:
-					contentParser := newXMLParser([]byte(content))
+					contentParser := newContentParser(content, parser.span())
 					result := executeQuery(contentParser, segments, segIndex+1)
 					if result.Type != Null {
 						if result.Type == Array {
@@ -805,6 +1289,17 @@ func recursiveSearchWithContext(parser *xmlParser, targetSeg PathSegment, segmen
 				}
 			}
 		}
+
+		// Then recurse into content for deeper matches
+		if !isSelfClosing && content != "" {
+			contentParser := newContentParser(content, parser.span())
+			recursiveSearchWithContext(contentParser, targetSeg, segments, segIndex, ctx, depth+1)
+		}
+
+		// Check operation limit again after recursion
+		if ctx.operations >= MaxRecursiveOperations {
+			return
+		}
 	}
 }
 
@@ -822,6 +1317,519 @@ func GetMany(xml string, paths ...string) []Result {
 	return results
 }
 
+// GetManyBytes is like GetMany but accepts xml as a byte slice for zero-copy efficiency.
+//
+// Concurrency: GetManyBytes is safe for concurrent use from multiple goroutines.
+func GetManyBytes(xml []byte, paths ...string) []Result {
+	results := make([]Result, len(paths))
+	for i, path := range paths {
+		results[i] = GetBytes(xml, path)
+	}
+	return results
+}
+
+// GetManyParallel is like GetMany but evaluates the paths concurrently on a
+// bounded pool of worker goroutines (at most GOMAXPROCS). It pays off on large
+// documents with several expensive paths, such as recursive wildcards; for
+// small documents or cheap paths, GetMany is usually faster.
+//
+// Results are returned in the same order as paths. Paths are evaluated
+// independently: a path that is invalid or matches nothing yields a Null
+// Result for that path only and does not affect others. A panic while
+// evaluating a path, such as in a custom modifier, is re-raised in the
+// calling goroutine once every worker has finished, as GetMany would raise
+// it, rather than crashing the program from a worker.
+//
+// Example:
+//
+//	results := GetManyParallel(xml, "root.**.price", "root.**.title", "root.store.@name")
+//	// results[0] corresponds to "root.**.price", and so on
+//
+// Concurrency: GetManyParallel is safe for concurrent use from multiple goroutines.
+// The xml string is shared read-only between workers.
+func GetManyParallel(xml string, paths ...string) []Result {
+	results := make([]Result, len(paths))
+	if len(paths) == 0 {
+		return results
+	}
+
+	workers := runtime.GOMAXPROCS(0)
+	if workers > len(paths) {
+		workers = len(paths)
+	}
+
+	data := stringToBytes(xml)
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	var panicOnce sync.Once
+	var panicValue interface{}
+	get := func(i int) {
+		defer func() {
+			if r := recover(); r != nil {
+				panicOnce.Do(func() { panicValue = r })
+				results[i] = Result{Type: Null}
+			}
+		}()
+		results[i] = GetBytes(data, paths[i])
+	}
+
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				get(i)
+			}
+		}()
+	}
+
+	for i := range paths {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	if panicValue != nil {
+		panic(panicValue)
+	}
+	return results
+}
+
+// GetEach evaluates the same path against each document in docs and returns
+// one Result per document, in the same order. The path is parsed once and a
+// single parser is reused for the whole batch, which makes GetEach cheaper
+// than calling Get in a loop for many small documents.
+//
+// Each slot holds what Get would return for that document: like Get, GetEach
+// does not validate the documents, so a malformed one yields whatever its
+// matching part parses to (use Valid first to reject it). Documents that are
+// empty or larger than MaxDocumentSize yield a Null Result in their slot
+// without affecting the rest of the batch.
+//
+// Example:
+//
+//	docs := []string{`<user><name>Alice</name></user>`, `<user><name>Bob</name></user>`}
+//	names := GetEach(docs, "user.name")
+//	// names[0].String() → "Alice", names[1].String() → "Bob"
+//
+// Concurrency: GetEach is safe for concurrent use from multiple goroutines.
+func GetEach(docs []string, path string) []Result {
+	data := make([][]byte, len(docs))
+	for i, doc := range docs {
+		data[i] = stringToBytes(doc)
+	}
+	return GetEachBytes(data, path)
+}
+
+// GetEachBytes is like GetEach but accepts the documents as byte slices.
+//
+// Concurrency: GetEachBytes is safe for concurrent use from multiple goroutines.
+func GetEachBytes(docs [][]byte, path string) []Result {
+	results := make([]Result, len(docs))
+	segments := parsePath(path)
+	if len(segments) == 0 {
+		for i := range results {
+			results[i] = Result{Type: Null}
+		}
+		return results
+	}
+
+	parser := newXMLParser(nil)
+	for i, doc := range docs {
+		if len(doc) == 0 || len(doc) > MaxDocumentSize {
+			results[i] = Result{Type: Null}
+			continue
+		}
+		parser.reset(doc)
+		results[i] = markMulti(executeQuery(parser, segments, 0), segments)
+	}
+	return results
+}
+
+// Exists reports whether the specified path exists in xml. It is equivalent to
+// Get(xml, path).Exists() but faster for plain element paths (optionally ending
+// in an attribute, e.g. "root.user.@id"): the document is scanned in a single
+// pass that stops as soon as the target's opening tag is found, and element
+// content is never captured. Other paths (wildcards, filters, indices, modifiers) fall back to Get.
+//
+// Example:
+//
+//	xml := `<root><user id="1"><name>John</name></user></root>`
+//	Exists(xml, "root.user")     // true
+//	Exists(xml, "root.user.@id") // true
+//	Exists(xml, "root.admin")    // false
+//
+// Concurrency: Exists is safe for concurrent use from multiple goroutines.
+func Exists(xml, path string) bool {
+	return ExistsBytes(stringToBytes(xml), path)
+}
+
+// ExistsBytes is like Exists but accepts xml as a byte slice.
+// Security: Documents larger than MaxDocumentSize (10MB) are rejected.
+func ExistsBytes(xml []byte, path string) bool {
+	// Security check: reject documents that are too large
+	if len(xml) > MaxDocumentSize {
+		return false
+	}
+
+	segments := parsePath(path)
+	if len(segments) == 0 {
+		return false
+	}
+
+	if !isSimpleExistsPath(segments) {
+		return executeQuery(newXMLParser(xml), segments, 0).Exists()
+	}
+
+	return existsQuery(newXMLParser(xml), segments)
+}
+
+// Absent reports whether path does not resolve in xml, the opposite of
+// Exists for documents that can be read. It is meant for validation rules
+// that require a setting to be missing:
+//
+//	if !Absent(config, "server.debug") {
+//	    return errors.New("debug must not be enabled in production")
+//	}
+//
+// Absent only reports true when absence is certain: it returns false if xml
+// is not well-formed, exceeds MaxDocumentSize or path is invalid, so that a
+// broken document never passes an "is absent" check. Use CheckAbsent to tell
+// these cases apart.
+//
+// Concurrency: Absent is safe for concurrent use from multiple goroutines.
+func Absent(xml, path string) bool {
+	absent, _ := CheckAbsentBytes(stringToBytes(xml), path)
+	return absent
+}
+
+// AbsentBytes is like Absent but accepts xml as a byte slice.
+func AbsentBytes(xml []byte, path string) bool {
+	absent, _ := CheckAbsentBytes(xml, path)
+	return absent
+}
+
+// CheckAbsent is like Absent but also reports why absence could not be
+// determined. The error is:
+//   - ErrMalformedXML if xml is not well-formed
+//   - ErrLimitExceeded if xml exceeds MaxDocumentSize
+//   - ErrInvalidPath if path cannot be parsed
+//
+// The bool is false whenever the error is non-nil.
+func CheckAbsent(xml, path string) (bool, error) {
+	return CheckAbsentBytes(stringToBytes(xml), path)
+}
+
+// CheckAbsentBytes is like CheckAbsent but accepts xml as a byte slice.
+func CheckAbsentBytes(xml []byte, path string) (bool, error) {
+	// Security check: reject documents that are too large
+	if len(xml) > MaxDocumentSize {
+		return false, fmt.Errorf("%w: document larger than MaxDocumentSize (%d bytes)", ErrLimitExceeded, MaxDocumentSize)
+	}
+	if len(parsePath(path)) == 0 {
+		return false, ErrInvalidPath
+	}
+	if err := checkWellFormed(xml); err != nil {
+		return false, err
+	}
+	return !ExistsBytes(xml, path), nil
+}
+
+// RootName returns the name of the root element of xml, as written in the
+// document (including any namespace prefix). Only the prolog and the root's
+// opening tag are read, so it is a cheap way to tell documents apart before
+// querying them:
+//
+//	name, err := xmldot.RootName(`<?xml version="1.0"?><manifest><app/></manifest>`)
+//	// name == "manifest"
+//
+// The error is:
+//   - ErrMalformedXML if there is no root element, or the prolog or the root's
+//     opening tag is not well-formed
+//   - ErrLimitExceeded if xml exceeds MaxDocumentSize
+//
+// Content after the root's opening tag is not checked; use Valid for that.
+func RootName(xml string) (string, error) {
+	return RootNameBytes(stringToBytes(xml))
+}
+
+// RootNameBytes is like RootName but accepts xml as a byte slice.
+func RootNameBytes(xml []byte) (string, error) {
+	// Security check: reject documents that are too large
+	if len(xml) > MaxDocumentSize {
+		return "", fmt.Errorf("%w: document larger than MaxDocumentSize (%d bytes)", ErrLimitExceeded, MaxDocumentSize)
+	}
+	name, err := newValidatingParser(xml).readRootName()
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrMalformedXML, err.Message)
+	}
+	return name, nil
+}
+
+// Between returns the element startPath selects and the sibling elements
+// that follow it, up to but not including the next sibling named stopName,
+// as an Array in document order. It models flat, section-delimited documents
+// in which a heading is followed by its content rather than containing it:
+//
+//	xml := `<body><h2>Intro</h2><p>a</p><p>b</p><h2>Usage</h2><p>c</p></body>`
+//	section := xmldot.Between(xml, "body.h2", "h2")
+//	// section.Array(): <h2>Intro</h2>, <p>a</p>, <p>b</p>
+//
+// Use a filter to pick a later start, e.g. "body.h2.#(%==Usage)". Without a
+// following sibling named stopName (or with stopName ""), the Array runs to
+// the end of the parent. stopName matches like a path segment, so an
+// unprefixed name matches any namespace prefix. Text, comments and processing
+// instructions between the siblings are skipped.
+//
+// Between returns Null if startPath does not select an element; for a path
+// selecting several elements, the first one starts the range. At most
+// MaxWildcardResults elements are returned.
+func Between(xml, startPath, stopName string) Result {
+	return BetweenBytes(stringToBytes(xml), startPath, stopName)
+}
+
+// BetweenBytes is like Between but accepts xml as a byte slice.
+func BetweenBytes(xml []byte, startPath, stopName string) Result {
+	first := GetBytes(xml, startPath)
+	if first.Type == Array && len(first.Results) > 0 {
+		first = first.Results[0]
+	}
+	if first.Type != Element {
+		return Result{Type: Null}
+	}
+	start, end := first.Span()
+	if start < 0 {
+		return Result{Type: Null}
+	}
+	first.multi = false
+
+	stop := PathSegment{Type: SegmentElement, Value: stopName}
+	items := []Result{first}
+	parser := newXMLParser(xml)
+	parser.pos = end
+	for len(items) < MaxWildcardResults && skipToNextSibling(parser) {
+		parser.next() // skip '<'
+		name, attrs, attrOrder, isSelfClosing := parser.parseElementTag()
+		tag := parser.tag
+		if stopName != "" && stop.matches(name) {
+			break
+		}
+		var content string
+		if !isSelfClosing {
+			content = parser.parseElementContent(name)
+		}
+		item := newElementResult(elementMatch{name: name, attrs: attrs, attrOrder: attrOrder, content: content, isSelfClosing: isSelfClosing, tag: tag, span: parser.span()})
+		items = append(items, item)
+	}
+	return Result{Type: Array, Results: items, multi: true}
+}
+
+// Under returns the matches of path that lie inside an element named
+// ancestorName, for values that should only be read in one context when the
+// same element name appears in several:
+//
+//	xml := `<store><item><price>10</price></item><sale><item><price>8</price></item></sale></store>`
+//	xmldot.Under(xml, "sale", "**.price")     // → "8"
+//	xmldot.Under(xml, "sale", "item.#.price") // → "8"
+//
+// The path is evaluated below each element named ancestorName, as with
+// Result.Get on that element, so "item.#.price" selects the prices of its
+// item children and "**.price" prices at any depth. ancestorName matches like a
+// path segment, so an unprefixed name matches any namespace prefix. An
+// ancestor nested inside another is searched as part of the outer one; use
+// ** in path to reach matches below it.
+//
+// Matches from all ancestors are combined in document order: none returns
+// Null, one returns that match and several return an Array. At most
+// MaxWildcardResults matches are returned.
+func Under(xml, ancestorName, path string) Result {
+	return UnderBytes(stringToBytes(xml), ancestorName, path)
+}
+
+// UnderBytes is like Under but accepts xml as a byte slice.
+func UnderBytes(xml []byte, ancestorName, path string) Result {
+	if len(xml) > MaxDocumentSize || ancestorName == "" {
+		return Result{Type: Null}
+	}
+
+	ancestor := PathSegment{Type: SegmentElement, Value: ancestorName}
+	var items []Result
+	parser := newXMLParser(xml)
+	for len(items) < MaxWildcardResults && parser.skipToNextElement() {
+		parser.next() // skip '<'
+		name, attrs, attrOrder, isSelfClosing := parser.parseElementTag()
+		tag := parser.tag
+		if isSelfClosing || !ancestor.matches(name) {
+			// Keep scanning, which descends into the element's children
+			continue
+		}
+		contentStart := parser.pos
+		content := parser.parseElementContent(name)
+		elem := newElementResult(elementMatch{name: name, attrs: attrs, attrOrder: attrOrder, content: content, tag: tag, span: parser.span()})
+		match := shiftSpan(elem.Get(path), contentStart)
+		switch match.Type {
+		case Null:
+		case Array:
+			items = append(items, match.Results...)
+		default:
+			items = append(items, match)
+		}
+	}
+
+	switch {
+	case len(items) == 0:
+		return Result{Type: Null}
+	case len(items) == 1:
+		return items[0]
+	case len(items) > MaxWildcardResults:
+		items = items[:MaxWildcardResults]
+	}
+	return Result{Type: Array, Results: items, multi: true}
+}
+
+// skipToNextSibling advances the parser to the opening tag of the next
+// element at the current level, skipping text, comments, CDATA and
+// processing instructions. It returns false at a closing tag (the end of the
+// parent) or the end of the input.
+func skipToNextSibling(p *xmlParser) bool {
+	for p.pos < p.dataLen {
+		i := bytes.IndexByte(p.data[p.pos:], '<')
+		if i < 0 || p.pos+i+1 >= p.dataLen {
+			p.pos = p.dataLen
+			return false
+		}
+		p.pos += i
+		switch rest := p.data[p.pos:]; {
+		case rest[1] == '/':
+			return false
+		case rest[1] == '?':
+			p.pos = processingInstructionEnd(p.data, p.pos)
+		case bytes.HasPrefix(rest, []byte("<!--")):
+			p.pos = skipPast(p.data, p.pos+4, "-->")
+		case bytes.HasPrefix(rest, []byte("<![CDATA[")):
+			p.pos = skipPast(p.data, p.pos+9, "]]>")
+		case rest[1] == '!':
+			p.pos = skipPast(p.data, p.pos+2, ">")
+		default:
+			return true
+		}
+	}
+	return false
+}
+
+// isSimpleExistsPath reports whether segments consist only of plain element
+// names, optionally followed by a single final attribute, with no modifiers.
+func isSimpleExistsPath(segments []PathSegment) bool {
+	for i, seg := range segments {
+		if len(seg.Modifiers) > 0 || seg.Filter != nil {
+			return false
+		}
+		switch seg.Type {
+		case SegmentElement:
+		case SegmentAttribute:
+			if i == 0 || i != len(segments)-1 {
+				return false
+			}
+			if _, _, ok := attributePosition(seg); ok {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// existsQuery scans the document once, descending into elements that match
+// the path and skipping all others without capturing their content. level is
+// the number of path segments matched by the currently open ancestors, so a
+// closing tag seen here always belongs to the innermost matched ancestor.
+// Like executeQuery, when the first matching element lacks the requested
+// attribute the search stops.
+func existsQuery(parser *xmlParser, segments []PathSegment) bool {
+	elementSegs := len(segments)
+	attrName := ""
+	if segments[elementSegs-1].Type == SegmentAttribute {
+		elementSegs--
+		attrName = segments[elementSegs].Value
+	}
+
+	level := 0
+	data := parser.data
+	for parser.pos < parser.dataLen {
+		idx := bytes.IndexByte(data[parser.pos:], '<')
+		if idx < 0 || parser.pos+idx+1 >= parser.dataLen {
+			return false
+		}
+		parser.pos += idx
+
+		switch data[parser.pos+1] {
+		case '/':
+			// Closing tag of the innermost matched ancestor
+			if level > 0 {
+				level--
+			}
+			parser.readUntil('>')
+			parser.next()
+			continue
+		case '?':
+			parser.pos = processingInstructionEnd(data, parser.pos)
+			continue
+		case '!':
+			switch {
+			case bytes.HasPrefix(data[parser.pos:], []byte("<!--")):
+				parser.pos = skipPast(data, parser.pos, "-->")
+			case bytes.HasPrefix(data[parser.pos:], []byte("<![CDATA[")):
+				parser.pos = skipPast(data, parser.pos, "]]>")
+			default:
+				// DOCTYPE or other declaration - skip it, including any internal subset
+				if level == 0 && !parser.skipToNextElement() {
+					return false
+				}
+				if level > 0 {
+					parser.pos = skipPast(data, parser.pos, ">")
+				}
+			}
+			continue
+		}
+
+		parser.next() // skip '<'
+		elemName, attrs, isSelfClosing := parser.parseElementName()
+
+		if !segments[level].matches(elemName) {
+			if !isSelfClosing {
+				parser.skipElementContent(elemName)
+			}
+			continue
+		}
+
+		if level+1 == elementSegs {
+			// Target element found - no need to read its content
+			if attrName == "" {
+				return true
+			}
+			_, ok := attrs[attrName]
+			return ok
+		}
+
+		if !isSelfClosing {
+			level++
+		}
+	}
+
+	return false
+}
+
+// skipPast returns the position just after the first occurrence of delim at
+// or after pos, or len(data) if delim does not occur.
+func skipPast(data []byte, pos int, delim string) int {
+	idx := bytes.Index(data[pos:], []byte(delim))
+	if idx < 0 {
+		return len(data)
+	}
+	return pos + idx + len(delim)
+}
+
 // GetWithOptions is like Get but accepts Options for behavioral control.
 // Most users should use Get(); this function is for advanced use cases.
 //
@@ -856,32 +1864,203 @@ func GetStringWithOptions(xml string, path string, opts *Options) Result {
 // Security: Documents larger than MaxDocumentSize (10MB) are rejected to prevent
 // memory exhaustion attacks.
 func GetBytesWithOptions(xml []byte, path string, opts *Options) Result {
+	result, _ := QueryBytesWithOptions(xml, path, opts)
+	return result
+}
+
+// QueryWithOptions is like GetWithOptions but also reports why a query could
+// not be answered. A path that simply matches nothing returns a Null Result
+// and a nil error. Errors are:
+//   - ErrInvalidPath if the path cannot be parsed
+//   - ErrLimitExceeded if the document exceeds MaxDocumentSize, a strict
+//     limit option (AttributeOverflowError, RecursiveOverflowError) is hit,
+//     or Strict is set and the document is nested deeper than the nesting
+//     limit (Options.MaxNestingDepth or MaxNestingDepth)
+//   - ErrMalformedXML if RejectDuplicateAttributes is set and the document
+//     repeats an attribute, or Strict is set and the document is not
+//     well-formed
+//   - ErrUnknownModifier if Strict is set and the path names a modifier that
+//     is not registered (the error names it)
+//
+// The Result is Null whenever the error is non-nil.
+//
+// Example:
+//
+//	opts := &Options{CaseSensitive: true, RecursiveOverflowError: true}
+//	result, err := QueryWithOptions(xml, "root.**.item.#", opts)
+//	if errors.Is(err, ErrLimitExceeded) {
+//	    // the count would have been incomplete
+//	}
+//
+// Concurrency: QueryWithOptions is safe for concurrent use from multiple goroutines.
+func QueryWithOptions(xml, path string, opts *Options) (Result, error) {
+	return QueryBytesWithOptions(stringToBytes(xml), path, opts)
+}
+
+// QueryBytesWithOptions is like QueryWithOptions but accepts xml as a byte slice.
+func QueryBytesWithOptions(xml []byte, path string, opts *Options) (Result, error) {
 	// Security check: reject documents that are too large
 	if len(xml) > MaxDocumentSize {
-		return Result{Type: Null}
+		return Result{Type: Null}, fmt.Errorf("%w: document larger than MaxDocumentSize (%d bytes)", ErrLimitExceeded, MaxDocumentSize)
 	}
 
 	// Fast path: if opts uses all defaults, use standard Get path
 	if isDefaultOptions(opts) {
 		segments := parsePath(path)
 		if len(segments) == 0 {
-			return Result{Type: Null}
+			return Result{Type: Null}, ErrInvalidPath
 		}
 		parser := newXMLParser(xml)
-		return executeQuery(parser, segments, 0)
+		return markMulti(executeQuery(parser, segments, 0), segments), nil
+	}
+
+	// Strict attribute checks: rejected documents yield Null
+	if err := checkAttributeOptions(xml, opts); err != nil {
+		return Result{Type: Null}, err
+	}
+	if opts.Strict {
+		if err := checkQueryWellFormed(xml, opts); err != nil {
+			return Result{Type: Null}, err
+		}
 	}
 
 	// Parse path with options-aware parsing
 	segments := parsePathWithOptions(path, opts)
 	if len(segments) == 0 {
-		return Result{Type: Null}
+		return Result{Type: Null}, ErrInvalidPath
+	}
+	if opts.Strict {
+		if err := checkModifiers(segments); err != nil {
+			return Result{Type: Null}, err
+		}
+	}
+
+	// Track truncated recursive searches on a private copy of opts so the
+	// caller's Options stay safe to share between goroutines
+	if opts.RecursiveOverflowError {
+		local := *opts
+		local.state = &queryState{}
+		opts = &local
 	}
 
 	// Create parser
 	parser := newXMLParser(xml)
 
 	// Execute query with options
-	return executeQueryWithOptions(parser, segments, 0, opts)
+	result := executeQueryWithOptions(parser, segments, 0, opts)
+	if opts.state != nil && opts.state.recursiveOverflow != nil {
+		return Result{Type: Null}, opts.state.recursiveOverflow
+	}
+	if opts.BlankIsAbsent {
+		result = dropBlankElements(result)
+	}
+	return markMulti(result, segments), nil
+}
+
+// checkModifiers reports the first modifier in segments that is not
+// registered, or that is given an argument it does not accept.
+func checkModifiers(segments []PathSegment) error {
+	for _, seg := range segments {
+		for _, name := range seg.Modifiers {
+			if _, err := resolveModifier(name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// fragmentWrapperStart is the opening tag Result.Get wraps a multi-root
+// fragment in before querying it.
+const fragmentWrapperStart = "<_xmldot_root>"
+
+// shiftSpan moves the spans of r (and of its items) by delta bytes, for
+// results queried from a document that was wrapped or embedded.
+func shiftSpan(r Result, delta int) Result {
+	shift := func(item *Result) {
+		if item.span.known() {
+			item.span.start += delta
+			item.span.content += delta
+			item.span.end += delta
+		}
+	}
+	shift(&r)
+	if len(r.Results) > 0 {
+		r.Results = append([]Result(nil), r.Results...)
+		for i := range r.Results {
+			shift(&r.Results[i])
+		}
+	}
+	return r
+}
+
+// withoutSpans clears the spans of r and of its items, for modifier output,
+// whose elements are no longer the matches as they lie in the input.
+func withoutSpans(r Result) Result {
+	r.span = docSpan{}
+	if len(r.Results) > 0 {
+		r.Results = append([]Result(nil), r.Results...)
+		for i := range r.Results {
+			r.Results[i].span = docSpan{}
+		}
+	}
+	return r
+}
+
+// markMulti records on r whether segments describe a collection, for
+// Result.IsMulti. Wildcards, field extraction, all-matches filters and #root
+// make a path collection-shaped; a later index, count or first-match filter
+// selects a single value again.
+func markMulti(r Result, segments []PathSegment) Result {
+	multi := false
+	for _, seg := range segments {
+		switch seg.Type {
+		case SegmentWildcard, SegmentFieldExtraction, SegmentRoot:
+			multi = true
+		case SegmentFilter:
+			multi = seg.FilterAll
+		case SegmentIndex, SegmentCount:
+			multi = false
+		}
+	}
+	r.multi = multi
+	return r
+}
+
+// dropBlankElements implements Options.BlankIsAbsent: an Element whose
+// content is empty or only whitespace becomes Null, and such elements are
+// removed from an Array. An Array left empty becomes Null.
+func dropBlankElements(r Result) Result {
+	switch r.Type {
+	case Element:
+		if isBlank(r.Raw) {
+			return Result{Type: Null}
+		}
+	case Array:
+		kept := make([]Result, 0, len(r.Results))
+		for _, item := range r.Results {
+			if item.Type != Element || !isBlank(item.Raw) {
+				kept = append(kept, item)
+			}
+		}
+		if len(kept) == 0 {
+			return Result{Type: Null}
+		}
+		if len(kept) < len(r.Results) {
+			return Result{Type: Array, Results: kept}
+		}
+	}
+	return r
+}
+
+// isBlank reports whether s holds only XML whitespace.
+func isBlank(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if !isWhitespace(s[i]) {
+			return false
+		}
+	}
+	return true
 }
 
 // parsePathWithOptions parses a path with options-aware parsing.
@@ -895,7 +2074,20 @@ func parsePathWithOptions(path string, opts *Options) []PathSegment {
 
 	// Phase 6: For case-insensitive matching, we'll normalize the path during parsing
 	// The actual case-insensitive matching happens in PathSegment.matchesWithOptions
-	segments := parsePath(path)
+	var segments []PathSegment
+	if opts.DisableCache {
+		if path != "" {
+			segments = parsePathInternal(path)
+		}
+	} else {
+		segments = parsePath(path)
+	}
+
+	// A raised nesting limit admits paths longer than MaxPathSegments, which
+	// are parsed without caching
+	if len(segments) == 0 && path != "" && opts.nestingLimit() >= MaxPathSegments {
+		segments = parsePathSegments(path, opts.nestingLimit()+1)
+	}
 
 	// If case-insensitive, convert all segment values to lowercase for matching
 	if !opts.CaseSensitive {
@@ -918,15 +2110,43 @@ func executeQueryWithOptions(parser *xmlParser, segments []PathSegment, segIndex
 		return Result{Type: Null}
 	}
 
+	if segIndex == 0 {
+		if boundary := modifierBoundary(segments); boundary >= 0 {
+			result := executeQueryWithOptions(parser, segments[:boundary+1], 0, opts)
+			return continueAfterModifier(result, segments[boundary+1:], opts)
+		}
+		if pos := positionalAttribute(segments); pos > 0 {
+			result := executeQueryWithOptions(parser, segments[:pos], 0, opts)
+			return continueAfterModifier(result, segments[pos:], opts)
+		}
+	}
+
 	currentSeg := segments[segIndex]
 	isLastSegment := segIndex == len(segments)-1
 
+	// Synthetic #root accessor for top-level elements
+	if currentSeg.Type == SegmentRoot {
+		if segIndex != 0 {
+			return Result{Type: Null}
+		}
+		return handleRootQuery(parser, segments, opts)
+	}
+
 	// Fragment root array support: Check if we're at root level (segIndex==0) with array operations
 	// This enables: <user>A</user><user>B</user> + query "user.#" → 2
 	// Note: Only use fast path for case-sensitive matching; case-insensitive needs generic path
 	if segIndex == 0 && !isLastSegment && currentSeg.Type == SegmentElement && opts.CaseSensitive {
 		nextSeg := segments[1]
 		if nextSeg.Type == SegmentIndex || nextSeg.Type == SegmentCount || nextSeg.Type == SegmentFieldExtraction {
+			// Counting streams through the roots without collecting them
+			if nextSeg.Type == SegmentCount {
+				count := countMatchingElements(parser, func(name string) bool { return name == currentSeg.Value })
+				if count == 0 {
+					return Result{Type: Null}
+				}
+				return newCountResult(count)
+			}
+
 			// Array operation on fragment roots - collect all matching roots
 			matches := collectFragmentRoots(parser, currentSeg.Value)
 
@@ -950,11 +2170,7 @@ func executeQueryWithOptions(parser *xmlParser, segments []PathSegment, segIndex
 						if segments[segIndex+2].Type == SegmentAttribute {
 							attrName := segments[segIndex+2].Value
 							if attrValue, ok := match.attrs[attrName]; ok {
-								return Result{
-									Type: Attribute,
-									Str:  attrValue,
-									Raw:  attrValue,
-								}
+								return newAttributeResult(match.tag, attrName, attrValue)
 							}
 							return Result{Type: Null}
 						}
@@ -964,22 +2180,18 @@ func executeQueryWithOptions(parser *xmlParser, segments []PathSegment, segIndex
 							textContent := extractDirectTextOnly(match.content)
 							return Result{
 								Type: String,
-								Str:  unescapeXML(textContent),
+								Str:  decodeText(textContent, opts),
 								Raw:  match.content,
 							}
 						}
 
 						// Continue matching within selected root element
-						contentParser := newXMLParser([]byte(match.content))
+						contentParser := newContentParser(match.content, match.span)
 						return executeQueryWithOptions(contentParser, segments, segIndex+2, opts)
 					}
 
 					// No more segments - return the indexed root element
-					return Result{
-						Type: Element,
-						Str:  unescapeXML(extractTextContent(match.content)),
-						Raw:  match.content,
-					}
+					return newElementResult(match)
 				}
 				return Result{Type: Null} // Out of bounds
 
@@ -1011,7 +2223,8 @@ func executeQueryWithOptions(parser *xmlParser, segments []PathSegment, segIndex
 		var allMatches []elementMatch
 		for parser.skipToNextElement() {
 			parser.next()
-			elemName, attrs, isSelfClosing := parser.parseElementName()
+			elemName, attrs, attrOrder, isSelfClosing := parser.parseElementTag()
+			tag := parser.tag
 
 			if !currentSeg.matchesWithOptions(elemName, opts) {
 				if !isSelfClosing {
@@ -1030,6 +2243,9 @@ func executeQueryWithOptions(parser *xmlParser, segments []PathSegment, segIndex
 			allMatches = append(allMatches, elementMatch{
 				name:          elemName,
 				attrs:         attrs,
+				tag:           tag,
+				span:          parser.span(),
+				attrOrder:     attrOrder,
 				content:       content,
 				isSelfClosing: isSelfClosing,
 			})
@@ -1046,10 +2262,17 @@ func executeQueryWithOptions(parser *xmlParser, segments []PathSegment, segIndex
 	// This is the GJSON pattern with options: element.#(condition)
 	hasFollowingFilter := !isLastSegment && segments[segIndex+1].Type == SegmentFilter
 	if hasFollowingFilter && currentSeg.Type == SegmentElement {
-		var allMatches []elementMatch
-		for parser.skipToNextElement() {
+		if isFilterCount(segments, segIndex+1) {
+			match := func(name string) bool { return currentSeg.matchesWithOptions(name, opts) }
+			return countFilterMatches(parser, match, segments[segIndex+1])
+		}
+
+		filterSeg := segments[segIndex+1]
+		var filtered []elementMatch
+		for position := 0; parser.skipToNextElement(); {
 			parser.next()
-			elemName, attrs, isSelfClosing := parser.parseElementName()
+			elemName, attrs, attrOrder, isSelfClosing := parser.parseElementTag()
+			tag := parser.tag
 
 			if !currentSeg.matchesWithOptions(elemName, opts) {
 				if !isSelfClosing {
@@ -1065,19 +2288,29 @@ func executeQueryWithOptions(parser *xmlParser, segments []PathSegment, segIndex
 				content = parser.parseElementContent(elemName)
 			}
 
-			allMatches = append(allMatches, elementMatch{
+			match := elementMatch{
 				name:          elemName,
 				attrs:         attrs,
+				tag:           tag,
+				span:          parser.span(),
+				attrOrder:     attrOrder,
 				content:       content,
 				isSelfClosing: isSelfClosing,
-			})
+			}
+			if evaluateFilterOnMatch(filterSeg.Filter, match, position) {
+				filtered = append(filtered, match)
+				if len(filtered) >= filterLimit(filterSeg) {
+					break
+				}
+			}
 
-			if len(allMatches) >= MaxWildcardResults {
+			position++
+			if position >= MaxWildcardResults {
 				break
 			}
 		}
 
-		return handleFilterQueryOnMatchesWithOptions(allMatches, segments, segIndex+1, opts)
+		return routeFilterMatchesWithOptions(filtered, segments, segIndex+1, opts)
 	}
 
 	// Handle array index or count on previous match
@@ -1095,14 +2328,26 @@ func executeQueryWithOptions(parser *xmlParser, segments []PathSegment, segIndex
 		return handleRecursiveWildcardWithOptions(parser, segments, segIndex, opts)
 	}
 
+	// Counting (element.#) tallies matches without collecting them
+	if currentSeg.Type == SegmentElement && currentSeg.Filter == nil && !isLastSegment && segments[segIndex+1].Type == SegmentCount {
+		count := countMatchingElements(parser, func(name string) bool { return currentSeg.matchesWithOptions(name, opts) })
+		if count == 0 {
+			return Result{Type: Null}
+		}
+		return newCountResult(count)
+	}
+
 	// Find matching elements
 	var matches []elementMatch
-	isWildcard := currentSeg.Type == SegmentWildcard && !currentSeg.Wildcard
+	isWildcard := (currentSeg.Type == SegmentWildcard && !currentSeg.Wildcard) ||
+		(isLastSegment && currentSeg.Type == SegmentElement && modifiesMatchSet(currentSeg.Modifiers))
 	hasFilter := currentSeg.Filter != nil
+	position := 0
 
 	for parser.skipToNextElement() {
 		parser.next() // skip '<'
-		elemName, attrs, isSelfClosing := parser.parseElementName()
+		elemName, attrs, attrOrder, isSelfClosing := parser.parseElementTag()
+		tag := parser.tag
 
 		// Check if this segment is an attribute request
 		if currentSeg.Type == SegmentAttribute {
@@ -1136,14 +2381,18 @@ func executeQueryWithOptions(parser *xmlParser, segments []PathSegment, segIndex
 			match := elementMatch{
 				name:          elemName,
 				attrs:         attrs,
+				tag:           tag,
+				span:          parser.span(),
+				attrOrder:     attrOrder,
 				content:       content,
 				isSelfClosing: isSelfClosing,
 			}
 
 			if hasFilter {
-				if evaluateFilterOnMatch(currentSeg.Filter, match) {
+				if evaluateFilterOnMatch(currentSeg.Filter, match, position) {
 					matches = append(matches, match)
 				}
+				position++
 			} else {
 				matches = append(matches, match)
 			}
@@ -1162,11 +2411,7 @@ func executeQueryWithOptions(parser *xmlParser, segments []PathSegment, segIndex
 						if segIndex+2 < len(segments) {
 							return Result{Type: Null}
 						}
-						return Result{
-							Type: Attribute,
-							Str:  v,
-							Raw:  v,
-						}
+						return newAttributeResult(tag, k, v)
 					}
 				}
 				return Result{Type: Null}
@@ -1175,11 +2420,7 @@ func executeQueryWithOptions(parser *xmlParser, segments []PathSegment, segIndex
 				if segIndex+2 < len(segments) {
 					return Result{Type: Null}
 				}
-				return Result{
-					Type: Attribute,
-					Str:  attrValue,
-					Raw:  attrValue,
-				}
+				return newAttributeResult(tag, attrName, attrValue)
 			}
 			return Result{Type: Null}
 		}
@@ -1189,31 +2430,44 @@ func executeQueryWithOptions(parser *xmlParser, segments []PathSegment, segIndex
 			textContent := extractDirectTextOnly(content)
 			return Result{
 				Type: String,
-				Str:  unescapeXML(textContent),
+				Str:  decodeText(textContent, opts),
 				Raw:  content,
 			}
 		}
 
 		// If this is the last segment, return the element content
 		if isLastSegment {
-			return Result{
-				Type: Element,
-				Str:  unescapeXML(extractTextContent(content)),
-				Raw:  content,
+			result := newElementResult(elementMatch{
+				name:      elemName,
+				attrs:     attrs,
+				tag:       tag,
+				attrOrder: attrOrder,
+				content:   content,
+				span:      parser.span(),
+			})
+			if len(currentSeg.Modifiers) > 0 {
+				result = applyModifiersWithOptions(result, currentSeg.Modifiers, opts)
 			}
+			return result
 		}
 
 		// Otherwise, parse the content and continue matching
-		contentParser := newXMLParser([]byte(content))
+		contentParser := newContentParser(content, parser.span())
 		result := executeQueryWithOptions(contentParser, segments, segIndex+1, opts)
 		if result.Type != Null {
 			return result
 		}
 	}
 
-	// Handle wildcard or filter results
-	if (isWildcard || hasFilter) && len(matches) > 0 {
-		return handleWildcardMatchesWithOptions(matches, segments, segIndex, opts)
+	// Handle wildcard or filter results. An index or count after a
+	// single-level wildcard (root.*.0, root.*.#) selects from or counts its
+	// matches, like it does after an element name.
+	if (isWildcard || hasFilter) && len(matches) > 0 && !selectsWildcardMatch(segments, segIndex) {
+		result := handleWildcardMatchesWithOptions(matches, segments, segIndex, opts)
+		if isLastSegment && len(currentSeg.Modifiers) > 0 {
+			result = applyModifiersWithOptions(result, currentSeg.Modifiers, opts)
+		}
+		return result
 	}
 
 	// Handle array operations
@@ -1230,34 +2484,22 @@ func executeQueryWithOptions(parser *xmlParser, segments []PathSegment, segIndex
 						if !opts.CaseSensitive {
 							for k, v := range match.attrs {
 								if toLowerASCII(k) == attrName {
-									return Result{
-										Type: Attribute,
-										Str:  v,
-										Raw:  v,
-									}
+									return newAttributeResult(match.tag, k, v)
 								}
 							}
 							return Result{Type: Null}
 						}
 						if attrValue, ok := match.attrs[attrName]; ok {
-							return Result{
-								Type: Attribute,
-								Str:  attrValue,
-								Raw:  attrValue,
-							}
+							return newAttributeResult(match.tag, attrName, attrValue)
 						}
 						return Result{Type: Null}
 					}
 
-					contentParser := newXMLParser([]byte(match.content))
+					contentParser := newContentParser(match.content, match.span)
 					return executeQueryWithOptions(contentParser, segments, segIndex+2, opts)
 				}
 
-				return Result{
-					Type: Element,
-					Str:  unescapeXML(extractTextContent(match.content)),
-					Raw:  match.content,
-				}
+				return newElementResult(match)
 			}
 			return Result{Type: Null}
 		case SegmentCount:
@@ -1278,19 +2520,11 @@ func handleWildcardMatchesWithOptions(matches []elementMatch, segments []PathSeg
 
 	if isLastSegment {
 		if len(matches) == 1 {
-			return Result{
-				Type: Element,
-				Str:  unescapeXML(extractTextContent(matches[0].content)),
-				Raw:  matches[0].content,
-			}
+			return newElementResult(matches[0])
 		}
 		results := make([]Result, 0, len(matches))
 		for _, match := range matches {
-			results = append(results, Result{
-				Type: Element,
-				Str:  unescapeXML(extractTextContent(match.content)),
-				Raw:  match.content,
-			})
+			results = append(results, newElementResult(match))
 		}
 		return Result{
 			Type:    Array,
@@ -1308,11 +2542,7 @@ func handleWildcardMatchesWithOptions(matches []elementMatch, segments []PathSeg
 				for k, v := range match.attrs {
 					if toLowerASCII(k) == attrName {
 						if segIndex+2 >= len(segments) {
-							allResults = append(allResults, Result{
-								Type: Attribute,
-								Str:  v,
-								Raw:  v,
-							})
+							allResults = append(allResults, newAttributeResult(match.tag, k, v))
 						}
 					}
 				}
@@ -1320,11 +2550,7 @@ func handleWildcardMatchesWithOptions(matches []elementMatch, segments []PathSeg
 			}
 			if attrValue, ok := match.attrs[attrName]; ok {
 				if segIndex+2 >= len(segments) {
-					allResults = append(allResults, Result{
-						Type: Attribute,
-						Str:  attrValue,
-						Raw:  attrValue,
-					})
+					allResults = append(allResults, newAttributeResult(match.tag, attrName, attrValue))
 				}
 			}
 			continue
@@ -1334,13 +2560,13 @@ func handleWildcardMatchesWithOptions(matches []elementMatch, segments []PathSeg
 			textContent := extractDirectTextOnly(match.content)
 			allResults = append(allResults, Result{
 				Type: String,
-				Str:  unescapeXML(textContent),
+				Str:  decodeText(textContent, opts),
 				Raw:  match.content,
 			})
 			continue
 		}
 
-		contentParser := newXMLParser([]byte(match.content))
+		contentParser := newContentParser(match.content, match.span)
 		result := executeQueryWithOptions(contentParser, segments, segIndex+1, opts)
 		if result.Type != Null {
 			if result.Type == Array {
@@ -1369,7 +2595,7 @@ func handleWildcardMatchesWithOptions(matches []elementMatch, segments []PathSeg
 	// Apply modifiers from the next segment if present (Phase 6)
 	// The next segment after the wildcard is the one that was matched
 	if segIndex+1 < len(segments) && len(segments[segIndex+1].Modifiers) > 0 {
-		result = applyModifiers(result, segments[segIndex+1].Modifiers)
+		result = applyModifiersWithOptions(result, segments[segIndex+1].Modifiers, opts)
 	}
 
 	return result
@@ -1384,34 +2610,65 @@ func handleRecursiveWildcardWithOptions(parser *xmlParser, segments []PathSegmen
 	nextSegIndex := segIndex + 1
 	targetSeg := segments[nextSegIndex]
 
+	// Modifiers on the final segment apply to the combined matches rather
+	// than to each match on its own
+	modifiers := segments[len(segments)-1].Modifiers
+	if len(modifiers) > 0 {
+		segments = withoutFinalModifiers(segments)
+		targetSeg = segments[nextSegIndex]
+	}
+
+	// An index or count after the target selects among all matches, like
+	// on a single-level array (root.**.price.0, .-1, .#)
+	var selection []PathSegment
+	segments, selection = splitRecursiveSelection(segments, nextSegIndex)
+
 	var allResults []Result
 	ctx := &searchContext{operations: 0, results: &allResults}
-	recursiveSearchWithContextAndOptions(parser, targetSeg, segments, nextSegIndex, ctx, 0, opts)
-
-	if len(allResults) == 0 {
-		return Result{Type: Null}
+	if opts.RecursiveOrder == BreadthFirst {
+		breadthFirstSearchWithOptions(parser, targetSeg, segments, nextSegIndex, ctx, opts)
+	} else {
+		recursiveSearchWithContextAndOptions(parser, targetSeg, segments, nextSegIndex, ctx, 0, opts)
 	}
-	if len(allResults) == 1 {
-		return allResults[0]
+	if opts.state != nil {
+		if ctx.operations >= MaxRecursiveOperations {
+			opts.state.recursiveOverflow = fmt.Errorf("%w: recursive wildcard search exceeded MaxRecursiveOperations (%d)", ErrLimitExceeded, MaxRecursiveOperations)
+		} else if ctx.truncated {
+			opts.state.recursiveOverflow = fmt.Errorf("%w: recursive wildcard matched more than MaxWildcardResults (%d)", ErrLimitExceeded, MaxWildcardResults)
+		}
 	}
-	return Result{
-		Type:    Array,
-		Results: allResults,
+
+	var result Result
+	switch {
+	case selection != nil:
+		result = continueAfterModifier(Result{Type: Array, Results: allResults}, selection, opts)
+	case len(allResults) == 0:
+		result = Result{Type: Null}
+	case len(allResults) == 1:
+		result = allResults[0]
+	default:
+		result = Result{
+			Type:    Array,
+			Results: allResults,
+		}
+	}
+	if len(modifiers) > 0 {
+		result = applyModifiersWithOptions(result, modifiers, opts)
 	}
+	return result
 }
 
 // recursiveSearchWithContextAndOptions is like recursiveSearchWithContext but with Options support
 func recursiveSearchWithContextAndOptions(parser *xmlParser, targetSeg PathSegment, segments []PathSegment, segIndex int, ctx *searchContext, depth int, opts *Options) {
 	ctx.operations++
-	if depth > MaxNestingDepth || len(*ctx.results) >= MaxWildcardResults || ctx.operations >= MaxRecursiveOperations {
+	if depth > opts.nestingLimit() || len(*ctx.results) >= MaxWildcardResults || ctx.operations >= MaxRecursiveOperations {
 		return
 	}
 
-	isLastSegment := segIndex == len(segments)-1
-
 	for parser.skipToNextElement() {
 		parser.next()
-		elemName, attrs, isSelfClosing := parser.parseElementName()
+		elemName, attrs, attrOrder, isSelfClosing := parser.parseElementTag()
+		tag := parser.tag
 
 		var content string
 		if isSelfClosing {
@@ -1420,73 +2677,125 @@ func recursiveSearchWithContextAndOptions(parser *xmlParser, targetSeg PathSegme
 			content = parser.parseElementContent(elemName)
 		}
 
+		if targetSeg.matchesWithOptions(elemName, opts) {
+			if len(*ctx.results) >= MaxWildcardResults {
+				ctx.truncated = true
+				return
+			}
+			appendRecursiveMatchWithOptions(ctx, segments, segIndex, elementMatch{
+				name:          elemName,
+				attrs:         attrs,
+				tag:           tag,
+				span:          parser.span(),
+				attrOrder:     attrOrder,
+				content:       content,
+				isSelfClosing: isSelfClosing,
+			}, opts)
+		}
+
 		if !isSelfClosing && content != "" {
-			contentParser := newXMLParser([]byte(content))
+			contentParser := newContentParser(content, parser.span())
 			recursiveSearchWithContextAndOptions(contentParser, targetSeg, segments, segIndex, ctx, depth+1, opts)
 		}
 
 		if ctx.operations >= MaxRecursiveOperations {
 			return
 		}
+	}
+}
 
-		if targetSeg.matchesWithOptions(elemName, opts) {
-			if len(*ctx.results) >= MaxWildcardResults {
+// breadthFirstSearchWithOptions is like recursiveSearchWithContextAndOptions
+// but visits the document level by level, so shallower matches come first.
+// Matches at the same depth keep their document order.
+// Security: applies the same depth, result count, and operation limits
+func breadthFirstSearchWithOptions(parser *xmlParser, targetSeg PathSegment, segments []PathSegment, segIndex int, ctx *searchContext, opts *Options) {
+	level := []*xmlParser{parser}
+	for depth := 0; len(level) > 0 && depth <= opts.nestingLimit(); depth++ {
+		var next []*xmlParser
+		for _, levelParser := range level {
+			ctx.operations++
+			if ctx.operations >= MaxRecursiveOperations {
 				return
 			}
 
-			if isLastSegment {
-				*ctx.results = append(*ctx.results, Result{
-					Type: Element,
-					Str:  unescapeXML(extractTextContent(content)),
-					Raw:  content,
-				})
-			} else {
-				nextSegment := segments[segIndex+1]
-				switch nextSegment.Type {
-				case SegmentAttribute:
-					attrName := nextSegment.Value
-					if !opts.CaseSensitive {
-						for k, v := range attrs {
-							if toLowerASCII(k) == attrName {
-								if segIndex+2 >= len(segments) {
-									*ctx.results = append(*ctx.results, Result{
-										Type: Attribute,
-										Str:  v,
-										Raw:  v,
-									})
-								}
-							}
-						}
-					} else {
-						if attrValue, ok := attrs[attrName]; ok {
-							if segIndex+2 >= len(segments) {
-								*ctx.results = append(*ctx.results, Result{
-									Type: Attribute,
-									Str:  attrValue,
-									Raw:  attrValue,
-								})
-							}
-						}
+			for levelParser.skipToNextElement() {
+				levelParser.next()
+				elemName, attrs, attrOrder, isSelfClosing := levelParser.parseElementTag()
+				tag := levelParser.tag
+
+				var content string
+				if !isSelfClosing {
+					content = levelParser.parseElementContent(elemName)
+				}
+
+				if targetSeg.matchesWithOptions(elemName, opts) {
+					if len(*ctx.results) >= MaxWildcardResults {
+						ctx.truncated = true
+						return
 					}
-				case SegmentText:
-					textContent := extractDirectTextOnly(content)
-					*ctx.results = append(*ctx.results, Result{
-						Type: String,
-						Str:  unescapeXML(textContent),
-						Raw:  content,
-					})
-				default:
-					contentParser := newXMLParser([]byte(content))
-					result := executeQueryWithOptions(contentParser, segments, segIndex+1, opts)
-					if result.Type != Null {
-						if result.Type == Array {
-							*ctx.results = append(*ctx.results, result.Results...)
-						} else {
-							*ctx.results = append(*ctx.results, result)
-						}
+					appendRecursiveMatchWithOptions(ctx, segments, segIndex, elementMatch{
+						name:          elemName,
+						attrs:         attrs,
+						tag:           tag,
+						span:          levelParser.span(),
+						attrOrder:     attrOrder,
+						content:       content,
+						isSelfClosing: isSelfClosing,
+					}, opts)
+				}
+
+				if content != "" {
+					next = append(next, newContentParser(content, levelParser.span()))
+				}
+			}
+		}
+		level = next
+	}
+}
+
+// appendRecursiveMatchWithOptions applies the segments following a recursive
+// wildcard target to one matched element and appends the results to ctx
+func appendRecursiveMatchWithOptions(ctx *searchContext, segments []PathSegment, segIndex int, match elementMatch, opts *Options) {
+	if segIndex == len(segments)-1 {
+		*ctx.results = append(*ctx.results, newElementResult(match))
+		return
+	}
+
+	nextSegment := segments[segIndex+1]
+	switch nextSegment.Type {
+	case SegmentAttribute:
+		attrName := nextSegment.Value
+		if !opts.CaseSensitive {
+			for k, v := range match.attrs {
+				if toLowerASCII(k) == attrName {
+					if segIndex+2 >= len(segments) {
+						*ctx.results = append(*ctx.results, newAttributeResult(match.tag, k, v))
 					}
 				}
 			}
+		} else {
+			if attrValue, ok := match.attrs[attrName]; ok {
+				if segIndex+2 >= len(segments) {
+					*ctx.results = append(*ctx.results, newAttributeResult(match.tag, attrName, attrValue))
+				}
+			}
+		}
+	case SegmentText:
+		textContent := extractDirectTextOnly(match.content)
+		*ctx.results = append(*ctx.results, Result{
+			Type: String,
+			Str:  decodeText(textContent, opts),
+			Raw:  match.content,
+		})
+	default:
+		contentParser := newContentParser(match.content, match.span)
+		result := executeQueryWithOptions(contentParser, segments, segIndex+1, opts)
+		if result.Type != Null {
+			if result.Type == Array {
+				*ctx.results = append(*ctx.results, result.Results...)
+			} else {
+				*ctx.results = append(*ctx.results, result)
+			}
 		}
 	}
 }
@@ -1521,11 +2830,7 @@ func executeFieldExtraction(matches []elementMatch, segment PathSegment) Result
 			// Extract attribute value
 			attrName := fieldName[1:] // Remove @ prefix
 			if attrValue, ok := match.attrs[attrName]; ok {
-				results = append(results, Result{
-					Type: Attribute,
-					Str:  attrValue,
-					Raw:  attrValue,
-				})
+				results = append(results, newAttributeResult(match.tag, attrName, attrValue))
 				totalExtracted++
 			}
 		} else if isText {
@@ -1541,7 +2846,7 @@ func executeFieldExtraction(matches []elementMatch, segment PathSegment) Result
 			}
 		} else {
 			// Extract child element(s) with matching name
-			parser := newXMLParser([]byte(match.content))
+			parser := newContentParser(match.content, match.span)
 			for parser.skipToNextElement() {
 				// Security: Check limit on each iteration
 				if totalExtracted >= MaxWildcardResults {
@@ -1549,7 +2854,8 @@ func executeFieldExtraction(matches []elementMatch, segment PathSegment) Result
 				}
 
 				parser.next() // skip '<'
-				elemName, _, isSelfClosing := parser.parseElementName()
+				elemName, attrs, attrOrder, isSelfClosing := parser.parseElementTag()
+				tag := parser.tag
 
 				// Check if element name matches field name
 				if elemName != fieldName {
@@ -1568,11 +2874,14 @@ func executeFieldExtraction(matches []elementMatch, segment PathSegment) Result
 					content = parser.parseElementContent(elemName)
 				}
 
-				results = append(results, Result{
-					Type: Element,
-					Str:  unescapeXML(extractTextContent(content)),
-					Raw:  content,
-				})
+				results = append(results, newElementResult(elementMatch{
+					name:      elemName,
+					attrs:     attrs,
+					tag:       tag,
+					span:      parser.span(),
+					attrOrder: attrOrder,
+					content:   content,
+				}))
 				totalExtracted++
 			}
 		}
@@ -1633,11 +2942,7 @@ func executeFieldExtractionWithOptions(matches []elementMatch, segment PathSegme
 				attrNameLower := toLowerASCII(attrName)
 				for k, v := range match.attrs {
 					if toLowerASCII(k) == attrNameLower {
-						results = append(results, Result{
-							Type: Attribute,
-							Str:  v,
-							Raw:  v,
-						})
+						results = append(results, newAttributeResult(match.tag, k, v))
 						totalExtracted++
 						break // Only match first attribute with this name
 					}
@@ -1645,11 +2950,7 @@ func executeFieldExtractionWithOptions(matches []elementMatch, segment PathSegme
 			} else {
 				// Case-sensitive attribute lookup
 				if attrValue, ok := match.attrs[attrName]; ok {
-					results = append(results, Result{
-						Type: Attribute,
-						Str:  attrValue,
-						Raw:  attrValue,
-					})
+					results = append(results, newAttributeResult(match.tag, attrName, attrValue))
 					totalExtracted++
 				}
 			}
@@ -1658,14 +2959,14 @@ func executeFieldExtractionWithOptions(matches []elementMatch, segment PathSegme
 			if textContent != "" {
 				results = append(results, Result{
 					Type: String,
-					Str:  unescapeXML(textContent),
+					Str:  decodeText(textContent, opts),
 					Raw:  textContent,
 				})
 				totalExtracted++
 			}
 		} else {
 			// Extract child element(s) with matching name (case-insensitive if needed)
-			parser := newXMLParser([]byte(match.content))
+			parser := newContentParser(match.content, match.span)
 			fieldNameCmp := fieldName
 			if !opts.CaseSensitive {
 				fieldNameCmp = toLowerASCII(fieldName)
@@ -1677,7 +2978,8 @@ func executeFieldExtractionWithOptions(matches []elementMatch, segment PathSegme
 				}
 
 				parser.next()
-				elemName, _, isSelfClosing := parser.parseElementName()
+				elemName, attrs, attrOrder, isSelfClosing := parser.parseElementTag()
+				tag := parser.tag
 
 				// Case-aware comparison
 				elemNameCmp := elemName
@@ -1699,11 +3001,14 @@ func executeFieldExtractionWithOptions(matches []elementMatch, segment PathSegme
 					content = parser.parseElementContent(elemName)
 				}
 
-				results = append(results, Result{
-					Type: Element,
-					Str:  unescapeXML(extractTextContent(content)),
-					Raw:  content,
-				})
+				results = append(results, newElementResult(elementMatch{
+					name:      elemName,
+					attrs:     attrs,
+					tag:       tag,
+					span:      parser.span(),
+					attrOrder: attrOrder,
+					content:   content,
+				}))
 				totalExtracted++
 			}
 		}
@@ -1722,24 +3027,28 @@ func executeFieldExtractionWithOptions(matches []elementMatch, segment PathSegme
 	}
 
 	if len(segment.Modifiers) > 0 {
-		result = applyModifiers(result, segment.Modifiers)
+		result = applyModifiersWithOptions(result, segment.Modifiers, opts)
 	}
 
 	return result
 }
 
 // handleFilterQuery processes GJSON-style filter queries #(condition) or #(condition)#
-// This function collects all matching elements, then routes to first-match or all-match processing.
+// This function collects the matching elements, then routes to first-match or all-match processing.
 func handleFilterQuery(parser *xmlParser, segments []PathSegment, segIndex int) Result {
 	currentSeg := segments[segIndex]
-	isLastSegment := segIndex == len(segments)-1
+
+	if isFilterCount(segments, segIndex) {
+		return countFilterMatches(parser, func(string) bool { return true }, currentSeg)
+	}
 
 	// Collect ALL matching elements
 	var matches []elementMatch
 
-	for parser.skipToNextElement() {
+	for position := 0; parser.skipToNextElement(); position++ {
 		parser.next() // skip '<'
-		elemName, attrs, isSelfClosing := parser.parseElementName()
+		elemName, attrs, attrOrder, isSelfClosing := parser.parseElementTag()
+		tag := parser.tag
 
 		// Extract content
 		var content string
@@ -1752,33 +3061,24 @@ func handleFilterQuery(parser *xmlParser, segments []PathSegment, segIndex int)
 		match := elementMatch{
 			name:          elemName,
 			attrs:         attrs,
+			tag:           tag,
+			span:          parser.span(),
+			attrOrder:     attrOrder,
 			content:       content,
 			isSelfClosing: isSelfClosing,
 		}
 
-		// Evaluate filter condition
-		if evaluateFilterOnMatch(currentSeg.Filter, match) {
+		// Evaluate filter condition, stopping once the filter has all the
+		// matches it keeps (also enforces MaxWildcardResults)
+		if evaluateFilterOnMatch(currentSeg.Filter, match, position) {
 			matches = append(matches, match)
-
-			// Security: enforce result limit
-			if len(matches) >= MaxWildcardResults {
+			if len(matches) >= filterLimit(currentSeg) {
 				break
 			}
 		}
 	}
 
-	// No matches found
-	if len(matches) == 0 {
-		return Result{Type: Null}
-	}
-
-	// Route based on FilterAll flag
-	if currentSeg.FilterAll {
-		// #(condition)# - Return ALL matches
-		return processAllMatches(matches, segments, segIndex, isLastSegment)
-	}
-	// #(condition) - Return FIRST match
-	return processFirstMatch(matches[0], segments, segIndex, isLastSegment)
+	return routeFilterMatches(matches, segments, segIndex)
 }
 
 // processFirstMatch processes the first matching element from a filter query
@@ -1787,11 +3087,7 @@ func processFirstMatch(match elementMatch, segments []PathSegment, segIndex int,
 
 	// If this is the last segment, return the element
 	if isLastSegment {
-		result := Result{
-			Type: Element,
-			Str:  unescapeXML(extractTextContent(match.content)),
-			Raw:  match.content,
-		}
+		result := newElementResult(match)
 		// Apply modifiers if present
 		if len(currentSeg.Modifiers) > 0 {
 			result = applyModifiers(result, currentSeg.Modifiers)
@@ -1806,11 +3102,7 @@ func processFirstMatch(match elementMatch, segments []PathSegment, segIndex int,
 	if nextSeg.Type == SegmentAttribute {
 		attrName := nextSeg.Value
 		if attrValue, ok := match.attrs[attrName]; ok {
-			result := Result{
-				Type: Attribute,
-				Str:  attrValue,
-				Raw:  attrValue,
-			}
+			result := newAttributeResult(match.tag, attrName, attrValue)
 			// Apply modifiers from the attribute segment if present
 			if len(nextSeg.Modifiers) > 0 {
 				result = applyModifiers(result, nextSeg.Modifiers)
@@ -1841,7 +3133,7 @@ func processFirstMatch(match elementMatch, segments []PathSegment, segIndex int,
 	}
 
 	// Continue query within matched element
-	contentParser := newXMLParser([]byte(match.content))
+	contentParser := newContentParser(match.content, match.span)
 	return executeQuery(contentParser, segments, segIndex+1)
 }
 
@@ -1853,11 +3145,7 @@ func processAllMatches(matches []elementMatch, segments []PathSegment, segIndex
 	if isLastSegment {
 		results := make([]Result, 0, len(matches))
 		for _, match := range matches {
-			results = append(results, Result{
-				Type: Element,
-				Str:  unescapeXML(extractTextContent(match.content)),
-				Raw:  match.content,
-			})
+			results = append(results, newElementResult(match))
 		}
 
 		result := Result{
@@ -1880,11 +3168,7 @@ func processAllMatches(matches []elementMatch, segments []PathSegment, segIndex
 		if nextSeg.Type == SegmentAttribute {
 			attrName := nextSeg.Value
 			if attrValue, ok := match.attrs[attrName]; ok {
-				allResults = append(allResults, Result{
-					Type: Attribute,
-					Str:  attrValue,
-					Raw:  attrValue,
-				})
+				allResults = append(allResults, newAttributeResult(match.tag, attrName, attrValue))
 			}
 			continue
 		}
@@ -1908,7 +3192,7 @@ func processAllMatches(matches []elementMatch, segments []PathSegment, segIndex
 		}
 
 		// Continue query within matched element
-		contentParser := newXMLParser([]byte(match.content))
+		contentParser := newContentParser(match.content, match.span)
 		result := executeQuery(contentParser, segments, segIndex+1)
 		if result.Type != Null {
 			if result.Type == Array {
@@ -1951,42 +3235,70 @@ func processAllMatches(matches []elementMatch, segments []PathSegment, segIndex
 // handleFilterQueryOnMatches processes GJSON-style filters against a pre-collected set of matches
 // This is used when an element segment is followed by a filter segment (element.#(condition))
 func handleFilterQueryOnMatches(allMatches []elementMatch, segments []PathSegment, segIndex int) Result {
-	currentSeg := segments[segIndex]
-	isLastSegment := segIndex == len(segments)-1
+	return routeFilterMatches(filterMatches(allMatches, segments[segIndex]), segments, segIndex)
+}
 
-	// Filter the matches
+// filterMatches returns the matches that pass the filter of seg, up to the
+// number of matches the filter keeps.
+func filterMatches(allMatches []elementMatch, seg PathSegment) []elementMatch {
 	var filteredMatches []elementMatch
-	for _, match := range allMatches {
-		if evaluateFilterOnMatch(currentSeg.Filter, match) {
+	for i, match := range allMatches {
+		if evaluateFilterOnMatch(seg.Filter, match, i) {
 			filteredMatches = append(filteredMatches, match)
+			if len(filteredMatches) >= filterLimit(seg) {
+				break
+			}
 		}
 	}
+	return filteredMatches
+}
+
+// filterLimit returns how many matches the filter segment seg keeps: one for
+// #(condition), N for #(condition)#:N and MaxWildcardResults for
+// #(condition)#. Scans stop as soon as the limit is reached.
+func filterLimit(seg PathSegment) int {
+	switch {
+	case !seg.FilterAll:
+		return 1
+	case seg.Limit > 0 && seg.Limit < MaxWildcardResults:
+		return seg.Limit
+	default:
+		return MaxWildcardResults
+	}
+}
 
+// routeFilterMatches returns the filtered matches of the filter segment at
+// segIndex: all of them for #(condition)# and the first for #(condition).
+func routeFilterMatches(matches []elementMatch, segments []PathSegment, segIndex int) Result {
 	// No matches found
-	if len(filteredMatches) == 0 {
+	if len(matches) == 0 {
 		return Result{Type: Null}
 	}
 
-	// Route based on FilterAll flag
-	if currentSeg.FilterAll {
+	isLastSegment := segIndex == len(segments)-1
+	if segments[segIndex].FilterAll {
 		// #(condition)# - Return ALL matches
-		return processAllMatches(filteredMatches, segments, segIndex, isLastSegment)
+		return processAllMatches(matches, segments, segIndex, isLastSegment)
 	}
 	// #(condition) - Return FIRST match
-	return processFirstMatch(filteredMatches[0], segments, segIndex, isLastSegment)
+	return processFirstMatch(matches[0], segments, segIndex, isLastSegment)
 }
 
 // handleFilterQueryWithOptions processes GJSON-style filter queries with Options support
 func handleFilterQueryWithOptions(parser *xmlParser, segments []PathSegment, segIndex int, opts *Options) Result {
 	currentSeg := segments[segIndex]
-	isLastSegment := segIndex == len(segments)-1
+
+	if isFilterCount(segments, segIndex) {
+		return countFilterMatches(parser, func(string) bool { return true }, currentSeg)
+	}
 
 	// Collect ALL matching elements
 	var matches []elementMatch
 
-	for parser.skipToNextElement() {
+	for position := 0; parser.skipToNextElement(); position++ {
 		parser.next() // skip '<'
-		elemName, attrs, isSelfClosing := parser.parseElementName()
+		elemName, attrs, attrOrder, isSelfClosing := parser.parseElementTag()
+		tag := parser.tag
 
 		// Extract content
 		var content string
@@ -1999,60 +3311,45 @@ func handleFilterQueryWithOptions(parser *xmlParser, segments []PathSegment, seg
 		match := elementMatch{
 			name:          elemName,
 			attrs:         attrs,
+			tag:           tag,
+			span:          parser.span(),
+			attrOrder:     attrOrder,
 			content:       content,
 			isSelfClosing: isSelfClosing,
 		}
 
-		// Evaluate filter condition
-		if evaluateFilterOnMatch(currentSeg.Filter, match) {
+		// Evaluate filter condition, stopping once the filter has all the
+		// matches it keeps (also enforces MaxWildcardResults)
+		if evaluateFilterOnMatch(currentSeg.Filter, match, position) {
 			matches = append(matches, match)
-
-			// Security: enforce result limit
-			if len(matches) >= MaxWildcardResults {
+			if len(matches) >= filterLimit(currentSeg) {
 				break
 			}
 		}
 	}
 
-	// No matches found
-	if len(matches) == 0 {
-		return Result{Type: Null}
-	}
-
-	// Route based on FilterAll flag
-	if currentSeg.FilterAll {
-		// #(condition)# - Return ALL matches
-		return processAllMatchesWithOptions(matches, segments, segIndex, isLastSegment, opts)
-	}
-	// #(condition) - Return FIRST match
-	return processFirstMatchWithOptions(matches[0], segments, segIndex, isLastSegment, opts)
+	return routeFilterMatchesWithOptions(matches, segments, segIndex, opts)
 }
 
 // handleFilterQueryOnMatchesWithOptions processes GJSON-style filters against pre-collected matches with Options
 func handleFilterQueryOnMatchesWithOptions(allMatches []elementMatch, segments []PathSegment, segIndex int, opts *Options) Result {
-	currentSeg := segments[segIndex]
-	isLastSegment := segIndex == len(segments)-1
-
-	// Filter the matches
-	var filteredMatches []elementMatch
-	for _, match := range allMatches {
-		if evaluateFilterOnMatch(currentSeg.Filter, match) {
-			filteredMatches = append(filteredMatches, match)
-		}
-	}
+	return routeFilterMatchesWithOptions(filterMatches(allMatches, segments[segIndex]), segments, segIndex, opts)
+}
 
+// routeFilterMatchesWithOptions is routeFilterMatches with Options support.
+func routeFilterMatchesWithOptions(matches []elementMatch, segments []PathSegment, segIndex int, opts *Options) Result {
 	// No matches found
-	if len(filteredMatches) == 0 {
+	if len(matches) == 0 {
 		return Result{Type: Null}
 	}
 
-	// Route based on FilterAll flag
-	if currentSeg.FilterAll {
+	isLastSegment := segIndex == len(segments)-1
+	if segments[segIndex].FilterAll {
 		// #(condition)# - Return ALL matches
-		return processAllMatchesWithOptions(filteredMatches, segments, segIndex, isLastSegment, opts)
+		return processAllMatchesWithOptions(matches, segments, segIndex, isLastSegment, opts)
 	}
 	// #(condition) - Return FIRST match
-	return processFirstMatchWithOptions(filteredMatches[0], segments, segIndex, isLastSegment, opts)
+	return processFirstMatchWithOptions(matches[0], segments, segIndex, isLastSegment, opts)
 }
 
 // processFirstMatchWithOptions processes the first matching element with Options support
@@ -2061,14 +3358,10 @@ func processFirstMatchWithOptions(match elementMatch, segments []PathSegment, se
 
 	// If this is the last segment, return the element
 	if isLastSegment {
-		result := Result{
-			Type: Element,
-			Str:  unescapeXML(extractTextContent(match.content)),
-			Raw:  match.content,
-		}
+		result := newElementResult(match)
 		// Apply modifiers if present
 		if len(currentSeg.Modifiers) > 0 {
-			result = applyModifiers(result, currentSeg.Modifiers)
+			result = applyModifiersWithOptions(result, currentSeg.Modifiers, opts)
 		}
 		return result
 	}
@@ -2083,14 +3376,10 @@ func processFirstMatchWithOptions(match elementMatch, segments []PathSegment, se
 		if !opts.CaseSensitive {
 			for k, v := range match.attrs {
 				if toLowerASCII(k) == attrName {
-					result := Result{
-						Type: Attribute,
-						Str:  v,
-						Raw:  v,
-					}
+					result := newAttributeResult(match.tag, k, v)
 					// Apply modifiers from the attribute segment if present
 					if len(nextSeg.Modifiers) > 0 {
-						result = applyModifiers(result, nextSeg.Modifiers)
+						result = applyModifiersWithOptions(result, nextSeg.Modifiers, opts)
 					}
 					return result
 				}
@@ -2098,14 +3387,10 @@ func processFirstMatchWithOptions(match elementMatch, segments []PathSegment, se
 			return Result{Type: Null}
 		}
 		if attrValue, ok := match.attrs[attrName]; ok {
-			result := Result{
-				Type: Attribute,
-				Str:  attrValue,
-				Raw:  attrValue,
-			}
+			result := newAttributeResult(match.tag, attrName, attrValue)
 			// Apply modifiers from the attribute segment if present
 			if len(nextSeg.Modifiers) > 0 {
-				result = applyModifiers(result, nextSeg.Modifiers)
+				result = applyModifiersWithOptions(result, nextSeg.Modifiers, opts)
 			}
 			return result
 		}
@@ -2117,12 +3402,12 @@ func processFirstMatchWithOptions(match elementMatch, segments []PathSegment, se
 		textContent := extractDirectTextOnly(match.content)
 		result := Result{
 			Type: String,
-			Str:  unescapeXML(textContent),
+			Str:  decodeText(textContent, opts),
 			Raw:  match.content,
 		}
 		// Apply modifiers from the text segment if present
 		if len(nextSeg.Modifiers) > 0 {
-			result = applyModifiers(result, nextSeg.Modifiers)
+			result = applyModifiersWithOptions(result, nextSeg.Modifiers, opts)
 		}
 		return result
 	}
@@ -2133,7 +3418,7 @@ func processFirstMatchWithOptions(match elementMatch, segments []PathSegment, se
 	}
 
 	// Continue query within matched element
-	contentParser := newXMLParser([]byte(match.content))
+	contentParser := newContentParser(match.content, match.span)
 	return executeQueryWithOptions(contentParser, segments, segIndex+1, opts)
 }
 
@@ -2145,11 +3430,7 @@ func processAllMatchesWithOptions(matches []elementMatch, segments []PathSegment
 	if isLastSegment {
 		results := make([]Result, 0, len(matches))
 		for _, match := range matches {
-			results = append(results, Result{
-				Type: Element,
-				Str:  unescapeXML(extractTextContent(match.content)),
-				Raw:  match.content,
-			})
+			results = append(results, newElementResult(match))
 		}
 
 		result := Result{
@@ -2158,7 +3439,7 @@ func processAllMatchesWithOptions(matches []elementMatch, segments []PathSegment
 		}
 		// Apply modifiers if present
 		if len(currentSeg.Modifiers) > 0 {
-			result = applyModifiers(result, currentSeg.Modifiers)
+			result = applyModifiersWithOptions(result, currentSeg.Modifiers, opts)
 		}
 		return result
 	}
@@ -2174,21 +3455,13 @@ func processAllMatchesWithOptions(matches []elementMatch, segments []PathSegment
 			if !opts.CaseSensitive {
 				for k, v := range match.attrs {
 					if toLowerASCII(k) == attrName {
-						allResults = append(allResults, Result{
-							Type: Attribute,
-							Str:  v,
-							Raw:  v,
-						})
+						allResults = append(allResults, newAttributeResult(match.tag, k, v))
 					}
 				}
 				continue
 			}
 			if attrValue, ok := match.attrs[attrName]; ok {
-				allResults = append(allResults, Result{
-					Type: Attribute,
-					Str:  attrValue,
-					Raw:  attrValue,
-				})
+				allResults = append(allResults, newAttributeResult(match.tag, attrName, attrValue))
 			}
 			continue
 		}
@@ -2198,7 +3471,7 @@ func processAllMatchesWithOptions(matches []elementMatch, segments []PathSegment
 			textContent := extractDirectTextOnly(match.content)
 			allResults = append(allResults, Result{
 				Type: String,
-				Str:  unescapeXML(textContent),
+				Str:  decodeText(textContent, opts),
 				Raw:  match.content,
 			})
 			continue
@@ -2212,7 +3485,7 @@ func processAllMatchesWithOptions(matches []elementMatch, segments []PathSegment
 		}
 
 		// Continue query within matched element
-		contentParser := newXMLParser([]byte(match.content))
+		contentParser := newContentParser(match.content, match.span)
 		result := executeQueryWithOptions(contentParser, segments, segIndex+1, opts)
 		if result.Type != Null {
 			if result.Type == Array {
@@ -2246,7 +3519,7 @@ func processAllMatchesWithOptions(matches []elementMatch, segments []PathSegment
 
 	// Apply modifiers from the next segment if present
 	if len(nextSeg.Modifiers) > 0 {
-		result = applyModifiers(result, nextSeg.Modifiers)
+		result = applyModifiersWithOptions(result, nextSeg.Modifiers, opts)
 	}
 
 	return result
@@ -2269,3 +3542,13 @@ func processAllMatchesWithOptions(matches []elementMatch, segments []PathSegment
 func stringToBytes(s string) []byte {
 	return unsafe.Slice(unsafe.StringData(s), len(s))
 }
+
+// bytesToString converts a byte slice to a string without copying, the
+// inverse of stringToBytes. The string shares memory with b, so b must not
+// be modified while the string is in use.
+func bytesToString(b []byte) string {
+	if len(b) == 0 {
+		return ""
+	}
+	return unsafe.String(&b[0], len(b))
+}
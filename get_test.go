@@ -4,6 +4,7 @@
 package xmldot
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 	"testing"
@@ -101,6 +102,124 @@ func TestGet_Attributes(t *testing.T) {
 	}
 }
 
+// TestGet_EmptyVersusAbsentAttribute tests that attr="" exists with an empty
+// value while a missing attribute does not, directly and through filters and
+// wildcards
+func TestGet_EmptyVersusAbsentAttribute(t *testing.T) {
+	xml := `<root><item id="" name="a"/><item id="2" name="b"/><item name="c"/></root>`
+
+	tests := []struct {
+		name   string
+		path   string
+		exists bool
+		str    string
+	}{
+		{"empty attribute", "root.item.@id", true, ""},
+		{"missing attribute", "root.item.2.@id", false, ""},
+		{"filter on empty value", `root.item.#(@id=="").@name`, true, "a"},
+		{"missing is not empty in filter", `root.item.#(@id=="")#.#`, true, "1"},
+		{"missing is not unequal in filter", `root.item.#(@id!="")#.#`, true, "1"},
+		{"existence filter counts empty", "root.item.#(@id)#.#", true, "2"},
+		{"existence filter on missing", "root.item.#(@other)#", false, ""},
+		{"wildcard keeps empty, skips missing", "root.*.@id", true, `["","2"]`},
+		{"recursive keeps empty, skips missing", "root.**.item.@id", true, `["","2"]`},
+		{"field extraction keeps empty, skips missing", "root.item.#.@id", true, `["","2"]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Get(xml, tt.path)
+			if result.Exists() != tt.exists || result.String() != tt.str {
+				t.Errorf("Get(%q) = Exists %v, String %q; want %v, %q", tt.path, result.Exists(), result.String(), tt.exists, tt.str)
+			}
+		})
+	}
+}
+
+// TestGet_PositionalAttribute tests @N and @# access to attributes in document order
+func TestGet_PositionalAttribute(t *testing.T) {
+	xml := `<root><item id="7" name="a" x:y="z"/><item b="2"/><empty/></root>`
+
+	tests := []struct {
+		name    string
+		path    string
+		wantRaw string
+		wantStr string
+		exists  bool
+	}{
+		{"first", "root.item.@0", `id="7"`, "7", true},
+		{"second", "root.item.@1", `name="a"`, "a", true},
+		{"prefixed name", "root.item.@2", `x:y="z"`, "z", true},
+		{"out of range", "root.item.@3", "", "", false},
+		{"indexed element", "root.item.1.@0", `b="2"`, "2", true},
+		{"after modifier", "root.item|@reverse.@0", `b="2"`, "2", true},
+		{"count", "root.item.@#", "", "3", true},
+		{"count without attributes", "root.empty.@#", "", "0", true},
+		{"count of missing element", "root.missing.@#", "", "", false},
+		{"followed by a segment", "root.item.@0.x", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Get(xml, tt.path)
+			if result.Raw != tt.wantRaw || result.String() != tt.wantStr || result.Exists() != tt.exists {
+				t.Errorf("Get(%q) = Raw %q, String %q, Exists %v; want %q, %q, %v",
+					tt.path, result.Raw, result.String(), result.Exists(), tt.wantRaw, tt.wantStr, tt.exists)
+			}
+			if got := Exists(xml, tt.path); got != tt.exists {
+				t.Errorf("Exists(%q) = %v, want %v", tt.path, got, tt.exists)
+			}
+		})
+	}
+
+	if _, err := Set(xml, "root.item.@0", "x"); !errors.Is(err, ErrInvalidPath) {
+		t.Errorf("Set() positional attribute error = %v, want ErrInvalidPath", err)
+	}
+}
+
+// TestGet_AttributeRaw tests that attribute Results carry their declaration,
+// as written in the input, in Raw
+func TestGet_AttributeRaw(t *testing.T) {
+	xml := `<root><item id='1' title="a &amp; b" note = "it&apos;s"/><item id="2" id="3"/></root>`
+
+	tests := []struct {
+		name    string
+		path    string
+		opts    *Options
+		wantRaw string
+		wantStr string
+	}{
+		{"single quotes", "root.item.@id", nil, `id='1'`, "1"},
+		{"escaped value", "root.item.@title", nil, `title="a &amp; b"`, "a & b"},
+		{"entity and spaces", "root.item.@note", nil, `note = "it&apos;s"`, "it's"},
+		{"indexed, first of duplicates", "root.item.1.@id", nil, `id="2"`, "2"},
+		{"element result", "root.item|@first.@id", nil, `id='1'`, "1"},
+		{"modifier", "root.item.@title|@ugly", nil, `title="a &amp; b"`, "a & b"},
+		{"case-insensitive", "ROOT.ITEM.@TITLE", &Options{}, `title="a &amp; b"`, "a & b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := GetWithOptions(xml, tt.path, tt.opts)
+			if result.Raw != tt.wantRaw || result.Str != tt.wantStr {
+				t.Errorf("Get(%q) Raw = %q, Str = %q; want %q, %q", tt.path, result.Raw, result.Str, tt.wantRaw, tt.wantStr)
+			}
+		})
+	}
+
+	var raws []string
+	for _, item := range Get(xml, "root.item.#.@id").Array() {
+		raws = append(raws, item.Raw)
+	}
+	if got := strings.Join(raws, " "); got != `id='1' id="2"` {
+		t.Errorf("field extraction Raw = %s, want id='1' id=\"2\"", got)
+	}
+	// Element Raw keeps nested start tags as written
+	if got := Get(xml, "root").Raw; got != `<item id='1' title="a &amp; b" note = "it&apos;s"/><item id="2" id="3"/>` {
+		t.Errorf("element Raw = %s", got)
+	}
+}
+
 // Test text content extraction
 func TestGet_TextContent(t *testing.T) {
 	xml := `<item>
@@ -212,6 +331,31 @@ func TestGet_ArrayCount(t *testing.T) {
 	}
 }
 
+// Test counting beyond MaxWildcardResults (streamed, nothing collected)
+func TestGet_ArrayCountLarge(t *testing.T) {
+	const n = 100000
+	var sb strings.Builder
+	sb.WriteString("<root><list>")
+	for i := 0; i < n; i++ {
+		sb.WriteString(`<item id="1"><v>x</v></item><other/>`)
+	}
+	sb.WriteString("</list></root>")
+	xml := sb.String()
+
+	if got := Get(xml, "root.list.item.#").Int(); got != n {
+		t.Errorf("Count = %d, want %d", got, n)
+	}
+	if got := GetWithOptions(xml, "ROOT.LIST.ITEM.#", &Options{CaseSensitive: false}).Int(); got != n {
+		t.Errorf("Case-insensitive count = %d, want %d", got, n)
+	}
+
+	// Fragment roots
+	fragment := strings.Repeat("<user>a</user><admin/>", 2*MaxWildcardResults)
+	if got := Get(fragment, "user.#").Int(); got != 2*MaxWildcardResults {
+		t.Errorf("Fragment count = %d, want %d", got, 2*MaxWildcardResults)
+	}
+}
+
 // Test empty array
 func TestGet_EmptyArray(t *testing.T) {
 	xml := `<root><users></users></root>`
@@ -303,6 +447,130 @@ func TestGetBytes(t *testing.T) {
 	}
 }
 
+// Test GetManyParallel preserves order and matches GetMany
+func TestGetManyParallel(t *testing.T) {
+	var sb strings.Builder
+	sb.WriteString("<root>")
+	for i := 0; i < 50; i++ {
+		sb.WriteString(fmt.Sprintf(`<group><item id="%d"><price>%d</price></item></group>`, i, i*10))
+	}
+	sb.WriteString("</root>")
+	xml := sb.String()
+
+	paths := []string{
+		"root.**.price",
+		"root.group.item.@id",
+		"root.missing",
+		"root.group.#",
+		"root.group.5.item.price",
+		"root.**.item.@id",
+		"",
+	}
+
+	expected := GetMany(xml, paths...)
+	results := GetManyParallel(xml, paths...)
+	if len(results) != len(expected) {
+		t.Fatalf("GetManyParallel() returned %d results, want %d", len(results), len(expected))
+	}
+	for i := range paths {
+		if results[i].Type != expected[i].Type || results[i].String() != expected[i].String() {
+			t.Errorf("path %q: got %v %q, want %v %q", paths[i], results[i].Type, results[i].String(), expected[i].Type, expected[i].String())
+		}
+	}
+
+	if got := GetManyParallel(xml); len(got) != 0 {
+		t.Errorf("GetManyParallel() with no paths returned %d results", len(got))
+	}
+}
+
+// Test GetManyParallel re-raises a panic from a path in the caller
+func TestGetManyParallel_Panic(t *testing.T) {
+	const modName = "testParallelPanic"
+	if err := RegisterModifier(modName, NewModifierFunc(modName, func(Result) Result {
+		panic("modifier failed")
+	})); err != nil {
+		t.Fatalf("RegisterModifier() error = %v", err)
+	}
+	defer func() { _ = UnregisterModifier(modName) }()
+
+	xml := `<root><a>1</a><b>2</b></root>`
+	paths := []string{"root.a", "root.b|@" + modName, "root.a", "root.b", "root.a"}
+
+	defer func() {
+		if r := recover(); r != "modifier failed" {
+			t.Errorf("GetManyParallel() panic = %v, want %q", r, "modifier failed")
+		}
+	}()
+	GetManyParallel(xml, paths...)
+	t.Error("GetManyParallel() did not panic")
+}
+
+// Test GetManyBytes mirrors GetMany
+func TestGetManyBytes(t *testing.T) {
+	xml := `<root><user id="123"><name>John</name><age>30</age></user></root>`
+	paths := []string{"root.user.name", "root.user.age", "root.user.@id", "root.missing"}
+
+	expected := GetMany(xml, paths...)
+	results := GetManyBytes([]byte(xml), paths...)
+	if len(results) != len(expected) {
+		t.Fatalf("GetManyBytes() returned %d results, want %d", len(results), len(expected))
+	}
+	for i := range paths {
+		if results[i].Type != expected[i].Type || results[i].String() != expected[i].String() {
+			t.Errorf("path %q: got %v %q, want %v %q", paths[i], results[i].Type, results[i].String(), expected[i].Type, expected[i].String())
+		}
+	}
+}
+
+// Test GetEach and GetEachBytes
+func TestGetEach(t *testing.T) {
+	docs := []string{
+		`<user id="1"><name>Alice</name></user>`,
+		``,
+		`<user id="2"><name>Bob</name></user>`,
+		`<user><name>Broken</user>`,
+		`<user id="3"/>`,
+		`<user id="4"><name>Dave</name></user>`,
+	}
+	expected := []string{"Alice", "", "Bob", "", "", "Dave"}
+
+	results := GetEach(docs, "user.name")
+	if len(results) != len(docs) {
+		t.Fatalf("GetEach() returned %d results, want %d", len(results), len(docs))
+	}
+	for i, doc := range docs {
+		// Each slot matches Get, including for the malformed document
+		want := Get(doc, "user.name")
+		if results[i].String() != want.String() || results[i].Exists() != want.Exists() {
+			t.Errorf("doc %d: got %q (exists %v), want Get's %q (exists %v)",
+				i, results[i].String(), results[i].Exists(), want.String(), want.Exists())
+		}
+		if i != 3 && results[i].String() != expected[i] {
+			t.Errorf("doc %d: got %q, want %q", i, results[i].String(), expected[i])
+		}
+	}
+
+	data := make([][]byte, len(docs))
+	for i, doc := range docs {
+		data[i] = []byte(doc)
+	}
+	ids := GetEachBytes(data, "user.@id")
+	for i, want := range []string{"1", "", "2", "", "3", "4"} {
+		if ids[i].String() != want {
+			t.Errorf("doc %d: got id %q, want %q", i, ids[i].String(), want)
+		}
+	}
+
+	if results := GetEach(nil, "user.name"); len(results) != 0 {
+		t.Errorf("GetEach(nil) returned %d results, want 0", len(results))
+	}
+	for i, r := range GetEach(docs, "") {
+		if r.Exists() {
+			t.Errorf("doc %d: empty path should yield Null, got %q", i, r.String())
+		}
+	}
+}
+
 // Test GetMany
 func TestGetMany(t *testing.T) {
 	xml := `<root>
@@ -429,6 +697,157 @@ func TestGet_Exists(t *testing.T) {
 	}
 }
 
+func TestExists(t *testing.T) {
+	xml := `<?xml version="1.0"?>
+<!-- leading comment -->
+<root>
+	<a><x>1</x></a>
+	<a><b id="2"><c/></b></a>
+	<user id="1"><name>John</name><!-- <email>hidden</email> --></user>
+	<data><![CDATA[<secret>text</secret>]]></data>
+	<ns:item xmlns:ns="urn:x">v</ns:item>
+	<empty/>
+</root>`
+
+	tests := []struct {
+		name   string
+		path   string
+		exists bool
+	}{
+		{"Root", "root", true},
+		{"Nested element", "root.user.name", true},
+		{"Missing element", "root.user.email", false},
+		{"Backtracks to later sibling", "root.a.b.c", true},
+		{"Attribute exists", "root.user.@id", true},
+		{"Attribute missing", "root.user.@missing", false},
+		{"Attribute on later match", "root.a.b.@id", true},
+		{"Self-closing element", "root.empty", true},
+		{"Child of self-closing", "root.empty.child", false},
+		{"Namespaced element", "root.ns:item", true},
+		{"Local name match", "root.item", true},
+		{"Wrong root", "other.user", false},
+		{"Index fallback", "root.a.1.b", true},
+		{"Count fallback", "root.a.#", true},
+		{"Wildcard fallback", "root.*.name", true},
+		{"Filter fallback", "root.a.#(x==1)", true},
+		{"Text fallback", "root.user.name.%", true},
+		{"Empty path", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Exists(xml, tt.path); got != tt.exists {
+				t.Errorf("Exists(%q) = %v, want %v", tt.path, got, tt.exists)
+			}
+			if got := Get(xml, tt.path).Exists(); got != tt.exists {
+				t.Errorf("Get(%q).Exists() = %v, want %v", tt.path, got, tt.exists)
+			}
+		})
+	}
+}
+
+func TestExists_LargeSubtree(t *testing.T) {
+	var sb strings.Builder
+	sb.WriteString("<root><big>")
+	for i := 0; i < 10000; i++ {
+		sb.WriteString(`<item id="x">value</item>`)
+	}
+	sb.WriteString("</big><tail/></root>")
+	xml := sb.String()
+
+	if !Exists(xml, "root.big") {
+		t.Error("Exists(root.big) should be true")
+	}
+	if !Exists(xml, "root.tail") {
+		t.Error("Exists(root.tail) should be true")
+	}
+	if Exists(xml, "root.big.missing") {
+		t.Error("Exists(root.big.missing) should be false")
+	}
+	if !ExistsBytes([]byte(xml), "root.big.item.@id") {
+		t.Error("ExistsBytes(root.big.item.@id) should be true")
+	}
+}
+
+func TestAbsent(t *testing.T) {
+	xml := `<server><port>80</port><tls enabled=""/></server>`
+
+	tests := []struct {
+		name    string
+		xml     string
+		path    string
+		absent  bool
+		wantErr error
+	}{
+		{"missing element", xml, "server.debug", true, nil},
+		{"missing attribute", xml, "server.tls.@cert", true, nil},
+		{"present element", xml, "server.port", false, nil},
+		{"empty attribute is present", xml, "server.tls.@enabled", false, nil},
+		{"filter without match", xml, "server.tls.#(@enabled==yes)", true, nil},
+		{"malformed XML", `<server><port>80</server>`, "server.debug", false, ErrMalformedXML},
+		{"empty document", "", "server.debug", false, ErrMalformedXML},
+		{"invalid path", xml, "", false, ErrInvalidPath},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Absent(tt.xml, tt.path); got != tt.absent {
+				t.Errorf("Absent(%q) = %v, want %v", tt.path, got, tt.absent)
+			}
+			if got := AbsentBytes([]byte(tt.xml), tt.path); got != tt.absent {
+				t.Errorf("AbsentBytes(%q) = %v, want %v", tt.path, got, tt.absent)
+			}
+			got, err := CheckAbsent(tt.xml, tt.path)
+			if got != tt.absent || !errors.Is(err, tt.wantErr) {
+				t.Errorf("CheckAbsent(%q) = %v, %v, want %v, %v", tt.path, got, err, tt.absent, tt.wantErr)
+			}
+		})
+	}
+
+	if _, err := CheckAbsentBytes(make([]byte, MaxDocumentSize+1), "a"); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("CheckAbsentBytes(oversized) error = %v, want ErrLimitExceeded", err)
+	}
+}
+
+// TestRootName tests reading the root element name from the prolog
+func TestRootName(t *testing.T) {
+	tests := []struct {
+		name     string
+		xml      string
+		expected string
+		wantErr  error
+	}{
+		{"plain root", `<manifest><app/></manifest>`, "manifest", nil},
+		{"declaration and comments", "<?xml version=\"1.0\"?>\n<!-- generated -->\n<project a=\"1\">x</project>", "project", nil},
+		{"doctype", `<!DOCTYPE svg><svg/>`, "svg", nil},
+		{"namespace prefix", `<svg:svg xmlns:svg="http://www.w3.org/2000/svg"/>`, "svg:svg", nil},
+		{"content after root tag is not read", `<root><a></b>`, "root", nil},
+		{"empty document", "", "", ErrMalformedXML},
+		{"only a comment", `<!-- x -->`, "", ErrMalformedXML},
+		{"text before root", `hello<root/>`, "", ErrMalformedXML},
+		{"closing tag first", `</root>`, "", ErrMalformedXML},
+		{"unterminated root tag", `<root a="1"`, "", ErrMalformedXML},
+		{"invalid root name", `<1root/>`, "", ErrMalformedXML},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RootName(tt.xml)
+			if got != tt.expected || !errors.Is(err, tt.wantErr) {
+				t.Errorf("RootName(%q) = %q, %v, want %q, %v", tt.xml, got, err, tt.expected, tt.wantErr)
+			}
+			got, err = RootNameBytes([]byte(tt.xml))
+			if got != tt.expected || !errors.Is(err, tt.wantErr) {
+				t.Errorf("RootNameBytes(%q) = %q, %v, want %q, %v", tt.xml, got, err, tt.expected, tt.wantErr)
+			}
+		})
+	}
+
+	if _, err := RootNameBytes(make([]byte, MaxDocumentSize+1)); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("RootNameBytes(oversized) error = %v, want ErrLimitExceeded", err)
+	}
+}
+
 // Helper function to normalize whitespace for comparison
 func normalizeWhitespace(s string) string {
 	// Simple normalization: collapse multiple spaces/tabs/newlines to single space
@@ -773,6 +1192,36 @@ func TestFieldExtractionText(t *testing.T) {
 	}
 }
 
+// TestGetWithOptions_RawText tests that RawText keeps entity references in % text
+func TestGetWithOptions_RawText(t *testing.T) {
+	xml := `<r><s k="1">Tom &amp; Jerry<x>&lt;i&gt;</x></s><s>a &lt; b</s></r>`
+	raw := &Options{CaseSensitive: true, RawText: true}
+
+	tests := []struct {
+		path    string
+		decoded string
+		raw     string
+	}{
+		{"r.s.%", "Tom & Jerry", "Tom &amp; Jerry"},
+		{"r.s.x.%", "<i>", "&lt;i&gt;"},
+		{"r.s.#.%", `["Tom & Jerry","a < b"]`, `["Tom &amp; Jerry","a &lt; b"]`},
+		{"r.s.#(@k==1)#.%", "Tom & Jerry", "Tom &amp; Jerry"},
+		{"r.**.s.%", `["Tom & Jerry","a < b"]`, `["Tom &amp; Jerry","a &lt; b"]`},
+		{"r.*.%", `["Tom & Jerry","a < b"]`, `["Tom &amp; Jerry","a &lt; b"]`},
+		{"r.s.%|@this", "Tom & Jerry", "Tom &amp; Jerry"},
+		{"r.s", "Tom & Jerry<i>", "Tom & Jerry<i>"}, // only % is affected
+	}
+
+	for _, tt := range tests {
+		if got := Get(xml, tt.path).String(); got != tt.decoded {
+			t.Errorf("Get(%q) = %q, want %q", tt.path, got, tt.decoded)
+		}
+		if got := GetWithOptions(xml, tt.path, raw).String(); got != tt.raw {
+			t.Errorf("GetWithOptions(%q, RawText) = %q, want %q", tt.path, got, tt.raw)
+		}
+	}
+}
+
 // TestFieldExtractionEmpty tests #.field with empty array
 func TestFieldExtractionEmpty(t *testing.T) {
 	xml := `<root><items></items></root>`
@@ -1611,3 +2060,168 @@ func TestFieldExtractionWildcardAttribute(t *testing.T) {
 		}
 	}
 }
+
+// TestGet_NumericElementNames tests that a numeric path component is an index
+// unless escaped or bracket-quoted, which address an element with that name
+func TestGet_NumericElementNames(t *testing.T) {
+	xml := `<config><0>zero</0><1>one</1><item>a</item></config>`
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"config.0", "zeroonea"},
+		{`config.\0`, "zero"},
+		{`config['1']`, "one"},
+		{`config.\1.%`, "one"},
+		{`config.\1|@this`, "one"},
+		{`config.\2`, ""},
+		{"config.item.0", "a"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := Get(xml, tt.path).String(); got != tt.want {
+				t.Errorf("Get(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGet_AttributePredicates(t *testing.T) {
+	xml := `<servers>
+	<server id="dev"><hostname>dev.local</hostname></server>
+	<server id="prod" primary="true"><hostname>prod.example.com</hostname></server>
+	<server id="prod-2"><hostname>backup.example.com</hostname></server>
+</servers>`
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"servers.server[id=prod].hostname", "prod.example.com"},
+		{"servers.server[@id=prod].hostname", "prod.example.com"},
+		{"servers.server[@id==prod].hostname", "prod.example.com"},
+		{"servers.server[id=prod-2].hostname", "backup.example.com"},
+		{"servers.server[@primary].@id", "prod"},
+		{"servers.server[id!=dev].hostname", "prod.example.com"},
+		{"servers.server[id=missing].hostname", ""},
+		{"servers.server[id=prod].hostname|@this", "prod.example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := Get(xml, tt.path).String(); got != tt.want {
+				t.Errorf("Get(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+
+	// The predicate is sugar for a first-match filter
+	if got, want := Get(xml, "servers.server[id=prod]").Raw, Get(xml, "servers.server.#(@id==prod)").Raw; got != want {
+		t.Errorf("predicate Raw = %q, filter Raw = %q", got, want)
+	}
+}
+
+func TestBetween(t *testing.T) {
+	xml := `<body><h1>Title</h1><h2>Intro</h2><p>a</p><!-- note --><p>b</p><ul><h2>nested</h2></ul>` +
+		`<h2>Usage</h2><p>c</p><?pi x?><pre><![CDATA[<h2>]]></pre></body>`
+
+	tests := []struct {
+		name      string
+		startPath string
+		stopName  string
+		want      []string // name of each item
+	}{
+		{"first section", "body.h2", "h2", []string{"h2", "p", "p", "ul"}},
+		{"last section runs to end", "body.h2.#(%==Usage)", "h2", []string{"h2", "p", "pre"}},
+		{"no stop name", "body.h2.1", "", []string{"h2", "p", "pre"}},
+		{"stop at start of next heading level", "body.h1", "h2", []string{"h1"}},
+		{"stop name absent", "body.p", "h9", []string{"p", "p", "ul", "h2", "p", "pre"}},
+		{"array start uses first match", "body.h2.#(%!=x)#", "h2", []string{"h2", "p", "p", "ul"}},
+		{"missing start", "body.h3", "h2", nil},
+		{"attribute start", "body.@id", "h2", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Between(xml, tt.startPath, tt.stopName)
+			if tt.want == nil {
+				if result.Exists() {
+					t.Fatalf("Between() = %v, want Null", result)
+				}
+				return
+			}
+			if result.Type != Array || !result.IsMulti() {
+				t.Fatalf("Between() type = %v, want Array", result.Type)
+			}
+			var names []string
+			for _, item := range result.Array() {
+				names = append(names, item.Name())
+				start, end := item.Span()
+				if start < 0 || !strings.HasPrefix(xml[start:end], "<"+item.Name()) {
+					t.Errorf("item %q Span() = %d, %d", item.Name(), start, end)
+				}
+			}
+			if strings.Join(names, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("Between() names = %v, want %v", names, tt.want)
+			}
+		})
+	}
+
+	section := BetweenBytes([]byte(xml), "body.h2", "h2")
+	if got := strings.Join(section.Strings(), ","); got != "Intro,a,b,nested" {
+		t.Errorf("section texts = %s", got)
+	}
+}
+
+func TestUnder(t *testing.T) {
+	xml := `<store>
+		<item><price>10</price></item>
+		<sale>
+			<item><price>8</price></item>
+			<item><price>7</price></item>
+			<sale><item><price>5</price></item></sale>
+		</sale>
+		<sale/>
+		<x:sale><price>1</price></x:sale>
+	</store>`
+
+	tests := []struct {
+		name     string
+		ancestor string
+		path     string
+		want     string
+	}{
+		{"any depth", "sale", "**.price", `["8","7","5","1"]`},
+		{"all item children", "sale", "item.#.price", `["8","7"]`},
+		{"first match per ancestor", "sale", "price", "1"},
+		{"count per ancestor", "sale", "item.#", "2"},
+		{"prefixed ancestor", "x:sale", "price", "1"},
+		{"no ancestor", "clearance", "**.price", ""},
+		{"no match below ancestor", "sale", "discount", ""},
+		{"empty ancestor name", "", "**.price", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Under(xml, tt.ancestor, tt.path).String(); got != tt.want {
+				t.Errorf("Under(%q, %q) = %s, want %s", tt.ancestor, tt.path, got, tt.want)
+			}
+		})
+	}
+
+	// A single match is returned as is, with its span in the document
+	price := UnderBytes([]byte(xml), "x:sale", "price")
+	if price.Type != Element || price.IsMulti() {
+		t.Fatalf("UnderBytes() type = %v, want a single Element", price.Type)
+	}
+	if start, end := price.Span(); start < 0 || xml[start:end] != "<price>1</price>" {
+		t.Errorf("UnderBytes() Span() = %d, %d", start, end)
+	}
+
+	// Several matches form an Array
+	if prices := Under(xml, "sale", "item.#.price"); prices.Type != Array || !prices.IsMulti() {
+		t.Errorf("Under() type = %v, want Array", prices.Type)
+	}
+}
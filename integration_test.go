@@ -1043,6 +1043,35 @@ func TestIntegrationSpringConfig(t *testing.T) {
 			t.Errorf("Expected 'superadmin', got %q", newUser.String())
 		}
 	})
+
+	t.Run("bracket and EscapeName paths", func(t *testing.T) {
+		paths := []string{
+			"configuration.properties['database.url']",
+			`configuration.properties["database.url"]`,
+			"configuration.properties." + EscapeName("database.url"),
+		}
+		for _, path := range paths {
+			if got := Get(config, path).String(); got != "jdbc:mysql://localhost:3306/mydb" {
+				t.Errorf("Get(%q) = %q", path, got)
+			}
+		}
+
+		updated, err := Set(config, "configuration.properties['database.url']", "jdbc:h2:mem")
+		if err != nil {
+			t.Fatalf("Set failed: %v", err)
+		}
+		if got := Get(updated, "configuration.properties.database\\.url").String(); got != "jdbc:h2:mem" {
+			t.Errorf("Expected updated URL, got %q", got)
+		}
+
+		updated, err = Delete(config, "configuration.properties['database.password']")
+		if err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+		if Get(updated, "configuration.properties."+EscapeName("database.password")).Exists() {
+			t.Error("Password should have been deleted")
+		}
+	})
 }
 
 // TestIntegrationSpringContextFile tests with real Spring context file
@@ -8,6 +8,7 @@ import (
 	"encoding/xml"
 	"fmt"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 )
@@ -26,6 +27,19 @@ const (
 //  2. @sort modifier sorts the array
 //  3. @last modifier returns the last element
 //
+// What a modifier receives depends on the Result type. For an Element, Str is
+// the element's text and Raw its inner markup (without the element's own
+// tag), while Name and Attributes describe the element itself. Text
+// modifiers should work on Str and structural modifiers on Raw. Changing a
+// field of the received Result and returning it keeps an Element an Element,
+// so the path can continue after the modifier; returning a new Result of
+// type String turns it into plain text. For an Attribute, Str is the value;
+// for an Array, Results holds the items, each with its Type and Raw set as
+// a single match of the path would have them. NewStringResult and
+// NewArrayResult build the results a modifier returns without copying
+// fields by hand; to transform the items of an Array, change a copy of each
+// item so that its Raw, name and attributes are kept.
+//
 // Thread Safety: Modifier implementations must be safe for concurrent use.
 // Each modifier receives a copy of the Result and returns a new Result.
 type Modifier interface {
@@ -71,6 +85,29 @@ func (m *ModifierFunc) Name() string {
 	return m.name
 }
 
+// NewStringResult returns a String Result holding s, the form modifiers use
+// for text they produce (such as @join). Raw is s as well.
+func NewStringResult(s string) Result {
+	return Result{Type: String, Str: s, Raw: s}
+}
+
+// NewArrayResult returns an Array Result holding items, which are kept as
+// given, including their Raw, names and attributes.
+//
+// Example:
+//
+//	lower := NewModifierFunc("lower", func(r Result) Result {
+//	    items := make([]Result, 0, len(r.Array()))
+//	    for _, item := range r.Array() {
+//	        item.Str = strings.ToLower(item.Str)
+//	        items = append(items, item)
+//	    }
+//	    return NewArrayResult(items)
+//	})
+func NewArrayResult(items []Result) Result {
+	return Result{Type: Array, Results: items}
+}
+
 // modifierRegistry is a global registry for built-in and custom modifiers.
 // Thread-safe for concurrent registration and lookup.
 var (
@@ -132,7 +169,7 @@ func UnregisterModifier(name string) error {
 
 // isBuiltinModifier checks if a modifier name is built-in (cannot be unregistered)
 func isBuiltinModifier(name string) bool {
-	builtins := []string{"reverse", "sort", "first", "last", "flatten", "pretty", "ugly"}
+	builtins := []string{"reverse", "sort", "first", "last", "flatten", "pretty", "ugly", "keys", "values", "entries", "group-by", "join", "this"}
 	for _, b := range builtins {
 		if name == b {
 			return true
@@ -180,6 +217,13 @@ func isBuiltinModifier(name string) bool {
 // Future Enhancement: Consider returning Result with error information
 // instead of silent Null to improve debuggability.
 func applyModifiers(r Result, modifierNames []string) Result {
+	return applyModifiersWithOptions(r, modifierNames, nil)
+}
+
+// applyModifiersWithOptions is applyModifiers for the *WithOptions query
+// paths: built-in modifiers that depend on Options (see optionsModifier) see
+// opts. nil opts use the defaults.
+func applyModifiersWithOptions(r Result, modifierNames []string, opts *Options) Result {
 	// Security check: limit modifier chain depth
 	if len(modifierNames) > MaxModifierChainDepth {
 		return Result{Type: Null} // Return error for excessive chaining
@@ -188,14 +232,12 @@ func applyModifiers(r Result, modifierNames []string) Result {
 	current := r
 
 	for _, name := range modifierNames {
-		mod := GetModifier(name)
-		if mod == nil {
-			// Unknown modifier - return Null to indicate failure
-			// Future enhancement: return error type with modifier name
+		step, err := resolveModifier(name)
+		if err != nil {
+			// Unknown modifier, or an argument it does not accept
 			return Result{Type: Null}
 		}
-
-		current = mod.Apply(current)
+		current = step.applyWithOptions(current, opts)
 
 		// Stop if modifier returned Null - propagate failure
 		// Future enhancement: track which modifier failed
@@ -204,13 +246,124 @@ func applyModifiers(r Result, modifierNames []string) Result {
 		}
 	}
 
+	if len(modifierNames) > 0 {
+		current = withoutSpans(current)
+	}
 	return current
 }
 
+// modifierStep is one resolved modifier of a chain, with its argument.
+type modifierStep struct {
+	mod    Modifier
+	arg    string
+	hasArg bool
+}
+
+// resolveModifier looks up a modifier as written in a chain, without the
+// '@' (e.g. "sort" or "group-by:team"). It fails with ErrUnknownModifier for
+// a name that is not registered, and with ErrInvalidPath for an argument
+// given to a modifier that takes none.
+func resolveModifier(name string) (modifierStep, error) {
+	// Modifiers may take an argument after a colon (e.g., @group-by:department)
+	name, arg, hasArg := strings.Cut(name, ":")
+
+	mod := GetModifier(name)
+	if mod == nil {
+		return modifierStep{}, fmt.Errorf("%w: @%s", ErrUnknownModifier, name)
+	}
+	if hasArg {
+		if _, ok := mod.(argModifier); !ok {
+			return modifierStep{}, fmt.Errorf("%w: modifier @%s does not take an argument", ErrInvalidPath, name)
+		}
+	}
+	return modifierStep{mod: mod, arg: arg, hasArg: hasArg}, nil
+}
+
+// apply runs the modifier on r.
+func (s modifierStep) apply(r Result) Result {
+	return s.applyWithOptions(r, nil)
+}
+
+// applyWithOptions runs the modifier on r, passing opts to modifiers whose
+// output depends on them.
+func (s modifierStep) applyWithOptions(r Result, opts *Options) Result {
+	if s.hasArg {
+		return s.mod.(argModifier).applyArg(r, s.arg)
+	}
+	if om, ok := s.mod.(optionsModifier); ok && opts != nil {
+		return om.applyOptions(r, opts)
+	}
+	return s.mod.Apply(r)
+}
+
+// CompileModifiers parses a modifier chain such as "|@sort|@reverse|@first"
+// once and returns a function that applies it to a Result, for running the
+// same chain over many Results without parsing it each time. The leading '|'
+// is optional.
+//
+// Unlike a chain in a path, which yields Null when it names an unknown
+// modifier, CompileModifiers reports problems up front: an unknown modifier
+// returns an error wrapping ErrUnknownModifier that names it, and a chain
+// that is empty, malformed or longer than MaxModifierChainDepth returns
+// ErrInvalidPath. Modifiers are looked up when the chain is compiled, so
+// registering or unregistering one later does not change the returned
+// function. The function is safe for concurrent use.
+//
+// Example:
+//
+//	top, err := xmldot.CompileModifiers("|@sort|@reverse|@first")
+//	if err != nil {
+//	    return err
+//	}
+//	for _, doc := range docs {
+//	    fmt.Println(top(xmldot.Get(doc, "scores.score.#.%")))
+//	}
+func CompileModifiers(chain string) (func(Result) Result, error) {
+	chain = strings.TrimPrefix(strings.TrimSpace(chain), "|")
+	if chain == "" {
+		return nil, fmt.Errorf("%w: empty modifier chain", ErrInvalidPath)
+	}
+	parts := splitModifiers(chain)
+	if len(parts) > MaxModifierChainDepth {
+		return nil, fmt.Errorf("%w: modifier chain longer than %d", ErrInvalidPath, MaxModifierChainDepth)
+	}
+
+	steps := make([]modifierStep, 0, len(parts))
+	for _, part := range parts {
+		part = strings.TrimSpace(part)
+		if !strings.HasPrefix(part, "@") || len(part) == 1 {
+			return nil, fmt.Errorf("%w: %q is not a modifier", ErrInvalidPath, part)
+		}
+		step, err := resolveModifier(part[1:])
+		if err != nil {
+			return nil, err
+		}
+		steps = append(steps, step)
+	}
+
+	return func(r Result) Result {
+		for _, step := range steps {
+			r = step.apply(r)
+			if r.Type == Null {
+				break
+			}
+		}
+		return r
+	}, nil
+}
+
 // parseModifiers extracts modifiers from a path segment.
 // Example: "element|@reverse|@first" → element="element", modifiers=["reverse", "first"]
+// A '|' inside a filter's quoted value (#(name=="a|b")) is not a separator,
+// and neither is an escaped '\|' (left in place by splitPath), which becomes a
+// literal '|' in the element path.
 func parseModifiers(pathPart string) (elementPath string, modifiers []string) {
-	parts := strings.Split(pathPart, "|")
+	filterEnd := 0
+	if strings.HasPrefix(pathPart, "#(") {
+		filterEnd = quotedFilterEnd(pathPart)
+	}
+	parts := splitModifiers(pathPart[filterEnd:])
+	parts[0] = pathPart[:filterEnd] + parts[0]
 	elementPath = parts[0]
 
 	for i := 1; i < len(parts); i++ {
@@ -223,8 +376,91 @@ func parseModifiers(pathPart string) (elementPath string, modifiers []string) {
 	return elementPath, modifiers
 }
 
+// splitModifiers splits s on unescaped '|' characters. splitPath keeps the
+// backslash of an escaped '|' or '\\', so the escapes are resolved here, after
+// the separators are known.
+func splitModifiers(s string) []string {
+	if !strings.Contains(s, "\\") {
+		return strings.Split(s, "|")
+	}
+	var parts []string
+	var current strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\\' && i+1 < len(s):
+			i++
+			current.WriteByte(s[i])
+		case c == '|':
+			parts = append(parts, current.String())
+			current.Reset()
+		default:
+			current.WriteByte(c)
+		}
+	}
+	return append(parts, current.String())
+}
+
+// quotedFilterEnd returns the position just past the last quoted value in a
+// #(...) filter component, so that separators inside quotes are ignored.
+func quotedFilterEnd(part string) int {
+	end := 0
+	var quote byte
+	for i := 0; i < len(part); i++ {
+		c := part[i]
+		switch {
+		case quote != 0 && c == '\\':
+			i++
+		case quote != 0 && c == quote:
+			quote = 0
+			end = i + 1
+		case quote == 0 && (c == '\'' || c == '"'):
+			quote = c
+		}
+	}
+	if quote != 0 {
+		// Unterminated quote: the rest belongs to the filter
+		return len(part)
+	}
+	return end
+}
+
+// argModifier is implemented by built-in modifiers that accept an argument
+// after a colon (e.g., @group-by:department).
+type argModifier interface {
+	applyArg(r Result, arg string) Result
+}
+
+// optionsModifier is implemented by built-in modifiers whose output depends
+// on Options (e.g., @pretty and @ugly with PreserveCDATA).
+type optionsModifier interface {
+	applyOptions(r Result, opts *Options) Result
+}
+
 // Core Modifiers Implementation (P6.2)
 
+// matchSetModifiers lists the built-in modifiers that select from, reorder
+// or combine a set of matches and are meaningless on a single element.
+var matchSetModifiers = map[string]bool{
+	"reverse": true,
+	"sort":    true,
+	"first":   true,
+	"last":    true,
+	"join":    true,
+}
+
+// modifiesMatchSet reports whether a modifier chain starts with a modifier
+// that needs every match. A final element segment carrying such a chain
+// collects all of its matching siblings, so "users.user|@last" returns the
+// last user instead of the first and "item.category|@join:," joins every
+// category.
+func modifiesMatchSet(modifiers []string) bool {
+	if len(modifiers) == 0 {
+		return false
+	}
+	name, _, _ := strings.Cut(modifiers[0], ":")
+	return matchSetModifiers[name]
+}
+
 // reverseModifier reverses array order
 type reverseModifier struct{}
 
@@ -327,25 +563,39 @@ func (m *lastModifier) Apply(r Result) Result {
 	return r
 }
 
-// flattenModifier flattens nested arrays one level
+// flattenModifier flattens nested arrays. Without an argument it removes one
+// level of nesting; @flatten:N removes N levels and @flatten:deep removes all.
+//
+// Example: departments.department|@group-by:team|@flatten:deep
 type flattenModifier struct{}
 
 func (m *flattenModifier) Name() string { return "flatten" }
 
 func (m *flattenModifier) Apply(r Result) Result {
-	if r.Type != Array {
-		return r
+	return flattenResult(r, 1)
+}
+
+// applyArg accepts "deep" or a positive number of levels; anything else
+// returns Null.
+func (m *flattenModifier) applyArg(r Result, arg string) Result {
+	if arg == "deep" {
+		return flattenResult(r, -1)
 	}
+	depth, err := strconv.Atoi(arg)
+	if err != nil || depth < 1 {
+		return Result{Type: Null}
+	}
+	return flattenResult(r, depth)
+}
 
-	var flattened []Result
-	for _, res := range r.Results {
-		if res.Type == Array {
-			flattened = append(flattened, res.Results...)
-		} else {
-			flattened = append(flattened, res)
-		}
+// flattenResult removes up to depth levels of array nesting from r. A
+// negative depth flattens completely.
+func flattenResult(r Result, depth int) Result {
+	if r.Type != Array {
+		return r
 	}
 
+	flattened := appendFlattened(nil, r.Results, depth)
 	if len(flattened) == 0 {
 		return Result{Type: Null}
 	}
@@ -353,13 +603,34 @@ func (m *flattenModifier) Apply(r Result) Result {
 	return Result{Type: Array, Results: flattened}
 }
 
+// appendFlattened appends items to dst, expanding nested arrays up to depth
+// levels deep (all levels when depth is negative).
+func appendFlattened(dst, items []Result, depth int) []Result {
+	for _, res := range items {
+		if res.Type == Array && depth != 0 {
+			dst = appendFlattened(dst, res.Results, depth-1)
+		} else {
+			dst = append(dst, res)
+		}
+	}
+	return dst
+}
+
 // prettyModifier formats XML with indentation
 type prettyModifier struct{}
 
 func (m *prettyModifier) Name() string { return "pretty" }
 
 func (m *prettyModifier) Apply(r Result) Result {
-	if r.Raw == "" {
+	return m.applyOptions(r, nil)
+}
+
+// applyOptions indents the markup of r. CDATA sections are copied as written
+// unless opts set PreserveCDATA to false, which re-emits them as escaped
+// text.
+func (m *prettyModifier) applyOptions(r Result, opts *Options) Result {
+	// Attributes are not markup
+	if r.Raw == "" || r.Type == Attribute {
 		return r
 	}
 
@@ -372,6 +643,7 @@ func (m *prettyModifier) Apply(r Result) Result {
 
 	// Copy tokens from decoder to encoder
 	for {
+		offset := decoder.InputOffset()
 		token, err := decoder.Token()
 		if err != nil {
 			// End of document or parse error
@@ -410,6 +682,18 @@ func (m *prettyModifier) Apply(r Result) Result {
 			}
 		}
 
+		// The decoder reports CDATA as plain character data; copy the section
+		// from the input so it is not escaped
+		if _, ok := token.(xml.CharData); ok && opts.preserveCDATA() {
+			if raw := r.Raw[offset:decoder.InputOffset()]; strings.HasPrefix(raw, "<![CDATA[") {
+				if err := encoder.Flush(); err != nil {
+					return r
+				}
+				buf.WriteString(raw)
+				continue
+			}
+		}
+
 		if err := encoder.EncodeToken(token); err != nil {
 			return r
 		}
@@ -420,12 +704,10 @@ func (m *prettyModifier) Apply(r Result) Result {
 		return r
 	}
 
-	return Result{
-		Type: r.Type,
-		Str:  r.Str,
-		Raw:  buf.String(),
-		Num:  r.Num,
-	}
+	// Only the markup changes; an element keeps its name and attributes
+	formatted := r
+	formatted.Raw = buf.String()
+	return formatted
 }
 
 // deduplicateXmlnsAttrs removes duplicate xmlns namespace declarations from attributes.
@@ -474,25 +756,26 @@ type uglyModifier struct{}
 func (m *uglyModifier) Name() string { return "ugly" }
 
 func (m *uglyModifier) Apply(r Result) Result {
-	if r.Raw == "" {
+	return m.applyOptions(r, nil)
+}
+
+func (m *uglyModifier) applyOptions(r Result, opts *Options) Result {
+	// Attributes are not markup
+	if r.Raw == "" || r.Type == Attribute {
 		return r
 	}
 
-	// Remove whitespace between tags
-	compacted := compactXML(r.Raw)
-
-	return Result{
-		Type: r.Type,
-		Str:  r.Str,
-		Raw:  compacted,
-		Num:  r.Num,
-	}
+	// Remove whitespace between tags; an element keeps its name and attributes
+	compacted := r
+	compacted.Raw = compactXML(r.Raw, opts.preserveCDATA())
+	return compacted
 }
 
 // compactXML removes unnecessary whitespace from XML while preserving CDATA sections.
 // CDATA sections are preserved verbatim including all whitespace, as they may contain
 // pre-formatted text, code snippets, or other content where whitespace is significant.
-func compactXML(xmlStr string) string {
+// Without keepCDATA their content is kept the same way but written as escaped text.
+func compactXML(xmlStr string, keepCDATA bool) string {
 	var buf strings.Builder
 	buf.Grow(len(xmlStr))
 
@@ -502,6 +785,15 @@ func compactXML(xmlStr string) string {
 	for i := 0; i < len(xmlStr); i++ {
 		// Check for CDATA start
 		if !inTag && i+9 <= len(xmlStr) && xmlStr[i:i+9] == "<![CDATA[" {
+			if !keepCDATA {
+				end := strings.Index(xmlStr[i+9:], "]]>")
+				if end < 0 {
+					end = len(xmlStr) - i - 9
+				}
+				buf.WriteString(escapeXML(xmlStr[i+9 : i+9+end]))
+				i += 9 + end + 2
+				continue
+			}
 			inCDATA = true
 			buf.WriteString(xmlStr[i : i+9])
 			i += 8
@@ -544,6 +836,223 @@ func compactXML(xmlStr string) string {
 	return buf.String()
 }
 
+// keysModifier returns the names of an element's immediate children in document order
+type keysModifier struct{}
+
+func (m *keysModifier) Name() string { return "keys" }
+
+func (m *keysModifier) Apply(r Result) Result {
+	children, ok := immediateChildren(r)
+	if !ok {
+		return Result{Type: Null}
+	}
+
+	keys := make([]Result, 0, len(children))
+	for _, child := range children {
+		keys = append(keys, Result{
+			Type: String,
+			Str:  child.name,
+			Raw:  child.name,
+		})
+	}
+
+	return Result{Type: Array, Results: keys}
+}
+
+// valuesModifier returns an element's immediate children in document order
+type valuesModifier struct{}
+
+func (m *valuesModifier) Name() string { return "values" }
+
+func (m *valuesModifier) Apply(r Result) Result {
+	children, ok := immediateChildren(r)
+	if !ok {
+		return Result{Type: Null}
+	}
+
+	values := make([]Result, 0, len(children))
+	for _, child := range children {
+		values = append(values, newElementResult(child))
+	}
+
+	return Result{Type: Array, Results: values}
+}
+
+// entriesModifier returns an element's immediate children in document order
+// as name/value pairs: each entry is the child element itself, so
+// ForEachNamed passes its name together with its value. This replaces
+// zipping the parallel arrays of @keys and @values.
+//
+// Example: post|@entries
+type entriesModifier struct{}
+
+func (m *entriesModifier) Name() string { return "entries" }
+
+func (m *entriesModifier) Apply(r Result) Result {
+	children, ok := immediateChildren(r)
+	if !ok {
+		return Result{Type: Null}
+	}
+
+	entries := make([]Result, 0, len(children))
+	for _, child := range children {
+		entries = append(entries, newElementResult(child))
+	}
+
+	return Result{Type: Array, Results: entries}
+}
+
+// groupByModifier groups an array of elements by the value of a field.
+//
+// The field is a child element name or an attribute (@name) of each element.
+// The result is an Array of groups in order of first appearance; each group is
+// an Array holding the elements that share the same field value, so a group's
+// key is available from any member (e.g. group.Array()[0].Get(field)).
+// Elements without the field are grouped under the empty key.
+//
+// Example: company.employee|@group-by:department
+type groupByModifier struct{}
+
+func (m *groupByModifier) Name() string { return "group-by" }
+
+// Apply without a field cannot group and returns Null.
+func (m *groupByModifier) Apply(r Result) Result {
+	return Result{Type: Null}
+}
+
+func (m *groupByModifier) applyArg(r Result, field string) Result {
+	if field == "" {
+		return Result{Type: Null}
+	}
+
+	var items []Result
+	switch r.Type {
+	case Array:
+		items = r.Results
+	case Element:
+		items = []Result{r}
+	default:
+		return Result{Type: Null}
+	}
+
+	index := make(map[string]int)
+	var groups []Result
+	for _, item := range items {
+		key := groupKey(item, field)
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, Result{Type: Array})
+		}
+		groups[i].Results = append(groups[i].Results, item)
+	}
+
+	if len(groups) == 0 {
+		return Result{Type: Null}
+	}
+	return Result{Type: Array, Results: groups}
+}
+
+// groupKey returns the value of field (a child element or @attribute) for item.
+func groupKey(item Result, field string) string {
+	if attrName, ok := strings.CutPrefix(field, "@"); ok {
+		for _, attr := range item.Attributes() {
+			if attr.Name == attrName {
+				return attr.Value
+			}
+		}
+		return ""
+	}
+	return item.Get(field).String()
+}
+
+// immediateChildren parses the immediate child elements of an Element Result
+// in document order. Array Results delegate to their first element, matching
+// Map(). Returns false for Null and primitive Results.
+//
+// Security: Limited to MaxWildcardResults (1000) children.
+func immediateChildren(r Result) ([]elementMatch, bool) {
+	if r.Type == Array {
+		if len(r.Results) == 0 {
+			return nil, false
+		}
+		r = r.Results[0]
+	}
+	if r.Type != Element {
+		return nil, false
+	}
+
+	var children []elementMatch
+	parser := newXMLParser([]byte(r.Raw))
+	for parser.skipToNextElement() {
+		if len(children) >= MaxWildcardResults {
+			break
+		}
+
+		parser.next() // skip '<'
+		name, attrs, attrOrder, isSelfClosing := parser.parseElementTag()
+		tag := parser.tag
+
+		var content string
+		if !isSelfClosing {
+			content = parser.parseElementContent(name)
+		}
+
+		children = append(children, elementMatch{
+			name:          name,
+			attrs:         attrs,
+			tag:           tag,
+			attrOrder:     attrOrder,
+			content:       content,
+			isSelfClosing: isSelfClosing,
+		})
+	}
+
+	return children, true
+}
+
+// joinModifier concatenates the string values of an array. @join:sep puts
+// sep between the values. A single value is returned as a String and an
+// empty array yields Null.
+//
+// Example: users.user.#(@active==true)#.%|@join:,
+type joinModifier struct{}
+
+func (m *joinModifier) Name() string { return "join" }
+
+func (m *joinModifier) Apply(r Result) Result {
+	return m.applyArg(r, "")
+}
+
+func (m *joinModifier) applyArg(r Result, sep string) Result {
+	switch r.Type {
+	case Null:
+		return r
+	case Array:
+		if len(r.Results) == 0 {
+			return Result{Type: Null}
+		}
+		values := make([]string, len(r.Results))
+		for i, item := range r.Results {
+			values[i] = item.String()
+		}
+		return NewStringResult(strings.Join(values, sep))
+	default:
+		return NewStringResult(r.String())
+	}
+}
+
+// thisModifier returns its input unchanged. It makes the hand-off of the
+// current Result explicit, e.g. before continuing a path ("post.0|@this.title").
+type thisModifier struct{}
+
+func (m *thisModifier) Name() string { return "this" }
+
+func (m *thisModifier) Apply(r Result) Result {
+	return r
+}
+
 // init registers all built-in modifiers
 func init() {
 	// Register all built-in modifiers
@@ -555,4 +1064,10 @@ func init() {
 	modifierRegistry["flatten"] = &flattenModifier{}
 	modifierRegistry["pretty"] = &prettyModifier{}
 	modifierRegistry["ugly"] = &uglyModifier{}
+	modifierRegistry["keys"] = &keysModifier{}
+	modifierRegistry["values"] = &valuesModifier{}
+	modifierRegistry["entries"] = &entriesModifier{}
+	modifierRegistry["group-by"] = &groupByModifier{}
+	modifierRegistry["join"] = &joinModifier{}
+	modifierRegistry["this"] = &thisModifier{}
 }
@@ -4,6 +4,7 @@
 package xmldot
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 	"sync"
@@ -458,6 +459,99 @@ func TestModifierLast_NonArray(t *testing.T) {
 	}
 }
 
+// TestModifierFirstLast_ElementPaths tests that @first/@last on a repeated
+// element return full Element results that can be queried further
+func TestModifierFirstLast_ElementPaths(t *testing.T) {
+	xml := `<users>
+		<user id="1"><name>Ann</name><role>admin</role></user>
+		<user id="2"><name>Bob</name><role>dev</role></user>
+		<user id="3"><name>Cid</name><role>ops</role></user>
+	</users>`
+
+	tests := []struct {
+		path string
+		id   string
+		name string
+	}{
+		{"users.user|@first", "1", "Ann"},
+		{"users.user|@last", "3", "Cid"},
+		{"users.*|@last", "3", "Cid"},
+		{"users.user|@reverse|@first", "3", "Cid"},
+		{"users.user.#(role!=admin)#|@last", "3", "Cid"},
+		{"users.user.#(role!=admin)#|@first", "2", "Bob"},
+	}
+
+	for _, tt := range tests {
+		for _, opts := range []*Options{nil, {CaseSensitive: true, Indent: "  "}} {
+			t.Run(tt.path, func(t *testing.T) {
+				result := GetWithOptions(xml, tt.path, opts)
+				if result.Type != Element {
+					t.Fatalf("Type = %v, want Element", result.Type)
+				}
+				if result.Name() != "user" {
+					t.Errorf("Name() = %q, want user", result.Name())
+				}
+				if !strings.Contains(result.Raw, "<name>"+tt.name+"</name>") {
+					t.Errorf("Raw = %q, want it to contain %s", result.Raw, tt.name)
+				}
+				if got := result.Get("name").String(); got != tt.name {
+					t.Errorf("Get(name) = %q, want %q", got, tt.name)
+				}
+				if attrs := result.Attributes(); len(attrs) != 1 || attrs[0].Value != tt.id {
+					t.Errorf("Attributes() = %v, want id=%s", attrs, tt.id)
+				}
+			})
+		}
+	}
+
+	// The path can continue after the modifier
+	if got := Get(xml, "users.user|@last.name").String(); got != "Cid" {
+		t.Errorf("users.user|@last.name = %q, want Cid", got)
+	}
+	// Element-oriented modifiers still apply to the first match
+	if got := Get(xml, "users.user|@keys").String(); got != `["name","role"]` {
+		t.Errorf("users.user|@keys = %s", got)
+	}
+}
+
+// TestModifierJoin_RepeatedSiblings tests that @join on a repeated element
+// joins every sibling, while the plain path still returns the first
+func TestModifierJoin_RepeatedSiblings(t *testing.T) {
+	xml := `<rss><channel><item>
+		<title>Release</title>
+		<category>go</category>
+		<category>xml</category>
+		<category>parsing</category>
+	</item></channel></rss>`
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"rss.channel.item.category", "go"},
+		{"rss.channel.item.category|@join", "goxmlparsing"},
+		{"rss.channel.item.category|@join:,", "go,xml,parsing"},
+		{"rss.channel.item.category.#.%|@join:,", "go,xml,parsing"},
+		{"rss.channel.item.title|@join:,", "Release"},
+		{"rss.channel.item.missing|@join:,", ""},
+	}
+
+	for _, tt := range tests {
+		for _, opts := range []*Options{nil, {CaseSensitive: true}} {
+			t.Run(tt.path, func(t *testing.T) {
+				if got := GetWithOptions(xml, tt.path, opts).String(); got != tt.want {
+					t.Errorf("GetWithOptions(%q) = %q, want %q", tt.path, got, tt.want)
+				}
+			})
+		}
+	}
+
+	item := Get(xml, "rss.channel.item")
+	if got := item.Get("category|@join:,").String(); got != "go,xml,parsing" {
+		t.Errorf("Result.Get(category|@join:,) = %q", got)
+	}
+}
+
 // @flatten Tests (5 tests)
 
 func TestModifierFlatten_NestedArrays(t *testing.T) {
@@ -574,6 +668,90 @@ func TestModifierFlatten_MixedContent(t *testing.T) {
 	}
 }
 
+func TestModifierFlatten_Depth(t *testing.T) {
+	leaf := func(s string) Result { return Result{Type: String, Str: s} }
+	arr := func(items ...Result) Result { return Result{Type: Array, Results: items} }
+	// [a, [b, [c, [d]]]]
+	input := arr(leaf("a"), arr(leaf("b"), arr(leaf("c"), arr(leaf("d")))))
+
+	tests := []struct {
+		modifier string
+		expected string
+	}{
+		{"flatten", "[a b [c [d]]]"},
+		{"flatten:1", "[a b [c [d]]]"},
+		{"flatten:2", "[a b c [d]]"},
+		{"flatten:deep", "[a b c d]"},
+	}
+
+	var shape func(r Result) string
+	shape = func(r Result) string {
+		if r.Type != Array {
+			return r.Str
+		}
+		parts := make([]string, len(r.Results))
+		for i, item := range r.Results {
+			parts[i] = shape(item)
+		}
+		return "[" + strings.Join(parts, " ") + "]"
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.modifier, func(t *testing.T) {
+			result := applyModifiers(input, []string{tt.modifier})
+			if shape(result) != tt.expected {
+				t.Errorf("@%s = %s, want %s", tt.modifier, shape(result), tt.expected)
+			}
+		})
+	}
+
+	for _, modifier := range []string{"flatten:0", "flatten:-1", "flatten:all"} {
+		if result := applyModifiers(input, []string{modifier}); result.Type != Null {
+			t.Errorf("@%s = %v, want Null", modifier, result.Type)
+		}
+	}
+
+	// Groups produced from a wildcard match flatten back into elements
+	xml := `<team><dev dept="a">Ann</dev><dev dept="b">Bob</dev><dev dept="a">Cid</dev></team>`
+	result := Get(xml, "team.*|@group-by:@dept|@flatten:deep")
+	if result.String() != `["Ann","Cid","Bob"]` {
+		t.Errorf("group-by then @flatten:deep = %s, want [\"Ann\",\"Cid\",\"Bob\"]", result.String())
+	}
+}
+
+// @join Tests
+
+func TestModifierJoin(t *testing.T) {
+	items := Result{Type: Array, Results: []Result{
+		{Type: String, Str: "a"},
+		{Type: Number, Str: "2", Num: 2},
+		{Type: Element, Str: "c", Raw: "c"},
+	}}
+
+	tests := []struct {
+		name     string
+		input    Result
+		modifier string
+		wantType Type
+		want     string
+	}{
+		{"concatenate", items, "join", String, "a2c"},
+		{"separator", items, "join:;", String, "a;2;c"},
+		{"single value", Result{Type: Element, Str: "x", Raw: "x"}, "join:,", String, "x"},
+		{"empty array", Result{Type: Array, Results: []Result{}}, "join", Null, ""},
+		{"null", Result{Type: Null}, "join", Null, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := applyModifiers(tt.input, []string{tt.modifier})
+			if result.Type != tt.wantType || result.String() != tt.want {
+				t.Errorf("@%s = %v %q, want %v %q", tt.modifier, result.Type, result.String(), tt.wantType, tt.want)
+			}
+		})
+	}
+}
+
 // @pretty Tests (4 tests)
 
 func TestModifierPretty_SimpleXML(t *testing.T) {
@@ -855,6 +1033,326 @@ func TestModifierUgly_PreservesCDATA(t *testing.T) {
 	}
 }
 
+func TestModifierPreserveCDATA(t *testing.T) {
+	xml := `<root><code><![CDATA[<b> & c]]></code><p>x</p></root>`
+
+	// An Options literal that leaves PreserveCDATA out keeps CDATA, like nil
+	keep := &Options{CaseSensitive: true}
+	preserve, noPreserve := true, false
+	explicit := &Options{CaseSensitive: true, PreserveCDATA: &preserve}
+	escape := DefaultOptions()
+	escape.PreserveCDATA = &noPreserve
+
+	tests := []struct {
+		name string
+		path string
+		opts *Options
+		want string
+	}{
+		{"ugly default", "root|@ugly", nil, `<code><![CDATA[<b> & c]]></code><p>x</p>`},
+		{"ugly literal options", "root|@ugly", keep, `<code><![CDATA[<b> & c]]></code><p>x</p>`},
+		{"ugly preserved", "root|@ugly", explicit, `<code><![CDATA[<b> & c]]></code><p>x</p>`},
+		{"ugly escaped", "root|@ugly", escape, `<code>&lt;b&gt; &amp; c</code><p>x</p>`},
+		{"pretty default", "root|@pretty", nil, "<code><![CDATA[<b> & c]]></code>\n<p>x</p>"},
+		{"pretty literal options", "root|@pretty", keep, "<code><![CDATA[<b> & c]]></code>\n<p>x</p>"},
+		{"case-insensitive literal options", "ROOT|@ugly", &Options{}, `<code><![CDATA[<b> & c]]></code><p>x</p>`},
+		{"pretty escaped", "root|@pretty", escape, "<code>&lt;b&gt; &amp; c</code>\n<p>x</p>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := GetWithOptions(xml, tt.path, tt.opts).Raw; got != tt.want {
+				t.Errorf("GetWithOptions(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+
+	// The option also reaches modifiers after wildcards and filters
+	xml = `<root><item><![CDATA[a & b]]></item><item id="2"><![CDATA[c & d]]></item></root>`
+	got := GetWithOptions(xml, "root.item.#(@id==2)|@ugly", escape).Raw
+	if got != "c &amp; d" {
+		t.Errorf("root.item.#(@id==2)|@ugly = %q, want %q", got, "c &amp; d")
+	}
+	got = GetWithOptions(xml, "root.item|@ugly", escape).Raw
+	if got != "a &amp; b" {
+		t.Errorf("root.item|@ugly = %q, want %q", got, "a &amp; b")
+	}
+	got = GetWithOptions(`<r><a><x><![CDATA[<]]></x></a></r>`, "r.*|@ugly", escape).Raw
+	if got != "<x>&lt;</x>" {
+		t.Errorf("r.*|@ugly = %q, want %q", got, "<x>&lt;</x>")
+	}
+}
+
+func TestModifierKeys_DocumentOrder(t *testing.T) {
+	xml := `<post id="3"><title>Getting Started</title><views>150</views><author>Ann</author><tag>a</tag><tag>b</tag></post>`
+
+	expected := []string{"title", "views", "author", "tag", "tag"}
+
+	// Repeat to catch any dependence on map iteration order
+	for run := 0; run < 20; run++ {
+		result := Get(xml, "post|@keys")
+		if !result.IsArray() {
+			t.Fatalf("@keys should return Array, got %v", result.Type)
+		}
+		keys := result.Array()
+		if len(keys) != len(expected) {
+			t.Fatalf("Expected %d keys, got %d", len(expected), len(keys))
+		}
+		for i, key := range keys {
+			if key.String() != expected[i] {
+				t.Fatalf("run %d: key[%d] = %q, expected %q", run, i, key.String(), expected[i])
+			}
+		}
+	}
+}
+
+func TestModifierKeys_NonElement(t *testing.T) {
+	mod := GetModifier("keys")
+
+	if result := mod.Apply(Result{Type: Null}); result.Exists() {
+		t.Error("@keys on Null should return Null")
+	}
+	if result := mod.Apply(Result{Type: String, Str: "text"}); result.Exists() {
+		t.Error("@keys on String should return Null")
+	}
+
+	// Element without children yields an empty Array
+	result := mod.Apply(Result{Type: Element, Raw: "just text", Str: "just text"})
+	if !result.IsArray() || len(result.Array()) != 0 {
+		t.Errorf("@keys on leaf element should return empty Array, got %v", result)
+	}
+}
+
+func TestModifierValues_DocumentOrder(t *testing.T) {
+	xml := `<post id="3"><title>Getting Started</title><views>150</views><meta a="1" b="2" c="3">m</meta></post>`
+
+	expected := []string{"Getting Started", "150", "m"}
+
+	for run := 0; run < 20; run++ {
+		values := Get(xml, "post|@values").Array()
+		if len(values) != len(expected) {
+			t.Fatalf("Expected %d values, got %d", len(expected), len(values))
+		}
+		for i, value := range values {
+			if value.String() != expected[i] {
+				t.Fatalf("run %d: value[%d] = %q, expected %q", run, i, value.String(), expected[i])
+			}
+		}
+
+		// Values are element Results and keep their attributes in order
+		attrs := values[2].Attributes()
+		if len(attrs) != 3 || attrs[0].Name != "a" || attrs[1].Name != "b" || attrs[2].Name != "c" {
+			t.Fatalf("run %d: unexpected attribute order %v", run, attrs)
+		}
+	}
+}
+
+func TestModifierEntries(t *testing.T) {
+	xml := `<posts><post id="3"><title>Getting Started</title><views>150</views><tag>a</tag><tag>b</tag></post></posts>`
+
+	type entry struct{ name, value string }
+	expected := []entry{{"title", "Getting Started"}, {"views", "150"}, {"tag", "a"}, {"tag", "b"}}
+
+	for run := 0; run < 20; run++ {
+		var got []entry
+		Get(xml, "posts.post.0|@entries").ForEachNamed(func(_ int, name string, value Result) bool {
+			got = append(got, entry{name, value.String()})
+			return true
+		})
+		if len(got) != len(expected) {
+			t.Fatalf("run %d: got %d entries %v, expected %v", run, len(got), got, expected)
+		}
+		for i := range expected {
+			if got[i] != expected[i] {
+				t.Fatalf("run %d: entry[%d] = %v, expected %v", run, i, got[i], expected[i])
+			}
+		}
+	}
+
+	// Entries are element Results, so a path can continue into them
+	if got := Get(xml, "posts.post|@entries.1").Int(); got != 150 {
+		t.Errorf("@entries.1 = %d, want 150", got)
+	}
+	if got := Get(xml, "posts.post|@entries.#").Int(); got != 4 {
+		t.Errorf("@entries.# = %d, want 4", got)
+	}
+
+	mod := GetModifier("entries")
+	if result := mod.Apply(Result{Type: String, Str: "text"}); result.Exists() {
+		t.Error("@entries on String should return Null")
+	}
+	result := mod.Apply(Result{Type: Element, Raw: "just text", Str: "just text"})
+	if !result.IsArray() || len(result.Array()) != 0 {
+		t.Errorf("@entries on leaf element should return empty Array, got %v", result)
+	}
+}
+
+func TestModifierGroupBy(t *testing.T) {
+	xml := `<company>
+		<employee dept="a"><name>Ann</name><department>Eng</department></employee>
+		<employee dept="b"><name>Bob</name><department>Ops</department></employee>
+		<employee><name>Cid</name><department>Eng</department></employee>
+		<employee dept="a"><name>Dee</name></employee>
+	</company>`
+
+	groupNames := func(r Result) [][]string {
+		var out [][]string
+		r.ForEach(func(_ int, group Result) bool {
+			var names []string
+			group.ForEach(func(_ int, member Result) bool {
+				names = append(names, member.Get("name").String())
+				return true
+			})
+			out = append(out, names)
+			return true
+		})
+		return out
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		expected [][]string
+	}{
+		{"by child element", "company.*|@group-by:department", [][]string{{"Ann", "Cid"}, {"Bob"}, {"Dee"}}},
+		{"by attribute", "company.*|@group-by:@dept", [][]string{{"Ann", "Dee"}, {"Bob"}, {"Cid"}}},
+		{"filtered input", "company.employee.#(department)#|@group-by:department", [][]string{{"Ann", "Cid"}, {"Bob"}}},
+		{"single element", "company.employee|@group-by:department", [][]string{{"Ann"}}},
+		{"chained", "company.*|@group-by:department|@first", [][]string{{"Ann"}, {"Cid"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := groupNames(Get(xml, tt.path))
+			if fmt.Sprint(got) != fmt.Sprint(tt.expected) {
+				t.Errorf("Get(%q) groups = %v, want %v", tt.path, got, tt.expected)
+			}
+		})
+	}
+
+	t.Run("group key from first member", func(t *testing.T) {
+		groups := Get(xml, "company.*|@group-by:department").Array()
+		if key := groups[1].Array()[0].Get("department").String(); key != "Ops" {
+			t.Errorf("Expected key 'Ops', got %q", key)
+		}
+	})
+
+	t.Run("invalid usage", func(t *testing.T) {
+		for _, path := range []string{
+			"company.*|@group-by",
+			"company.*|@group-by:",
+			"company.*|@reverse:x",
+			"company.employee.name.%|@group-by:x",
+		} {
+			if r := Get(xml, path); r.Exists() {
+				t.Errorf("Get(%q) expected Null, got %v", path, r)
+			}
+		}
+	})
+}
+
+func TestModifierThisAndContinuation(t *testing.T) {
+	xml := `<blog>
+		<post id="1"><title>First</title><tag>go</tag><tag>xml</tag></post>
+		<post id="2"><title>Second</title></post>
+	</blog>`
+
+	tests := []struct {
+		name     string
+		path     string
+		expected string
+	}{
+		{"this is a no-op", "blog.post.1|@this", "Second"},
+		{"child of element", "blog.post.0|@this.title", "First"},
+		{"own attribute", "blog.post.1|@this.@id", "2"},
+		{"count children", "blog.post.0|@this.tag.#", "2"},
+		{"index children", "blog.post.0|@this.tag.1", "xml"},
+		{"array index", "blog.*|@reverse.0.title", "Second"},
+		{"array negative index", "blog.*|@reverse.-1.@id", "1"},
+		{"array count", "blog.*|@reverse.#", "2"},
+		{"array first item", "blog.*|@reverse.title", "Second"},
+		{"after filter", "blog.post.#(@id==2)|@this.title", "Second"},
+		{"repeated boundary", "blog|@this.post.1|@this.title", "Second"},
+		{"modifier on continuation", "blog.post.0|@this.tag|@first", "go"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Get(xml, tt.path).String(); got != tt.expected {
+				t.Errorf("Get(%q) = %q, want %q", tt.path, got, tt.expected)
+			}
+		})
+	}
+
+	t.Run("unresolvable continuation", func(t *testing.T) {
+		for _, path := range []string{
+			"blog.post.0|@this.missing",
+			"blog.*|@reverse.5",
+			"blog.post.0.title|@this.x",
+			"blog.post.0|@this.@id.x",
+		} {
+			if r := Get(xml, path); r.Exists() {
+				t.Errorf("Get(%q) expected Null, got %v", path, r)
+			}
+		}
+	})
+
+	t.Run("options", func(t *testing.T) {
+		opts := &Options{CaseSensitive: false}
+		if got := GetWithOptions(xml, "BLOG.POST.1|@this.TITLE", opts).String(); got != "Second" {
+			t.Errorf("GetWithOptions() = %q, want %q", got, "Second")
+		}
+	})
+}
+
+// TestModifierContinuation_NavigableModifiers tests which built-in modifiers
+// yield Results a path can continue into after a repeated element
+func TestModifierContinuation_NavigableModifiers(t *testing.T) {
+	xml := `<catalog><books>
+		<book id="2"><title>Zen</title></book>
+		<book id="1"><title>Go</title></book>
+	</books></catalog>`
+
+	navigable := []struct {
+		path     string
+		expected string
+	}{
+		{"catalog.books.book|@first.title", "Zen"},
+		{"catalog.books.book|@last.title", "Go"},
+		{"catalog.books.book|@last.@id", "1"},
+		{"catalog.books.book|@reverse.0.title", "Go"},
+		{"catalog.books.book|@reverse.title", "Go"},
+		{"catalog.books.book|@sort.0.@id", "1"},
+		{"catalog.books.book|@this.title", "Zen"},
+		{"catalog.books.book|@pretty.title", "Zen"},
+		{"catalog.books.book|@ugly.@id", "2"},
+		{"catalog.books.book|@reverse|@first.title", "Go"},
+		{"catalog.books|@first.book.1.title", "Go"},
+	}
+	for _, tt := range navigable {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := Get(xml, tt.path).String(); got != tt.expected {
+				t.Errorf("Get(%q) = %q, want %q", tt.path, got, tt.expected)
+			}
+		})
+	}
+
+	// Modifiers producing text end navigation; an index still selects from
+	// an array of strings
+	if got := Get(xml, "catalog.books.book|@keys.0").String(); got != "title" {
+		t.Errorf("@keys.0 = %q, want title", got)
+	}
+	for _, path := range []string{
+		"catalog.books.book|@join.title",
+		"catalog.books.book|@keys.0.x",
+		"catalog.books.book.title|@values.0.x",
+	} {
+		if r := Get(xml, path); r.Exists() {
+			t.Errorf("Get(%q) expected Null, got %v", path, r)
+		}
+	}
+}
+
 // Modifier Chaining Tests (8 tests)
 
 func TestModifierChain_SortReverse(t *testing.T) {
@@ -1048,6 +1546,52 @@ func TestModifierIntegration_WithArrayIndex(t *testing.T) {
 	}
 }
 
+// TestModifier_ElementDispatch tests what modifiers receive for an element and
+// that formatting or updating Str keeps the result an element
+func TestModifier_ElementDispatch(t *testing.T) {
+	xml := `<root id="1"><child><item>value</item></child><user id="7">ann</user></root>`
+
+	var got Result
+	mod := NewModifierFunc("test-element-dispatch", func(r Result) Result {
+		got = r
+		r.Str = strings.ToUpper(r.Str)
+		return r
+	})
+	if err := RegisterModifier("test-element-dispatch", mod); err != nil {
+		t.Fatalf("RegisterModifier() error = %v", err)
+	}
+	defer func() { _ = UnregisterModifier("test-element-dispatch") }()
+
+	result := Get(xml, "root.user|@test-element-dispatch")
+	if got.Type != Element || got.Name() != "user" || got.Str != "ann" || got.Raw != "ann" {
+		t.Errorf("modifier received %v %q Str=%q Raw=%q, want the user element", got.Type, got.Name(), got.Str, got.Raw)
+	}
+	if result.Type != Element || result.String() != "ANN" || result.Name() != "user" {
+		t.Errorf("result = %v %q %q, want Element user ANN", result.Type, result.Name(), result.String())
+	}
+	if id := Get(xml, "root.user|@test-element-dispatch.@id").String(); id != "7" {
+		t.Errorf("@id after modifier = %q, want 7", id)
+	}
+
+	Get(xml, "root|@test-element-dispatch")
+	if got.Raw != `<child><item>value</item></child><user id="7">ann</user>` {
+		t.Errorf("modifier received Raw %q, want the inner markup", got.Raw)
+	}
+
+	for _, path := range []string{"root|@pretty", "root|@ugly"} {
+		result := Get(xml, path)
+		if result.Type != Element || result.Name() != "root" || len(result.Attributes()) != 1 {
+			t.Errorf("Get(%q) = %v %q, want the root element", path, result.Type, result.Name())
+		}
+		if id := Get(xml, path+".@id").String(); id != "1" {
+			t.Errorf("Get(%q.@id) = %q, want 1", path, id)
+		}
+		if item := Get(xml, path+".child.item").String(); item != "value" {
+			t.Errorf("Get(%q.child.item) = %q, want value", path, item)
+		}
+	}
+}
+
 // Test helper: custom modifier for testing
 type testUppercaseModifier struct{}
 
@@ -1202,6 +1746,138 @@ func BenchmarkModifierChain3Deep(b *testing.B) {
 	}
 }
 
+// TestModifier_ArrayItemsAndConstructors tests that a modifier receives every
+// item of an Array with its Type and Raw set, and that items changed in place
+// and returned through NewArrayResult keep their Raw, name and attributes
+func TestModifier_ArrayItemsAndConstructors(t *testing.T) {
+	xml := `<root><user id="1">Ann<b/></user><user id="2">Bob</user></root>`
+
+	var got []Result
+	mod := NewModifierFunc("test-array-items", func(r Result) Result {
+		got = r.Array()
+		items := make([]Result, 0, len(got))
+		for _, item := range got {
+			item.Str = strings.ToLower(item.Str)
+			items = append(items, item)
+		}
+		return NewArrayResult(items)
+	})
+	if err := RegisterModifier("test-array-items", mod); err != nil {
+		t.Fatalf("RegisterModifier() error = %v", err)
+	}
+	defer func() { _ = UnregisterModifier("test-array-items") }()
+
+	tests := []struct {
+		path     string
+		wantType Type
+		wantRaw  []string
+	}{
+		{"root.user.#(@id>0)#", Element, []string{"Ann<b/>", "Bob"}},
+		{"root.*", Element, []string{"Ann<b/>", "Bob"}},
+		{"root.user.#.@id", Attribute, []string{`id="1"`, `id="2"`}},
+		{"root.user.#.%", String, []string{"Ann", "Bob"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			result := Get(xml, tt.path+"|@test-array-items")
+			if len(got) != len(tt.wantRaw) {
+				t.Fatalf("modifier received %d items, want %d", len(got), len(tt.wantRaw))
+			}
+			for i, item := range got {
+				if item.Type != tt.wantType || item.Raw != tt.wantRaw[i] {
+					t.Errorf("item %d = %v Raw %q, want %v Raw %q", i, item.Type, item.Raw, tt.wantType, tt.wantRaw[i])
+				}
+			}
+			if result.Type != Array || len(result.Results) != len(tt.wantRaw) {
+				t.Fatalf("result = %v with %d items, want Array with %d", result.Type, len(result.Results), len(tt.wantRaw))
+			}
+			for i, item := range result.Results {
+				if item.Raw != tt.wantRaw[i] || item.Type != tt.wantType {
+					t.Errorf("result item %d = %v Raw %q, want %v Raw %q", i, item.Type, item.Raw, tt.wantType, tt.wantRaw[i])
+				}
+			}
+		})
+	}
+
+	result := Get(xml, "root.user.#(@id>0)#|@test-array-items")
+	if first := result.Array()[0]; first.String() != "ann" || first.Name() != "user" || len(first.Attributes()) != 1 {
+		t.Errorf("first item = %q name %q, want ann with name and attributes kept", first.String(), first.Name())
+	}
+
+	if s := NewStringResult("a, b"); s.Type != String || s.Str != "a, b" || s.Raw != "a, b" {
+		t.Errorf("NewStringResult() = %+v", s)
+	}
+	if a := NewArrayResult(nil); a.Type != Array || len(a.Array()) != 0 {
+		t.Errorf("NewArrayResult(nil) = %+v", a)
+	}
+}
+
+func TestCompileModifiers(t *testing.T) {
+	xml := `<scores><score>3</score><score>10</score><score>7</score></scores>`
+	scores := Get(xml, "scores.score.#.%")
+
+	tests := []struct {
+		chain string
+		want  string
+	}{
+		{"|@sort|@reverse|@first", "10"},
+		{"@sort|@first", "3"},
+		{" | @reverse | @last ", "3"},
+		{"|@join:-", "3-10-7"},
+		{"|@first|@sort", "3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.chain, func(t *testing.T) {
+			apply, err := CompileModifiers(tt.chain)
+			if err != nil {
+				t.Fatalf("CompileModifiers() error = %v", err)
+			}
+			if got := apply(scores).String(); got != tt.want {
+				t.Errorf("apply() = %q, want %q", got, tt.want)
+			}
+			if got, want := apply(scores).String(), Get(xml, "scores.score.#.%"+"|"+strings.TrimPrefix(strings.ReplaceAll(tt.chain, " ", ""), "|")).String(); got != want {
+				t.Errorf("apply() = %q, path chain = %q", got, want)
+			}
+		})
+	}
+
+	errTests := []struct {
+		chain   string
+		wantErr error
+		wantMsg string
+	}{
+		{"|@sort|@nonsense", ErrUnknownModifier, "@nonsense"},
+		{"|@reverse:3", ErrInvalidPath, "does not take an argument"},
+		{"", ErrInvalidPath, "empty"},
+		{"|sort", ErrInvalidPath, "not a modifier"},
+		{"|@sort|", ErrInvalidPath, "not a modifier"},
+		{strings.Repeat("|@this", MaxModifierChainDepth+1), ErrInvalidPath, "longer than"},
+	}
+	for _, tt := range errTests {
+		t.Run("error "+tt.chain, func(t *testing.T) {
+			apply, err := CompileModifiers(tt.chain)
+			if apply != nil || !errors.Is(err, tt.wantErr) || !strings.Contains(err.Error(), tt.wantMsg) {
+				t.Errorf("CompileModifiers() error = %v, want %v containing %q", err, tt.wantErr, tt.wantMsg)
+			}
+		})
+	}
+
+	// Modifiers are resolved when the chain is compiled
+	if err := RegisterModifier("test-compiled", NewModifierFunc("test-compiled", func(r Result) Result {
+		return NewStringResult("compiled")
+	})); err != nil {
+		t.Fatalf("RegisterModifier() error = %v", err)
+	}
+	apply, err := CompileModifiers("|@test-compiled")
+	_ = UnregisterModifier("test-compiled")
+	if err != nil {
+		t.Fatalf("CompileModifiers() error = %v", err)
+	}
+	if got := apply(scores).String(); got != "compiled" {
+		t.Errorf("compiled custom modifier after unregistering = %q, want compiled", got)
+	}
+}
+
 // Example functions for godoc
 
 // ExampleRegisterModifier demonstrates registering a custom modifier
@@ -1268,6 +1944,11 @@ func TestModifierNames(t *testing.T) {
 		{"flatten", "flatten"},
 		{"pretty", "pretty"},
 		{"ugly", "ugly"},
+		{"keys", "keys"},
+		{"values", "values"},
+		{"entries", "entries"},
+		{"join", "join"},
+		{"this", "this"},
 	}
 
 	for _, tt := range tests {
@@ -1282,3 +1963,40 @@ func TestModifierNames(t *testing.T) {
 		})
 	}
 }
+
+// TestModifier_EscapedPipe tests that \| is a literal pipe rather than a
+// modifier separator, in element names and in unquoted filter values
+func TestModifier_EscapedPipe(t *testing.T) {
+	xml := `<root><a|b>hi</a|b><a>no</a><item><n>x|y</n></item><item><n>z</n></item><c\d>bs</c\d></root>`
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{`root.a\|b`, "hi"},
+		{`root.a\|b|@this`, "hi"},
+		{`root['a|b']|@this`, "hi"},
+		{"root." + EscapeName("a|b"), "hi"},
+		{`root.a|@this`, "no"},
+		{`root.c\\d`, "bs"},
+		{`root.item.#(n==x\|y).n`, "x|y"},
+		{`root.item.#(n=="x|y").n`, "x|y"},
+		{`root.item.#(n!=x\|y)#.n`, "z"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := Get(xml, tt.path).String(); got != tt.want {
+				t.Errorf("Get(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+
+	result, err := Set(`<root/>`, `root.a\|b`, "v")
+	if err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if want := `<root><a|b>v</a|b></root>`; result != want {
+		t.Errorf("Set() = %q, want %q", result, want)
+	}
+}
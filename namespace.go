@@ -0,0 +1,254 @@
+// SPDX-License-Identifier: MIT
+// Copyright (c) 2025 Daniel Schmidt
+
+package xmldot
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+// declaredPrefixes returns the namespace prefixes declared (xmlns:prefix) on
+// the existing elements that will enclose a value written at segments, i.e.
+// the target element and its ancestors. Elements the write would create
+// declare nothing, and an append (-1) index starts a new sibling, so the
+// element before it does not enclose the value.
+func declaredPrefixes(xml []byte, segments []PathSegment, opts *Options) map[string]bool {
+	declared := map[string]bool{}
+
+	// The value is written inside the element holding a final attribute
+	end := len(segments)
+	for end > 0 && (segments[end-1].Type == SegmentAttribute || segments[end-1].Type == SegmentText) {
+		end--
+	}
+
+	for i := 1; i <= end; i++ {
+		if segments[i-1].Type == SegmentIndex && segments[i-1].Index < 0 {
+			continue
+		}
+		if i < end && segments[i].Type == SegmentIndex && segments[i].Index < 0 {
+			continue
+		}
+		result := executeQueryWithOptions(newXMLParser(xml), segments[:i], 0, opts)
+		if result.Type != Element {
+			continue
+		}
+		for _, attr := range result.Attributes() {
+			if prefix, ok := strings.CutPrefix(attr.Name, "xmlns:"); ok {
+				declared[prefix] = true
+			}
+		}
+	}
+	return declared
+}
+
+// unboundPrefix is a namespace prefix used in a fragment without a
+// declaration in scope.
+type unboundPrefix struct {
+	prefix  string
+	rootTag int // offset just past the name of the top-level element using it
+}
+
+// unboundPrefixes returns the namespace prefixes used by element and
+// attribute names in fragment that are neither in declared nor declared by
+// the fragment itself on the element or an enclosing one. Each prefix is
+// reported once per top-level element. The reserved xml and xmlns prefixes
+// are always bound.
+func unboundPrefixes(fragment string, declared map[string]bool) []unboundPrefix {
+	inScope := make(map[string]int, len(declared))
+	for prefix := range declared {
+		inScope[prefix] = 1
+	}
+
+	var unbound []unboundPrefix
+	var rootTag int
+	var scopes [][]string // prefixes bound by each open element
+	use := func(prefix string) {
+		if prefix == "" || prefix == "xml" || prefix == "xmlns" || inScope[prefix] > 0 {
+			return
+		}
+		unbound = append(unbound, unboundPrefix{prefix: prefix, rootTag: rootTag})
+		// A declaration on the top-level element covers the rest of it
+		inScope[prefix]++
+		scopes[0] = append(scopes[0], prefix)
+	}
+
+	decoder := xml.NewDecoder(strings.NewReader(fragment))
+	decoder.Strict = false
+	for {
+		tokenStart := decoder.InputOffset()
+		token, err := decoder.RawToken()
+		if err != nil {
+			// The fragment has already been checked for balanced tags
+			return unbound
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			if len(scopes) == 0 {
+				rootTag = int(tokenStart) + 1 + len(qualifiedName(t.Name))
+			}
+			var own []string
+			for _, attr := range t.Attr {
+				if attr.Name.Space == "xmlns" {
+					inScope[attr.Name.Local]++
+					own = append(own, attr.Name.Local)
+				}
+			}
+			scopes = append(scopes, own)
+			use(t.Name.Space)
+			for _, attr := range t.Attr {
+				use(attr.Name.Space)
+			}
+		case xml.EndElement:
+			if len(scopes) == 0 {
+				return unbound
+			}
+			for _, prefix := range scopes[len(scopes)-1] {
+				inScope[prefix]--
+			}
+			scopes = scopes[:len(scopes)-1]
+		}
+	}
+}
+
+// xmlNamespaceURI is the namespace the reserved xml prefix is bound to.
+const xmlNamespaceURI = "http://www.w3.org/XML/1998/namespace"
+
+// checkNamespaceDeclaration rejects a write to an xmlns attribute that is
+// not a valid namespace declaration: a prefixed declaration (xmlns:prefix)
+// needs a prefix and a non-empty URI, xmlns:xmlns cannot be declared, and
+// xmlns:xml may only bind the XML namespace. The default namespace (xmlns)
+// accepts any value, including "" to undeclare it. Only string values are
+// checked against the URI rules.
+func checkNamespaceDeclaration(path []PathSegment, value interface{}) error {
+	last := path[len(path)-1]
+	if last.Type != SegmentAttribute {
+		return nil
+	}
+	prefix, ok := strings.CutPrefix(last.Value, "xmlns:")
+	if !ok {
+		return nil
+	}
+	if prefix == "" {
+		return fmt.Errorf("%w: namespace declaration without a prefix", ErrInvalidPath)
+	}
+	if prefix == "xmlns" {
+		return fmt.Errorf("%w: the xmlns prefix cannot be declared", ErrInvalidValue)
+	}
+	uri, ok := value.(string)
+	if !ok {
+		return nil
+	}
+	switch {
+	case prefix == "xml" && uri != xmlNamespaceURI:
+		return fmt.Errorf("%w: the xml prefix can only be bound to %s", ErrInvalidValue, xmlNamespaceURI)
+	case uri == "":
+		return fmt.Errorf("%w: namespace prefix %q cannot be bound to an empty URI", ErrInvalidValue, prefix)
+	}
+	return nil
+}
+
+// rawFragment returns the markup of a value written without escaping: a
+// []byte fragment or an Element Result.
+func rawFragment(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case []byte:
+		return string(v), true
+	case Result:
+		if v.Type == Element {
+			return v.Raw, true
+		}
+	}
+	return "", false
+}
+
+// pathDeclarations implements Options.AutoDeclareNamespaces for the names in
+// a path: it returns xmlns markup for the prefixes of element and attribute
+// segments that have a URI in namespaces but are not in declared, and adds
+// those prefixes to declared.
+func pathDeclarations(segments []PathSegment, declared map[string]bool, namespaces map[string]string) string {
+	var decls strings.Builder
+	for _, seg := range segments {
+		if seg.Type != SegmentElement && seg.Type != SegmentAttribute {
+			continue
+		}
+		prefix, _ := splitNamespace(seg.Value)
+		if prefix == "" || prefix == "xmlns" || declared[prefix] {
+			continue
+		}
+		if uri, ok := namespaces[prefix]; ok {
+			writeDeclaration(&decls, prefix, uri)
+			declared[prefix] = true
+		}
+	}
+	return decls.String()
+}
+
+// declareFragmentNamespaces implements Options.AutoDeclareNamespaces for a
+// raw fragment: each top-level element using a prefix that is not declared
+// but has a URI in namespaces gets an xmlns declaration for it.
+func declareFragmentNamespaces(fragment string, declared map[string]bool, namespaces map[string]string) string {
+	var out strings.Builder
+	last := 0
+	for _, u := range unboundPrefixes(fragment, declared) {
+		uri, ok := namespaces[u.prefix]
+		if !ok {
+			continue
+		}
+		out.WriteString(fragment[last:u.rootTag])
+		writeDeclaration(&out, u.prefix, uri)
+		last = u.rootTag
+	}
+	if last == 0 {
+		return fragment
+	}
+	out.WriteString(fragment[last:])
+	return out.String()
+}
+
+// writeDeclaration writes ` xmlns:prefix="uri"`.
+func writeDeclaration(sb *strings.Builder, prefix, uri string) {
+	sb.WriteString(" xmlns:")
+	sb.WriteString(prefix)
+	sb.WriteString(`="`)
+	sb.WriteString(escapeXML(uri))
+	sb.WriteString(`"`)
+}
+
+// declareNamespaces applies Options.AutoDeclareNamespaces and
+// Options.RequireDeclaredNamespaces to a value written at segments. It
+// returns the value, with declarations added to a raw fragment, and the
+// declarations the builder must place on the first element it creates or
+// the element receiving an attribute.
+func declareNamespaces(xml []byte, segments []PathSegment, value interface{}, opts *Options) (interface{}, string, error) {
+	fragment, isFragment := rawFragment(value)
+	needsFragment := isFragment && strings.Contains(fragment, ":")
+	if len(opts.AutoDeclareNamespaces) == 0 && !(opts.RequireDeclaredNamespaces && needsFragment) {
+		return value, "", nil
+	}
+
+	declared := declaredPrefixes(xml, segments, opts)
+	var decls string
+	if len(opts.AutoDeclareNamespaces) > 0 {
+		decls = pathDeclarations(segments, declared, opts.AutoDeclareNamespaces)
+		if needsFragment {
+			if declaredFragment := declareFragmentNamespaces(fragment, declared, opts.AutoDeclareNamespaces); declaredFragment != fragment {
+				fragment = declaredFragment
+				if r, ok := value.(Result); ok {
+					r.Raw = fragment
+					value = r
+				} else {
+					value = []byte(fragment)
+				}
+			}
+		}
+	}
+
+	if opts.RequireDeclaredNamespaces && needsFragment {
+		if unbound := unboundPrefixes(fragment, declared); len(unbound) > 0 {
+			return value, "", fmt.Errorf("%w: namespace prefix %q is not declared", ErrInvalidValue, unbound[0].prefix)
+		}
+	}
+	return value, decls, nil
+}
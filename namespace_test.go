@@ -4,6 +4,7 @@
 package xmldot
 
 import (
+	"errors"
 	"strings"
 	"testing"
 )
@@ -201,6 +202,39 @@ func TestWildcardUnprefixedMatchesNamespaced(t *testing.T) {
 	}
 }
 
+// TestNamespacePrefixWildcard verifies ns:* matches any element with that prefix
+func TestNamespacePrefixWildcard(t *testing.T) {
+	xml := `<soap:Envelope><soap:Header>h</soap:Header><soap:Body>b</soap:Body><Footer>f</Footer><other:Body>o</other:Body></soap:Envelope>`
+
+	result := Get(xml, "soap:Envelope.soap:*")
+	if result.Type != Array {
+		t.Fatalf("Expected array result, got: %v", result.Type)
+	}
+	if len(result.Results) != 2 {
+		t.Fatalf("Expected 2 soap: children, got: %d", len(result.Results))
+	}
+	if result.Results[0].String() != "h" || result.Results[1].String() != "b" {
+		t.Errorf("Expected [h b], got: [%s %s]", result.Results[0].String(), result.Results[1].String())
+	}
+
+	// *:name matches prefixed elements only
+	result = Get(xml, "soap:Envelope.*:Body")
+	if len(result.Array()) != 2 {
+		t.Errorf("Expected 2 prefixed Body elements, got: %d", len(result.Array()))
+	}
+
+	// Prefix wildcard after recursive wildcard
+	result = Get(`<root><a><soap:X>1</soap:X></a><soap:Y>2</soap:Y></root>`, "root.**.soap:*")
+	if len(result.Array()) != 2 {
+		t.Errorf("Expected 2 recursive soap: matches, got: %d", len(result.Array()))
+	}
+
+	// No matches for an unknown prefix
+	if Get(xml, "soap:Envelope.wsse:*").Exists() {
+		t.Error("Expected no match for unknown prefix")
+	}
+}
+
 // TestFilterNamespacedElements verifies filters work with namespaced elements
 func TestFilterNamespacedElements(t *testing.T) {
 	xml := `<ns:items>
@@ -332,3 +366,92 @@ func TestUnprefixedCaseInsensitiveNamespace(t *testing.T) {
 		t.Errorf("Expected 'content', got: %s", result.String())
 	}
 }
+
+// TestSetNamespaceDeclarations verifies that xmlns declarations can be added,
+// changed and removed like other attributes
+func TestSetNamespaceDeclarations(t *testing.T) {
+	xml := `<soap:Envelope xmlns="urn:default"><soap:Body/></soap:Envelope>`
+	const soapURI = "http://schemas.xmlsoap.org/soap/envelope/"
+
+	result, err := Set(xml, "soap:Envelope.@xmlns:soap", soapURI)
+	if err != nil {
+		t.Fatalf("Set(@xmlns:soap) error = %v", err)
+	}
+	want := `<soap:Envelope xmlns="urn:default" xmlns:soap="` + soapURI + `"><soap:Body/></soap:Envelope>`
+	if result != want {
+		t.Errorf("Set(@xmlns:soap) = %s, want %s", result, want)
+	}
+	if got := Get(result, "soap:Envelope.@xmlns:soap").String(); got != soapURI {
+		t.Errorf("Get(@xmlns:soap) = %q, want %q", got, soapURI)
+	}
+
+	// URIs are escaped once, and reading one back and writing it again is stable
+	result, err = Set(result, "soap:Envelope.@xmlns:q", "http://example.com/?a=1&b=2")
+	if err != nil {
+		t.Fatalf("Set(@xmlns:q) error = %v", err)
+	}
+	if !strings.Contains(result, `xmlns:q="http://example.com/?a=1&amp;b=2"`) {
+		t.Errorf("Set(@xmlns:q) = %s", result)
+	}
+	again, err := Set(result, "soap:Envelope.@xmlns:q", Get(result, "soap:Envelope.@xmlns:q").String())
+	if err != nil || again != result {
+		t.Errorf("Set(@xmlns:q) round trip = %s, %v", again, err)
+	}
+
+	// The default namespace can be changed
+	result, err = Set(result, "soap:Envelope.@xmlns", "urn:other")
+	if err != nil || !strings.Contains(result, ` xmlns="urn:other"`) {
+		t.Errorf("Set(@xmlns) = %s, %v", result, err)
+	}
+
+	// Declarations are removed with Delete
+	result, err = Delete(result, "soap:Envelope.@xmlns:q")
+	if err != nil {
+		t.Fatalf("Delete(@xmlns:q) error = %v", err)
+	}
+	if strings.Contains(result, "xmlns:q") || !strings.Contains(result, "xmlns:soap") {
+		t.Errorf("Delete(@xmlns:q) = %s", result)
+	}
+
+	// A declaration can create the element that holds it
+	result, err = Set("", "feed.@xmlns:atom", "http://www.w3.org/2005/Atom")
+	if err != nil || result != `<feed xmlns:atom="http://www.w3.org/2005/Atom"/>` {
+		t.Errorf("Set() new element = %s, %v", result, err)
+	}
+}
+
+// TestSetNamespaceDeclarations_Invalid verifies that writes producing invalid
+// namespace declarations are rejected
+func TestSetNamespaceDeclarations_Invalid(t *testing.T) {
+	xml := `<root/>`
+	tests := []struct {
+		name  string
+		path  string
+		value interface{}
+		want  error
+	}{
+		{"missing prefix", "root.@xmlns:", "urn:a", ErrInvalidPath},
+		{"empty URI", "root.@xmlns:a", "", ErrInvalidValue},
+		{"xmlns prefix", "root.@xmlns:xmlns", "urn:a", ErrInvalidValue},
+		{"xml prefix rebound", "root.@xmlns:xml", "urn:a", ErrInvalidValue},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Set(xml, tt.path, tt.value)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Set() error = %v, want %v", err, tt.want)
+			}
+			if result != xml {
+				t.Errorf("Set() = %s, want input unchanged", result)
+			}
+		})
+	}
+
+	// The default namespace may be undeclared, and xml may bind its own URI
+	if _, err := Set(xml, "root.@xmlns", ""); err != nil {
+		t.Errorf("Set(@xmlns, \"\") error = %v", err)
+	}
+	if _, err := Set(xml, "root.@xmlns:xml", xmlNamespaceURI); err != nil {
+		t.Errorf("Set(@xmlns:xml) error = %v", err)
+	}
+}
@@ -3,6 +3,23 @@
 
 package xmldot
 
+import "fmt"
+
+// RecursiveOrder selects the order in which recursive wildcard (**) matches
+// are returned.
+type RecursiveOrder int
+
+const (
+	// DepthFirst returns matches in document order (a pre-order depth-first
+	// walk): each element comes before its descendants, which come before
+	// its following siblings.
+	DepthFirst RecursiveOrder = iota
+
+	// BreadthFirst returns shallower matches before deeper ones. Matches at
+	// the same depth keep their document order.
+	BreadthFirst
+)
+
 // Options configures xmldot behavior for advanced use cases.
 // Zero value (Options{}) uses default safe behavior.
 //
@@ -20,6 +37,10 @@ type Options struct {
 	CaseSensitive bool
 
 	// Indent specifies indentation for formatted output (Set operations).
+	// Elements created in a document formatted one element per line copy
+	// their previous sibling's indentation; Indent is used for the first
+	// child of an element and for each level of a newly created chain,
+	// including inside a self-closing or empty element.
 	// Empty string (default) preserves original formatting.
 	// Use "  " or "\t" for pretty printing.
 	Indent string
@@ -32,6 +53,188 @@ type Options struct {
 	// Namespaces maps namespace prefixes to URIs (future use).
 	// Phase 6: Reserved for future implementation.
 	Namespaces map[string]string
+
+	// NormalizeNewlines converts CRLF and lone CR line endings to LF in the
+	// output of write operations (SetWithOptions, DeleteBytesWithOptions, ...).
+	// CDATA sections are copied verbatim. Reading is unaffected.
+	// Default: false (line endings are preserved as written)
+	NormalizeNewlines bool
+
+	// RejectDuplicateAttributes rejects documents in which an element declares
+	// the same attribute more than once (e.g. <item a="1" a="2">). Write
+	// operations return ErrMalformedXML and GetWithOptions returns Null.
+	// It is independent of Strict, which only affects queries and does not
+	// look for repeated attributes: this check also guards writes and
+	// ValidWithOptions. Set both to reject every malformed document in
+	// queries.
+	// Default: false (duplicates are tolerated and the first value wins)
+	RejectDuplicateAttributes bool
+
+	// MaxAttributes is the per-element attribute limit checked when
+	// AttributeOverflowError is set. Zero or values above the package-level
+	// MaxAttributes use MaxAttributes.
+	// Default: 0 (use MaxAttributes)
+	MaxAttributes int
+
+	// MaxNestingDepth is the element nesting limit for this call, for
+	// documents that are legitimately deeper than the package-level
+	// MaxNestingDepth. Paths may then have up to MaxNestingDepth+1 segments,
+	// so the deepest elements can be addressed, and recursive wildcards (**)
+	// descend as far. With Strict, QueryWithOptions returns ErrLimitExceeded
+	// for a document nested deeper than the limit; ValidWithOptions checks
+	// it as well. Zero or negative values use MaxNestingDepth.
+	// Default: 0 (use MaxNestingDepth)
+	MaxNestingDepth int
+
+	// AttributeOverflowError rejects documents in which an element has more
+	// attributes than the limit, instead of ignoring the excess attributes.
+	// Write operations return ErrLimitExceeded and GetWithOptions returns Null.
+	// Default: false (attributes beyond MaxAttributes are silently ignored)
+	AttributeOverflowError bool
+
+	// RecursiveOrder controls the order of recursive wildcard (**) matches,
+	// which matters when results are combined with @first or @last.
+	// Default: DepthFirst (document order)
+	RecursiveOrder RecursiveOrder
+
+	// RecursiveOverflowError reports a recursive wildcard (**) search that
+	// stopped at MaxRecursiveOperations or MaxWildcardResults instead of
+	// returning the partial matches found so far. QueryWithOptions returns ErrLimitExceeded and
+	// GetWithOptions returns Null.
+	// Default: false (partial results are returned silently)
+	RecursiveOverflowError bool
+
+	// BlankIsAbsent makes GetWithOptions treat elements that are empty or hold
+	// only whitespace (<item/>, <item>  </item>) as missing: such a result
+	// is Null, so Exists() is false, and such elements are left out of
+	// Array results. Counts (#) and filters are unaffected.
+	// Default: false (blank elements exist with an empty String)
+	BlankIsAbsent bool
+
+	// TimeLayout is the time.Format layout used when a time.Time value is
+	// written by SetWithOptions and related functions.
+	// Default: "" (time.RFC3339)
+	TimeLayout string
+
+	// FloatFormat is the fmt verb used when a float32 or float64 value is
+	// written, e.g. "%.2f" for prices. Verbs that do not format a float
+	// return ErrInvalidValue.
+	// Default: "" (%g, the shortest exact representation)
+	FloatFormat string
+
+	// DisableCache parses the path afresh on every call instead of using the
+	// shared path cache, and does not add the path to it. Use it for
+	// reproducible benchmarks, and when paths come from untrusted input so
+	// that throwaway paths cannot evict the application's own.
+	// Default: false (parsed paths are cached)
+	DisableCache bool
+
+	// RequireDeclaredNamespaces rejects raw XML written by SetRawWithOptions
+	// (or SetWithOptions with a []byte or Element Result value) that uses a
+	// namespace prefix not declared in the fragment itself or on the existing
+	// elements enclosing the target. The error wraps ErrInvalidValue and
+	// names the prefix.
+	// Default: false (prefixes are not checked)
+	RequireDeclaredNamespaces bool
+
+	// AutoDeclareNamespaces maps namespace prefixes to URIs that
+	// SetWithOptions and SetRawWithOptions declare when a write introduces a
+	// prefixed element or attribute whose prefix is not declared yet: the
+	// xmlns:prefix attribute is added to the first element the write
+	// creates, to the element receiving the attribute, or to each top-level
+	// element of a raw fragment. Prefixes without an entry are left alone.
+	// Default: nil (no declarations are added)
+	AutoDeclareNamespaces map[string]string
+
+	// SelfCloseEmpty writes elements that SetWithOptions creates without
+	// content, or empties by setting "", in self-closing form (<item/>)
+	// instead of as an empty pair (<item></item>). Existing elements the
+	// write does not touch keep their form.
+	// Default: false (empty elements are written as <item></item>)
+	SelfCloseEmpty bool
+
+	// PreserveInnerComments keeps the comments and processing instructions
+	// that are direct children of an element whose content SetWithOptions
+	// replaces. Those that come before any text or child element are written
+	// before the new value, the others after it. Comments inside replaced
+	// child elements are removed with them.
+	// Default: false (the whole content is replaced)
+	PreserveInnerComments bool
+
+	// RenameOverwrite lets RenameAttributeWithOptions rename an attribute onto
+	// a name the element already uses: the existing attribute is removed and
+	// the renamed one keeps its value and position.
+	// Default: false (such a rename returns ErrInvalidValue)
+	RenameOverwrite bool
+
+	// Strict makes QueryWithOptions report problems with the input as errors
+	// instead of answering from whatever could be parsed: a document that is
+	// not well-formed, such as one with an unterminated processing
+	// instruction, returns ErrMalformedXML with the position of the problem,
+	// and a path naming an unknown modifier (|@nonsense) returns
+	// ErrUnknownModifier naming it. GetWithOptions returns Null for both.
+	// Repeated attributes are not detected; see RejectDuplicateAttributes.
+	// Default: false (best-effort results from malformed documents)
+	Strict bool
+
+	// RawText makes % (element.%, #.% and #(condition)#.%) return the direct
+	// text as written in the document, with entity references such as &amp;
+	// kept, for re-emitting it into other XML without escaping it twice.
+	// Default: false (the text is decoded: Tom &amp; Jerry reads as Tom & Jerry)
+	RawText bool
+
+	// SortAttributes makes writes emit the attributes of every element they
+	// change in sorted order by name: elements whose attributes or content
+	// are set or deleted, and attributes renamed with
+	// RenameAttributeWithOptions. The output is then independent of the
+	// order in which attributes were added. Raw fragments are written as
+	// given, and untouched elements keep their markup.
+	// Default: false (existing attributes keep their order and new ones are
+	// added last)
+	SortAttributes bool
+
+	// TextOnlySet makes SetWithOptions with a text value (a string, number,
+	// bool, or anything else written as escaped text) replace only the
+	// element's direct text, keeping its child elements, comments and the
+	// whitespace between them, for editing text around inline markup. The
+	// first run of direct text takes the new value and later runs are
+	// removed; without direct text the value is inserted before the
+	// children. Raw XML values still replace the whole content.
+	// Default: false (the element's whole content is replaced, children
+	// included)
+	TextOnlySet bool
+
+	// PreserveCDATA controls whether the @pretty and @ugly modifiers of
+	// GetWithOptions keep CDATA sections (<![CDATA[...]]>) as written. Set it
+	// to false to re-emit their content as escaped character data (a < b
+	// becomes a &lt; b) instead:
+	//
+	//	escape := false
+	//	opts.PreserveCDATA = &escape
+	//
+	// It is a pointer so that Options literals which leave it out keep CDATA
+	// sections, like nil Options and DefaultOptions.
+	// Default: nil (CDATA sections are kept, as with true)
+	PreserveCDATA *bool
+
+	// AllowFragment accepts documents with more than one root element
+	// (<a/><b/>), as produced by templating systems that omit a wrapper
+	// element, in ValidWithOptions and in Strict queries. Without it those
+	// report the second root as malformed. Paths address the top-level
+	// siblings of a fragment with #root.0, #root.1 and so on. Functions
+	// without Options, such as Valid and Get, always accept fragments.
+	// Default: false (a document has a single root element)
+	AllowFragment bool
+
+	// state holds per-query bookkeeping on a private copy of the caller's
+	// Options; it is never set on Options passed in by callers.
+	state *queryState
+}
+
+// queryState records conditions found while executing a single query that
+// must be reported once the query completes.
+type queryState struct {
+	recursiveOverflow error
 }
 
 // DefaultOptions returns a pointer to Options with recommended defaults.
@@ -42,6 +245,29 @@ type Options struct {
 //   - Indent: "" (preserve original formatting)
 //   - PreserveWhitespace: false (trim whitespace)
 //   - Namespaces: nil (no namespace mapping)
+//   - NormalizeNewlines: false (preserve line endings)
+//   - RejectDuplicateAttributes: false (first duplicate attribute wins)
+//   - MaxAttributes: 0 (use the package-level MaxAttributes)
+//   - MaxNestingDepth: 0 (use the package-level MaxNestingDepth)
+//   - AttributeOverflowError: false (ignore attributes beyond the limit)
+//   - RecursiveOrder: DepthFirst (recursive matches in document order)
+//   - RecursiveOverflowError: false (return partial recursive matches)
+//   - BlankIsAbsent: false (blank elements exist)
+//   - TimeLayout: "" (format time.Time values as RFC 3339)
+//   - FloatFormat: "" (format floats with %g)
+//   - DisableCache: false (use the path cache)
+//   - RequireDeclaredNamespaces: false (do not check fragment prefixes)
+//   - AutoDeclareNamespaces: nil (do not add namespace declarations)
+//   - SelfCloseEmpty: false (write empty elements as <item></item>)
+//   - PreserveInnerComments: false (replace comments with the content)
+//   - RenameOverwrite: false (renaming onto an existing attribute fails)
+//   - Strict: false (query malformed documents on a best-effort basis)
+//   - RawText: false (decode entity references in % text)
+//   - SortAttributes: false (keep attribute order when writing)
+//   - TextOnlySet: false (setting text replaces the whole content)
+//   - PreserveCDATA: nil (@pretty and @ugly keep CDATA sections)
+//   - AllowFragment: false (ValidWithOptions and Strict queries require a
+//     single root element)
 //
 // Example:
 //
@@ -50,10 +276,32 @@ type Options struct {
 //	result := GetWithOptions(xml, path, opts)
 func DefaultOptions() *Options {
 	return &Options{
-		CaseSensitive:      true,
-		Indent:             "",
-		PreserveWhitespace: false,
-		Namespaces:         nil,
+		CaseSensitive:             true,
+		Indent:                    "",
+		PreserveWhitespace:        false,
+		Namespaces:                nil,
+		NormalizeNewlines:         false,
+		RejectDuplicateAttributes: false,
+		MaxAttributes:             0,
+		MaxNestingDepth:           0,
+		AttributeOverflowError:    false,
+		RecursiveOrder:            DepthFirst,
+		RecursiveOverflowError:    false,
+		BlankIsAbsent:             false,
+		TimeLayout:                "",
+		FloatFormat:               "",
+		DisableCache:              false,
+		RequireDeclaredNamespaces: false,
+		AutoDeclareNamespaces:     nil,
+		SelfCloseEmpty:            false,
+		PreserveInnerComments:     false,
+		RenameOverwrite:           false,
+		Strict:                    false,
+		RawText:                   false,
+		SortAttributes:            false,
+		TextOnlySet:               false,
+		PreserveCDATA:             nil,
+		AllowFragment:             false,
 	}
 }
 
@@ -67,5 +315,67 @@ func isDefaultOptions(opts *Options) bool {
 	return opts.CaseSensitive &&
 		opts.Indent == "" &&
 		!opts.PreserveWhitespace &&
-		opts.Namespaces == nil
+		opts.Namespaces == nil &&
+		!opts.NormalizeNewlines &&
+		!opts.RejectDuplicateAttributes &&
+		opts.MaxAttributes == 0 &&
+		opts.MaxNestingDepth == 0 &&
+		!opts.AttributeOverflowError &&
+		opts.RecursiveOrder == DepthFirst &&
+		!opts.RecursiveOverflowError &&
+		!opts.BlankIsAbsent &&
+		opts.TimeLayout == "" &&
+		opts.FloatFormat == "" &&
+		!opts.DisableCache &&
+		!opts.RequireDeclaredNamespaces &&
+		opts.AutoDeclareNamespaces == nil &&
+		!opts.SelfCloseEmpty &&
+		!opts.PreserveInnerComments &&
+		!opts.RenameOverwrite &&
+		!opts.Strict &&
+		!opts.RawText &&
+		!opts.SortAttributes &&
+		!opts.TextOnlySet &&
+		opts.PreserveCDATA == nil &&
+		!opts.AllowFragment
+}
+
+// attributeLimit returns the effective per-element attribute limit.
+func (opts *Options) attributeLimit() int {
+	if opts.MaxAttributes <= 0 || opts.MaxAttributes > MaxAttributes {
+		return MaxAttributes
+	}
+	return opts.MaxAttributes
+}
+
+// nestingLimit returns the effective element nesting limit. nil options use
+// MaxNestingDepth.
+func (opts *Options) nestingLimit() int {
+	if opts == nil || opts.MaxNestingDepth <= 0 {
+		return MaxNestingDepth
+	}
+	return opts.MaxNestingDepth
+}
+
+// preserveCDATA reports whether reformatting keeps CDATA sections. nil
+// options, and an unset PreserveCDATA, keep them.
+func (opts *Options) preserveCDATA() bool {
+	return opts == nil || opts.PreserveCDATA == nil || *opts.PreserveCDATA
+}
+
+// checkAttributeOptions applies the strict attribute checks requested by opts
+// to a whole document. nil options request no checks.
+func checkAttributeOptions(xml []byte, opts *Options) error {
+	if opts == nil {
+		return nil
+	}
+	if opts.AttributeOverflowError {
+		if limit := opts.attributeLimit(); exceedsAttributeLimit(xml, limit) {
+			return fmt.Errorf("%w: element has more than %d attributes", ErrLimitExceeded, limit)
+		}
+	}
+	if opts.RejectDuplicateAttributes && hasDuplicateAttributes(xml) {
+		return fmt.Errorf("%w: duplicate attribute", ErrMalformedXML)
+	}
+	return nil
 }
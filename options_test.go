@@ -4,6 +4,7 @@
 package xmldot
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 	"testing"
@@ -27,6 +28,12 @@ func TestDefaultOptions(t *testing.T) {
 	if opts.Namespaces != nil {
 		t.Error("Expected Namespaces to be nil")
 	}
+	if opts.PreserveCDATA != nil {
+		t.Error("Expected PreserveCDATA to be nil")
+	}
+	if opts.AllowFragment {
+		t.Error("Expected AllowFragment to be false")
+	}
 }
 
 func TestOptionsStructInitialization(t *testing.T) {
@@ -89,6 +96,8 @@ func TestOptionsAllFieldsSet(t *testing.T) {
 }
 
 func TestIsDefaultOptions(t *testing.T) {
+	escapeCDATA := false
+
 	tests := []struct {
 		name     string
 		opts     *Options
@@ -129,6 +138,116 @@ func TestIsDefaultOptions(t *testing.T) {
 			opts:     &Options{CaseSensitive: true, Namespaces: map[string]string{}},
 			expected: false,
 		},
+		{
+			name:     "with newline normalization",
+			opts:     &Options{CaseSensitive: true, NormalizeNewlines: true},
+			expected: false,
+		},
+		{
+			name:     "rejecting duplicate attributes",
+			opts:     &Options{CaseSensitive: true, RejectDuplicateAttributes: true},
+			expected: false,
+		},
+		{
+			name:     "with max attributes",
+			opts:     &Options{CaseSensitive: true, MaxAttributes: 10},
+			expected: false,
+		},
+		{
+			name:     "with attribute overflow error",
+			opts:     &Options{CaseSensitive: true, AttributeOverflowError: true},
+			expected: false,
+		},
+		{
+			name:     "with breadth-first recursive order",
+			opts:     &Options{CaseSensitive: true, RecursiveOrder: BreadthFirst},
+			expected: false,
+		},
+		{
+			name:     "with recursive overflow error",
+			opts:     &Options{CaseSensitive: true, RecursiveOverflowError: true},
+			expected: false,
+		},
+		{
+			name:     "with blank is absent",
+			opts:     &Options{CaseSensitive: true, BlankIsAbsent: true},
+			expected: false,
+		},
+		{
+			name:     "with time layout",
+			opts:     &Options{CaseSensitive: true, TimeLayout: "2006-01-02"},
+			expected: false,
+		},
+		{
+			name:     "with float format",
+			opts:     &Options{CaseSensitive: true, FloatFormat: "%.2f"},
+			expected: false,
+		},
+		{
+			name:     "with cache disabled",
+			opts:     &Options{CaseSensitive: true, DisableCache: true},
+			expected: false,
+		},
+		{
+			name:     "with declared namespaces required",
+			opts:     &Options{CaseSensitive: true, RequireDeclaredNamespaces: true},
+			expected: false,
+		},
+		{
+			name:     "with auto-declared namespaces",
+			opts:     &Options{CaseSensitive: true, AutoDeclareNamespaces: map[string]string{"ns": "urn:ns"}},
+			expected: false,
+		},
+		{
+			name:     "with self-closing empty elements",
+			opts:     &Options{CaseSensitive: true, SelfCloseEmpty: true},
+			expected: false,
+		},
+		{
+			name:     "with preserved inner comments",
+			opts:     &Options{CaseSensitive: true, PreserveInnerComments: true},
+			expected: false,
+		},
+		{
+			name:     "with rename overwrite",
+			opts:     &Options{CaseSensitive: true, RenameOverwrite: true},
+			expected: false,
+		},
+		{
+			name:     "with nesting depth limit",
+			opts:     &Options{CaseSensitive: true, MaxNestingDepth: 500},
+			expected: false,
+		},
+		{
+			name:     "with strict",
+			opts:     &Options{CaseSensitive: true, Strict: true},
+			expected: false,
+		},
+		{
+			name:     "with raw text",
+			opts:     &Options{CaseSensitive: true, RawText: true},
+			expected: false,
+		},
+		{
+			name:     "with sorted attributes",
+			opts:     &Options{CaseSensitive: true, SortAttributes: true},
+			expected: false,
+		},
+		{
+			name:     "with text-only set",
+			opts:     &Options{CaseSensitive: true, TextOnlySet: true},
+			expected: false,
+		},
+		{
+			name:     "with escaped CDATA",
+			opts:     &Options{CaseSensitive: true, PreserveCDATA: &escapeCDATA},
+			expected: false,
+		},
+		{
+			name:     "with fragments allowed",
+			opts:     &Options{CaseSensitive: true, AllowFragment: true},
+			expected: false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -272,6 +391,70 @@ func TestGetWithOptionsCaseInsensitiveRecursiveWildcard(t *testing.T) {
 	}
 }
 
+func TestGetWithOptionsRecursiveOrder(t *testing.T) {
+	xml := `<r><a id="1"><a id="2"><a id="3"/></a></a><b><a id="4"/></b><a id="5"/></r>`
+
+	tests := []struct {
+		name  string
+		path  string
+		order RecursiveOrder
+		want  string
+	}{
+		{"document order", "r.**.a.@id", DepthFirst, `["1","2","3","4","5"]`},
+		{"breadth first", "r.**.a.@id", BreadthFirst, `["1","5","2","4","3"]`},
+		{"document order first", "r.**.a.@id|@first", DepthFirst, "1"},
+		{"document order last", "r.**.a.@id|@last", DepthFirst, "5"},
+		{"breadth first last", "r.**.a.@id|@last", BreadthFirst, "3"},
+		{"breadth first reverse", "r.**.a.@id|@reverse", BreadthFirst, `["3","4","2","5","1"]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := &Options{CaseSensitive: true, RecursiveOrder: tt.order}
+			if got := GetWithOptions(xml, tt.path, opts).String(); got != tt.want {
+				t.Errorf("GetWithOptions(%q) = %s, want %s", tt.path, got, tt.want)
+			}
+		})
+	}
+
+	// Get without options uses document order
+	if got := Get(xml, "r.**.a.@id").String(); got != `["1","2","3","4","5"]` {
+		t.Errorf("Get() = %s, want document order", got)
+	}
+}
+
+func TestGetWithOptionsBlankIsAbsent(t *testing.T) {
+	xml := "<r><empty/><blank>  \n\t</blank><value>x</value><nested><c/></nested><item>a</item><item> </item></r>"
+	opts := &Options{CaseSensitive: true, BlankIsAbsent: true}
+
+	tests := []struct {
+		path   string
+		exists bool
+		want   string
+	}{
+		{"r.empty", false, ""},
+		{"r.blank", false, ""},
+		{"r.value", true, "x"},
+		{"r.nested", true, ""},
+		{"r.*", true, `["x","","a"]`},
+		{"r.item.#", true, "2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			result := GetWithOptions(xml, tt.path, opts)
+			if result.Exists() != tt.exists || result.String() != tt.want {
+				t.Errorf("GetWithOptions(%q) = %q (exists %v), want %q (exists %v)",
+					tt.path, result.String(), result.Exists(), tt.want, tt.exists)
+			}
+			// Without the option blank elements exist
+			if !Get(xml, tt.path).Exists() {
+				t.Errorf("Get(%q) should exist by default", tt.path)
+			}
+		})
+	}
+}
+
 func TestGetBytesWithOptions(t *testing.T) {
 	xml := []byte(`<ROOT><CHILD>value</CHILD></ROOT>`)
 
@@ -402,6 +585,49 @@ func TestSetWithOptionsPreserveStructure(t *testing.T) {
 	}
 }
 
+func TestSetWithOptionsNormalizeNewlines(t *testing.T) {
+	xml := "<root>\r\n  <a>1</a>\r\n  <b>2</b>\r  <c><![CDATA[x\r\ny]]></c>\r\n</root>"
+
+	t.Run("set", func(t *testing.T) {
+		opts := &Options{CaseSensitive: true, NormalizeNewlines: true}
+		result, err := SetWithOptions(xml, "root.a", "one", opts)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		expected := "<root>\n  <a>one</a>\n  <b>2</b>\n  <c><![CDATA[x\r\ny]]></c>\n</root>"
+		if result != expected {
+			t.Errorf("Expected %q, got %q", expected, result)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		opts := &Options{CaseSensitive: true, NormalizeNewlines: true}
+		result, err := DeleteBytesWithOptions([]byte(xml), "root.b", opts)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if strings.Contains(strings.ReplaceAll(string(result), "<![CDATA[x\r\ny]]>", ""), "\r") {
+			t.Errorf("Expected no CR outside CDATA, got %q", string(result))
+		}
+	})
+
+	t.Run("disabled by default", func(t *testing.T) {
+		result, err := Set(xml, "root.a", "one")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !strings.Contains(result, "<root>\r\n") {
+			t.Errorf("Expected line endings to be preserved, got %q", result)
+		}
+	})
+
+	t.Run("reading is transparent", func(t *testing.T) {
+		if got := Get(xml, "root.b").String(); got != "2" {
+			t.Errorf("Expected '2', got %q", got)
+		}
+	})
+}
+
 func TestSetBytesWithOptions(t *testing.T) {
 	xml := []byte(`<ROOT><USER><NAME>John</NAME></USER></ROOT>`)
 
@@ -612,3 +838,44 @@ func ExampleGetWithOptions_caseInsensitive() {
 	fmt.Printf("%s %s\n", firstName.String(), lastName.String())
 	// Output: John Doe
 }
+
+// TestGetWithOptionsDisableCache tests that DisableCache bypasses the path cache
+func TestGetWithOptionsDisableCache(t *testing.T) {
+	t.Cleanup(ClearPathCache)
+	ClearPathCache()
+
+	cached := func(path string) bool {
+		pathCacheMu.Lock()
+		defer pathCacheMu.Unlock()
+		_, ok := pathCache[path]
+		return ok
+	}
+
+	xml := `<root><Item id="1">first</Item></root>`
+	opts := &Options{CaseSensitive: true, DisableCache: true}
+
+	if got := GetWithOptions(xml, "root.Item", opts).String(); got != "first" {
+		t.Errorf("GetWithOptions() = %q, want first", got)
+	}
+	if got := GetWithOptions(xml, "ROOT.item.@id", &Options{DisableCache: true}).String(); got != "1" {
+		t.Errorf("case-insensitive GetWithOptions() = %q, want 1", got)
+	}
+	updated, err := SetWithOptions(xml, "root.Item", "second", opts)
+	if err != nil || GetWithOptions(updated, "root.Item", opts).String() != "second" {
+		t.Errorf("SetWithOptions() = %q, %v", updated, err)
+	}
+	if _, err := QueryWithOptions(xml, "", opts); !errors.Is(err, ErrInvalidPath) {
+		t.Errorf("QueryWithOptions(\"\") error = %v, want ErrInvalidPath", err)
+	}
+
+	for _, path := range []string{"root.Item", "ROOT.item.@id"} {
+		if cached(path) {
+			t.Errorf("path %q was cached despite DisableCache", path)
+		}
+	}
+
+	GetWithOptions(xml, "root.Item", &Options{CaseSensitive: true, Indent: "  "})
+	if !cached("root.Item") {
+		t.Error("path should be cached without DisableCache")
+	}
+}
@@ -4,6 +4,7 @@
 package xmldot
 
 import (
+	"bytes"
 	"strings"
 )
 
@@ -69,7 +70,10 @@ type xmlParser struct {
 	pos         int
 	depth       int
 	filterDepth int
-	dataLen     int // Cache data length to avoid repeated len() calls
+	dataLen     int    // Cache data length to avoid repeated len() calls
+	tag         []byte // Opening tag read by the last parseElementTag, for attribute Raw
+	tagStart    int    // Position of tag in data
+	base        int    // Offset of data in the queried document, -1 if unknown
 }
 
 // newXMLParser creates a new XML parser
@@ -82,6 +86,19 @@ func newXMLParser(data []byte) *xmlParser {
 	}
 }
 
+// reset prepares the parser to parse data from the beginning, allowing a
+// parser to be reused across documents
+func (p *xmlParser) reset(data []byte) {
+	p.data = data
+	p.pos = 0
+	p.depth = 0
+	p.filterDepth = 0
+	p.dataLen = len(data)
+	p.tag = nil
+	p.tagStart = 0
+	p.base = 0
+}
+
 // skipWhitespace advances the position past any whitespace characters
 // Optimized: Use cached dataLen and inline isWhitespace check
 func (p *xmlParser) skipWhitespace() {
@@ -164,9 +181,26 @@ func (p *xmlParser) readUntilAny(delims string) string {
 
 // parseAttributes extracts attributes from an element opening tag
 // Returns a map of attribute names to values
-// Optimized: Pre-allocate map with capacity hint to reduce allocations
 func (p *xmlParser) parseAttributes() map[string]string {
+	attrs, _ := p.parseAttributeList()
+	return attrs
+}
+
+// parseAttributeList extracts attributes from an element opening tag
+// Returns a map of attribute names to values and the attribute names in document order
+// An attribute declared more than once keeps its first value (first-wins).
+func (p *xmlParser) parseAttributeList() (map[string]string, []string) {
+	attrs, order, _ := p.parseAttributeListCounted()
+	return attrs, order
+}
+
+// parseAttributeListCounted is like parseAttributeList but also returns the
+// number of attribute declarations, which exceeds len(order) when a name is
+// declared more than once.
+// Optimized: Pre-allocate map with capacity hint to reduce allocations
+func (p *xmlParser) parseAttributeListCounted() (map[string]string, []string, int) {
 	attrs := make(map[string]string, 4) // Most elements have 0-4 attributes
+	var order []string
 	attrCount := 0
 
 	for {
@@ -193,6 +227,10 @@ func (p *xmlParser) parseAttributes() map[string]string {
 			break
 		}
 		attrCount++
+		_, duplicate := attrs[name]
+		if !duplicate {
+			order = append(order, name)
+		}
 
 		p.skipWhitespace()
 
@@ -210,26 +248,80 @@ func (p *xmlParser) parseAttributes() map[string]string {
 			p.next() // skip opening quote
 			value := p.readUntil(quote)
 			p.next() // skip closing quote
-			attrs[name] = unescapeXML(value)
+			if !duplicate {
+				attrs[name] = unescapeXML(value)
+			}
 		} else {
 			// Unquoted attribute value (read until whitespace or >)
 			value := p.readUntilAny(" \t\n\r/>")
-			attrs[name] = unescapeXML(value)
+			if !duplicate {
+				attrs[name] = unescapeXML(value)
+			}
 		}
 	}
 
-	return attrs
+	return attrs, order, attrCount
+}
+
+// exceedsAttributeLimit reports whether any start tag in data declares more
+// than limit attributes. limit must not exceed MaxAttributes.
+func exceedsAttributeLimit(data []byte, limit int) bool {
+	p := newXMLParser(data)
+	for p.skipToNextElement() {
+		p.next() // skip '<'
+		p.readUntilAny(" \t\n\r/>")
+		_, _, count := p.parseAttributeListCounted()
+		if count > limit {
+			return true
+		}
+		if count == MaxAttributes {
+			// Parsing stops at MaxAttributes; anything left in the tag is excess
+			p.skipWhitespace()
+			if c := p.peek(); p.pos < p.dataLen && c != '>' && c != '/' {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// hasDuplicateAttributes reports whether any start tag in data declares the
+// same attribute name more than once (e.g. <item a="1" a="2">).
+func hasDuplicateAttributes(data []byte) bool {
+	p := newXMLParser(data)
+	for p.skipToNextElement() {
+		p.next() // skip '<'
+		p.readUntilAny(" \t\n\r/>")
+		_, order, count := p.parseAttributeListCounted()
+		if count > len(order) {
+			return true
+		}
+	}
+	return false
 }
 
 // parseElementName extracts the element name and attributes from an opening tag
 // Assumes the parser is positioned after the '<' character
-// Returns: elementName, attributes, isSelfClosing, error
+// Returns: elementName, attributes, isSelfClosing
 func (p *xmlParser) parseElementName() (string, map[string]string, bool) {
+	name, attrs, _, isSelfClosing := p.parseElementTag()
+	return name, attrs, isSelfClosing
+}
+
+// parseElementTag is like parseElementName but also returns the attribute
+// names in document order, for callers that expose attributes to users.
+// The opening tag is left in p.tag until the next call.
+func (p *xmlParser) parseElementTag() (string, map[string]string, []string, bool) {
+	start := p.pos
+	if start > 0 && p.data[start-1] == '<' {
+		start--
+	}
+
 	// Read element name (until whitespace, '>', or '/')
 	name := p.readUntilAny(" \t\n\r/>")
 
 	// Parse attributes
-	attrs := p.parseAttributes()
+	attrs, order := p.parseAttributeList()
 
 	// Check for self-closing tag
 	isSelfClosing := false
@@ -243,106 +335,192 @@ func (p *xmlParser) parseElementName() (string, map[string]string, bool) {
 		p.next()
 	}
 
-	return name, attrs, isSelfClosing
+	p.tag = p.data[start:p.pos]
+	p.tagStart = start
+	return name, attrs, order, isSelfClosing
 }
 
-// parseElementContent extracts the content between opening and closing tags
-// Returns the text content, handling nested elements and escaping
+// span returns the document offsets of the element whose opening tag was
+// read by the last parseElementTag, once its content (if any) has been read.
+// The span is unknown (zero) if the parser does not know where its data lies
+// in the document.
+func (p *xmlParser) span() docSpan {
+	if p.base < 0 {
+		return docSpan{}
+	}
+	start := p.base + p.tagStart
+	return docSpan{start: start, content: start + len(p.tag), end: p.base + p.pos}
+}
+
+// newContentParser creates a parser over the content of the element at
+// span, so that the elements it reads are located in the document too.
+func newContentParser(content string, span docSpan) *xmlParser {
+	p := newXMLParser([]byte(content))
+	p.base = -1
+	if span.known() {
+		p.base = span.content
+	}
+	return p
+}
+
+// attributeSource returns the declaration of the attribute name in the
+// opening tag, exactly as written (e.g. id='1' or title="a &amp; b").
+// A repeated attribute returns its first declaration, like the attribute
+// maps. The boolean is false if tag does not declare name.
+func attributeSource(tag []byte, name string) (string, bool) {
+	p := newXMLParser(tag)
+	if p.peek() == '<' {
+		p.next()
+	}
+	p.readUntilAny(" \t\n\r/>")
+
+	for attrCount := 0; attrCount < MaxAttributes; attrCount++ {
+		p.skipWhitespace()
+		if p.pos >= p.dataLen || p.peek() == '>' || p.peek() == '/' {
+			break
+		}
+
+		start := p.pos
+		attrName := p.readUntilAny("= \t\n\r/>")
+		if attrName == "" {
+			break
+		}
+		p.skipWhitespace()
+		if p.peek() != '=' {
+			break
+		}
+		p.next()
+		p.skipWhitespace()
+
+		quote := p.peek()
+		if quote == '"' || quote == '\'' {
+			p.next() // skip opening quote
+			p.readUntil(quote)
+			p.next() // skip closing quote
+		} else {
+			p.readUntilAny(" \t\n\r/>")
+		}
+		if attrName == name {
+			return string(tag[start:p.pos]), true
+		}
+	}
+	return "", false
+}
+
+// parseElementContent extracts the content between opening and closing tags,
+// exactly as written in the document
 func (p *xmlParser) parseElementContent(elementName string) string {
+	start := p.pos
+	end := p.skipElementContent(elementName)
+	if end < start {
+		end = start
+	}
+	return string(p.data[start:end])
+}
+
+// processingInstructionEnd returns the position just past the processing
+// instruction that starts at data[pos] ("<?"). A PI ends at the first "?>".
+// An unterminated PI is taken to end before the next '<', so that the
+// elements after it are still found rather than swallowed.
+func processingInstructionEnd(data []byte, pos int) int {
+	start := pos + 2
+	if start > len(data) {
+		return len(data)
+	}
+	if i := bytes.Index(data[start:], []byte("?>")); i >= 0 {
+		return start + i + 2
+	}
+	if i := bytes.IndexByte(data[start:], '<'); i >= 0 {
+		return start + i
+	}
+	return len(data)
+}
+
+// skipElementContent advances past the content and closing tag of elementName
+// like parseElementContent, but without building the content string.
+// Returns the end position of the content (the '<' of the closing tag), so
+// callers can inspect p.data[start:end] without copying it.
+func (p *xmlParser) skipElementContent(elementName string) int {
 	// Track nesting depth to prevent stack overflow attacks
 	p.depth++
 	if p.depth > MaxNestingDepth {
-		// Exceeded maximum nesting depth - stop parsing and return empty
+		// Exceeded maximum nesting depth - same as parseElementContent returning ""
 		p.depth--
-		return ""
+		return p.pos
 	}
 	defer func() { p.depth-- }()
 
-	var content strings.Builder
-	// Track element-specific depth for matching tags
 	elementDepth := 1
 
-	for p.pos < p.dataLen && elementDepth > 0 {
-		c := p.peek()
-
-		if c == '<' {
-			// Check if this is a closing tag, opening tag, or comment/CDATA
-			if p.pos+1 < p.dataLen {
-				next := p.data[p.pos+1]
-
-				if next == '/' {
-					// Closing tag
-					p.next() // skip '<'
-					p.next() // skip '/'
-					closeName := p.readUntil('>')
-					p.next() // skip '>'
-
-					// Extract just the element name (before any whitespace)
-					closeName = strings.TrimSpace(closeName)
-					if idx := strings.IndexAny(closeName, " \t\n\r"); idx >= 0 {
-						closeName = closeName[:idx]
-					}
-
-					if closeName == elementName {
-						elementDepth--
-						if elementDepth == 0 {
-							break
-						}
-					}
-					content.WriteString("</")
-					content.WriteString(closeName)
-					content.WriteString(">")
-				} else if next == '!' {
-					// Comment or CDATA - include in content for now
-					content.WriteByte(c)
-					p.next()
-				} else {
-					// Opening tag of nested element
-					p.next() // skip '<'
-					nestedName := p.readUntilAny(" \t\n\r/>")
-
-					// Write the opening tag
-					content.WriteString("<")
-					content.WriteString(nestedName)
-
-					// Parse attributes and check for self-closing
-					attrs := p.parseAttributes()
-					for attrName, attrValue := range attrs {
-						content.WriteString(" ")
-						content.WriteString(attrName)
-						content.WriteString("=\"")
-						content.WriteString(escapeXML(attrValue))
-						content.WriteString("\"")
-					}
-
-					isSelfClosing := false
-					if p.peek() == '/' {
-						p.next()
-						isSelfClosing = true
-						content.WriteString("/")
-					}
+	for p.pos < p.dataLen {
+		if p.data[p.pos] != '<' || p.pos+1 >= p.dataLen {
+			p.pos++
+			continue
+		}
 
-					if p.peek() == '>' {
-						p.next()
-						content.WriteString(">")
-					}
+		switch p.data[p.pos+1] {
+		case '/':
+			// Closing tag
+			tagStart := p.pos
+			p.pos += 2
+			nameStart := p.pos
+			for p.pos < p.dataLen && p.data[p.pos] != '>' {
+				p.pos++
+			}
+			closeName := strings.TrimSpace(string(p.data[nameStart:p.pos]))
+			if idx := strings.IndexAny(closeName, " \t\n\r"); idx >= 0 {
+				closeName = closeName[:idx]
+			}
+			p.next() // skip '>'
 
-					// Only increment elementDepth if this is the same element type we're tracking
-					if !isSelfClosing && nestedName == elementName {
-						elementDepth++
+			if closeName == elementName {
+				elementDepth--
+				if elementDepth == 0 {
+					return tagStart
+				}
+			}
+		case '!':
+			// Comment or CDATA - treated as content, like parseElementContent
+			p.pos++
+		case '?':
+			// Processing instruction - skipped whole, so '>' or '<' inside it
+			// is not taken for markup
+			p.pos = processingInstructionEnd(p.data, p.pos)
+		default:
+			// Opening tag of nested element
+			p.pos++ // skip '<'
+			nameStart := p.pos
+			for p.pos < p.dataLen && !isWhitespace(p.data[p.pos]) && p.data[p.pos] != '/' && p.data[p.pos] != '>' {
+				p.pos++
+			}
+			sameName := string(p.data[nameStart:p.pos]) == elementName
+
+			// Skip attributes, honoring quoted values that may contain '>'
+			isSelfClosing := false
+			var quote byte
+			for p.pos < p.dataLen {
+				c := p.data[p.pos]
+				if quote != 0 {
+					if c == quote {
+						quote = 0
 					}
+				} else if c == '"' || c == '\'' {
+					quote = c
+				} else if c == '>' {
+					isSelfClosing = p.data[p.pos-1] == '/'
+					p.pos++
+					break
 				}
-			} else {
-				content.WriteByte(c)
-				p.next()
+				p.pos++
+			}
+
+			if !isSelfClosing && sameName {
+				elementDepth++
 			}
-		} else {
-			content.WriteByte(c)
-			p.next()
 		}
 	}
 
-	return content.String()
+	return p.dataLen
 }
 
 // extractTextContent extracts only text content, stripping out all XML tags
@@ -453,8 +631,7 @@ func (p *xmlParser) skipToNextElement() bool {
 					}
 				} else if next == '?' {
 					// Skip processing instruction
-					p.readUntil('>')
-					p.next()
+					p.pos = processingInstructionEnd(p.data, p.pos)
 				} else {
 					// Skip closing tag
 					p.readUntil('>')
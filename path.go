@@ -4,10 +4,13 @@
 package xmldot
 
 import (
+	"container/list"
 	"fmt"
 	"strconv"
 	"strings"
 	"sync"
+
+	"github.com/netascode/xmldot/internal/pattern"
 )
 
 // MaxPathSegments is the maximum number of path segments allowed in a query path.
@@ -18,14 +21,26 @@ const MaxPathSegments = 100
 // This prevents DoS attacks with extremely long field names.
 const MaxFieldNameLength = 256
 
+// DefaultPathCacheLimit is the number of parsed paths kept in the path
+// cache unless changed with SetPathCacheLimit.
+const DefaultPathCacheLimit = 256
+
 // Path cache for performance optimization
-// Thread-safe LRU-style cache for parsed paths to avoid repeated parsing
+// Thread-safe LRU cache for parsed paths to avoid repeated parsing. The
+// list holds *pathCacheEntry values, most recently used first.
 var (
-	pathCache      = make(map[string][]PathSegment)
-	pathCacheMu    sync.RWMutex
-	pathCacheLimit = 256 // Keep cache size reasonable
+	pathCache      = make(map[string]*list.Element)
+	pathCacheOrder = list.New()
+	pathCacheMu    sync.Mutex
+	pathCacheLimit = DefaultPathCacheLimit
 )
 
+// pathCacheEntry is a parsed path stored in the path cache.
+type pathCacheEntry struct {
+	path     string
+	segments []PathSegment
+}
+
 // SegmentType represents the type of a path segment.
 type SegmentType int
 
@@ -36,7 +51,8 @@ const (
 	SegmentAttribute
 	// SegmentIndex represents an array index access ([n] or .n).
 	SegmentIndex
-	// SegmentWildcard represents a wildcard match (* or **).
+	// SegmentWildcard represents a wildcard match (* or **), or a glob name
+	// pattern such as db_* or item? when Value is set.
 	SegmentWildcard
 	// SegmentFilter represents a query filter ([condition]).
 	SegmentFilter
@@ -46,6 +62,9 @@ const (
 	SegmentCount
 	// SegmentFieldExtraction represents field extraction from all array elements (#.field).
 	SegmentFieldExtraction
+	// SegmentRoot represents the synthetic #root accessor that addresses the
+	// top-level elements of a document or fragment (e.g., #root.0, #root.#).
+	SegmentRoot
 )
 
 // IndexIntent represents the semantic intent of an index operation.
@@ -90,6 +109,9 @@ type PathSegment struct {
 	// FilterAll indicates if #()# syntax is used (returns ALL matches instead of first).
 	// Only applies when Type is SegmentFilter.
 	FilterAll bool
+	// Limit caps the number of matches of a #()#:N filter; the scan stops
+	// once N matches are found. Zero means no limit.
+	Limit int
 	// Field is the field name for FieldExtraction type (#.field syntax).
 	// The field can be an element name, attribute (@attr), or text (%).
 	Field string
@@ -107,6 +129,24 @@ type Filter struct {
 	Op FilterOp
 	// Value is the value to compare against.
 	Value string
+	// ValuePath is set when the right-hand operand is an unquoted attribute
+	// reference (e.g. "@max" in "@min<=@max"). The condition then compares
+	// against that attribute of the same element instead of Value, and does
+	// not match elements without it.
+	ValuePath string
+	// CustomOp is the operator token of a registered filter operator
+	// (see RegisterFilterOp). Only set when Op is OpCustom.
+	CustomOp string
+	// And holds further conditions joined to this one with &&; the filter
+	// matches only if every condition does. Conditions in And have no And
+	// of their own.
+	And []*Filter
+
+	// segments holds Path parsed once by the filter parser when it is an
+	// element path, so evaluating the filter on each candidate element does
+	// not parse it again. parsed reports whether segments was set.
+	segments []PathSegment
+	parsed   bool
 }
 
 // parsePath parses a path string into a slice of PathSegments.
@@ -120,50 +160,119 @@ type Filter struct {
 //   - "root.**.price" - recursive wildcard
 //
 // Security: Paths with more than MaxPathSegments segments are rejected.
-// Performance: Uses a thread-safe LRU cache to avoid re-parsing common paths.
+// Performance: Uses a thread-safe LRU cache (see SetPathCacheLimit) to avoid
+// re-parsing common paths.
 func parsePath(path string) []PathSegment {
 	if path == "" {
 		return nil
 	}
 
-	// Check cache first (read lock)
-	pathCacheMu.RLock()
-	if cached, ok := pathCache[path]; ok {
-		pathCacheMu.RUnlock()
-		// Return a copy to prevent modification of cached data
-		result := make([]PathSegment, len(cached))
-		copy(result, cached)
-		return result
+	// Check cache first
+	if cached, ok := cachedPath(path); ok {
+		return cached
 	}
-	pathCacheMu.RUnlock()
 
 	// Parse the path
 	segments := parsePathInternal(path)
 
-	// Cache the result (write lock)
+	// Cache the result
 	if segments != nil {
-		pathCacheMu.Lock()
-		// Simple cache eviction: clear cache when it exceeds limit
-		if len(pathCache) >= pathCacheLimit {
-			pathCache = make(map[string][]PathSegment)
-		}
-		// Store a copy to prevent external modification
-		cached := make([]PathSegment, len(segments))
-		copy(cached, segments)
-		pathCache[path] = cached
-		pathCacheMu.Unlock()
+		storePath(path, segments)
 	}
 
 	return segments
 }
 
+// cachedPath returns a copy of the cached segments for path and marks the
+// entry as most recently used.
+func cachedPath(path string) ([]PathSegment, bool) {
+	pathCacheMu.Lock()
+	defer pathCacheMu.Unlock()
+	elem, ok := pathCache[path]
+	if !ok {
+		return nil, false
+	}
+	pathCacheOrder.MoveToFront(elem)
+	// Return a copy to prevent modification of cached data
+	cached := elem.Value.(*pathCacheEntry).segments
+	result := make([]PathSegment, len(cached))
+	copy(result, cached)
+	return result, true
+}
+
+// storePath caches a copy of segments for path, evicting the least recently
+// used entries beyond the limit.
+func storePath(path string, segments []PathSegment) {
+	pathCacheMu.Lock()
+	defer pathCacheMu.Unlock()
+	if pathCacheLimit <= 0 {
+		return
+	}
+	if _, ok := pathCache[path]; ok {
+		return
+	}
+	// Store a copy to prevent external modification
+	cached := make([]PathSegment, len(segments))
+	copy(cached, segments)
+	pathCache[path] = pathCacheOrder.PushFront(&pathCacheEntry{path: path, segments: cached})
+	evictPaths(pathCacheLimit)
+}
+
+// evictPaths removes least recently used entries until at most limit
+// remain. The caller must hold pathCacheMu.
+func evictPaths(limit int) {
+	for pathCacheOrder.Len() > limit {
+		oldest := pathCacheOrder.Back()
+		pathCacheOrder.Remove(oldest)
+		delete(pathCache, oldest.Value.(*pathCacheEntry).path)
+	}
+}
+
+// ClearPathCache discards all cached parsed paths. Paths are parsed again on
+// their next use.
+func ClearPathCache() {
+	resetPathCache()
+}
+
+// SetPathCacheLimit sets how many parsed paths are cached (default
+// DefaultPathCacheLimit). When the cache is full, the least recently used
+// path is evicted; lowering the limit evicts immediately. A limit of zero or
+// less disables caching.
+//
+// Long-running servers that build many distinct paths, e.g. from request
+// data, can use it to bound the memory held by the cache.
+func SetPathCacheLimit(n int) {
+	pathCacheMu.Lock()
+	defer pathCacheMu.Unlock()
+	if n < 0 {
+		n = 0
+	}
+	pathCacheLimit = n
+	evictPaths(n)
+}
+
+// resetPathCache discards all cached parsed paths. Used when the meaning of
+// a path can change, e.g. after a filter operator is registered.
+func resetPathCache() {
+	pathCacheMu.Lock()
+	pathCache = make(map[string]*list.Element)
+	pathCacheOrder.Init()
+	pathCacheMu.Unlock()
+}
+
 // parsePathInternal performs the actual path parsing logic.
 // This is separated from parsePath to enable caching.
 func parsePathInternal(path string) []PathSegment {
+	return parsePathSegments(path, MaxPathSegments)
+}
+
+// parsePathSegments parses path into at most maxSegments segments. Longer
+// paths return nil.
+func parsePathSegments(path string, maxSegments int) []PathSegment {
 	parts := splitPath(path)
 
 	// Security check: enforce maximum path segment count
-	if len(parts) > MaxPathSegments {
+	if len(parts) > maxSegments {
 		return nil
 	}
 
@@ -174,6 +283,10 @@ func parsePathInternal(path string) []PathSegment {
 			continue
 		}
 
+		// An escaped first character (\0) or a bracket-quoted name (['0'])
+		// is always an element name, never an index, attribute or wildcard
+		literal := part[0] == '\\'
+
 		// Parse modifiers from this path component (Phase 6)
 		pathPart, modifiers := parseModifiers(part)
 
@@ -181,13 +294,29 @@ func parsePathInternal(path string) []PathSegment {
 			Modifiers: modifiers, // Store modifiers for this segment
 		}
 
-		// Check for GJSON filter syntax #(...) or #(...)#
+		if literal {
+			seg.Type = SegmentElement
+			seg.Value = pathPart
+			segments = append(segments, seg)
+			continue
+		}
+
+		// Check for GJSON filter syntax #(...), #(...)# or #(...)#:N
 		if strings.HasPrefix(pathPart, "#(") {
 			// Validate proper closing
 			var validSyntax bool
 			var filterAll bool
 			var endIdx int
 
+			// A #(...)#:N filter keeps at most the first N matches
+			if limit, rest, ok := splitFilterLimit(pathPart); ok {
+				if limit <= 0 {
+					return nil
+				}
+				seg.Limit = limit
+				pathPart = rest
+			}
+
 			if strings.HasSuffix(pathPart, ")#") {
 				// Check it's exactly )# at the end, not multiple #'s
 				if len(pathPart) >= 3 && pathPart[len(pathPart)-2] == ')' && pathPart[len(pathPart)-1] == '#' {
@@ -230,8 +359,8 @@ func parsePathInternal(path string) []PathSegment {
 			continue
 		}
 
-		// Note: Old bracket filter syntax [condition] is not supported
-		// Only GJSON-style #(condition) syntax is supported
+		// Note: Bracket attribute predicates ([id=prod]) are rewritten to
+		// #(@id==prod) by splitPath
 		// Note: #.field syntax is handled in post-processing (see end of function)
 
 		if strings.HasPrefix(pathPart, "@") {
@@ -241,6 +370,9 @@ func parsePathInternal(path string) []PathSegment {
 		} else if pathPart == "%" {
 			// Text content
 			seg.Type = SegmentText
+		} else if pathPart == "#root" {
+			// Synthetic accessor for top-level elements
+			seg.Type = SegmentRoot
 		} else if pathPart == "#" {
 			// Array count
 			seg.Type = SegmentCount
@@ -252,6 +384,11 @@ func parsePathInternal(path string) []PathSegment {
 			// Recursive wildcard
 			seg.Type = SegmentWildcard
 			seg.Wildcard = true
+		} else if isGlobPattern(pathPart) {
+			// Glob name pattern (e.g., db_*, *_url, item?)
+			seg.Type = SegmentWildcard
+			seg.Wildcard = false
+			seg.Value = pathPart
 		} else if isNumeric(pathPart) {
 			// Array index (numeric)
 			seg.Type = SegmentIndex
@@ -309,7 +446,12 @@ func parsePathInternal(path string) []PathSegment {
 	return processedSegments
 }
 
-// splitPath splits a path on dots, handling escapes
+// splitPath splits a path on dots, handling escapes. Dots and parentheses
+// inside a filter's quoted value (#(name=="a.b")) are not split points.
+// A bracket-quoted name (['a.b'] or ["a.b"]) starts a new component holding
+// the name verbatim, so properties['database.url'] splits like
+// properties.database\.url.
+// Returns nil if a quoted filter value or bracketed name is not terminated.
 func splitPath(path string) []string {
 	if path == "" {
 		return nil
@@ -318,11 +460,38 @@ func splitPath(path string) []string {
 	var parts []string
 	var current strings.Builder
 	escaped := false
+	filterDepth := 0 // nesting depth inside #(...) filter expressions
+	var quote byte   // quote character of an open quoted filter value, or 0
 
 	for i := 0; i < len(path); i++ {
 		c := path[i]
 
+		// Quoted filter values are copied verbatim, escapes included, and are
+		// unquoted by the filter parser
+		if quote != 0 {
+			current.WriteByte(c)
+			if c == '\\' && i+1 < len(path) {
+				i++
+				current.WriteByte(path[i])
+			} else if c == quote {
+				quote = 0
+			}
+			continue
+		}
+		if filterDepth > 0 && (c == '\'' || c == '"') {
+			quote = c
+			current.WriteByte(c)
+			continue
+		}
+
 		if escaped {
+			// The modifier separator and the escape itself keep their
+			// backslash until parseModifiers has split off the modifiers,
+			// and so does an escaped first character, which marks the
+			// component as a literal element name (\0, \#)
+			if c == '|' || c == '\\' || current.Len() == 0 {
+				current.WriteByte('\\')
+			}
 			current.WriteByte(c)
 			escaped = false
 			continue
@@ -333,7 +502,47 @@ func splitPath(path string) []string {
 			continue
 		}
 
-		if c == '.' {
+		// Bracket-quoted name: ['name'] or ["name"]
+		if c == '[' && filterDepth == 0 && i+1 < len(path) && (path[i+1] == '\'' || path[i+1] == '"') {
+			name, end, ok := readBracketName(path, i)
+			if !ok {
+				return nil
+			}
+			if current.Len() > 0 {
+				parts = append(parts, current.String())
+				current.Reset()
+			}
+			if isReservedName(name) {
+				current.WriteByte('\\')
+			}
+			current.WriteString(escapeModifierSeparators(name))
+			i = end
+			continue
+		}
+
+		// Attribute predicate: server[id=prod] or server[@id=prod] reads as
+		// server.#(@id==prod)
+		if c == '[' && filterDepth == 0 && current.Len() > 0 {
+			cond, end, ok := readBracketPredicate(path, i)
+			if !ok {
+				return nil
+			}
+			parts = append(parts, current.String())
+			current.Reset()
+			current.WriteString("#(" + cond + ")")
+			i = end
+			continue
+		}
+
+		// Dots inside a filter expression belong to the filter's own path
+		// (e.g., #(item.@sku==ABC)), so they are not split points.
+		if c == '(' && (filterDepth > 0 || (i > 0 && path[i-1] == '#')) {
+			filterDepth++
+		} else if c == ')' && filterDepth > 0 {
+			filterDepth--
+		}
+
+		if c == '.' && filterDepth == 0 {
 			// Split point
 			parts = append(parts, current.String())
 			current.Reset()
@@ -342,12 +551,185 @@ func splitPath(path string) []string {
 		}
 	}
 
+	// An unterminated quoted filter value makes the whole path invalid
+	if quote != 0 {
+		return nil
+	}
+
 	// Add the last part (even if empty, for cases like "root.child.")
 	parts = append(parts, current.String())
 
 	return parts
 }
 
+// isReservedName reports whether name, used as a path component, would be
+// read as something other than an element name: an index (0), a count or
+// keyword (#, #root), an attribute (@id), text (%) or a wildcard (*, db_*).
+func isReservedName(name string) bool {
+	if name == "" {
+		return false
+	}
+	switch name[0] {
+	case '@', '#', '%':
+		return true
+	}
+	return isNumeric(name) || name == "*" || name == "**" || isGlobPattern(name)
+}
+
+// escapeModifierSeparators escapes '|' and '\\' in a bracket-quoted name, so
+// that parseModifiers keeps the name whole (['a|b'] names the element a|b).
+func escapeModifierSeparators(name string) string {
+	if !strings.ContainsAny(name, "|\\") {
+		return name
+	}
+	var sb strings.Builder
+	for i := 0; i < len(name); i++ {
+		if name[i] == '|' || name[i] == '\\' {
+			sb.WriteByte('\\')
+		}
+		sb.WriteByte(name[i])
+	}
+	return sb.String()
+}
+
+// readBracketName reads a bracket-quoted name starting at path[start] == '['.
+// Within the quotes a backslash escapes the next character. It returns the
+// unquoted name and the index of the closing ']'.
+func readBracketName(path string, start int) (string, int, bool) {
+	quote := path[start+1]
+	var name strings.Builder
+	for i := start + 2; i < len(path); i++ {
+		c := path[i]
+		switch {
+		case c == '\\' && i+1 < len(path):
+			i++
+			name.WriteByte(path[i])
+		case c == quote:
+			if i+1 < len(path) && path[i+1] == ']' && name.Len() > 0 {
+				return name.String(), i + 1, true
+			}
+			return "", 0, false
+		default:
+			name.WriteByte(c)
+		}
+	}
+	return "", 0, false
+}
+
+// readBracketPredicate reads an attribute predicate starting at
+// path[start] == '[' and returns it as a filter condition, with the index of
+// the closing ']'. The attribute's '@' is optional and a single '=' means
+// equality, so [id=prod], [@id=prod] and [@id==prod] all become @id==prod;
+// [@id] tests that the attribute exists. The predicate must be followed by
+// the end of the path, a '.' or a '|'.
+func readBracketPredicate(path string, start int) (string, int, bool) {
+	var quote byte
+	end := -1
+	for i := start + 1; i < len(path) && end < 0; i++ {
+		c := path[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == ']':
+			end = i
+		}
+	}
+	if end < 0 || (end+1 < len(path) && path[end+1] != '.' && path[end+1] != '|') {
+		return "", 0, false
+	}
+
+	cond := strings.TrimSpace(path[start+1 : end])
+	if !strings.HasPrefix(cond, "@") {
+		cond = "@" + cond
+	}
+	op := strings.IndexAny(cond, "=!<>%^")
+	if len(cond) == 1 || op == 1 {
+		// No attribute name
+		return "", 0, false
+	}
+	if op > 0 && cond[op] == '=' && (op+1 == len(cond) || cond[op+1] != '=') {
+		cond = cond[:op] + "==" + cond[op+1:]
+	}
+	return cond, end, true
+}
+
+// EscapeName escapes an element or attribute name for use as a single path
+// component, so that dots, pipes and backslashes in the name are matched
+// literally rather than read as separators or modifiers:
+//
+//	path := "configuration.properties." + EscapeName("database.url")
+//	// "configuration.properties.database\\.url"
+//
+// The bracket form configuration.properties['database.url'] is equivalent.
+// A name that would otherwise read as an index or keyword, such as "0", gets
+// its first character escaped ("\\0"), so it names an element.
+func EscapeName(name string) string {
+	reserved := isReservedName(name)
+	if !reserved && !strings.ContainsAny(name, ".|\\") {
+		return name
+	}
+	var sb strings.Builder
+	sb.Grow(len(name) + 2)
+	if reserved {
+		sb.WriteByte('\\')
+	}
+	for i := 0; i < len(name); i++ {
+		if name[i] == '.' || name[i] == '|' || name[i] == '\\' {
+			sb.WriteByte('\\')
+		}
+		sb.WriteByte(name[i])
+	}
+	return sb.String()
+}
+
+// isGlobPattern reports whether a path component is a glob name pattern:
+// an element name containing '*' (any run of characters) or '?' (exactly one
+// character). The plain "*" and "**" wildcards are handled separately.
+func isGlobPattern(s string) bool {
+	return strings.ContainsAny(s, "*?")
+}
+
+// matchGlob matches an element name against a glob pattern. Like plain
+// element segments, a pattern without a namespace prefix matches the local
+// name only, while a prefixed pattern (e.g., ns:item*) matches the full name.
+// Security: Matching is bounded by MaxPatternIterations.
+func matchGlob(globPattern, elementName string) bool {
+	if !strings.Contains(globPattern, ":") {
+		_, elementName = splitNamespace(elementName)
+	}
+	matched, stopped := pattern.Match(elementName, globPattern, MaxPatternIterations)
+	return matched && !stopped
+}
+
+// splitFilterLimit splits the ":N" suffix from a "#(...)#:N" path part. It
+// returns the limit, the part without the suffix, and whether a suffix was
+// present. Everything after the last ")#:" outside the condition is the
+// suffix; a limit that is not a positive decimal number is returned as 0.
+func splitFilterLimit(part string) (int, string, bool) {
+	i := strings.LastIndex(part, ")#:")
+	if i < 0 || strings.Contains(part[i+3:], ")") {
+		// No suffix, or the ")#:" is inside the condition
+		return 0, part, false
+	}
+	digits := part[i+3:]
+	for j := 0; j < len(digits); j++ {
+		if digits[j] < '0' || digits[j] > '9' {
+			return 0, part, true
+		}
+	}
+	limit, err := strconv.Atoi(digits)
+	if err != nil {
+		return 0, part, true
+	}
+	return limit, part[:i+2], true
+}
+
 // isNumeric checks if a string is a valid integer
 func isNumeric(s string) bool {
 	if s == "" {
@@ -437,6 +819,9 @@ func (seg PathSegment) matches(elementName string) bool {
 		return pathLocal == elemLocal
 
 	case SegmentWildcard:
+		if seg.Value != "" {
+			return matchGlob(seg.Value, elementName)
+		}
 		return true // Wildcards match any element
 	default:
 		return false
@@ -478,6 +863,12 @@ func (seg PathSegment) matchesWithOptions(elementName string, opts *Options) boo
 		return pathLocal == elemLocal
 
 	case SegmentWildcard:
+		if seg.Value != "" {
+			if !opts.CaseSensitive {
+				return matchGlob(toLowerASCII(seg.Value), toLowerASCII(elementName))
+			}
+			return matchGlob(seg.Value, elementName)
+		}
 		return true // Wildcards match any element
 	default:
 		return false
@@ -531,7 +922,9 @@ func toLowerASCII(s string) string {
 // What DOES NOT Work (Critical Limitations):
 //
 //	❌ NO namespace URI resolution (xmlns attributes are completely ignored)
-//	❌ NO validation that prefixes are declared
+//	❌ NO validation that prefixes are declared (except for raw fragments
+//	   written with Options.RequireDeclaredNamespaces; writes can add
+//	   declarations with Options.AutoDeclareNamespaces)
 //	❌ NO default namespace support (xmlns="..." is ignored)
 //	❌ Elements with same local name but different namespace URIs are NOT distinguished
 //	❌ No namespace inheritance or scoping
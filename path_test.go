@@ -4,6 +4,7 @@
 package xmldot
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -148,6 +149,41 @@ func TestSplitPath(t *testing.T) {
 			path: "root.child.",
 			want: []string{"root", "child", ""},
 		},
+		{
+			name: "Bracket name",
+			path: "root.props['database.url'].value",
+			want: []string{"root", "props", "database.url", "value"},
+		},
+		{
+			name: "Double-quoted bracket name",
+			path: `root["a.b"]["c.d"]`,
+			want: []string{"root", "a.b", "c.d"},
+		},
+		{
+			name: "Bracket name after dot",
+			path: "root.['a.b']",
+			want: []string{"root", "a.b"},
+		},
+		{
+			name: "Bracket name with escaped quote",
+			path: `root['it\'s']`,
+			want: []string{"root", "it's"},
+		},
+		{
+			name: "Bracket name with modifier",
+			path: "root['a.b']|@reverse",
+			want: []string{"root", "a.b|@reverse"},
+		},
+		{
+			name: "Unterminated bracket name",
+			path: "root['a.b",
+			want: []string{},
+		},
+		{
+			name: "Empty bracket name",
+			path: "root['']",
+			want: []string{},
+		},
 	}
 
 	for _, tt := range tests {
@@ -166,6 +202,35 @@ func TestSplitPath(t *testing.T) {
 	}
 }
 
+func TestEscapeName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"plain", "plain"},
+		{"database.url", `database\.url`},
+		{`a\b.c`, `a\\b\.c`},
+		{"a|b", `a\|b`},
+		{"0", `\0`},
+		{"#root", `\#root`},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := EscapeName(tt.name)
+			if got != tt.want {
+				t.Errorf("EscapeName(%q) = %q, want %q", tt.name, got, tt.want)
+			}
+			if tt.name != "" {
+				if segs := parsePath(got); len(segs) != 1 || segs[0].Type != SegmentElement || segs[0].Value != tt.name || len(segs[0].Modifiers) != 0 {
+					t.Errorf("parsePath(EscapeName(%q)) = %+v, want one %q segment", tt.name, segs, tt.name)
+				}
+			}
+		})
+	}
+}
+
 func TestIsNumeric(t *testing.T) {
 	tests := []struct {
 		name string
@@ -487,6 +552,34 @@ func TestParseGJSONFilterInvalidSyntax(t *testing.T) {
 			name: "malformed all-matches marker",
 			path: "items.item.#(age>21)##",
 		},
+		{
+			name: "zero limit",
+			path: "items.item.#(age>21)#:0",
+		},
+		{
+			name: "missing limit",
+			path: "items.item.#(age>21)#:",
+		},
+		{
+			name: "limit on first-match filter",
+			path: "items.item.#(age>21):3",
+		},
+		{
+			name: "negative limit",
+			path: "items.item.#(age>21)#:-1",
+		},
+		{
+			name: "non-numeric limit",
+			path: "items.item.#(age>21)#:x",
+		},
+		{
+			name: "limit with trailing characters",
+			path: "items.item.#(age>21)#:3x",
+		},
+		{
+			name: "signed limit",
+			path: "items.item.#(age>21)#:+3",
+		},
 	}
 
 	for _, tt := range tests {
@@ -503,6 +596,11 @@ func TestParseGJSONFilterInvalidSyntax(t *testing.T) {
 					t.Errorf("Expected malformed filter to be rejected or have nil Filter, but got parsed filter")
 				}
 			}
+
+			// A malformed :N limit makes the whole path invalid
+			if strings.Contains(tt.path, ")#:") && segments != nil {
+				t.Errorf("parsePath(%q) = %d segments, want nil", tt.path, len(segments))
+			}
 		})
 	}
 }
@@ -714,3 +812,155 @@ func TestParseFilterCondition(t *testing.T) {
 	// Use the shared helper function from filter_test.go
 	testFilterConditionParsing(t, tests)
 }
+
+// TestPathCacheLimit tests LRU eviction, ClearPathCache and SetPathCacheLimit
+func TestPathCacheLimit(t *testing.T) {
+	t.Cleanup(func() {
+		SetPathCacheLimit(DefaultPathCacheLimit)
+		ClearPathCache()
+	})
+
+	cached := func(path string) bool {
+		pathCacheMu.Lock()
+		defer pathCacheMu.Unlock()
+		_, ok := pathCache[path]
+		return ok
+	}
+
+	ClearPathCache()
+	SetPathCacheLimit(2)
+	parsePath("cache.a")
+	parsePath("cache.b")
+	parsePath("cache.a") // a is now the most recently used
+	parsePath("cache.c") // evicts b
+
+	if !cached("cache.a") || cached("cache.b") || !cached("cache.c") {
+		t.Errorf("after eviction a=%v b=%v c=%v, want a and c cached", cached("cache.a"), cached("cache.b"), cached("cache.c"))
+	}
+
+	// Cached results are copies
+	segments := parsePath("cache.a")
+	segments[0].Value = "changed"
+	if got := parsePath("cache.a")[0].Value; got != "cache" {
+		t.Errorf("cached segment = %q, want it unchanged", got)
+	}
+
+	// Lowering the limit evicts immediately
+	SetPathCacheLimit(1)
+	if cached("cache.c") || !cached("cache.a") {
+		t.Error("SetPathCacheLimit(1) should keep only the most recently used path")
+	}
+
+	ClearPathCache()
+	if cached("cache.a") {
+		t.Error("ClearPathCache() should empty the cache")
+	}
+
+	// A zero limit disables caching but parsing still works
+	SetPathCacheLimit(0)
+	if got := Get("<r><a>1</a></r>", "r.a").String(); got != "1" {
+		t.Errorf("Get() with caching disabled = %q, want 1", got)
+	}
+	if cached("r.a") {
+		t.Error("SetPathCacheLimit(0) should disable caching")
+	}
+}
+
+// TestParsePath_LiteralNames tests that an escaped first character or a
+// bracket-quoted name is an element name even when it reads as an index,
+// keyword or wildcard unquoted
+func TestParsePath_LiteralNames(t *testing.T) {
+	tests := []struct {
+		path     string
+		wantType SegmentType
+		want     string
+	}{
+		{`config.0`, SegmentIndex, ""},
+		{`config.\0`, SegmentElement, "0"},
+		{`config['0']`, SegmentElement, "0"},
+		{`config["12"]`, SegmentElement, "12"},
+		{`config.\#`, SegmentElement, "#"},
+		{`config['*']`, SegmentElement, "*"},
+		{`config.\0|@this`, SegmentElement, "0"},
+		{`config['a.b']`, SegmentElement, "a.b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			segs := parsePath(tt.path)
+			if len(segs) != 2 {
+				t.Fatalf("parsePath(%q) returned %d segments, want 2", tt.path, len(segs))
+			}
+			if segs[1].Type != tt.wantType || segs[1].Value != tt.want {
+				t.Errorf("segment = %v %q, want %v %q", segs[1].Type, segs[1].Value, tt.wantType, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePath_AttributePredicates(t *testing.T) {
+	tests := []struct {
+		path     string
+		wantPath string
+		wantOp   FilterOp
+		want     string
+	}{
+		{"servers.server[id=prod]", "@id", OpEqual, "prod"},
+		{"servers.server[@id=prod]", "@id", OpEqual, "prod"},
+		{"servers.server[@id==prod]", "@id", OpEqual, "prod"},
+		{"servers.server[ id = 'a.b' ]", "@id", OpEqual, "a.b"},
+		{"servers.server[port>=8000]", "@port", OpGreaterThanOrEqual, "8000"},
+		{"servers.server[id!=prod]", "@id", OpNotEqual, "prod"},
+		{"servers.server[@primary]", "@primary", OpExists, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			segs := parsePath(tt.path)
+			if len(segs) != 3 {
+				t.Fatalf("parsePath(%q) returned %d segments, want 3", tt.path, len(segs))
+			}
+			if segs[1].Type != SegmentElement || segs[1].Value != "server" {
+				t.Errorf("segment 1 = %v %q, want element server", segs[1].Type, segs[1].Value)
+			}
+			f := segs[2].Filter
+			if segs[2].Type != SegmentFilter || segs[2].FilterAll || f == nil {
+				t.Fatalf("segment 2 = %+v, want a first-match filter", segs[2])
+			}
+			if f.Path != tt.wantPath || f.Op != tt.wantOp || f.Value != tt.want {
+				t.Errorf("filter = %q %v %q, want %q %v %q", f.Path, f.Op, f.Value, tt.wantPath, tt.wantOp, tt.want)
+			}
+		})
+	}
+
+	for _, path := range []string{"a.b[id=1", "a.b[]", "a.b[@]", "a.b[=1]", "a.b[id=1]c"} {
+		if segs := parsePath(path); len(segs) != 0 {
+			t.Errorf("parsePath(%q) = %v, want no segments", path, segs)
+		}
+	}
+}
+
+func TestParsePath_FilterLimit(t *testing.T) {
+	segs := parsePath("product.#(stock>0)#:3.name")
+	if len(segs) != 3 {
+		t.Fatalf("parsePath() = %d segments, want 3", len(segs))
+	}
+	filter := segs[1]
+	if filter.Type != SegmentFilter || !filter.FilterAll || filter.Limit != 3 {
+		t.Errorf("filter segment = %+v, want all-matches filter with Limit 3", filter)
+	}
+	if filter.Filter == nil || filter.Filter.Path != "stock" || filter.Filter.Value != "0" {
+		t.Errorf("filter condition = %+v, want stock>0", filter.Filter)
+	}
+
+	// A ")#:" inside a quoted value is part of the condition
+	segs = parsePath(`item.#(name=="a)#:1")#`)
+	if len(segs) != 2 || segs[1].Limit != 0 || segs[1].Filter == nil || segs[1].Filter.Value != "a)#:1" {
+		t.Errorf("parsePath() quoted marker = %+v", segs)
+	}
+
+	// #(condition)# without a limit keeps Limit at zero
+	if segs := parsePath("item.#(age>21)#"); len(segs) != 2 || segs[1].Limit != 0 {
+		t.Errorf("parsePath() without limit = %+v", segs)
+	}
+}
@@ -35,7 +35,8 @@ const (
 type Result struct {
 	// Type is the type of the result value.
 	Type Type
-	// Raw is the raw XML segment that was matched.
+	// Raw is the raw XML segment that was matched. For an Attribute it is
+	// the attribute's declaration as written in the input, e.g. id='1'.
 	Raw string
 	// Str is the parsed string value.
 	Str string
@@ -45,6 +46,25 @@ type Result struct {
 	Num float64
 	// Results holds child results for Array type (Phase 3+)
 	Results []Result
+
+	// attrs holds the element's own attributes in document order (Element type only)
+	attrs []Attr
+	// name is the element's tag name, including any namespace prefix (Element type only)
+	name string
+	// multi records that the path asked for a collection, even if it matched once
+	multi bool
+	// span locates the element in the input for Span (Element type only)
+	span docSpan
+	// tag is the element's opening tag, for the Raw of its attributes (Element type only)
+	tag string
+}
+
+// Attr is a single attribute of an element, as returned by Result.Attributes.
+type Attr struct {
+	// Name is the attribute name, including any namespace prefix.
+	Name string
+	// Value is the unescaped attribute value.
+	Value string
 }
 
 // Exists returns true if the result represents an existing value in the XML.
@@ -52,10 +72,201 @@ func (r Result) Exists() bool {
 	return r.Type != Null
 }
 
+// Span returns the byte offsets of the matched element's outer markup, from
+// its '<' to just after its closing tag (or "/>"), within the exact input
+// passed to Get or GetStream, so that xml[start:end] is the element as
+// written. This lets editors highlight a match or splice in a change without
+// the library rewriting the document.
+//
+// Span returns -1, -1 for results that are not a single element of the input:
+// attributes, text, counts, Arrays (use the span of each item) and modifier
+// output. For Result.Get on an Element, offsets are relative to the parent's
+// Raw.
+//
+// The offsets are recorded while the query runs, so they describe the input
+// as it was then; a Result keeps no reference to the input document.
+//
+// Example:
+//
+//	xml := `<config><db port="5432"/><log/></config>`
+//	start, end := Get(xml, "config.db").Span()
+//	fmt.Println(xml[start:end]) // <db port="5432"/>
+func (r Result) Span() (start, end int) {
+	if r.Type != Element || !r.span.known() {
+		return -1, -1
+	}
+	return r.span.start, r.span.end
+}
+
+// IsEmpty reports whether the result exists but holds an empty value, so
+// callers can tell attr="" from a missing attribute without checking both
+// Exists() and String().
+//
+// Behavior by Result type:
+//   - Attribute, String: The value is ""
+//   - Element: The content is empty or only whitespace (<item/>, <item> </item>)
+//   - Array: There are no items
+//   - Null, Number, True, False: Returns false
+//
+// Example:
+//
+//	xml := `<user id="" name="Ann"/>`
+//	xmldot.Get(xml, "user.@id").IsEmpty()    // true
+//	xmldot.Get(xml, "user.@name").IsEmpty()  // false
+//	xmldot.Get(xml, "user.@email").IsEmpty() // false (absent, Exists() is false)
+func (r Result) IsEmpty() bool {
+	switch r.Type {
+	case Attribute, String:
+		return r.Str == ""
+	case Element:
+		return isBlank(r.Raw)
+	case Array:
+		return len(r.Results) == 0
+	}
+	return false
+}
+
+// HasText reports whether the result has text of its own: for an Element,
+// non-whitespace text directly inside it (not inside child elements), for
+// other scalar results a non-empty value. It scans Raw without copying it,
+// so it is cheap to call on many elements. Null and Array results have no
+// text.
+//
+// Example:
+//
+//	xml := `<form><name> </name><city>Oslo</city><address><zip>1</zip></address></form>`
+//	xmldot.Get(xml, "form.name").HasText()    // false
+//	xmldot.Get(xml, "form.city").HasText()    // true
+//	xmldot.Get(xml, "form.address").HasText() // false (only child elements)
+func (r Result) HasText() bool {
+	switch r.Type {
+	case Null, Array:
+		return false
+	case Element:
+		return directTextLen(r.Raw, true) > 0
+	}
+	return r.Str != ""
+}
+
+// TextLen returns the length in bytes of the text HasText looks at, without
+// allocating it. For an Element this is the direct text with surrounding
+// whitespace trimmed, entity references counted as the character they stand
+// for and CDATA sections counted as text. For other scalar results it is
+// len(String()). Null and Array results return 0.
+func (r Result) TextLen() int {
+	switch r.Type {
+	case Null, Array:
+		return 0
+	case Element:
+		return directTextLen(r.Raw, false)
+	}
+	return len(r.Str)
+}
+
+// directTextLen measures the text directly inside element content, skipping
+// child elements, comments and processing instructions. Surrounding
+// whitespace is not counted and the five predefined entity references count
+// as one byte. With firstOnly it returns 1 as soon as any text is found.
+func directTextLen(content string, firstOnly bool) int {
+	n := 0       // text length up to the last non-whitespace byte
+	pending := 0 // whitespace seen since the last non-whitespace byte
+	depth := 0   // nesting depth of child elements
+	text := func(c byte) {
+		if !isWhitespace(c) {
+			n += pending + 1
+			pending = 0
+		} else if n > 0 {
+			pending++
+		}
+	}
+
+	for i := 0; i < len(content); {
+		c := content[i]
+		if c != '<' {
+			if depth > 0 {
+				i++
+				continue
+			}
+			text(c)
+			if firstOnly && n > 0 {
+				return 1
+			}
+			if c == '&' {
+				i += entityRefLen(content[i:])
+			} else {
+				i++
+			}
+			continue
+		}
+
+		rest := content[i:]
+		switch {
+		case strings.HasPrefix(rest, "<!--"):
+			i += skipTo(rest, "-->")
+		case strings.HasPrefix(rest, "<![CDATA["):
+			end := skipTo(rest, "]]>")
+			if depth == 0 {
+				for j := len("<![CDATA["); j < end-len("]]>"); j++ {
+					text(rest[j])
+				}
+				if firstOnly && n > 0 {
+					return 1
+				}
+			}
+			i += end
+		case strings.HasPrefix(rest, "<?"):
+			i = processingInstructionEnd(stringToBytes(content), i)
+		case strings.HasPrefix(rest, "</"):
+			depth--
+			i += skipTo(rest, ">")
+		case strings.HasPrefix(rest, "<!"):
+			i += skipTo(rest, ">")
+		default:
+			end := tagEnd(stringToBytes(rest))
+			if end == 0 {
+				return n
+			}
+			if rest[end-2] != '/' {
+				depth++
+			}
+			i += end
+		}
+	}
+	return n
+}
+
+// entityRefLen returns the length of the predefined entity reference at the
+// start of s, or 1 if s does not start with one.
+func entityRefLen(s string) int {
+	for _, ref := range [...]string{"&lt;", "&gt;", "&amp;", "&quot;", "&apos;"} {
+		if strings.HasPrefix(s, ref) {
+			return len(ref)
+		}
+	}
+	return 1
+}
+
+// skipTo returns the length of s up to and including the first occurrence
+// of marker, or len(s) if there is none.
+func skipTo(s, marker string) int {
+	if i := strings.Index(s, marker); i >= 0 {
+		return i + len(marker)
+	}
+	return len(s)
+}
+
 // String returns the string representation of the result.
 // For Null types, it returns an empty string.
-// For Array types, it returns a JSON-like array representation.
+// For Array types, it returns a JSON-like array representation of all
+// elements; unlike Int, Float and Bool it does not reduce to the first
+// element, so use Array()[0].String() or the @first modifier for that.
 // This implements the fmt.Stringer interface.
+//
+// Text taken from elements and attributes is returned exactly as written
+// (after entity decoding and, for elements, trimming surrounding whitespace),
+// even when it looks numeric: "01.50", "+3" and "1e2" stay as they are, and
+// only Float and Int parse them. Passing the Result itself to Set writes the
+// same text back.
 func (r Result) String() string {
 	if r.Type == Null {
 		return ""
@@ -93,9 +304,14 @@ func (r Result) String() string {
 }
 
 // Int returns the result as an int64. If the result cannot be converted,
-// it returns 0.
+// it returns 0. For Array types, it converts the first element (0 for an
+// empty array).
 func (r Result) Int() int64 {
 	switch r.Type {
+	case Array:
+		if len(r.Results) > 0 {
+			return r.Results[0].Int()
+		}
 	case Number:
 		return int64(r.Num)
 	case String, Element, Attribute:
@@ -112,9 +328,14 @@ func (r Result) Int() int64 {
 }
 
 // Float returns the result as a float64. If the result cannot be converted,
-// it returns 0.
+// it returns 0. For Array types, it converts the first element (0 for an
+// empty array).
 func (r Result) Float() float64 {
 	switch r.Type {
+	case Array:
+		if len(r.Results) > 0 {
+			return r.Results[0].Float()
+		}
 	case Number:
 		return r.Num
 	case String, Element, Attribute:
@@ -131,9 +352,14 @@ func (r Result) Float() float64 {
 }
 
 // Bool returns the result as a bool. The strings "true", "1", "yes" are
-// considered true. Everything else is false.
+// considered true. Everything else is false. For Array types, it converts
+// the first element (false for an empty array).
 func (r Result) Bool() bool {
 	switch r.Type {
+	case Array:
+		if len(r.Results) > 0 {
+			return r.Results[0].Bool()
+		}
 	case True:
 		return true
 	case False:
@@ -167,11 +393,102 @@ func (r Result) Value() interface{} {
 	return nil
 }
 
+// Attributes returns the element's own attributes in document order.
+// The order is stable across calls and matches the order of declaration in
+// the source XML, so it is safe to use for generating diffs or canonical output.
+//
+// Behavior by Result type:
+//   - Element: Attributes of the matched element
+//   - Array: Delegates to the first element (GJSON-compatible)
+//   - Null/Primitives: Returns nil
+//
+// An attribute declared more than once is listed once, at the position of its
+// first declaration.
+//
+// Example:
+//
+//	xml := `<user id="42" role="admin"><name>Alice</name></user>`
+//	for _, attr := range xmldot.Get(xml, "user").Attributes() {
+//	    fmt.Println(attr.Name, attr.Value) // "id 42", then "role admin"
+//	}
+func (r Result) Attributes() []Attr {
+	if r.Type == Array {
+		if len(r.Results) == 0 {
+			return nil
+		}
+		return r.Results[0].Attributes()
+	}
+	if r.Type != Element || len(r.attrs) == 0 {
+		return nil
+	}
+	// Return a copy so callers cannot mutate the Result
+	attrs := make([]Attr, len(r.attrs))
+	copy(attrs, r.attrs)
+	return attrs
+}
+
+// Name returns the tag name of an Element result, including any namespace
+// prefix (e.g. "circle" or "svg:rect"). It is useful after wildcard,
+// recursive or filter queries, where the path does not name the element.
+//
+// Behavior by Result type:
+//   - Element: The matched element's tag name
+//   - Array, Attribute, Null/Primitives: Returns ""
+//
+// Example:
+//
+//	xml := `<svg><circle r="5"/><rect w="2"/></svg>`
+//	xmldot.Get(xml, "svg.*").ForEach(func(_ int, shape xmldot.Result) bool {
+//	    fmt.Println(shape.Name()) // "circle", then "rect"
+//	    return true
+//	})
+func (r Result) Name() string {
+	if r.Type != Element {
+		return ""
+	}
+	return r.name
+}
+
+// orderedAttrs converts a parsed attribute map into a slice in document order.
+// Returns nil when the element has no attributes.
+func orderedAttrs(attrs map[string]string, order []string) []Attr {
+	if len(order) == 0 {
+		return nil
+	}
+	result := make([]Attr, 0, len(order))
+	for _, name := range order {
+		result = append(result, Attr{Name: name, Value: attrs[name]})
+	}
+	return result
+}
+
 // IsArray returns true if the Result represents an array (multiple elements).
 func (r Result) IsArray() bool {
 	return r.Type == Array
 }
 
+// IsMulti reports whether the result is a collection: an Array, or a
+// single match from a path shaped to return many. A path is collection-shaped
+// when it uses a wildcard (*, **, or a glob such as db_*), field extraction
+// (#.field), an all-matches filter (#(...)#) or #root, and no later index,
+// count or first-match filter narrows it back to one element.
+//
+// Unlike IsArray, which only sees the Type, IsMulti can tell a one-item
+// collection from a scalar: a collection with a single match is returned as
+// that match. IsMulti lets
+// callers choose list or single rendering from the query rather than from how
+// many elements happened to match.
+//
+// Example:
+//
+//	xml := `<root><item>a</item></root>`
+//	Get(xml, "root.item").IsMulti()      // false
+//	Get(xml, "root.*").IsMulti()         // true, one match
+//	Get(xml, "root.item.#.%").IsMulti()  // true
+func (r Result) IsMulti() bool {
+	return r.Type == Array || (r.multi && r.Type != Null)
+}
+
 // Array returns the Result as a slice of Results for array types.
 // For non-array types, returns a single-element slice containing the result.
 func (r Result) Array() []Result {
@@ -184,9 +501,101 @@ func (r Result) Array() []Result {
 	return []Result{r}
 }
 
+// ArrayBytes is like Array, but the items of an Array returned by GetBytes
+// refer to xml, the buffer passed to GetBytes, instead of holding copies:
+// each item's Raw is the element's content exactly as written in xml, and for
+// an element that holds only text without entity references its Str is a
+// view of that text too. Extracting many values this way keeps no second copy
+// of their text alive.
+//
+// The items are only valid while xml is not modified; copy the strings
+// (strings.Clone) to keep them longer. Items without a Span, such as modifier
+// output or attributes and text, and items that xml no longer holds at their
+// Span are returned as Array returns them.
+//
+// Example:
+//
+//	data, _ := os.ReadFile("feed.xml")
+//	for _, title := range xmldot.GetBytes(data, "rss.channel.item.#.title").ArrayBytes(data) {
+//	    index(title.String()) // title.Raw points into data
+//	}
+func (r Result) ArrayBytes(xml []byte) []Result {
+	if r.Type != Array || len(r.Results) == 0 {
+		return r.Array()
+	}
+	items := make([]Result, len(r.Results))
+	parser := newXMLParser(xml)
+	for i, item := range r.Results {
+		items[i] = item
+		start, end := item.Span()
+		if start < 0 || end > len(xml) || xml[start] != '<' {
+			continue
+		}
+		parser.pos = start + 1 // skip '<'
+		name, _, _, isSelfClosing := parser.parseElementTag()
+		contentStart, contentEnd := parser.pos, parser.pos
+		if !isSelfClosing {
+			contentEnd = parser.skipElementContent(name)
+		}
+		if name != item.name || parser.pos != end {
+			continue
+		}
+		content := bytesToString(xml[contentStart:contentEnd])
+		items[i].Raw = content
+		if strings.IndexByte(content, '<') < 0 && strings.IndexByte(content, '&') < 0 {
+			items[i].Str = strings.TrimSpace(content)
+		}
+	}
+	return items
+}
+
+// Strings returns the String value of each item of Array(), so a Null result
+// gives an empty slice and any other non-array result a single value.
+//
+// Example:
+//
+//	names := xmldot.Get(xml, "users.user.#.name").Strings() // ["Alice", "Bob"]
+func (r Result) Strings() []string {
+	items := r.Array()
+	values := make([]string, len(items))
+	for i, item := range items {
+		values[i] = item.String()
+	}
+	return values
+}
+
+// Floats is like Strings but converts each item with Float. Items that are
+// not numeric become 0 rather than being skipped, so values[i] always
+// corresponds to Array()[i].
+func (r Result) Floats() []float64 {
+	items := r.Array()
+	values := make([]float64, len(items))
+	for i, item := range items {
+		values[i] = item.Float()
+	}
+	return values
+}
+
+// Ints is like Strings but converts each item with Int. Items that are not
+// integers become 0 rather than being skipped, so values[i] always
+// corresponds to Array()[i].
+func (r Result) Ints() []int64 {
+	items := r.Array()
+	values := make([]int64, len(items))
+	for i, item := range items {
+		values[i] = item.Int()
+	}
+	return values
+}
+
 // ForEach iterates over array elements, calling the iterator function for each.
 // The iterator receives the index and value. Return false to stop iteration.
 // For non-array types, the iterator is called once with index 0.
+//
+// Elements are visited in the order of the Array, which for wildcard queries
+// is document order. For example, ForEach on "menu.*" visits every child of
+// menu regardless of name, with index being the child's position among
+// menu's child elements; use Name to tell the children apart.
 func (r Result) ForEach(iterator func(index int, value Result) bool) {
 	if r.Type == Array {
 		for i, result := range r.Results {
@@ -199,6 +608,76 @@ func (r Result) ForEach(iterator func(index int, value Result) bool) {
 	}
 }
 
+// ForEachNamed is like ForEach but also passes each value's element name, so
+// iteration over heterogeneous children (e.g. "svg.*") can branch on the tag.
+// The name is "" for values that are not elements; see Name.
+//
+// Example:
+//
+//	xml := `<svg><circle r="5"/><rect width="2"/></svg>`
+//	xmldot.Get(xml, "svg.*").ForEachNamed(func(_ int, name string, shape xmldot.Result) bool {
+//	    switch name {
+//	    case "circle":
+//	        fmt.Println("radius", shape.Get("@r"))
+//	    case "rect":
+//	        fmt.Println("width", shape.Get("@width"))
+//	    }
+//	    return true
+//	})
+func (r Result) ForEachNamed(iterator func(index int, name string, value Result) bool) {
+	r.ForEach(func(index int, value Result) bool {
+		return iterator(index, value.Name(), value)
+	})
+}
+
+// ArrayIter lazily iterates over the items of a Result, calling iterator for
+// each one in document order. Return false to stop iteration early; any items
+// after that point are never parsed.
+//
+// Behavior by Result type:
+//   - Array: Iterates over the already collected Results
+//   - Element: Iterates over the element's immediate child elements, parsing
+//     them one at a time from Raw instead of materializing a slice up front
+//   - Null: The iterator is never called
+//   - Primitives (String, Number, Attribute): The iterator is called once with index 0
+//
+// This is useful for large lists where only the first few items are needed,
+// or as a lazy alternative to "#.field" extraction over thousands of elements:
+//
+//	items := Get(xml, "catalog.items")
+//	items.ArrayIter(func(i int, item Result) bool {
+//	    fmt.Println(item.Get("name").String())
+//	    return i < 9 // stop after the first 10 items
+//	})
+func (r Result) ArrayIter(iterator func(index int, value Result) bool) {
+	switch r.Type {
+	case Array:
+		r.ForEach(iterator)
+	case Element:
+		parser := newXMLParser(stringToBytes(r.Raw))
+		index := 0
+		for parser.skipToNextElement() {
+			parser.next() // skip '<'
+			name, attrs, attrOrder, isSelfClosing := parser.parseElementTag()
+			tag := parser.tag
+
+			var content string
+			if !isSelfClosing {
+				content = parser.parseElementContent(name)
+			}
+
+			if !iterator(index, newElementResult(elementMatch{name: name, attrs: attrs, attrOrder: attrOrder, content: content, tag: tag})) {
+				return
+			}
+			index++
+		}
+	case Null:
+		return
+	default:
+		iterator(0, r)
+	}
+}
+
 // Helper functions for type conversion
 
 // parseInt64 parses a string to int64, handling various formats
@@ -291,10 +770,10 @@ func (r Result) Get(path string) Result {
 	// Example: <user>A</user><user>B</user> requires wrapping for "user.#" to work
 	if isMultiRootFragment(r.Raw) {
 		// Wrap fragment in temporary root element
-		wrapped := "<_xmldot_root>" + r.Raw + "</_xmldot_root>"
+		wrapped := fragmentWrapperStart + r.Raw + "</_xmldot_root>"
 		// Prepend root to path and query
 		result := GetString(wrapped, "_xmldot_root."+path)
-		return result
+		return shiftSpan(result, -len(fragmentWrapperStart))
 	}
 
 	// Single-root element: re-parse Raw XML using zero-copy helper
@@ -389,10 +868,10 @@ func (r Result) GetWithOptions(path string, opts *Options) Result {
 	// Multi-root fragments need special handling for array operations (#, #.field, indexing)
 	if isMultiRootFragment(r.Raw) {
 		// Wrap fragment in temporary root element
-		wrapped := "<_xmldot_root>" + r.Raw + "</_xmldot_root>"
+		wrapped := fragmentWrapperStart + r.Raw + "</_xmldot_root>"
 		// Prepend root to path and query
 		result := GetStringWithOptions(wrapped, "_xmldot_root."+path, opts)
-		return result
+		return shiftSpan(result, -len(fragmentWrapperStart))
 	}
 
 	// Single-root element: re-parse Raw XML with options using zero-copy helper
@@ -579,7 +1058,8 @@ func parseMapChildren(xml string) map[string]Result {
 		}
 
 		parser.next() // skip '<'
-		childName, _, childIsSelfClosing := parser.parseElementName()
+		childName, childAttrs, childAttrOrder, childIsSelfClosing := parser.parseElementTag()
+		childTag := parser.tag
 
 		var childContent string
 
@@ -589,12 +1069,14 @@ func parseMapChildren(xml string) map[string]Result {
 			childContent = parser.parseElementContent(childName)
 		}
 
-		// Create Result for this child
-		newChild := Result{
-			Type: Element,
-			Str:  unescapeXML(extractTextContent(childContent)),
-			Raw:  childContent, // Store content, not full XML
-		}
+		// Create Result for this child (Raw stores content, not full XML)
+		newChild := newElementResult(elementMatch{
+			name:      childName,
+			attrs:     childAttrs,
+			tag:       childTag,
+			attrOrder: childAttrOrder,
+			content:   childContent,
+		})
 
 		// Add child to map, handling duplicates by converting to Array
 		addChildToMap(result, childName, newChild)
@@ -648,7 +1130,8 @@ func parseMapChildrenWithOptions(xml string, opts *Options) map[string]Result {
 		}
 
 		parser.next() // skip '<'
-		childName, _, childIsSelfClosing := parser.parseElementName()
+		childName, childAttrs, childAttrOrder, childIsSelfClosing := parser.parseElementTag()
+		childTag := parser.tag
 
 		var childContent string
 
@@ -664,12 +1147,14 @@ func parseMapChildrenWithOptions(xml string, opts *Options) map[string]Result {
 			mapKey = strings.ToLower(childName)
 		}
 
-		// Create Result for this child
-		newChild := Result{
-			Type: Element,
-			Str:  unescapeXML(extractTextContent(childContent)),
-			Raw:  childContent, // Store content, not full XML
-		}
+		// Create Result for this child (Raw stores content, not full XML)
+		newChild := newElementResult(elementMatch{
+			name:      childName,
+			attrs:     childAttrs,
+			tag:       childTag,
+			attrOrder: childAttrOrder,
+			content:   childContent,
+		})
 
 		// Add child to map, handling duplicates by converting to Array
 		addChildToMap(result, mapKey, newChild)
@@ -4,6 +4,7 @@
 package xmldot
 
 import (
+	"bytes"
 	"fmt"
 	"testing"
 )
@@ -87,6 +88,44 @@ func TestResult_String(t *testing.T) {
 	}
 }
 
+func TestResult_String_NumericVerbatim(t *testing.T) {
+	xml := `<r><a> 01.50 </a><b v="+3">1e2</b><c>-0</c><d>.5</d></r>`
+
+	tests := []struct {
+		path  string
+		want  string
+		float float64
+	}{
+		{"r.a", "01.50", 1.5},
+		{"r.a.%", "01.50", 1.5},
+		{"r.b.@v", "+3", 3},
+		{"r.b", "1e2", 100},
+		{"r.c", "-0", 0},
+		{"r.d", ".5", 0.5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			result := Get(xml, tt.path)
+			if got := result.String(); got != tt.want {
+				t.Errorf("String() = %q, want %q", got, tt.want)
+			}
+			if got := result.Float(); got != tt.float {
+				t.Errorf("Float() = %v, want %v", got, tt.float)
+			}
+
+			// Writing the Result back keeps the original text
+			written, err := Set(xml, "r.copy", result)
+			if err != nil {
+				t.Fatalf("Set() error = %v", err)
+			}
+			if got := Get(written, "r.copy").String(); got != tt.want {
+				t.Errorf("round-trip = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestResult_String_Array(t *testing.T) {
 	tests := []struct {
 		name   string
@@ -331,6 +370,152 @@ func TestResult_Bool(t *testing.T) {
 	}
 }
 
+func TestResult_ScalarsFromArray(t *testing.T) {
+	xml := `<items>
+		<item><x>0</x><count>7</count><ok>no</ok></item>
+		<item><x>2</x><count>3</count><ok>true</ok></item>
+		<item><x>5</x><count>9</count><ok>yes</ok></item>
+	</items>`
+
+	counts := Get(xml, "items.item.#(x>0)#.count")
+	if !counts.IsArray() {
+		t.Fatalf("expected Array result, got %v", counts.Type)
+	}
+	if got := counts.Int(); got != 3 {
+		t.Errorf("Int() = %d, want 3", got)
+	}
+	if got := counts.Float(); got != 3 {
+		t.Errorf("Float() = %v, want 3", got)
+	}
+	if got := Get(xml, "items.item.#(x>0)#.ok").Bool(); !got {
+		t.Errorf("Bool() = %v, want true", got)
+	}
+	if got := Get(xml, "items.item.#.ok|@reverse").Bool(); !got {
+		t.Errorf("Bool() after @reverse = %v, want true", got)
+	}
+
+	// String keeps the JSON-like representation of the whole array
+	if got, want := counts.String(), `["3","9"]`; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+
+	empty := Result{Type: Array, Results: []Result{}}
+	if empty.Int() != 0 || empty.Float() != 0 || empty.Bool() {
+		t.Errorf("empty array conversions = %d, %v, %v, want zero values",
+			empty.Int(), empty.Float(), empty.Bool())
+	}
+}
+
+// TestResult_IsEmpty tests IsEmpty for each Result type
+func TestResult_IsEmpty(t *testing.T) {
+	xml := `<root><item id="" name="a"/><item id="2"/><box>  </box><box><c/></box><n>0</n></root>`
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"root.item.@id", true},
+		{"root.item.@name", false},
+		{"root.item.@missing", false},
+		{"root.box", true},
+		{"root.box.1", false},
+		{"root.item.0", true},
+		{"root.n", false},
+		{"root.n|@this", false},
+		{"root.item.#.@missing", true},
+		{"root.item.#.@id", false},
+		{"root.missing", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := Get(xml, tt.path).IsEmpty(); got != tt.want {
+				t.Errorf("Get(%q).IsEmpty() = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+
+	for _, r := range []Result{{Type: Number, Num: 0}, {Type: False}, {Type: Null}} {
+		if r.IsEmpty() {
+			t.Errorf("Result{Type: %v}.IsEmpty() = true, want false", r.Type)
+		}
+	}
+	if !(Result{Type: String}).IsEmpty() {
+		t.Error("empty String Result.IsEmpty() = false, want true")
+	}
+}
+
+// TestResult_IsMulti tests that IsMulti follows the shape of the path rather
+// than the number of matches
+func TestResult_IsMulti(t *testing.T) {
+	xml := `<root><item id="1">a</item><box><item>b</item></box></root>`
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"root.item", false},
+		{"root.item.0", false},
+		{"root.item.@id", false},
+		{"root.item.#", false},
+		{"root.*", true},
+		{"root.*.0", false},
+		{"root.it*", true},
+		{"root.*.@id", true},
+		{"root.**.item", true},
+		{"root.**.item.0", false},
+		{"root.item.#.%", true},
+		{"root.item.#(@id==1)#", true},
+		{"root.item.#(@id==1)", false},
+		{"#root", true},
+		{"root.*.missing", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := Get(xml, tt.path).IsMulti(); got != tt.want {
+				t.Errorf("Get(%q).IsMulti() = %v, want %v", tt.path, got, tt.want)
+			}
+			opts := &Options{CaseSensitive: false}
+			if got := GetWithOptions(xml, tt.path, opts).IsMulti(); got != tt.want {
+				t.Errorf("GetWithOptions(%q).IsMulti() = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+
+	if !Get(xml, "root").Get("*").IsMulti() {
+		t.Error("chained Get(\"*\").IsMulti() = false, want true")
+	}
+}
+
+// TestResult_TypedSlices tests Strings, Floats and Ints
+func TestResult_TypedSlices(t *testing.T) {
+	xml := `<items>
+		<item><name>a</name><price>1.5</price></item>
+		<item><name>b</name><price>n/a</price></item>
+		<item><name>c</name><price>3</price></item>
+	</items>`
+
+	if got := Get(xml, "items.item.#.name").Strings(); fmt.Sprint(got) != "[a b c]" {
+		t.Errorf("Strings() = %v, want [a b c]", got)
+	}
+	if got := Get(xml, "items.item.#.price").Floats(); fmt.Sprint(got) != "[1.5 0 3]" {
+		t.Errorf("Floats() = %v, want [1.5 0 3]", got)
+	}
+	if got := Get(xml, "items.item.#.price").Ints(); fmt.Sprint(got) != "[0 0 3]" {
+		t.Errorf("Ints() = %v, want [0 0 3]", got)
+	}
+
+	// Non-array results
+	if got := Get(xml, "items.item.name").Strings(); fmt.Sprint(got) != "[a]" {
+		t.Errorf("Strings() on a single value = %v, want [a]", got)
+	}
+	missing := Get(xml, "items.missing")
+	if len(missing.Strings()) != 0 || len(missing.Floats()) != 0 || len(missing.Ints()) != 0 {
+		t.Error("typed slices of Null should be empty")
+	}
+}
+
 func TestResult_Value(t *testing.T) {
 	tests := []struct {
 		name   string
@@ -509,6 +694,74 @@ func TestForEachEmpty(t *testing.T) {
 	}
 }
 
+// TestResultForEachMixedChildren tests that a single-level wildcard returns
+// children of any name in document order and ForEach keeps that order
+func TestResultForEachMixedChildren(t *testing.T) {
+	xml := `<menu><item id="1">Open</item><separator/><!-- gap --><item id="2">Save</item>text<group><item>Nested</item></group><separator kind="thin"/></menu>`
+
+	want := []struct {
+		name string
+		raw  string
+	}{
+		{"item", "Open"},
+		{"separator", ""},
+		{"item", "Save"},
+		{"group", "<item>Nested</item>"},
+		{"separator", ""},
+	}
+
+	for _, opts := range []*Options{nil, {CaseSensitive: false}} {
+		var got []int
+		GetWithOptions(xml, "menu.*", opts).ForEach(func(index int, child Result) bool {
+			got = append(got, index)
+			if index >= len(want) {
+				t.Errorf("unexpected child %d: %s", index, child.Name())
+				return false
+			}
+			if child.Name() != want[index].name || child.Raw != want[index].raw {
+				t.Errorf("child %d = <%s> %q, want <%s> %q", index, child.Name(), child.Raw, want[index].name, want[index].raw)
+			}
+			return true
+		})
+		if len(got) != len(want) {
+			t.Errorf("ForEach visited %d children, want %d", len(got), len(want))
+		}
+	}
+}
+
+// TestResultForEachNamed tests iterating with element names
+func TestResultForEachNamed(t *testing.T) {
+	xml := `<svg><circle r="5"/><rect width="2"/><text>hi</text><rect width="3"/></svg>`
+
+	var got []string
+	Get(xml, "svg.*").ForEachNamed(func(index int, name string, value Result) bool {
+		got = append(got, fmt.Sprintf("%d:%s:%s", index, name, value.Name()))
+		return name != "text"
+	})
+	if fmt.Sprint(got) != "[0:circle:circle 1:rect:rect 2:text:text]" {
+		t.Errorf("ForEachNamed() visited %v", got)
+	}
+
+	// Non-element values have no name
+	var names []string
+	Get(xml, "svg.rect.#.@width").ForEachNamed(func(_ int, name string, value Result) bool {
+		names = append(names, name+"="+value.String())
+		return true
+	})
+	if fmt.Sprint(names) != "[=2 =3]" {
+		t.Errorf("ForEachNamed() on attributes = %v", names)
+	}
+
+	called := false
+	Get(xml, "svg.missing").ForEachNamed(func(int, string, Result) bool {
+		called = true
+		return true
+	})
+	if called {
+		t.Error("ForEachNamed() should not call the iterator for Null")
+	}
+}
+
 // TestResultForEachEarlyTermination tests ForEach with early termination (return false)
 func TestResultForEachEarlyTermination(t *testing.T) {
 	// Create an array result manually for testing
@@ -1577,6 +1830,186 @@ func ExampleResult_Map() {
 // ============================================================================
 
 // TestResult_Get_MultiRootFieldExtraction tests #.field extraction on multi-root fragments
+func TestResult_ArrayIter(t *testing.T) {
+	xml := `<root><items><item id="1">a</item>text<item id="2"/><other>c</other></items></root>`
+
+	t.Run("Element iterates children lazily", func(t *testing.T) {
+		var values []string
+		Get(xml, "root.items").ArrayIter(func(i int, r Result) bool {
+			if i != len(values) {
+				t.Errorf("index = %d, want %d", i, len(values))
+			}
+			values = append(values, r.String())
+			return true
+		})
+		expected := []string{"a", "", "c"}
+		if len(values) != len(expected) {
+			t.Fatalf("got %d items, want %d", len(values), len(expected))
+		}
+		for i := range expected {
+			if values[i] != expected[i] {
+				t.Errorf("item[%d] = %q, want %q", i, values[i], expected[i])
+			}
+		}
+	})
+
+	t.Run("Early stop", func(t *testing.T) {
+		count := 0
+		Get(xml, "root.items").ArrayIter(func(_ int, r Result) bool {
+			count++
+			return false
+		})
+		if count != 1 {
+			t.Errorf("iterator called %d times, want 1", count)
+		}
+	})
+
+	t.Run("Array", func(t *testing.T) {
+		var ids []string
+		Get(xml, "root.items.item.#.@id").ArrayIter(func(_ int, r Result) bool {
+			ids = append(ids, r.String())
+			return true
+		})
+		if len(ids) != 2 || ids[0] != "1" || ids[1] != "2" {
+			t.Errorf("ids = %v, want [1 2]", ids)
+		}
+	})
+
+	t.Run("Null and primitives", func(t *testing.T) {
+		called := 0
+		Get(xml, "root.missing").ArrayIter(func(_ int, _ Result) bool {
+			called++
+			return true
+		})
+		if called != 0 {
+			t.Errorf("Null: iterator called %d times", called)
+		}
+		Get(xml, "root.items.item.@id").ArrayIter(func(i int, r Result) bool {
+			called++
+			if i != 0 || r.String() != "1" {
+				t.Errorf("Attribute: got (%d, %q)", i, r.String())
+			}
+			return true
+		})
+		if called != 1 {
+			t.Errorf("Attribute: iterator called %d times, want 1", called)
+		}
+	})
+}
+
+func TestResult_Attributes_DocumentOrder(t *testing.T) {
+	xml := `<root><item z="26" a="1" m="13" xmlns:ns="urn:x" ns:k="v">text</item></root>`
+
+	expected := []Attr{
+		{Name: "z", Value: "26"},
+		{Name: "a", Value: "1"},
+		{Name: "m", Value: "13"},
+		{Name: "xmlns:ns", Value: "urn:x"},
+		{Name: "ns:k", Value: "v"},
+	}
+
+	// Repeat to catch any dependence on map iteration order
+	for run := 0; run < 20; run++ {
+		attrs := Get(xml, "root.item").Attributes()
+		if len(attrs) != len(expected) {
+			t.Fatalf("Expected %d attributes, got %d", len(expected), len(attrs))
+		}
+		for i, attr := range attrs {
+			if attr != expected[i] {
+				t.Fatalf("run %d: attr[%d] = %v, expected %v", run, i, attr, expected[i])
+			}
+		}
+	}
+}
+
+func TestResult_Attributes_NestedRawOrder(t *testing.T) {
+	// Attributes of nested children must appear in document order in Raw
+	xml := `<root><parent><child z="1" y="2" x="3" w="4"/></parent></root>`
+
+	first := Get(xml, "root.parent").Raw
+	for run := 0; run < 20; run++ {
+		raw := Get(xml, "root.parent").Raw
+		if raw != first {
+			t.Fatalf("Raw is not deterministic: %q vs %q", raw, first)
+		}
+	}
+	if first != `<child z="1" y="2" x="3" w="4"/>` {
+		t.Errorf("Raw = %q, expected attributes in document order", first)
+	}
+}
+
+func TestResult_Attributes_EdgeCases(t *testing.T) {
+	xml := `<root><item a="1">x</item><item b="2">y</item><plain>z</plain></root>`
+
+	tests := []struct {
+		name     string
+		result   Result
+		expected int
+	}{
+		{"Null result", Get(xml, "root.missing"), 0},
+		{"Attribute result", Get(xml, "root.item.@a"), 0},
+		{"Element without attributes", Get(xml, "root.plain"), 0},
+		{"Array delegates to first", Get(xml, "root.*"), 1},
+		{"Map child", Get(xml, "root").Map()["plain"], 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := len(tt.result.Attributes()); got != tt.expected {
+				t.Errorf("len(Attributes()) = %d, expected %d", got, tt.expected)
+			}
+		})
+	}
+
+	// Returned slice is a copy
+	item := Get(xml, "root.item")
+	attrs := item.Attributes()
+	attrs[0].Value = "changed"
+	if item.Attributes()[0].Value != "1" {
+		t.Error("Attributes() should return a copy")
+	}
+}
+
+func TestResult_Name(t *testing.T) {
+	xml := `<svg xmlns:s="urn:s"><circle r="5"/><rect w="2"><s:title>t</s:title></rect><g><circle r="1"/></g></svg>`
+
+	tests := []struct {
+		name     string
+		result   Result
+		expected string
+	}{
+		{"Root element", Get(xml, "svg"), "svg"},
+		{"Prefixed element", Get(xml, "svg.rect.s:title"), "s:title"},
+		{"Wildcard first match", Get(xml, "svg.rect.*"), "s:title"},
+		{"Filter match", Get(xml, "svg.g.circle.#(@r==1)"), "circle"},
+		{"Fluent child", Get(xml, "svg").Get("g.circle"), "circle"},
+		{"Map child", Get(xml, "svg").Map()["rect"], "rect"},
+		{"Options", GetWithOptions(xml, "SVG.RECT", &Options{}), "rect"},
+		{"Array result", Get(xml, "svg.*"), ""},
+		{"Attribute result", Get(xml, "svg.rect.@w"), ""},
+		{"Text result", Get(xml, "svg.rect.s:title.%"), ""},
+		{"Null result", Get(xml, "svg.missing"), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.result.Name(); got != tt.expected {
+				t.Errorf("Name() = %q, expected %q", got, tt.expected)
+			}
+		})
+	}
+
+	// Array items carry their own names
+	var names []string
+	Get(xml, "svg.*").ForEach(func(_ int, r Result) bool {
+		names = append(names, r.Name())
+		return true
+	})
+	if fmt.Sprint(names) != "[circle rect g]" {
+		t.Errorf("ForEach names = %v, expected [circle rect g]", names)
+	}
+}
+
 func TestResult_Get_MultiRootFieldExtraction(t *testing.T) {
 	xml := `<root>
 		<filter>
@@ -1843,3 +2276,187 @@ func TestIsMultiRootFragment(t *testing.T) {
 		})
 	}
 }
+
+// TestResult_Span tests that Span returns the element's offsets in the input
+func TestResult_Span(t *testing.T) {
+	xml := "<config>\n  <db  port = \"5432\"><host>x</host></db>\n" +
+		`  <item id="1">a</item><item id="2"/><item id="3">c<b/></item>` + "\n</config>"
+
+	tests := []struct {
+		path string
+		want []string // markup of each span; nil when there is none
+	}{
+		{"config.db", []string{`<db  port = "5432"><host>x</host></db>`}},
+		{"config.db.host", []string{`<host>x</host>`}},
+		{"config.item.1", []string{`<item id="2"/>`}},
+		{"config.item.#(@id>1)", []string{`<item id="2"/>`}},
+		{"config.item.#(@id>1)#", []string{`<item id="2"/>`, `<item id="3">c<b/></item>`}},
+		{"config.*", []string{`<db  port = "5432"><host>x</host></db>`, `<item id="1">a</item>`, `<item id="2"/>`, `<item id="3">c<b/></item>`}},
+		{"config.item.@id", nil},
+		{"config.item.#", nil},
+		{"config.**.host", []string{`<host>x</host>`}},
+		{"config.**.b", []string{`<b/>`}},
+		{"config.item|@reverse", nil},
+		{"config.missing", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			result := Get(xml, tt.path)
+			items := []Result{result}
+			if result.Type == Array {
+				if start, end := result.Span(); start != -1 || end != -1 {
+					t.Errorf("Array Span() = %d, %d, want -1, -1", start, end)
+				}
+				items = result.Array()
+			}
+			for i, item := range items {
+				start, end := item.Span()
+				if tt.want == nil {
+					if start != -1 || end != -1 {
+						t.Errorf("Span() = %d, %d, want -1, -1", start, end)
+					}
+					continue
+				}
+				if i >= len(tt.want) || start < 0 || xml[start:end] != tt.want[i] {
+					t.Errorf("item %d Span() = %d, %d, want %q", i, start, end, tt.want[i])
+				}
+			}
+		})
+	}
+
+	opts := &Options{CaseSensitive: false}
+	if start, end := GetWithOptions(xml, "CONFIG.ITEM.2", opts).Span(); start < 0 || xml[start:end] != `<item id="3">c<b/></item>` {
+		t.Errorf("GetWithOptions Span() = %d, %d", start, end)
+	}
+
+	// Chained queries report offsets within the parent's Raw
+	parent := Get(`<r><u>a</u><u>b</u></r>`, "r")
+	if start, end := parent.Get("u.1").Span(); start < 0 || parent.Raw[start:end] != `<u>b</u>` {
+		t.Errorf("chained Span() = %d, %d in %q", start, end, parent.Raw)
+	}
+
+	// Spans are recorded by the query, so later changes to the buffer
+	// do not move them
+	data := []byte(`<r><a/><b/></r>`)
+	b := GetBytes(data, "r.b")
+	copy(data, `<r><b/><a/></r>`)
+	if start, end := b.Span(); start != 7 || end != 11 {
+		t.Errorf("Span() after changing the buffer = %d, %d, want 7, 11", start, end)
+	}
+}
+
+func TestResult_ArrayBytes(t *testing.T) {
+	data := []byte(`<feed><item id="1"><title> Go </title></item><item id="2"><title>A &amp; B</title></item><item id="3"><title/></item></feed>`)
+
+	result := GetBytes(data, "feed.item.#.title")
+	items := result.ArrayBytes(data)
+	if len(items) != 3 {
+		t.Fatalf("ArrayBytes() returned %d items, want 3", len(items))
+	}
+	wantStr := []string{"Go", "A & B", ""}
+	wantRaw := []string{" Go ", "A &amp; B", ""}
+	wantSpan := []string{"<title> Go </title>", "<title>A &amp; B</title>", "<title/>"}
+	for i, item := range items {
+		if item.String() != wantStr[i] || item.Raw != wantRaw[i] || item.Name() != "title" {
+			t.Errorf("item %d = %q (Raw %q, Name %q), want %q (Raw %q)", i, item.String(), item.Raw, item.Name(), wantStr[i], wantRaw[i])
+		}
+		if start, end := item.Span(); start < 0 || string(data[start:end]) != wantSpan[i] {
+			t.Errorf("item %d Span() = %d, %d, want %q", i, start, end, wantSpan[i])
+		}
+	}
+
+	// Raw refers to the input buffer rather than a copy
+	data[bytes.Index(data, []byte(" Go "))+1] = 'N'
+	if items[0].Raw != " No " {
+		t.Errorf("item 0 Raw = %q after changing the buffer, want it to alias the input", items[0].Raw)
+	}
+
+	// Results not backed by a buffer fall back to Array
+	for _, path := range []string{"feed.item.#.@id", "feed.item|@reverse", "feed.item.0"} {
+		r := GetBytes(data, path)
+		if got, want := len(r.ArrayBytes(data)), len(r.Array()); got != want {
+			t.Errorf("%s: ArrayBytes() returned %d items, want %d", path, got, want)
+		}
+	}
+
+	// Items the buffer no longer holds at their span keep their copies
+	edited := []byte(`<feed><item><title>x</title></item></feed>`)
+	copied := GetBytes(edited, "feed.item.#.title")
+	copy(edited, `<feed><item><Title>y</Title></item></feed>`)
+	if items := copied.ArrayBytes(edited); items[0].Raw != "x" {
+		t.Errorf("ArrayBytes() of an edited buffer: Raw = %q, want the copy %q", items[0].Raw, "x")
+	}
+
+	if items := (Result{}).ArrayBytes(nil); len(items) != 0 {
+		t.Errorf("Null ArrayBytes() returned %d items", len(items))
+	}
+}
+
+func TestResult_HasTextAndTextLen(t *testing.T) {
+	xml := `<form>
+	<name>  Ann  </name>
+	<blank> </blank>
+	<empty/>
+	<address><zip>0150</zip></address>
+	<mixed> a <b>bold</b> c </mixed>
+	<entity>&lt;&amp;&gt;</entity>
+	<cdata><![CDATA[ x & y ]]></cdata>
+	<commented><!-- note --><?pi data?></commented>
+	<nested><a><a>deep</a></a></nested>
+</form>`
+
+	tests := []struct {
+		path    string
+		hasText bool
+		textLen int
+	}{
+		{"form.name", true, 3},
+		{"form.blank", false, 0},
+		{"form.empty", false, 0},
+		{"form.address", false, 0},
+		{"form.address.zip", true, 4},
+		{"form.mixed", true, len("a  c")},
+		{"form.entity", true, 3},
+		{"form.cdata", true, len("x & y")},
+		{"form.commented", false, 0},
+		{"form.nested", false, 0},
+		{"form.missing", false, 0},
+		{"form.address.zip.%", true, 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			r := Get(xml, tt.path)
+			if got := r.HasText(); got != tt.hasText {
+				t.Errorf("HasText() = %v, want %v", got, tt.hasText)
+			}
+			if got := r.TextLen(); got != tt.textLen {
+				t.Errorf("TextLen() = %d, want %d", got, tt.textLen)
+			}
+		})
+	}
+
+	// Text-only elements match the length of String()
+	for _, path := range []string{"form.name", "form.entity", "form.address.zip"} {
+		r := Get(xml, path)
+		if r.TextLen() != len(r.String()) {
+			t.Errorf("%s: TextLen() = %d, len(String()) = %d", path, r.TextLen(), len(r.String()))
+		}
+	}
+
+	// Attributes and arrays
+	attrXML := `<item id="" name="x"><v>1</v><v>2</v></item>`
+	if Get(attrXML, "item.@id").HasText() || !Get(attrXML, "item.@name").HasText() {
+		t.Error("HasText() on attributes should report whether the value is non-empty")
+	}
+	if arr := Get(attrXML, "item.v.#.%"); arr.HasText() || arr.TextLen() != 0 {
+		t.Error("HasText() and TextLen() should be false and 0 for an Array")
+	}
+
+	// Measuring does not allocate
+	r := Get(xml, "form.mixed")
+	if allocs := testing.AllocsPerRun(100, func() { _ = r.TextLen(); _ = r.HasText() }); allocs != 0 {
+		t.Errorf("TextLen() and HasText() allocated %v times", allocs)
+	}
+}
@@ -4,6 +4,7 @@
 package xmldot
 
 import (
+	"bytes"
 	"fmt"
 	"strings"
 )
@@ -39,11 +40,39 @@ import (
 //
 //	xml := `<root></root>`
 //	result, _ := Set(xml, "root.user.@id", "123")
-//	// result: <root><user id="123"></user></root>
+//	// result: <root><user id="123"/></root>
+//
+// Fan-Out Writes:
+//
+// A wildcard (*), #.child or #(condition)# filter segment applies the write
+// to every matched element. Matches are never created; the rest of the path
+// is updated or created inside each match:
+//
+//	xml := `<catalog><product/><product><currency>EUR</currency></product></catalog>`
+//	result, _ := Set(xml, "catalog.product.#.currency", "USD")
+//	// result: <catalog><product><currency>USD</currency></product><product><currency>USD</currency></product></catalog>
+//
+// A * wildcard matches children of any name, so it selects the children of
+// the element before it, not every such element: catalog.product.*.currency
+// addresses a currency inside each child of the first product. Below a *,
+// elements are only updated; if one is missing the write returns
+// ErrInvalidPath instead of creating it inside an unrelated element. Use
+// name.#.child to write to a child of every name element.
+//
+// Delete removes the rest of the path inside each match, or, for a path
+// ending in the fan-out segment (root.item.#(@id>1)#, root.*), the matched
+// elements themselves.
+//
+// A fan-out write that matches more than MaxWildcardResults elements returns
+// ErrLimitExceeded and leaves the document unchanged.
 //
 // The value can be:
-//   - string, int, float, bool - converted to text content
+//   - string, float, bool - converted to text content
+//   - any signed or unsigned integer type - written exactly in decimal, so
+//     int64 IDs such as 9223372036854775807 round-trip
 //   - []byte - inserted as raw XML
+//   - Result - a scalar result's String() text verbatim (e.g. "01.50" is not
+//     reformatted), or an Element result's Raw content as XML
 //   - nil - removes the element (same as Delete)
 //
 // Security Considerations:
@@ -62,6 +91,9 @@ import (
 //   - XML exceeds size limits
 //   - XML fails well-formedness checks
 //
+// The error message describes the first problem found, for example
+// "mismatched closing tag: expected </item>, found </wrong> at offset 17".
+//
 // Example:
 //
 //	xml := `<root><user><name>John</name></user></root>`
@@ -80,6 +112,145 @@ func SetBytes(xml []byte, path string, value interface{}) ([]byte, error) {
 	return SetBytesWithOptions(xml, path, value, DefaultOptions())
 }
 
+// CanSet reports whether Set(xml, path, value) would succeed. It returns nil
+// if the path is resolvable or creatable and the value is acceptable, and
+// otherwise the same typed error Set would return (match it with errors.Is
+// against ErrMalformedXML, ErrInvalidPath or ErrInvalidValue).
+//
+// CanSet runs the checks of Set without building the modified document:
+// the document must be well-formed, the path writable and the value
+// convertible, and an index or fan-out path must fit the existing elements.
+// Only the size of the resulting document is left to Set, which may still
+// reject a write CanSet accepted with ErrInvalidValue when the result would
+// exceed MaxDocumentSize. This is useful for validating edits up front, for
+// example in editors or forms, without replacing the document.
+//
+// Example:
+//
+//	xml := `<root><items><item>a</item></items></root>`
+//	CanSet(xml, "root.items.item.-1", "b")      // nil
+//	CanSet(xml, "root.items.item.-2", "b")      // ErrInvalidPath
+//	CanSet(xml, "root.items", struct{}{})       // ErrInvalidValue
+func CanSet(xml, path string, value interface{}) error {
+	return CanSetBytes(stringToBytes(xml), path, value)
+}
+
+// CanSetBytes is like CanSet but accepts xml as a byte slice.
+func CanSetBytes(xml []byte, path string, value interface{}) error {
+	opts := DefaultOptions()
+	if value == nil {
+		builder, segments, err := validateDelete(xml, path, opts)
+		if err != nil {
+			return err
+		}
+		if isFanOutPath(segments) {
+			return builder.checkFanOut(segments, nil)
+		}
+		return nil
+	}
+	builder, plan, err := validateSet(xml, path, value, opts)
+	if err != nil {
+		return err
+	}
+	return builder.checkSetTargets(plan, value)
+}
+
+// SetN is like Set but also reports how many nodes were changed: 1 if the
+// document was modified, or 0 if the value already matched (in which case the
+// original XML is returned unchanged). This lets callers detect no-op updates,
+// for example to skip rewriting a file when nothing changed.
+//
+// Example:
+//
+//	xml := `<root><port>8080</port></root>`
+//	_, n, _ := SetN(xml, "root.port", 8080)
+//	// n: 0 (value already matched)
+//	_, n, _ = SetN(xml, "root.port", 9090)
+//	// n: 1
+func SetN(xml, path string, value interface{}) (string, int, error) {
+	result, n, err := setBytesN([]byte(xml), path, value)
+	if err != nil {
+		return xml, 0, err
+	}
+	if n == 0 {
+		return xml, 0, nil
+	}
+	return string(result), n, nil
+}
+
+// setBytesN applies a single Set and counts the changed nodes.
+func setBytesN(xml []byte, path string, value interface{}) ([]byte, int, error) {
+	if value == nil {
+		return deleteBytesN(xml, path)
+	}
+	if segments := parsePath(path); isFanOutPath(segments) {
+		return fanOutBytesN(xml, segments, value)
+	}
+
+	// Setting an attribute to its current value would still rewrite the tag
+	// (attributes are re-serialized), so detect that case up front
+	if attributeUnchanged(xml, path, value) {
+		return xml, 0, nil
+	}
+
+	result, err := SetBytes(xml, path, value)
+	if err != nil {
+		return xml, 0, err
+	}
+	if bytes.Equal(result, xml) {
+		return xml, 0, nil
+	}
+	return result, 1, nil
+}
+
+// fanOutBytesN applies a wildcard or filter write (a nil value deletes) and
+// counts the matched elements that changed.
+func fanOutBytesN(xml []byte, segments []PathSegment, value interface{}) ([]byte, int, error) {
+	if len(xml) > MaxDocumentSize {
+		return xml, 0, ErrMalformedXML
+	}
+	if err := checkWellFormed(xml); err != nil {
+		return xml, 0, err
+	}
+	builder := newXMLBuilder(xml)
+	n, err := builder.applyFanOut(segments, value)
+	if err != nil {
+		return xml, 0, err
+	}
+	if n == 0 {
+		return xml, 0, nil
+	}
+	return []byte(builder.getResult()), n, nil
+}
+
+// attributeUnchanged reports whether path addresses an existing attribute
+// whose value already equals value.
+func attributeUnchanged(xml []byte, path string, value interface{}) bool {
+	segments := parsePath(path)
+	if len(segments) < 2 || segments[len(segments)-1].Type != SegmentAttribute {
+		return false
+	}
+	for _, seg := range segments {
+		// Index segments may carry append intent (-1); let Set handle them
+		if seg.Type == SegmentIndex && seg.Index < 0 {
+			return false
+		}
+	}
+
+	xmlValue, _, err := valueToXML(value)
+	if err != nil || len(xml) == 0 || !ValidBytes(xml) {
+		return false
+	}
+
+	builder := newXMLBuilder(xml)
+	location, found := builder.findElementLocation(newXMLParser(xml), segments[:len(segments)-1], 0, 0)
+	if !found {
+		return false
+	}
+	current, ok := location.attrs[segments[len(segments)-1].Value]
+	return ok && escapeXML(current) == xmlValue
+}
+
 // SetRaw embeds pre-formatted XML at the specified path without parsing or escaping.
 // The raw XML must be well-formed. This function performs basic validation to ensure
 // the raw XML doesn't contain unmatched tags.
@@ -107,6 +278,22 @@ func SetRaw(xml, path, rawxml string) (string, error) {
 	return Set(xml, path, []byte(rawxml))
 }
 
+// SetRawWithOptions is like SetRaw but accepts Options for behavioral control.
+// With RequireDeclaredNamespaces, a fragment that uses a namespace prefix not
+// declared in the fragment or on an existing enclosing element is rejected
+// with ErrInvalidValue:
+//
+//	xml := `<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/"><soap:Body/></soap:Envelope>`
+//	opts := &Options{CaseSensitive: true, RequireDeclaredNamespaces: true}
+//	_, err := SetRawWithOptions(xml, "soap:Envelope.soap:Body", "<m:GetPrice/>", opts)
+//	// err: invalid value for XML: namespace prefix "m" is not declared
+func SetRawWithOptions(xml, path, rawxml string, opts *Options) (string, error) {
+	if err := validateRawXML(rawxml); err != nil {
+		return xml, err
+	}
+	return SetWithOptions(xml, path, []byte(rawxml), opts)
+}
+
 // validateRawXML performs basic validation on raw XML to prevent injection
 func validateRawXML(rawxml string) error {
 	// Track opening tags on a stack to verify they match closing tags
@@ -239,10 +426,570 @@ func validateRawXML(rawxml string) error {
 	return nil
 }
 
+// SetElement creates or replaces the child element name under the element at
+// path, giving it the provided attributes and text content in a single call.
+// Attribute values and text are escaped automatically, so no hand-built raw
+// XML is needed.
+//
+// If the parent already has a child called name, the first such child is
+// replaced entirely (attributes and content). Otherwise the new element is
+// appended as the last child of the parent. Missing parent elements are
+// created, as with Set. Attributes are written in sorted order for
+// deterministic output.
+//
+// Path Restrictions:
+//
+// The path addresses the parent element and may only contain element names
+// and non-negative array indices (e.g., "project.dependencies" or
+// "items.item.1"). Attributes, wildcards, filters and the -1 append index are
+// rejected with ErrInvalidPath.
+//
+// Error Handling:
+//
+// Returns ErrMalformedXML if the input XML is not well-formed, ErrInvalidPath
+// for an unsupported path, and ErrInvalidValue if name or an attribute name is
+// not a valid XML name, or if the text exceeds MaxValueSize.
+//
+// Example:
+//
+//	xml := `<project><dependencies></dependencies></project>`
+//	modified, _ := SetElement(xml, "project.dependencies", "dependency",
+//		map[string]string{"scope": "test"}, "junit & co")
+//	// modified: <project><dependencies><dependency scope="test">junit &amp; co</dependency></dependencies></project>
+func SetElement(xml, path, name string, attrs map[string]string, text string) (string, error) {
+	result, err := SetElementBytes([]byte(xml), path, name, attrs, text)
+	if err != nil {
+		return xml, err
+	}
+	return string(result), nil
+}
+
+// SetElementBytes is like SetElement but accepts and returns xml as byte slices for efficiency.
+func SetElementBytes(xml []byte, path, name string, attrs map[string]string, text string) ([]byte, error) {
+	// Security check: reject documents that are too large
+	if len(xml) > MaxDocumentSize {
+		return xml, ErrMalformedXML
+	}
+
+	// Empty XML is valid here: the element chain is created from scratch
+	if len(xml) > 0 {
+		if err := checkWellFormed(xml); err != nil {
+			return xml, err
+		}
+	}
+
+	// Validate element and attribute names to prevent XML injection
+	if err := validateName(name); err != nil {
+		return xml, fmt.Errorf("%w: invalid element name %q: %v", ErrInvalidValue, name, err)
+	}
+	if len(attrs) > MaxAttributes {
+		return xml, fmt.Errorf("%w: too many attributes (max %d)", ErrInvalidValue, MaxAttributes)
+	}
+	for attrName := range attrs {
+		if err := validateName(attrName); err != nil {
+			return xml, fmt.Errorf("%w: invalid attribute name %q: %v", ErrInvalidValue, attrName, err)
+		}
+	}
+
+	segments := parsePath(path)
+	if len(segments) == 0 {
+		return xml, ErrInvalidPath
+	}
+	for _, seg := range segments {
+		switch {
+		case seg.Type == SegmentElement:
+		case seg.Type == SegmentIndex && seg.Index >= 0:
+		default:
+			return xml, fmt.Errorf("%w: SetElement path must address an element", ErrInvalidPath)
+		}
+	}
+
+	markup := buildElementMarkup(name, attrs, text)
+
+	// Security check: reject values that are too large
+	if len(markup) > MaxValueSize {
+		return xml, fmt.Errorf("%w: value exceeds maximum size of %d bytes", ErrInvalidValue, MaxValueSize)
+	}
+
+	builder := newXMLBuilder(xml)
+	if err := builder.setChildElement(segments, name, markup); err != nil {
+		return xml, err
+	}
+
+	return []byte(builder.getResult()), nil
+}
+
+// PrependChild inserts rawxml as the first child of the element at path,
+// before its existing children and text, for documents whose schema fixes the
+// order of children. SetRaw and SetElement append instead. Like SetRaw, the
+// raw XML is inserted without escaping and must be balanced.
+//
+// In a document formatted one element per line, the new child takes the
+// line and indentation of the current first child. A self-closing parent is
+// expanded, and missing parent elements are created, as with Set.
+//
+// The path must address an element using element names and non-negative
+// array indices; other paths return ErrInvalidPath. Returns ErrMalformedXML
+// if the input XML is not well-formed and ErrInvalidValue if rawxml is
+// unbalanced or larger than MaxValueSize.
+//
+// Example:
+//
+//	xml := `<html><head><title>Home</title></head></html>`
+//	modified, _ := PrependChild(xml, "html.head", `<meta charset="utf-8"/>`)
+//	// modified: <html><head><meta charset="utf-8"/><title>Home</title></head></html>
+func PrependChild(xml, path, rawxml string) (string, error) {
+	result, err := PrependChildBytes([]byte(xml), path, rawxml)
+	if err != nil {
+		return xml, err
+	}
+	return string(result), nil
+}
+
+// PrependChildBytes is like PrependChild but accepts xml as a byte slice.
+func PrependChildBytes(xml []byte, path, rawxml string) ([]byte, error) {
+	if len(xml) > MaxDocumentSize {
+		return xml, ErrMalformedXML
+	}
+	if len(xml) > 0 {
+		if err := checkWellFormed(xml); err != nil {
+			return xml, err
+		}
+	}
+	if len(rawxml) > MaxValueSize {
+		return xml, fmt.Errorf("%w: value exceeds maximum size of %d bytes", ErrInvalidValue, MaxValueSize)
+	}
+	if err := validateRawXML(rawxml); err != nil {
+		return xml, err
+	}
+
+	segments := parsePath(path)
+	if len(segments) == 0 {
+		return xml, ErrInvalidPath
+	}
+	for _, seg := range segments {
+		switch {
+		case seg.Type == SegmentElement:
+		case seg.Type == SegmentIndex && seg.Index >= 0:
+		default:
+			return xml, fmt.Errorf("%w: PrependChild path must address an element", ErrInvalidPath)
+		}
+	}
+
+	builder := newXMLBuilder(xml)
+	if err := builder.prependChild(segments, rawxml); err != nil {
+		return xml, err
+	}
+	return []byte(builder.getResult()), nil
+}
+
+// Move removes the element at fromPath (with its attributes and children) and
+// inserts it at toPath. Both paths are resolved against the document before
+// the move, so indices refer to the original positions.
+//
+// Destination Rules:
+//
+//   - toPath without a trailing index replaces the element at toPath, or
+//     creates it (including missing parents) if it doesn't exist
+//   - toPath ending in a non-negative index (e.g., "list.item.0") inserts the
+//     element before the element currently at that index
+//   - toPath ending in -1 or # (e.g., "list.item.-1", "list.item.#") appends
+//     the element after the last sibling of that name
+//
+// If the final element name in toPath differs from the moved element's name,
+// the element is renamed. Continuation lines are re-indented to match the
+// destination's indentation.
+//
+// Path Restrictions:
+//
+// Both paths may only contain element names and array indices. Attributes,
+// wildcards, filters and modifiers are rejected with ErrInvalidPath.
+//
+// Error Handling:
+//
+// Returns ErrMalformedXML if the input XML is not well-formed, and
+// ErrInvalidPath if a path is unsupported, the source element doesn't exist,
+// or the destination lies inside the source element.
+//
+// Example:
+//
+//	xml := `<config><old><db>x</db></old><new></new></config>`
+//	modified, _ := Move(xml, "config.old.db", "config.new.db")
+//	// modified: <config><old></old><new><db>x</db></new></config>
+func Move(xml, fromPath, toPath string) (string, error) {
+	result, err := MoveBytes([]byte(xml), fromPath, toPath)
+	if err != nil {
+		return xml, err
+	}
+	return string(result), nil
+}
+
+// MoveBytes is like Move but accepts and returns xml as byte slices for efficiency.
+func MoveBytes(xml []byte, fromPath, toPath string) ([]byte, error) {
+	return transplantBytes(xml, fromPath, toPath, false)
+}
+
+// Copy duplicates the element at fromPath (deep, including attributes and
+// children) to toPath without removing the original. The destination follows
+// the same rules as Move: replace or create without a trailing index, insert
+// before an existing index, append with -1 or #. The copy is renamed to the
+// final name in toPath if it differs, re-indented to the destination, and the
+// resulting document is validated.
+//
+// Error Handling:
+//
+// Returns ErrMalformedXML if the input XML is not well-formed, and
+// ErrInvalidPath if a path is unsupported or the source element doesn't exist.
+//
+// Example (templating):
+//
+//	xml := `<servers><server name="proto"><port>80</port></server></servers>`
+//	modified, _ := Copy(xml, "servers.server", "servers.server.-1")
+//	modified, _ = Set(modified, "servers.server.1.@name", "web1")
+//	// modified: <servers><server name="proto"><port>80</port></server><server name="web1"><port>80</port></server></servers>
+func Copy(xml, fromPath, toPath string) (string, error) {
+	result, err := CopyBytes([]byte(xml), fromPath, toPath)
+	if err != nil {
+		return xml, err
+	}
+	return string(result), nil
+}
+
+// CopyBytes is like Copy but accepts and returns xml as byte slices for efficiency.
+func CopyBytes(xml []byte, fromPath, toPath string) ([]byte, error) {
+	return transplantBytes(xml, fromPath, toPath, true)
+}
+
+// transplantBytes implements MoveBytes and CopyBytes.
+func transplantBytes(xml []byte, fromPath, toPath string, keepSource bool) ([]byte, error) {
+	// Security check: reject documents that are too large
+	if len(xml) > MaxDocumentSize {
+		return xml, ErrMalformedXML
+	}
+	if err := checkWellFormed(xml); err != nil {
+		return xml, err
+	}
+
+	from := parsePath(fromPath)
+	if err := validateElementPath(from, false); err != nil {
+		return xml, err
+	}
+	to := parsePath(toPath)
+	if err := validateElementPath(to, true); err != nil {
+		return xml, err
+	}
+
+	// Normalize a trailing # to the -1 append index
+	to = normalizeAppendCount(to)
+
+	builder := newXMLBuilder(xml)
+	if err := builder.transplantElement(from, to, keepSource); err != nil {
+		return xml, err
+	}
+
+	return []byte(builder.getResult()), nil
+}
+
+// validateElementPath checks that a path addressing a single element (for
+// Move, Copy and PrettySubtree) only contains element names and indices.
+// Move and Copy destination paths may end in -1 or # to append.
+func validateElementPath(segments []PathSegment, isDest bool) error {
+	if len(segments) == 0 {
+		return ErrInvalidPath
+	}
+	for i, seg := range segments {
+		if len(seg.Modifiers) > 0 {
+			return fmt.Errorf("%w: element paths cannot contain modifiers", ErrInvalidPath)
+		}
+		isLast := i == len(segments)-1
+		afterElement := i > 0 && segments[i-1].Type == SegmentElement
+		switch {
+		case seg.Type == SegmentElement:
+		case seg.Type == SegmentIndex && afterElement && seg.Index >= 0:
+		case seg.Type == SegmentIndex && afterElement && seg.Index == -1 && isDest && isLast:
+		case seg.Type == SegmentCount && afterElement && isDest && isLast:
+		default:
+			return fmt.Errorf("%w: path must address an element", ErrInvalidPath)
+		}
+	}
+	return nil
+}
+
+// PrettySubtree returns xml with only the element at path pretty-printed in
+// place: its child elements are placed on their own lines, indented by one
+// indent per level, and the rest of the document is left byte for byte as
+// it was. This makes one section of a compact document readable without
+// reflowing the whole file.
+//
+// The subtree is aligned with the line the element starts on. Existing
+// whitespace between its child elements is replaced, while elements that
+// hold text (including mixed content and CDATA) are kept exactly as written,
+// so no value changes. Tags are copied verbatim, keeping namespace prefixes,
+// attribute quoting and self-closing tags.
+//
+// Path Restrictions:
+//
+// The path may only contain element names and array indices, as for Move.
+//
+// Error Handling:
+//
+// Returns ErrMalformedXML if the input XML is not well-formed, ErrInvalidPath
+// if the path is unsupported or the element doesn't exist, and
+// ErrInvalidValue if indent contains characters other than spaces and tabs.
+//
+// Example:
+//
+//	xml := `<config><db><host>x</host><port>5432</port></db><log/></config>`
+//	modified, _ := PrettySubtree(xml, "config.db", "  ")
+//	// modified: <config><db>
+//	//   <host>x</host>
+//	//   <port>5432</port>
+//	// </db><log/></config>
+func PrettySubtree(xml, path, indent string) (string, error) {
+	result, err := PrettySubtreeBytes([]byte(xml), path, indent)
+	if err != nil {
+		return xml, err
+	}
+	return string(result), nil
+}
+
+// PrettySubtreeBytes is like PrettySubtree but accepts and returns xml as byte slices for efficiency.
+func PrettySubtreeBytes(xml []byte, path, indent string) ([]byte, error) {
+	// Security check: reject documents that are too large
+	if len(xml) > MaxDocumentSize {
+		return xml, ErrMalformedXML
+	}
+	if strings.Trim(indent, " \t") != "" {
+		return xml, fmt.Errorf("%w: indent must contain only spaces and tabs", ErrInvalidValue)
+	}
+	if err := checkWellFormed(xml); err != nil {
+		return xml, err
+	}
+
+	segments := parsePath(path)
+	if err := validateElementPath(segments, false); err != nil {
+		return xml, err
+	}
+
+	builder := newXMLBuilder(xml)
+	location, found := builder.findElementLocation(newXMLParser(xml), segments, 0, 0)
+	if !found {
+		return xml, fmt.Errorf("%w: element not found", ErrInvalidPath)
+	}
+	end := elementEnd(location)
+	base, _ := lineIndent(xml, location.startPos)
+
+	pretty := indentSubtree(string(xml[location.startPos:end]), base, indent)
+	result := make([]byte, 0, len(xml)+len(pretty)-(end-location.startPos))
+	result = append(result, xml[:location.startPos]...)
+	result = append(result, pretty...)
+	result = append(result, xml[end:]...)
+	if len(result) > MaxDocumentSize {
+		return xml, fmt.Errorf("%w: resulting document exceeds maximum size", ErrInvalidValue)
+	}
+	return result, nil
+}
+
+// RenameAttribute renames the attribute oldName to newName on the element at
+// path. The value is kept, and the tag is edited in place, so the attribute
+// keeps its position and quoting and other attributes are untouched.
+//
+// A path with a wildcard, filter, #.child or a trailing # renames the
+// attribute on every element it selects, which makes bulk migrations a
+// single call:
+//
+//	xml := `<manifest><application><activity android:label="a"/><activity android:label="b"/></application></manifest>`
+//	modified, _ := RenameAttribute(xml, "manifest.application.activity.#", "android:label", "android:title")
+//	// modified: <manifest><application><activity android:title="a"/><activity android:title="b"/></application></manifest>
+//
+// Elements without oldName, and a path that matches nothing, are left
+// unchanged without an error.
+//
+// Error Handling:
+//
+// Returns ErrMalformedXML if the input XML is not well-formed, ErrInvalidPath
+// if the path does not address elements, and ErrInvalidValue if newName is
+// not a valid XML name or a selected element already has a newName
+// attribute. Use RenameAttributeWithOptions with RenameOverwrite to replace
+// such attributes instead. On error the document is returned unchanged.
+func RenameAttribute(xml, path, oldName, newName string) (string, error) {
+	return RenameAttributeWithOptions(xml, path, oldName, newName, nil)
+}
+
+// RenameAttributeBytes is like RenameAttribute but accepts and returns xml as byte slices for efficiency.
+func RenameAttributeBytes(xml []byte, path, oldName, newName string) ([]byte, error) {
+	return renameAttributeBytes(xml, path, oldName, newName, nil)
+}
+
+// RenameAttributeWithOptions is like RenameAttribute but accepts Options.
+// CaseSensitive applies to the path and to oldName, and RenameOverwrite
+// replaces an existing newName attribute instead of returning an error.
+func RenameAttributeWithOptions(xml, path, oldName, newName string, opts *Options) (string, error) {
+	result, err := renameAttributeBytes([]byte(xml), path, oldName, newName, opts)
+	if err != nil {
+		return xml, err
+	}
+	return string(result), nil
+}
+
+// renameAttributeBytes implements RenameAttribute and its variants.
+func renameAttributeBytes(xml []byte, path, oldName, newName string, opts *Options) ([]byte, error) {
+	// Security check: reject documents that are too large
+	if len(xml) > MaxDocumentSize {
+		return xml, ErrMalformedXML
+	}
+	if err := validateName(newName); err != nil {
+		return xml, fmt.Errorf("%w: invalid attribute name %q: %v", ErrInvalidValue, newName, err)
+	}
+	if err := checkWellFormed(xml); err != nil {
+		return xml, err
+	}
+
+	segments := parsePathWithOptions(path, opts)
+	if len(segments) == 0 {
+		return xml, ErrInvalidPath
+	}
+
+	builder := newXMLBuilderWithOptions(xml, opts)
+	n, err := builder.renameAttribute(segments, oldName, newName)
+	if err != nil {
+		return xml, err
+	}
+	if n == 0 {
+		return xml, nil
+	}
+	return []byte(builder.getResult()), nil
+}
+
+// EditEach calls fn with every element path selects, in document order, and
+// applies the answers to the original document in a single pass. When keep
+// is true the element's content is replaced by value as escaped text, and
+// when it is false the element is removed. Because all answers refer to the
+// original document, removing an element does not shift the ones after it.
+//
+// A plain element path selects every matching sibling, like a trailing #;
+// wildcards, filters and #.child select as in other fan-out writes. An
+// element for which fn returns its current text (r.String()) with keep true
+// is left untouched, including any child elements.
+//
+// Example:
+//
+//	xml := `<tags><tag>go</tag><tag></tag><tag>xml</tag></tags>`
+//	modified, _ := EditEach(xml, "tags.tag", func(r Result) (string, bool) {
+//		if r.String() == "" {
+//			return "", false
+//		}
+//		return strings.ToUpper(r.String()), true
+//	})
+//	// modified: <tags><tag>GO</tag><tag>XML</tag></tags>
+//
+// Error Handling:
+//
+// Returns ErrMalformedXML if the input XML is not well-formed, and
+// ErrInvalidPath if the path does not address elements or fn removes the
+// root element. On error the document is returned unchanged.
+func EditEach(xml, path string, fn func(r Result) (newValue string, keep bool)) (string, error) {
+	result, err := EditEachBytes([]byte(xml), path, fn)
+	if err != nil {
+		return xml, err
+	}
+	return string(result), nil
+}
+
+// EditEachBytes is like EditEach but accepts and returns xml as byte slices for efficiency.
+func EditEachBytes(xml []byte, path string, fn func(r Result) (newValue string, keep bool)) ([]byte, error) {
+	// Security check: reject documents that are too large
+	if len(xml) > MaxDocumentSize {
+		return xml, ErrMalformedXML
+	}
+	if err := checkWellFormed(xml); err != nil {
+		return xml, err
+	}
+
+	segments := parsePath(path)
+	if len(segments) == 0 {
+		return xml, ErrInvalidPath
+	}
+	if last := segments[len(segments)-1]; !isFanOutPath(segments) && last.Type == SegmentElement {
+		// IMPORTANT: Build a fresh path to avoid mutating cached paths
+		all := make([]PathSegment, 0, len(segments)+1)
+		all = append(all, segments...)
+		segments = append(all, PathSegment{Type: SegmentCount})
+	}
+
+	builder := newXMLBuilder(xml)
+	n, err := builder.editEach(segments, fn)
+	if err != nil {
+		return xml, err
+	}
+	if n == 0 {
+		return xml, nil
+	}
+	return []byte(builder.getResult()), nil
+}
+
+// Update sets the value at path to the string fn computes from the current
+// value, locating the target once instead of a Get followed by a Set. fn
+// receives the element or attribute path addresses, or a Null Result when it
+// does not exist yet (which Update then creates, as Set would). The returned
+// string is written as escaped text, like a string passed to Set.
+//
+// Example:
+//
+//	xml := `<manifest android:versionCode="41"/>`
+//	modified, _ := Update(xml, "manifest.@android:versionCode", func(old Result) string {
+//		return strconv.FormatInt(old.Int()+1, 10)
+//	})
+//	// modified: <manifest android:versionCode="42"/>
+//
+// Error Handling:
+//
+// Returns ErrMalformedXML if the input XML is not well-formed, and
+// ErrInvalidPath if the path cannot be parsed or selects several elements
+// (wildcards, filters with #, #.child); use EditEach to update each of
+// several elements. On error the document is returned unchanged.
+func Update(xml, path string, fn func(old Result) string) (string, error) {
+	result, err := UpdateBytes([]byte(xml), path, fn)
+	if err != nil {
+		return xml, err
+	}
+	return string(result), nil
+}
+
+// UpdateBytes is like Update but accepts and returns xml as byte slices for efficiency.
+func UpdateBytes(xml []byte, path string, fn func(old Result) string) ([]byte, error) {
+	// Security check: reject documents that are too large
+	if len(xml) > MaxDocumentSize {
+		return xml, ErrMalformedXML
+	}
+	if err := checkWellFormed(xml); err != nil {
+		return xml, err
+	}
+
+	segments := parsePath(path)
+	if len(segments) == 0 {
+		return xml, ErrInvalidPath
+	}
+	if isFanOutPath(segments) {
+		return xml, fmt.Errorf("%w: Update addresses a single element or attribute; use EditEach for several", ErrInvalidPath)
+	}
+
+	builder := newXMLBuilder(xml)
+	if err := builder.update(segments, fn); err != nil {
+		return xml, err
+	}
+	return []byte(builder.getResult()), nil
+}
+
 // SetMany performs multiple Set operations, applying each modification
 // sequentially. This is more convenient than calling Set multiple times manually.
 // If multiple paths overlap, later operations take precedence.
 //
+// SetMany is all-or-nothing: if any operation fails, the original xml is
+// returned unchanged together with the error, which names the failing path.
+// Operations before it are discarded and none after it are attempted, so a
+// caller never sees a partially modified document.
+//
 // Performance Characteristics:
 //   - Time complexity: O(n²) where n is the number of operations, as each operation
 //     reparses and rebuilds the entire document
@@ -309,6 +1056,58 @@ func SetManyBytes(xml []byte, paths []string, values []interface{}) ([]byte, err
 	return result, nil
 }
 
+// SetBytesMany is an alias for SetManyBytes, named to pair with SetBytes.
+// It applies multiple Set operations to xml given as a byte slice.
+func SetBytesMany(xml []byte, paths []string, values []interface{}) ([]byte, error) {
+	return SetManyBytes(xml, paths, values)
+}
+
+// SetManyN is like SetMany but also reports how many nodes the operations
+// changed, and whether the result differs from xml.
+//
+// The count is the sum of the per-operation counts of SetN, each taken
+// against the document as the earlier operations left it: an operation whose
+// value already matched counts as 0, and a node that one operation changes
+// and a later one changes back counts twice. Use changed, not the count, to
+// decide whether the document was modified; when it is false the original
+// XML is returned unchanged. Like SetMany it is all-or-nothing: on error it
+// returns the original XML, a count of 0 and false.
+//
+// Example:
+//
+//	xml := `<root><a>1</a><b>2</b></root>`
+//	_, n, changed, _ := SetManyN(xml, []string{"root.a", "root.b"}, []interface{}{1, 3})
+//	// n: 1 (only root.b changed), changed: true
+//	_, n, changed, _ = SetManyN(xml, []string{"root.a", "root.a"}, []interface{}{"z", "1"})
+//	// n: 2 (root.a changed twice), changed: false
+func SetManyN(xml string, paths []string, values []interface{}) (string, int, bool, error) {
+	// Security check: reject documents that are too large
+	if len(xml) > MaxDocumentSize {
+		return xml, 0, false, ErrMalformedXML
+	}
+
+	if len(paths) != len(values) {
+		return xml, 0, false, fmt.Errorf("%w: paths and values length mismatch", ErrInvalidPath)
+	}
+
+	result := []byte(xml)
+	total := 0
+	for i := 0; i < len(paths); i++ {
+		var n int
+		var err error
+		result, n, err = setBytesN(result, paths[i], values[i])
+		if err != nil {
+			return xml, 0, false, fmt.Errorf("error setting path %q: %w", paths[i], err)
+		}
+		total += n
+	}
+
+	if total == 0 || string(result) == xml {
+		return xml, total, false, nil
+	}
+	return string(result), total, true, nil
+}
+
 // Delete removes the element or attribute at the specified path and returns
 // the modified XML. If the path does not exist, the original XML is returned
 // unchanged (no error is returned).
@@ -342,11 +1141,51 @@ func DeleteBytes(xml []byte, path string) ([]byte, error) {
 	return DeleteBytesWithOptions(xml, path, DefaultOptions())
 }
 
+// DeleteN is like Delete but also reports how many nodes were removed: 1 if
+// the element or attribute existed and was deleted, or 0 if the path did not
+// exist (in which case the original XML is returned unchanged).
+//
+// Example:
+//
+//	xml := `<root><tmp/></root>`
+//	_, n, _ := DeleteN(xml, "root.tmp")
+//	// n: 1
+//	_, n, _ = DeleteN(xml, "root.missing")
+//	// n: 0
+func DeleteN(xml, path string) (string, int, error) {
+	result, n, err := deleteBytesN([]byte(xml), path)
+	if err != nil {
+		return xml, 0, err
+	}
+	if n == 0 {
+		return xml, 0, nil
+	}
+	return string(result), n, nil
+}
+
+// deleteBytesN applies a single Delete and counts the removed nodes.
+func deleteBytesN(xml []byte, path string) ([]byte, int, error) {
+	if segments := parsePath(path); isFanOutPath(segments) {
+		return fanOutBytesN(xml, segments, nil)
+	}
+	result, err := DeleteBytes(xml, path)
+	if err != nil {
+		return xml, 0, err
+	}
+	if bytes.Equal(result, xml) {
+		return xml, 0, nil
+	}
+	return result, 1, nil
+}
+
 // DeleteMany removes multiple paths sequentially. If multiple paths overlap
 // (e.g., parent and child), the parent deletion takes precedence. Paths are
 // processed in the order provided, and non-existent paths are silently skipped.
 // Duplicate paths are automatically deduplicated.
 //
+// Like SetMany, DeleteMany is all-or-nothing: if any path is invalid, the
+// original xml is returned unchanged together with the error.
+//
 // Performance Characteristics:
 //   - Time complexity: O(n²) where n is the number of operations, as each operation
 //     reparses and rebuilds the entire document
@@ -447,68 +1286,115 @@ func SetWithOptions(xml, path string, value interface{}, opts *Options) (string,
 // Security: Documents larger than MaxDocumentSize (10MB) are rejected to prevent
 // memory exhaustion attacks.
 func SetBytesWithOptions(xml []byte, path string, value interface{}, opts *Options) ([]byte, error) {
-	// Nil bytes are invalid
-	if xml == nil {
-		return xml, ErrMalformedXML
+	// Handle nil value as deletion
+	if value == nil {
+		return DeleteBytesWithOptions(xml, path, opts)
+	}
+
+	builder, plan, err := validateSet(xml, path, value, opts)
+	if err != nil {
+		return xml, err
+	}
+
+	// Execute the set operation
+	if err := builder.applySet(plan, value); err != nil {
+		return xml, err
 	}
 
+	return []byte(builder.getResult()), nil
+}
+
+// validateDelete makes the checks of DeleteBytesWithOptions that need only
+// the document and the path, and returns the builder and parsed path.
+func validateDelete(xml []byte, path string, opts *Options) (*xmlBuilder, []PathSegment, error) {
 	// Security check: reject documents that are too large
 	if len(xml) > MaxDocumentSize {
-		return xml, ErrMalformedXML
+		return nil, nil, ErrMalformedXML
 	}
 
-	// Validate XML well-formedness unless in optimistic mode (future feature)
-	// This prevents crashes from malformed XML discovered by fuzz testing
-	// Special case: empty XML is valid for Set operations (creating new XML from scratch)
-	if len(xml) > 0 && !ValidBytes(xml) {
-		return xml, ErrMalformedXML
+	// Strict attribute checks requested by opts
+	if err := checkAttributeOptions(xml, opts); err != nil {
+		return nil, nil, err
 	}
-	// Empty XML ([]byte{} or "") is valid for Set operations (not for Delete)
 
-	// Handle nil value as deletion
-	if value == nil {
-		return DeleteBytesWithOptions(xml, path, opts)
+	// Validate XML well-formedness. This prevents crashes from malformed
+	// XML discovered by fuzz testing
+	if err := checkWellFormed(xml); err != nil {
+		return nil, nil, err
 	}
 
 	// Parse the path with options-aware parsing
 	segments := parsePathWithOptions(path, opts)
 	if len(segments) == 0 {
-		return xml, ErrInvalidPath
+		return nil, nil, ErrInvalidPath
 	}
-
-	// Create builder with options
-	builder := newXMLBuilderWithOptions(xml, opts)
-
-	// Execute the set operation
-	if err := builder.setElement(segments, value); err != nil {
-		return xml, err
+	if err := checkDelete(segments); err != nil {
+		return nil, nil, err
 	}
-
-	return []byte(builder.getResult()), nil
+	return newXMLBuilderWithOptions(xml, opts), segments, nil
 }
 
-// DeleteBytesWithOptions is like DeleteBytes but accepts Options for behavioral control.
-// This is used internally by SetBytesWithOptions when value is nil.
-func DeleteBytesWithOptions(xml []byte, path string, opts *Options) ([]byte, error) {
+// validateSet makes the checks of SetBytesWithOptions for a non-nil value
+// that come before any output is built, and returns the builder and plan to
+// apply. CanSetBytes shares it, so both reject the same paths and values.
+func validateSet(xml []byte, path string, value interface{}, opts *Options) (*xmlBuilder, setPlan, error) {
+	// Nil bytes are invalid
+	if xml == nil {
+		return nil, setPlan{}, ErrMalformedXML
+	}
+
 	// Security check: reject documents that are too large
 	if len(xml) > MaxDocumentSize {
-		return xml, ErrMalformedXML
+		return nil, setPlan{}, ErrMalformedXML
 	}
 
-	// Validate XML well-formedness unless in optimistic mode (future feature)
-	// This prevents crashes from malformed XML discovered by fuzz testing
-	if !ValidBytes(xml) {
-		return xml, ErrMalformedXML
+	// Strict attribute checks requested by opts (before validation, which
+	// would report an attribute overflow as malformed XML)
+	if err := checkAttributeOptions(xml, opts); err != nil {
+		return nil, setPlan{}, err
+	}
+
+	// Validate XML well-formedness. This prevents crashes from malformed XML
+	// discovered by fuzz testing. Empty XML is valid for Set operations
+	// (creating new XML from scratch), though not for Delete.
+	if len(xml) > 0 {
+		if err := checkWellFormed(xml); err != nil {
+			return nil, setPlan{}, err
+		}
 	}
 
 	// Parse the path with options-aware parsing
 	segments := parsePathWithOptions(path, opts)
 	if len(segments) == 0 {
-		return xml, ErrInvalidPath
+		return nil, setPlan{}, ErrInvalidPath
+	}
+
+	// Namespace declarations for prefixes the value introduces
+	var declarations string
+	if opts != nil {
+		var err error
+		value, declarations, err = declareNamespaces(xml, segments, value, opts)
+		if err != nil {
+			return nil, setPlan{}, err
+		}
 	}
 
-	// Create builder with options
 	builder := newXMLBuilderWithOptions(xml, opts)
+	builder.declarations = declarations
+	plan, err := builder.checkSet(segments, value)
+	if err != nil {
+		return nil, setPlan{}, err
+	}
+	return builder, plan, nil
+}
+
+// DeleteBytesWithOptions is like DeleteBytes but accepts Options for behavioral control.
+// This is used internally by SetBytesWithOptions when value is nil.
+func DeleteBytesWithOptions(xml []byte, path string, opts *Options) ([]byte, error) {
+	builder, segments, err := validateDelete(xml, path, opts)
+	if err != nil {
+		return xml, err
+	}
 
 	// Execute the delete operation
 	if err := builder.deleteElement(segments); err != nil {
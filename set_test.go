@@ -4,9 +4,12 @@
 package xmldot
 
 import (
+	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 )
 
 // Test basic element setting (P2.3)
@@ -103,7 +106,7 @@ func TestSet_AttributeSetting(t *testing.T) {
 			xml:      `<user id="123"><name>John</name></user>`,
 			path:     "user.@active",
 			value:    "true",
-			expected: `<user active="true" id="123"><name>John</name></user>`, // Attributes are sorted alphabetically
+			expected: `<user id="123" active="true"><name>John</name></user>`, // New attributes are added last
 		},
 		{
 			name:     "update attribute with escaping",
@@ -136,6 +139,67 @@ func TestSet_AttributeSetting(t *testing.T) {
 }
 
 // Test element creation (P2.5)
+// TestSet_AttributeOrder tests that writes keep attribute order by default
+// and sort the attributes of changed elements with SortAttributes
+func TestSet_AttributeOrder(t *testing.T) {
+	xml := `<r><a z="1" b="2">t</a><c y="1" x="2"/></r>`
+	sorted := &Options{CaseSensitive: true, SortAttributes: true}
+
+	tests := []struct {
+		name     string
+		path     string
+		value    interface{}
+		expected string
+		sorted   string
+	}{
+		{"add attribute", "r.a.@m", "3",
+			`<r><a z="1" b="2" m="3">t</a><c y="1" x="2"/></r>`,
+			`<r><a b="2" m="3" z="1">t</a><c y="1" x="2"/></r>`},
+		{"update attribute", "r.a.@z", "9",
+			`<r><a z="9" b="2">t</a><c y="1" x="2"/></r>`,
+			`<r><a b="2" z="9">t</a><c y="1" x="2"/></r>`},
+		{"delete attribute", "r.a.@z", nil,
+			`<r><a b="2">t</a><c y="1" x="2"/></r>`,
+			`<r><a b="2">t</a><c y="1" x="2"/></r>`},
+		{"set content", "r.a", "new",
+			`<r><a z="1" b="2">new</a><c y="1" x="2"/></r>`,
+			`<r><a b="2" z="1">new</a><c y="1" x="2"/></r>`},
+		{"fill self-closing element", "r.c", "v",
+			`<r><a z="1" b="2">t</a><c y="1" x="2">v</c></r>`,
+			`<r><a z="1" b="2">t</a><c x="2" y="1">v</c></r>`},
+		{"fan-out", "r.*.@q", "1",
+			`<r><a z="1" b="2" q="1">t</a><c y="1" x="2" q="1"/></r>`,
+			`<r><a b="2" q="1" z="1">t</a><c q="1" x="2" y="1"/></r>`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SetWithOptions(xml, tt.path, tt.value, &Options{CaseSensitive: true})
+			if err != nil {
+				t.Fatalf("SetWithOptions() error = %v", err)
+			}
+			gotSorted, err := SetWithOptions(xml, tt.path, tt.value, sorted)
+			if err != nil {
+				t.Fatalf("SetWithOptions(SortAttributes) error = %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("default = %q, want %q", got, tt.expected)
+			}
+			if gotSorted != tt.sorted {
+				t.Errorf("SortAttributes = %q, want %q", gotSorted, tt.sorted)
+			}
+		})
+	}
+
+	renamed, err := RenameAttributeWithOptions(xml, "r.a", "z", "c", sorted)
+	if err != nil {
+		t.Fatalf("RenameAttributeWithOptions() error = %v", err)
+	}
+	if renamed != `<r><a b="2" c="1">t</a><c y="1" x="2"/></r>` {
+		t.Errorf("RenameAttributeWithOptions() = %q", renamed)
+	}
+}
+
 func TestSet_ElementCreation(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -201,49 +265,49 @@ func TestSet_AttributeCreation(t *testing.T) {
 			xml:      `<root></root>`,
 			path:     "root.user.@id",
 			value:    "123",
-			expected: `<root><user id="123"></user></root>`,
+			expected: `<root><user id="123"/></root>`,
 		},
 		{
 			name:     "create attribute on missing nested path",
 			xml:      `<root></root>`,
 			path:     "root.a.b.c.@id",
 			value:    "deep",
-			expected: `<root><a><b><c id="deep"></c></b></a></root>`,
+			expected: `<root><a><b><c id="deep"/></b></a></root>`,
 		},
 		{
 			name:     "create attribute in self-closing root",
 			xml:      `<root/>`,
 			path:     "root.user.@active",
 			value:    "true",
-			expected: `<root><user active="true"></user></root>`,
+			expected: `<root><user active="true"/></root>`,
 		},
 		{
 			name:     "create attribute with special characters",
 			xml:      `<root></root>`,
 			path:     "root.item.@value",
 			value:    `"test"`,
-			expected: `<root><item value="&quot;test&quot;"></item></root>`,
+			expected: `<root><item value="&quot;test&quot;"/></root>`,
 		},
 		{
 			name:     "create attribute with partial existing path",
 			xml:      `<root><user></user></root>`,
 			path:     "root.user.contact.@email",
 			value:    "test@example.com",
-			expected: `<root><user><contact email="test@example.com"></contact></user></root>`,
+			expected: `<root><user><contact email="test@example.com"/></user></root>`,
 		},
 		{
 			name:     "create attribute with empty value",
 			xml:      `<root></root>`,
 			path:     "root.item.@flag",
 			value:    "",
-			expected: `<root><item flag=""></item></root>`,
+			expected: `<root><item flag=""/></root>`,
 		},
 		{
 			name:     "create attribute with numeric value",
 			xml:      `<root></root>`,
 			path:     "root.item.@count",
 			value:    42,
-			expected: `<root><item count="42"></item></root>`,
+			expected: `<root><item count="42"/></root>`,
 		},
 		{
 			name:     "create attribute on root element",
@@ -277,6 +341,47 @@ func TestSet_AttributeCreation(t *testing.T) {
 	}
 }
 
+// TestSet_AttributeOnlyElement tests that an element created by an attribute
+// write holds only that attribute and reads back like any other element
+func TestSet_AttributeOnlyElement(t *testing.T) {
+	xml := "<html>\n  <head>\n    <title>Home</title>\n  </head>\n</html>"
+
+	result, err := Set(xml, "html.head.meta.@charset", "utf-8")
+	if err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	want := "<html>\n  <head>\n    <title>Home</title>\n    <meta charset=\"utf-8\"/>\n  </head>\n</html>"
+	if result != want {
+		t.Errorf("Set() = %q, want %q", result, want)
+	}
+
+	meta := Get(result, "html.head.meta")
+	if !meta.Exists() || !meta.IsEmpty() {
+		t.Errorf("Get(meta) Exists = %v, IsEmpty = %v, want an empty element", meta.Exists(), meta.IsEmpty())
+	}
+	if attrs := meta.Attributes(); len(attrs) != 1 || attrs[0].Name != "charset" || attrs[0].Value != "utf-8" {
+		t.Errorf("Get(meta).Attributes() = %v, want [charset=utf-8]", attrs)
+	}
+	if got := Get(result, "html.head.meta.@charset").String(); got != "utf-8" {
+		t.Errorf("Get(@charset) = %q, want utf-8", got)
+	}
+
+	// The byte slice variant creates the same element
+	resultBytes, err := SetBytes([]byte(xml), "html.head.meta.@charset", "utf-8")
+	if err != nil || string(resultBytes) != want {
+		t.Errorf("SetBytes() = %q, %v, want %q", resultBytes, err, want)
+	}
+
+	// A second attribute write adds to the created element
+	result, err = Set(result, "html.head.meta.@lang", "en")
+	if err != nil {
+		t.Fatalf("Set() second attribute error = %v", err)
+	}
+	if !strings.Contains(result, `<meta charset="utf-8" lang="en"/>`) {
+		t.Errorf("Set() second attribute = %q", result)
+	}
+}
+
 // Test multiple attributes on created elements
 func TestSet_AttributeCreationMultiple(t *testing.T) {
 	xml := `<root></root>`
@@ -288,7 +393,7 @@ func TestSet_AttributeCreationMultiple(t *testing.T) {
 	}
 
 	// Verify element was created
-	if !strings.Contains(result, `<user id="123">`) {
+	if !strings.Contains(result, `<user id="123"/>`) {
 		t.Errorf("First attribute not created correctly: %s", result)
 	}
 
@@ -307,6 +412,239 @@ func TestSet_AttributeCreationMultiple(t *testing.T) {
 	}
 }
 
+// Test writing query Results as values
+func TestSet_ResultValue(t *testing.T) {
+	xml := `<r><price cur="EUR">1.50</price><item><name>a &amp; b</name></item><x/></r>`
+
+	tests := []struct {
+		name     string
+		path     string
+		value    Result
+		expected string
+	}{
+		{"element text verbatim", "r.x", Get(xml, "r.price"), `<x>1.50</x>`},
+		{"attribute", "r.x.@cur", Get(xml, "r.price.@cur"), `<x cur="EUR"/>`},
+		{"escaped text", "r.x", Get(xml, "r.item.name"), `<x>a &amp; b</x>`},
+		{"element content", "r.x", Get(xml, "r.item"), `<x><name>a &amp; b</name></x>`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Set(xml, tt.path, tt.value)
+			if err != nil {
+				t.Fatalf("Set() error = %v", err)
+			}
+			if !strings.Contains(result, tt.expected) {
+				t.Errorf("Set() = %s, want it to contain %s", result, tt.expected)
+			}
+		})
+	}
+
+	for _, value := range []Result{Get(xml, "r.missing"), Get(xml, "r.*")} {
+		if _, err := Set(xml, "r.x", value); !errors.Is(err, ErrInvalidValue) {
+			t.Errorf("Set(%v) error = %v, want ErrInvalidValue", value.Type, err)
+		}
+	}
+}
+
+// Test time.Time values and the TimeLayout/FloatFormat options
+func TestSet_TimeAndFloatFormatting(t *testing.T) {
+	xml := `<order><price>0</price></order>`
+	when := time.Date(2025, 3, 14, 9, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		path     string
+		value    interface{}
+		opts     *Options
+		expected string
+	}{
+		{"time defaults to RFC3339", "order.created", when, nil, `<created>2025-03-14T09:30:00Z</created>`},
+		{"time attribute", "order.@at", when, nil, `at="2025-03-14T09:30:00Z"`},
+		{"custom time layout", "order.created", when, &Options{CaseSensitive: true, TimeLayout: "2006-01-02"}, `<created>2025-03-14</created>`},
+		{"float default", "order.price", 19.5, nil, `<price>19.5</price>`},
+		{"float32 default", "order.price", float32(1.1), nil, `<price>1.1</price>`},
+		{"fixed decimals", "order.price", 19.5, &Options{CaseSensitive: true, FloatFormat: "%.2f"}, `<price>19.50</price>`},
+		{"float32 fixed decimals", "order.price", float32(3), &Options{CaseSensitive: true, FloatFormat: "%.2f"}, `<price>3.00</price>`},
+		{"ints ignore FloatFormat", "order.price", 7, &Options{CaseSensitive: true, FloatFormat: "%.2f"}, `<price>7</price>`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var result string
+			var err error
+			if tt.opts == nil {
+				result, err = Set(xml, tt.path, tt.value)
+			} else {
+				result, err = SetWithOptions(xml, tt.path, tt.value, tt.opts)
+			}
+			if err != nil {
+				t.Fatalf("Set() error = %v", err)
+			}
+			if !strings.Contains(result, tt.expected) {
+				t.Errorf("Set() = %s, want it to contain %s", result, tt.expected)
+			}
+		})
+	}
+
+	_, err := SetWithOptions(xml, "order.price", 1.5, &Options{CaseSensitive: true, FloatFormat: "%d"})
+	if !errors.Is(err, ErrInvalidValue) {
+		t.Errorf("invalid FloatFormat error = %v, want ErrInvalidValue", err)
+	}
+}
+
+// Test that new elements in a formatted document are indented like their siblings
+func TestSet_InferIndentation(t *testing.T) {
+	doc := "<config>\n  <server>\n    <host>a</host>\n  </server>\n</config>"
+
+	tests := []struct {
+		name     string
+		xml      string
+		path     string
+		opts     *Options
+		expected string
+	}{
+		{
+			name:     "new child after sibling",
+			xml:      doc,
+			path:     "config.server.port",
+			expected: "<config>\n  <server>\n    <host>a</host>\n    <port>v</port>\n  </server>\n</config>",
+		},
+		{
+			name:     "nested chain",
+			xml:      doc,
+			path:     "config.db.host",
+			expected: "<config>\n  <server>\n    <host>a</host>\n  </server>\n  <db>\n    <host>v</host>\n  </db>\n</config>",
+		},
+		{
+			name:     "append after last match",
+			xml:      doc,
+			path:     "config.server.host.-1",
+			expected: "<config>\n  <server>\n    <host>a</host>\n    <host>v</host>\n  </server>\n</config>",
+		},
+		{
+			name:     "no sibling uses Options.Indent",
+			xml:      "<config>\n</config>",
+			path:     "config.a.b",
+			opts:     &Options{CaseSensitive: true, Indent: "\t"},
+			expected: "<config>\n\t<a>\n\t\t<b>v</b>\n\t</a>\n</config>",
+		},
+		{
+			name:     "self-closing parent uses Options.Indent",
+			xml:      "<r>\n  <a/>\n</r>",
+			path:     "r.a.b",
+			opts:     &Options{CaseSensitive: true, Indent: "    "},
+			expected: "<r>\n  <a>\n      <b>v</b>\n  </a>\n</r>",
+		},
+		{
+			name:     "self-closing root uses Options.Indent",
+			xml:      "<r/>",
+			path:     "r.a.b",
+			opts:     &Options{CaseSensitive: true, Indent: "    "},
+			expected: "<r>\n    <a>\n        <b>v</b>\n    </a>\n</r>",
+		},
+		{
+			name:     "empty parent uses Options.Indent",
+			xml:      "<r>\n  <a></a>\n</r>",
+			path:     "r.a.b",
+			opts:     &Options{CaseSensitive: true, Indent: "  "},
+			expected: "<r>\n  <a>\n    <b>v</b>\n  </a>\n</r>",
+		},
+		{
+			name:     "empty parent in compact document indents by depth",
+			xml:      "<r><s><a></a></s></r>",
+			path:     "r.s.a.b",
+			opts:     &Options{CaseSensitive: true, Indent: "  "},
+			expected: "<r><s><a>\n      <b>v</b>\n    </a></s></r>",
+		},
+		{
+			name:     "append to self-closing parent",
+			xml:      "<r>\n  <list/>\n</r>",
+			path:     "r.list.item.-1",
+			opts:     &Options{CaseSensitive: true, Indent: "  "},
+			expected: "<r>\n  <list>\n    <item>v</item>\n  </list>\n</r>",
+		},
+		{
+			name:     "compact document stays compact",
+			xml:      "<r><a>1</a></r>",
+			path:     "r.b",
+			expected: "<r><a>1</a><b>v</b></r>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var result string
+			var err error
+			if tt.opts == nil {
+				result, err = Set(tt.xml, tt.path, "v")
+			} else {
+				result, err = SetWithOptions(tt.xml, tt.path, "v", tt.opts)
+			}
+			if err != nil {
+				t.Fatalf("Set() error = %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Set() =\n%s\nexpected\n%s", result, tt.expected)
+			}
+		})
+	}
+}
+
+// Test that elements created for an attribute are self-closing and can be
+// extended by later writes
+func TestSet_AttributeCreationSelfClosing(t *testing.T) {
+	result, err := Set(`<root/>`, "root.a.b.@id", "1")
+	if err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if expected := `<root><a><b id="1"/></a></root>`; result != expected {
+		t.Errorf("Set() = %s, want %s", result, expected)
+	}
+	if got := Get(result, "root.a.b.@id").String(); got != "1" {
+		t.Errorf("Get(@id) = %q, want %q", got, "1")
+	}
+
+	result, err = Set(result, "root.a.b.c", "text")
+	if err != nil {
+		t.Fatalf("Set() child error = %v", err)
+	}
+	if expected := `<root><a><b id="1"><c>text</c></b></a></root>`; result != expected {
+		t.Errorf("Set() child = %s, want %s", result, expected)
+	}
+}
+
+// Test setting content on existing self-closing elements, including nested
+// tags whose formatting differs from the canonical form
+func TestSet_ExpandSelfClosing(t *testing.T) {
+	tests := []struct {
+		name     string
+		xml      string
+		path     string
+		value    interface{}
+		expected string
+	}{
+		{"root element", `<x/>`, "x", "v", `<x>v</x>`},
+		{"nested", `<r><x/></r>`, "r.x", "v", `<r><x>v</x></r>`},
+		{"space before slash", `<r><x a="1" /><y/></r>`, "r.x", "v", `<r><x a="1">v</x><y/></r>`},
+		{"raw xml", `<r><a><b id="1"/></a></r>`, "r.a.b", []byte("<z/>"), `<r><a><b id="1"><z/></b></a></r>`},
+		{"empty value", `<r><x/></r>`, "r.x", "", `<r><x/></r>`},
+		{"single-quoted parent", `<r><a k='1'><b>old</b></a></r>`, "r.a.b", "new", `<r><a k='1'><b>new</b></a></r>`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Set(tt.xml, tt.path, tt.value)
+			if err != nil {
+				t.Fatalf("Set() error = %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Set() = %s, want %s", result, tt.expected)
+			}
+		})
+	}
+}
+
 // Test attribute creation preserves existing structure
 func TestSet_AttributeCreationPreservesStructure(t *testing.T) {
 	xml := `<root><existing><data>value</data></existing></root>`
@@ -317,7 +655,7 @@ func TestSet_AttributeCreationPreservesStructure(t *testing.T) {
 	}
 
 	// Verify new element with attribute was added
-	if !strings.Contains(result, `<new attr="test">`) {
+	if !strings.Contains(result, `<new attr="test"/>`) {
 		t.Errorf("New element not created: %s", result)
 	}
 
@@ -385,549 +723,885 @@ func TestSetRaw(t *testing.T) {
 	}
 }
 
-// Test SetBytes
-func TestSetBytes(t *testing.T) {
-	xml := []byte(`<root><value>old</value></root>`)
-	expected := []byte(`<root><value>new</value></root>`)
-
-	result, err := SetBytes(xml, "root.value", "new")
-	if err != nil {
-		t.Fatalf("SetBytes() error = %v", err)
-	}
+// TestSetRawWithOptions_RequireDeclaredNamespaces tests rejecting fragments
+// with unbound namespace prefixes
+func TestSetRawWithOptions_RequireDeclaredNamespaces(t *testing.T) {
+	xml := `<soap:Envelope xmlns:soap="urn:soap"><soap:Body><m:Old xmlns:m="urn:m"/></soap:Body><list><item/></list></soap:Envelope>`
+	opts := &Options{CaseSensitive: true, RequireDeclaredNamespaces: true}
 
-	if string(result) != string(expected) {
-		t.Errorf("SetBytes() = %s, want %s", result, expected)
+	tests := []struct {
+		name       string
+		path       string
+		rawxml     string
+		wantPrefix string
+	}{
+		{"undeclared element prefix", "soap:Envelope.soap:Body", "<m:GetPrice/>", "m"},
+		{"declared in fragment", "soap:Envelope.soap:Body", `<m:GetPrice xmlns:m="urn:m"><m:Item/></m:GetPrice>`, ""},
+		{"declared on ancestor", "soap:Envelope.list.item", "<soap:Fault/>", ""},
+		{"declared on target", "soap:Envelope.soap:Body.m:Old", "<m:New/>", ""},
+		{"appended sibling", "soap:Envelope.soap:Body.m:Old.-1", "<m:New/>", "m"},
+		{"fragment scope ends", "soap:Envelope.list.item", `<a xmlns:x="urn:x"/><x:b/>`, "x"},
+		{"undeclared attribute prefix", "soap:Envelope.list.item", `<a x:y="1"/>`, "x"},
+		{"reserved xml prefix", "soap:Envelope.list.item", `<a xml:lang="en"/>`, ""},
+		{"created parents", "soap:Envelope.new.deep", "<soap:A/>", ""},
 	}
-}
-
-// Test nil value as deletion
-func TestSet_NilValue(t *testing.T) {
-	xml := `<root><user><name>John</name><age>30</age></user></root>`
-	expected := `<root><user><name>John</name></user></root>`
 
-	result, err := Set(xml, "root.user.age", nil)
-	if err != nil {
-		t.Fatalf("Set() with nil error = %v", err)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := SetRawWithOptions(xml, tt.path, tt.rawxml, opts)
+			if tt.wantPrefix == "" {
+				if err != nil {
+					t.Fatalf("SetRawWithOptions() error = %v", err)
+				}
+				if !strings.Contains(result, tt.rawxml) {
+					t.Errorf("SetRawWithOptions() = %s, want fragment inserted", result)
+				}
+				return
+			}
+			if !errors.Is(err, ErrInvalidValue) || !strings.Contains(err.Error(), `"`+tt.wantPrefix+`"`) {
+				t.Errorf("SetRawWithOptions() error = %v, want ErrInvalidValue naming %q", err, tt.wantPrefix)
+			}
+			if result != xml {
+				t.Errorf("SetRawWithOptions() modified the document on error")
+			}
+		})
 	}
 
-	if result != expected {
-		t.Errorf("Set() with nil = %v, want %v", result, expected)
+	// Without the option the fragment is inserted unchecked
+	if _, err := SetRawWithOptions(xml, "soap:Envelope.soap:Body", "<m:GetPrice/>", nil); err != nil {
+		t.Errorf("SetRawWithOptions() without option error = %v", err)
 	}
 }
 
-// Test error cases
-func TestSet_Errors(t *testing.T) {
+// TestSetWithOptions_AutoDeclareNamespaces tests declaring prefixes
+// TestSetWithOptions_PreserveInnerComments tests that an element's own comments
+// and processing instructions survive replacing its content
+func TestSetWithOptions_PreserveInnerComments(t *testing.T) {
+	opts := &Options{CaseSensitive: true, PreserveInnerComments: true}
+
 	tests := []struct {
-		name    string
-		xml     string
-		path    string
-		value   interface{}
-		wantErr error
+		name     string
+		xml      string
+		value    interface{}
+		expected string
 	}{
-		{
-			name:    "empty path",
-			xml:     `<root></root>`,
-			path:    "",
-			value:   "test",
-			wantErr: ErrInvalidPath,
-		},
-		{
-			name:    "document too large",
-			xml:     string(make([]byte, MaxDocumentSize+1)),
-			path:    "root.test",
-			value:   "test",
-			wantErr: ErrMalformedXML,
-		},
+		{"leading comment", `<root><item><!--keep-->old</item></root>`, "new", `<root><item><!--keep-->new</item></root>`},
+		{"trailing comment", `<root><item>old<!--tail--></item></root>`, "new", `<root><item>new<!--tail--></item></root>`},
+		{"processing instruction", `<root><item><?pi x?>old</item></root>`, []byte("<b/>"), `<root><item><?pi x?><b/></item></root>`},
+		{"emptied element", `<root><item><!--keep-->old</item></root>`, "", `<root><item><!--keep--></item></root>`},
+		{"comment in child and CDATA", `<root><item a=">"><a><!--deep--></a><![CDATA[<!--no-->]]></item></root>`, "new", `<root><item a=">">new</item></root>`},
+		{"self-closing element", `<root><item/></root>`, "new", `<root><item>new</item></root>`},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			_, err := Set(tt.xml, tt.path, tt.value)
-			if err == nil {
-				t.Fatalf("Set() expected error, got nil")
+			result, err := SetWithOptions(tt.xml, "root.item", tt.value, opts)
+			if err != nil {
+				t.Fatalf("SetWithOptions() error = %v", err)
 			}
-			if err != tt.wantErr {
-				t.Errorf("Set() error = %v, want %v", err, tt.wantErr)
+			if result != tt.expected {
+				t.Errorf("SetWithOptions() = %q, want %q", result, tt.expected)
 			}
 		})
 	}
+
+	// By default the comment is replaced along with the content
+	result, _ := Set(`<root><item><!--keep-->old</item></root>`, "root.item", "new")
+	if expected := `<root><item>new</item></root>`; result != expected {
+		t.Errorf("Set() = %q, want %q", result, expected)
+	}
 }
 
-// Test array index setting
-func TestSet_ArrayIndex(t *testing.T) {
+// TestSetWithOptions_TextOnlySet tests that setting text on mixed content
+// replaces the direct text and keeps the child elements
+func TestSetWithOptions_TextOnlySet(t *testing.T) {
+	opts := &Options{CaseSensitive: true, TextOnlySet: true}
+
 	tests := []struct {
 		name     string
 		xml      string
-		path     string
 		value    interface{}
 		expected string
 	}{
-		{
-			name: "update first element",
-			xml: `<root>
-				<item>first</item>
-				<item>second</item>
-				<item>third</item>
-			</root>`,
-			path:     "root.item.0",
-			value:    "updated",
-			expected: "updated",
-		},
-		{
-			name: "update middle element",
-			xml: `<root>
-				<item>first</item>
-				<item>second</item>
-				<item>third</item>
-			</root>`,
-			path:     "root.item.1",
-			value:    "updated",
-			expected: "updated",
-		},
+		{"text around child", `<root>old<child/>more</root>`, "new", `<root>new<child/></root>`},
+		{"text after child", `<root><b>bold</b> rest</root>`, "new", `<root><b>bold</b> new</root>`},
+		{"no direct text", `<root><b>bold</b></root>`, "new", `<root>new<b>bold</b></root>`},
+		{"escaped value", `<root>old<i/></root>`, "a < b", `<root>a &lt; b<i/></root>`},
+		{"number", `<root>1<i/></root>`, 2, `<root>2<i/></root>`},
+		{"comments and CDATA", `<root>a<![CDATA[b]]><!--c--><i>d</i>e</root>`, "new", `<root>new<!--c--><i>d</i></root>`},
+		{"indented", "<root>\n  old\n  <child>c</child>\n  tail\n</root>", "new", "<root>\n  new\n  <child>c</child>\n</root>"},
+		{"emptied", "<root>\n  old\n  <child>c</child>\n</root>", "", "<root>\n  <child>c</child>\n</root>"},
+		{"text only", `<root>old</root>`, "new", `<root>new</root>`},
+		{"self-closing", `<root/>`, "new", `<root>new</root>`},
+		{"raw value replaces content", `<root>old<child/></root>`, []byte("<x/>"), `<root><x/></root>`},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result, err := Set(tt.xml, tt.path, tt.value)
+			result, err := SetWithOptions(tt.xml, "root", tt.value, opts)
 			if err != nil {
-				t.Fatalf("Set() error = %v", err)
+				t.Fatalf("SetWithOptions() error = %v", err)
 			}
-			// Verify the value was set by reading it back
-			readResult := Get(result, tt.path)
-			if readResult.String() != tt.expected {
-				t.Errorf("Set() then Get() = %v, want %v", readResult.String(), tt.expected)
+			if result != tt.expected {
+				t.Errorf("SetWithOptions() = %q, want %q", result, tt.expected)
 			}
 		})
 	}
+
+	// By default the children are replaced along with the text
+	result, _ := Set(`<root>old<child/>more</root>`, "root", "new")
+	if expected := `<root>new</root>`; result != expected {
+		t.Errorf("Set() = %q, want %q", result, expected)
+	}
 }
 
-// Integration test: Set followed by Get
-func TestSet_Integration(t *testing.T) {
-	xml := `<root><user><name>John</name></user></root>`
+// TestSetWithOptions_SelfCloseEmpty tests that created and emptied elements
+// are written self-closing while untouched empty elements keep their form
+func TestSetWithOptions_SelfCloseEmpty(t *testing.T) {
+	xml := `<root><a x="1">1</a><b><c>2</c></b><e></e></root>`
+	opts := &Options{CaseSensitive: true, SelfCloseEmpty: true}
 
-	// Set a new value
-	modified, err := Set(xml, "root.user.age", 30)
-	if err != nil {
-		t.Fatalf("Set() error = %v", err)
+	tests := []struct {
+		name     string
+		xml      string
+		path     string
+		value    interface{}
+		expected string
+	}{
+		{"emptied element", xml, "root.a", "", `<root><a x="1"/><b><c>2</c></b><e></e></root>`},
+		{"emptied parent", xml, "root.b", "", `<root><a x="1">1</a><b/><e></e></root>`},
+		{"created element", xml, "root.n", "", `<root><a x="1">1</a><b><c>2</c></b><e></e><n/></root>`},
+		{"created chain", xml, "root.n.m", "", `<root><a x="1">1</a><b><c>2</c></b><e></e><n><m/></n></root>`},
+		{"appended sibling", xml, "root.b.c.-1", "", `<root><a x="1">1</a><b><c>2</c><c/></b><e></e></root>`},
+		{"empty document", "", "n", "", `<n/>`},
+		{"indented document", "<root>\n  <a>1</a>\n</root>", "root.n", "", "<root>\n  <a>1</a>\n  <n/>\n</root>"},
+		{"element with content", xml, "root.n", "v", `<root><a x="1">1</a><b><c>2</c></b><e></e><n>v</n></root>`},
 	}
 
-	// Get the value back
-	result := Get(modified, "root.user.age")
-	if result.Int() != 30 {
-		t.Errorf("Get() after Set() = %v, want 30", result.Int())
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := SetWithOptions(tt.xml, tt.path, tt.value, opts)
+			if err != nil {
+				t.Fatalf("SetWithOptions() error = %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("SetWithOptions() = %q, want %q", result, tt.expected)
+			}
+		})
 	}
 
-	// Verify original value still exists
-	name := Get(modified, "root.user.name")
-	if name.String() != "John" {
-		t.Errorf("Get() original value = %v, want John", name.String())
+	// Default options keep the expanded form
+	result, _ := SetWithOptions(xml, "root.n", "", DefaultOptions())
+	if expected := `<root><a x="1">1</a><b><c>2</c></b><e></e><n></n></root>`; result != expected {
+		t.Errorf("SetWithOptions() with defaults = %q, want %q", result, expected)
 	}
 }
 
-// Helper function to compare XML with attributes (attribute order may vary)
-func attributesEqual(xml1, xml2 string) bool {
-	// Simple comparison - in production might need to normalize attribute order
-	// For now, just check if both contain the same attributes
-	return xml1 == xml2
-}
+// introduced by a write
+func TestSetWithOptions_AutoDeclareNamespaces(t *testing.T) {
+	soap := `<soap:Envelope xmlns:soap="urn:soap"><soap:Body/></soap:Envelope>`
+	manifest := `<manifest><application/></manifest>`
+	opts := &Options{CaseSensitive: true, AutoDeclareNamespaces: map[string]string{
+		"m":       "urn:m",
+		"soap":    "urn:soap",
+		"android": "urn:android",
+	}}
 
-// Test deep nesting position tracking (bug fix verification)
-func TestSet_DeepNesting(t *testing.T) {
-	xml := `<root><level1><level2><level3><target>old</target></level3></level2></level1></root>`
-	result, err := Set(xml, "root.level1.level2.level3.target", "new")
-	if err != nil {
-		t.Fatalf("Set failed: %v", err)
+	tests := []struct {
+		name     string
+		xml      string
+		path     string
+		value    interface{}
+		expected string
+	}{
+		{
+			name:     "created element chain",
+			xml:      soap,
+			path:     "soap:Envelope.soap:Body.m:GetPrice.m:Item",
+			value:    "x",
+			expected: `<soap:Envelope xmlns:soap="urn:soap"><soap:Body><m:GetPrice xmlns:m="urn:m"><m:Item>x</m:Item></m:GetPrice></soap:Body></soap:Envelope>`,
+		},
+		{
+			name:     "declared prefix",
+			xml:      soap,
+			path:     "soap:Envelope.soap:Header",
+			value:    "h",
+			expected: `<soap:Envelope xmlns:soap="urn:soap"><soap:Body/><soap:Header>h</soap:Header></soap:Envelope>`,
+		},
+		{
+			name:     "raw fragment",
+			xml:      soap,
+			path:     "soap:Envelope.soap:Body",
+			value:    []byte(`<m:Item/><plain/><m:Other><m:Deep/></m:Other>`),
+			expected: `<soap:Envelope xmlns:soap="urn:soap"><soap:Body><m:Item xmlns:m="urn:m"/><plain/><m:Other xmlns:m="urn:m"><m:Deep/></m:Other></soap:Body></soap:Envelope>`,
+		},
+		{
+			name:     "raw fragment below created element",
+			xml:      soap,
+			path:     "soap:Envelope.soap:Body.m:GetPrice",
+			value:    []byte(`<m:Item/>`),
+			expected: `<soap:Envelope xmlns:soap="urn:soap"><soap:Body><m:GetPrice xmlns:m="urn:m"><m:Item/></m:GetPrice></soap:Body></soap:Envelope>`,
+		},
+		{
+			name:     "attribute on existing element",
+			xml:      manifest,
+			path:     "manifest.application.@android:label",
+			value:    "App",
+			expected: `<manifest><application xmlns:android="urn:android" android:label="App"/></manifest>`,
+		},
+		{
+			name:     "attribute on created element",
+			xml:      manifest,
+			path:     "manifest.activity.@android:name",
+			value:    ".Main",
+			expected: `<manifest><application/><activity xmlns:android="urn:android" android:name=".Main"/></manifest>`,
+		},
+		{
+			name:     "appended element",
+			xml:      manifest,
+			path:     "manifest.m:item.-1",
+			value:    "1",
+			expected: `<manifest><application/><m:item xmlns:m="urn:m">1</m:item></manifest>`,
+		},
+		{
+			name:     "declared on ancestor",
+			xml:      `<r><m:a xmlns:m="urn:m"/></r>`,
+			path:     "r.m:a.m:b",
+			value:    "1",
+			expected: `<r><m:a xmlns:m="urn:m"><m:b>1</m:b></m:a></r>`,
+		},
+		{
+			name:     "prefix without URI",
+			xml:      `<r/>`,
+			path:     "r.other:a",
+			value:    "1",
+			expected: `<r><other:a>1</other:a></r>`,
+		},
 	}
 
-	// Verify target was actually changed
-	got := Get(result, "root.level1.level2.level3.target")
-	if got.String() != "new" {
-		t.Errorf("Deep nesting set failed: got %q, want %q", got.String(), "new")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := SetWithOptions(tt.xml, tt.path, tt.value, opts)
+			if err != nil {
+				t.Fatalf("SetWithOptions() error = %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("SetWithOptions() = %s\nwant %s", result, tt.expected)
+			}
+		})
 	}
 
-	// Verify XML structure is intact
-	if !containsAll(result, []string{"<level1>", "<level2>", "<level3>", "</level1>", "</level2>", "</level3>"}) {
-		t.Error("XML structure corrupted during deep nesting set")
+	// Auto-declared prefixes satisfy RequireDeclaredNamespaces
+	strict := &Options{CaseSensitive: true, RequireDeclaredNamespaces: true, AutoDeclareNamespaces: opts.AutoDeclareNamespaces}
+	if _, err := SetRawWithOptions(soap, "soap:Envelope.soap:Body", "<m:A/>", strict); err != nil {
+		t.Errorf("SetRawWithOptions() error = %v", err)
+	}
+	if _, err := SetRawWithOptions(soap, "soap:Envelope.soap:Body", "<m:A/><z:B/>", strict); !errors.Is(err, ErrInvalidValue) {
+		t.Errorf("SetRawWithOptions() error = %v, want ErrInvalidValue for prefix z", err)
 	}
 }
 
-func TestSet_MultipleNested(t *testing.T) {
-	xml := `<root>
-		<user>
-			<name>John</name>
-			<address>
-				<city>NYC</city>
-				<zip>10001</zip>
-			</address>
-		</user>
-	</root>`
-
-	result, err := Set(xml, "root.user.address.city", "LA")
-	if err != nil {
-		t.Fatalf("Set failed: %v", err)
+// Test SetBytes
+func TestSetElement(t *testing.T) {
+	tests := []struct {
+		name     string
+		xml      string
+		path     string
+		elem     string
+		attrs    map[string]string
+		text     string
+		expected string
+	}{
+		{
+			name:     "append to existing parent",
+			xml:      `<project><dependencies><dependency>a</dependency></dependencies></project>`,
+			path:     "project.dependencies",
+			elem:     "note",
+			attrs:    map[string]string{"scope": "test"},
+			text:     "junit",
+			expected: `<project><dependencies><dependency>a</dependency><note scope="test">junit</note></dependencies></project>`,
+		},
+		{
+			name:     "replace existing element with attributes",
+			xml:      `<root><user id="1" old="x">John</user><other/></root>`,
+			path:     "root",
+			elem:     "user",
+			attrs:    map[string]string{"id": "2"},
+			text:     "Jane",
+			expected: `<root><user id="2">Jane</user><other/></root>`,
+		},
+		{
+			name:     "replace self-closing element",
+			xml:      `<root><user/></root>`,
+			path:     "root",
+			elem:     "user",
+			attrs:    nil,
+			text:     "Jane",
+			expected: `<root><user>Jane</user></root>`,
+		},
+		{
+			name:     "expand self-closing parent",
+			xml:      `<root><list a="1"/></root>`,
+			path:     "root.list",
+			elem:     "item",
+			attrs:    nil,
+			text:     "x",
+			expected: `<root><list a="1"><item>x</item></list></root>`,
+		},
+		{
+			name:     "create missing parents",
+			xml:      `<root></root>`,
+			path:     "root.a.b",
+			elem:     "c",
+			attrs:    map[string]string{"k": "v"},
+			text:     "",
+			expected: `<root><a><b><c k="v"></c></b></a></root>`,
+		},
+		{
+			name:     "escapes text and attribute values",
+			xml:      `<root></root>`,
+			path:     "root",
+			elem:     "data",
+			attrs:    map[string]string{"q": `a"b<c`},
+			text:     `<tag>&`,
+			expected: `<root><data q="a&quot;b&lt;c">&lt;tag&gt;&amp;</data></root>`,
+		},
+		{
+			name:     "attributes sorted",
+			xml:      `<root></root>`,
+			path:     "root",
+			elem:     "e",
+			attrs:    map[string]string{"z": "1", "a": "2", "m": "3"},
+			text:     "t",
+			expected: `<root><e a="2" m="3" z="1">t</e></root>`,
+		},
+		{
+			name:     "indexed parent",
+			xml:      `<items><item>a</item><item>b</item></items>`,
+			path:     "items.item.1",
+			elem:     "tag",
+			attrs:    nil,
+			text:     "new",
+			expected: `<items><item>a</item><item>b<tag>new</tag></item></items>`,
+		},
 	}
 
-	gotCity := Get(result, "root.user.address.city")
-	gotZip := Get(result, "root.user.address.zip")
-	gotName := Get(result, "root.user.name")
-
-	if gotCity.String() != "LA" {
-		t.Errorf("City not updated: got %q", gotCity.String())
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := SetElement(tt.xml, tt.path, tt.elem, tt.attrs, tt.text)
+			if err != nil {
+				t.Fatalf("SetElement() error = %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("SetElement() = %q, expected %q", result, tt.expected)
+			}
+			if !Valid(result) {
+				t.Errorf("SetElement() produced invalid XML: %q", result)
+			}
+		})
 	}
-	if gotZip.String() != "10001" {
-		t.Errorf("Zip corrupted: got %q", gotZip.String())
+}
+
+func TestSetElement_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		xml     string
+		path    string
+		elem    string
+		attrs   map[string]string
+		wantErr error
+	}{
+		{"malformed xml", `<root><a></root>`, "root", "x", nil, ErrMalformedXML},
+		{"invalid element name", `<root/>`, "root", "1bad", nil, ErrInvalidValue},
+		{"injection in element name", `<root/>`, "root", "x><y", nil, ErrInvalidValue},
+		{"invalid attribute name", `<root/>`, "root", "x", map[string]string{"a b": "v"}, ErrInvalidValue},
+		{"empty path", `<root/>`, "", "x", nil, ErrInvalidPath},
+		{"attribute path", `<root/>`, "root.@id", "x", nil, ErrInvalidPath},
+		{"wildcard path", `<root/>`, "root.*", "x", nil, ErrInvalidPath},
+		{"append index", `<root/>`, "root.item.-1", "x", nil, ErrInvalidPath},
 	}
-	if gotName.String() != "John" {
-		t.Errorf("Name corrupted: got %q", gotName.String())
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := SetElement(tt.xml, tt.path, tt.elem, tt.attrs, "text")
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("SetElement() error = %v, expected %v", err, tt.wantErr)
+			}
+			if result != tt.xml {
+				t.Errorf("SetElement() should return original XML on error, got %q", result)
+			}
+		})
 	}
 }
 
-func TestSet_DeepNestingWithAttributes(t *testing.T) {
-	xml := `<root><a><b id="1"><c><d>value</d></c></b></a></root>`
-
-	// Test modifying deeply nested element
-	result, err := Set(xml, "root.a.b.c.d", "newvalue")
+func TestSetElementBytes(t *testing.T) {
+	result, err := SetElementBytes([]byte(`<root/>`), "root", "child", map[string]string{"id": "1"}, "v")
 	if err != nil {
-		t.Fatalf("Set failed: %v", err)
+		t.Fatalf("SetElementBytes() error = %v", err)
 	}
-
-	got := Get(result, "root.a.b.c.d")
-	if got.String() != "newvalue" {
-		t.Errorf("Deep nesting set failed: got %q, want %q", got.String(), "newvalue")
+	if string(result) != `<root><child id="1">v</child></root>` {
+		t.Errorf("SetElementBytes() = %q", result)
 	}
-
-	// Verify attribute wasn't corrupted
-	gotAttr := Get(result, "root.a.b.@id")
-	if gotAttr.String() != "1" {
-		t.Errorf("Attribute corrupted: got %q", gotAttr.String())
+	if got := Get(string(result), "root.child.@id").String(); got != "1" {
+		t.Errorf("Get after SetElementBytes = %q, expected 1", got)
 	}
 }
 
-func TestSet_NestedSiblings(t *testing.T) {
-	xml := `<root>
-		<section>
-			<item>first</item>
-			<nested>
-				<value>nested1</value>
-			</nested>
-			<item>second</item>
-		</section>
-	</root>`
-
-	// Modify nested element
-	result, err := Set(xml, "root.section.nested.value", "modified")
-	if err != nil {
-		t.Fatalf("Set failed: %v", err)
+func TestPrependChild(t *testing.T) {
+	tests := []struct {
+		name     string
+		xml      string
+		path     string
+		raw      string
+		expected string
+	}{
+		{
+			name:     "before existing children",
+			xml:      `<html><head><title>Home</title><link/></head></html>`,
+			path:     "html.head",
+			raw:      `<meta charset="utf-8"/>`,
+			expected: `<html><head><meta charset="utf-8"/><title>Home</title><link/></head></html>`,
+		},
+		{
+			name:     "before leading text",
+			xml:      `<p>text<b>bold</b></p>`,
+			path:     "p",
+			raw:      `<i>x</i>`,
+			expected: `<p><i>x</i>text<b>bold</b></p>`,
+		},
+		{
+			name: "indented document",
+			xml: `<html>
+  <head>
+    <title>Home</title>
+  </head>
+</html>`,
+			path: "html.head",
+			raw:  `<meta charset="utf-8"/>`,
+			expected: `<html>
+  <head>
+    <meta charset="utf-8"/>
+    <title>Home</title>
+  </head>
+</html>`,
+		},
+		{
+			name:     "empty parent",
+			xml:      `<root><list></list></root>`,
+			path:     "root.list",
+			raw:      `<item>1</item>`,
+			expected: `<root><list><item>1</item></list></root>`,
+		},
+		{
+			name:     "self-closing parent",
+			xml:      `<root><list a="1"/></root>`,
+			path:     "root.list",
+			raw:      `<item>1</item>`,
+			expected: `<root><list a="1"><item>1</item></list></root>`,
+		},
+		{
+			name:     "indexed parent",
+			xml:      `<root><list><a/></list><list><b/></list></root>`,
+			path:     "root.list.1",
+			raw:      `<first/>`,
+			expected: `<root><list><a/></list><list><first/><b/></list></root>`,
+		},
+		{
+			name:     "missing parent is created",
+			xml:      `<root><a/></root>`,
+			path:     "root.list",
+			raw:      `<item>1</item>`,
+			expected: `<root><a/><list><item>1</item></list></root>`,
+		},
 	}
 
-	// Verify the nested element was modified
-	gotNested := Get(result, "root.section.nested.value")
-	if gotNested.String() != "modified" {
-		t.Errorf("Nested value not updated: got %q", gotNested.String())
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := PrependChild(tt.xml, tt.path, tt.raw)
+			if err != nil {
+				t.Fatalf("PrependChild() error = %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("PrependChild() =\n%s\nexpected:\n%s", result, tt.expected)
+			}
+			if !Valid(result) {
+				t.Errorf("PrependChild() produced invalid XML: %s", result)
+			}
+		})
 	}
 
-	// Verify siblings weren't affected
-	gotFirst := Get(result, "root.section.item.0")
-	gotSecond := Get(result, "root.section.item.1")
-	if gotFirst.String() != "first" || gotSecond.String() != "second" {
-		t.Error("Sibling elements were corrupted")
+	result, err := PrependChildBytes([]byte(`<root><b/></root>`), "root", `<a/>`)
+	if err != nil || string(result) != `<root><a/><b/></root>` {
+		t.Errorf("PrependChildBytes() = %q, %v", result, err)
 	}
 }
 
-// Helper function to check if string contains all substrings
-func containsAll(s string, substrs []string) bool {
-	for _, substr := range substrs {
-		if !containsSubstring(s, substr) {
-			return false
-		}
+func TestPrependChild_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		xml     string
+		path    string
+		raw     string
+		wantErr error
+	}{
+		{"malformed xml", `<root><a></root>`, "root", "<x/>", ErrMalformedXML},
+		{"unbalanced raw", `<root/>`, "root", "<x>", ErrInvalidValue},
+		{"empty path", `<root/>`, "", "<x/>", ErrInvalidPath},
+		{"attribute path", `<root/>`, "root.@id", "<x/>", ErrInvalidPath},
+		{"wildcard path", `<root/>`, "root.*", "<x/>", ErrInvalidPath},
+		{"append index", `<root/>`, "root.item.-1", "<x/>", ErrInvalidPath},
 	}
-	return true
-}
-
-func containsSubstring(s, substr string) bool {
-	return len(s) >= len(substr) && (s == substr || len(s) > len(substr) && containsSubstringHelper(s, substr))
-}
 
-func containsSubstringHelper(s, substr string) bool {
-	for i := 0; i <= len(s)-len(substr); i++ {
-		if s[i:i+len(substr)] == substr {
-			return true
-		}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := PrependChild(tt.xml, tt.path, tt.raw)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("PrependChild() error = %v, expected %v", err, tt.wantErr)
+			}
+			if result != tt.xml {
+				t.Errorf("PrependChild() should return original XML on error, got %q", result)
+			}
+		})
 	}
-	return false
 }
 
-// Benchmark Set operations
-func BenchmarkSet_SimpleElement(b *testing.B) {
-	xml := `<root><user><name>John</name></user></root>`
-	b.ResetTimer()
-	for i := 0; i < b.N; i++ {
-		_, _ = Set(xml, "root.user.name", "Jane")
-	}
-}
+func TestCanSet(t *testing.T) {
+	xml := `<root><items><item>a</item></items><user id="1"/></root>`
 
-func BenchmarkSet_CreateElement(b *testing.B) {
-	xml := `<root><user><name>John</name></user></root>`
-	b.ResetTimer()
-	for i := 0; i < b.N; i++ {
-		_, _ = Set(xml, "root.user.age", 30)
+	tests := []struct {
+		name    string
+		xml     string
+		path    string
+		value   interface{}
+		wantErr error
+	}{
+		{"existing element", xml, "root.items.item", "b", nil},
+		{"new nested element", xml, "root.a.b.c", 1, nil},
+		{"attribute", xml, "root.user.@id", 2, nil},
+		{"new attribute chain", xml, "root.new.@id", "x", nil},
+		{"append", xml, "root.items.item.-1", "b", nil},
+		{"raw value", xml, "root.raw", []byte("<x/>"), nil},
+		{"empty document", "", "root.a", "x", nil},
+		{"delete existing", xml, "root.items", nil, nil},
+		{"delete missing", xml, "root.missing", nil, nil},
+		{"malformed xml", `<root><a></root>`, "root.a", "x", ErrMalformedXML},
+		{"delete on empty document", "", "root.a", nil, ErrMalformedXML},
+		{"empty path", xml, "", "x", ErrInvalidPath},
+		{"reserved negative index", xml, "root.items.item.-2", "x", ErrInvalidPath},
+		{"nested append", xml, "root.items.item.-1.child", "x", ErrInvalidPath},
+		{"unsupported type", xml, "root.items", struct{}{}, ErrInvalidValue},
+		{"value too large", xml, "root.items", strings.Repeat("x", MaxValueSize+1), ErrInvalidValue},
+		{"namespace declaration without prefix", xml, "root.@xmlns:", "urn:x", ErrInvalidPath},
+		{"reserved namespace prefix", xml, "root.@xmlns:xmlns", "urn:x", ErrInvalidValue},
+		{"index past the end", xml, "root.items.item.3", "x", ErrInvalidPath},
+		{"next free index", xml, "root.items.item.1.name", "x", nil},
+		{"gap below a new index", xml, "root.items.item.1.name.1", "x", ErrInvalidPath},
+		{"fan-out update", xml, "root.items.#.item", "x", nil},
+		{"fan-out delete", xml, "root.*.item", nil, nil},
+		{"wildcard match lacks the path", xml, "root.*.item", "x", ErrInvalidPath},
+		{"fan-out with modifier", xml, "root.*|@reverse", "x", ErrInvalidPath},
+		{"delete root attribute alone", xml, "@id", nil, ErrInvalidPath},
 	}
-}
 
-func BenchmarkSet_Attribute(b *testing.B) {
-	xml := `<root><user id="123"><name>John</name></user></root>`
-	b.ResetTimer()
-	for i := 0; i < b.N; i++ {
-		_, _ = Set(xml, "root.user.@id", "456")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CanSet(tt.xml, tt.path, tt.value)
+			if tt.wantErr == nil && err != nil {
+				t.Errorf("CanSet() error = %v, want nil", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("CanSet() error = %v, want %v", err, tt.wantErr)
+			}
+
+			// CanSet must agree with Set
+			_, setErr := Set(tt.xml, tt.path, tt.value)
+			if (err == nil) != (setErr == nil) {
+				t.Errorf("CanSet() error = %v but Set() error = %v", err, setErr)
+			}
+			if tt.wantErr != nil && !errors.Is(setErr, tt.wantErr) {
+				t.Errorf("Set() error = %v, want %v", setErr, tt.wantErr)
+			}
+		})
 	}
-}
 
-func BenchmarkSetRaw(b *testing.B) {
-	xml := `<root></root>`
-	rawxml := "<item><name>Test</name></item>"
-	b.ResetTimer()
-	for i := 0; i < b.N; i++ {
-		_, _ = SetRaw(xml, "root.data", rawxml)
+	if err := CanSetBytes(nil, "root", "x"); !errors.Is(err, ErrMalformedXML) {
+		t.Errorf("CanSetBytes(nil) error = %v, want ErrMalformedXML", err)
 	}
 }
 
-// Test SetMany - Multiple unrelated paths
-func TestSetMany_MultipleUnrelated(t *testing.T) {
-	xml := `<root><user><name>John</name></user></root>`
-	paths := []string{"root.user.age", "root.user.email", "root.user.city"}
-	values := []interface{}{30, "john@example.com", "NYC"}
+func TestSetN(t *testing.T) {
+	xml := `<root><port>8080</port><user id="1" name="a&amp;b">John</user></root>`
 
-	result, err := SetMany(xml, paths, values)
-	if err != nil {
-		t.Fatalf("SetMany() error = %v", err)
+	tests := []struct {
+		name    string
+		path    string
+		value   interface{}
+		changes int
+	}{
+		{"same element value", "root.port", 8080, 0},
+		{"same string value", "root.user", "John", 0},
+		{"different element value", "root.port", 9090, 1},
+		{"same attribute value", "root.user.@id", "1", 0},
+		{"same escaped attribute value", "root.user.@name", "a&b", 0},
+		{"different attribute value", "root.user.@id", 2, 1},
+		{"new attribute", "root.user.@role", "admin", 1},
+		{"new element", "root.host", "localhost", 1},
+		{"nil deletes existing", "root.port", nil, 1},
+		{"nil on missing path", "root.missing", nil, 0},
+		{"append", "root.port.-1", "1", 1},
 	}
 
-	// Verify all values were set
-	if Get(result, "root.user.age").Int() != 30 {
-		t.Errorf("age not set correctly")
-	}
-	if Get(result, "root.user.email").String() != "john@example.com" {
-		t.Errorf("email not set correctly")
-	}
-	if Get(result, "root.user.city").String() != "NYC" {
-		t.Errorf("city not set correctly")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, n, err := SetN(xml, tt.path, tt.value)
+			if err != nil {
+				t.Fatalf("SetN() error = %v", err)
+			}
+			if n != tt.changes {
+				t.Errorf("SetN() changes = %d, want %d", n, tt.changes)
+			}
+			if n == 0 && result != xml {
+				t.Errorf("SetN() with no changes should return original XML, got %q", result)
+			}
+			if n > 0 && result == xml {
+				t.Errorf("SetN() reported changes but XML is unchanged")
+			}
+		})
 	}
 
-	// Verify original data preserved
-	if Get(result, "root.user.name").String() != "John" {
-		t.Errorf("original name corrupted")
+	if _, n, err := SetN(`<root><a>`, "root.a", "x"); err == nil || n != 0 {
+		t.Errorf("SetN() on malformed XML = (%d, %v), want error", n, err)
 	}
 }
 
-// Test SetMany - Multiple related paths (parent and child)
-func TestSetMany_RelatedPaths(t *testing.T) {
-	xml := `<root></root>`
-	paths := []string{"root.user.name", "root.user.address.city", "root.user.address.zip"}
-	values := []interface{}{"John", "NYC", "10001"}
+func TestDeleteN(t *testing.T) {
+	xml := `<root><tmp/><user id="1">John</user></root>`
 
-	result, err := SetMany(xml, paths, values)
-	if err != nil {
-		t.Fatalf("SetMany() error = %v", err)
+	tests := []struct {
+		name    string
+		path    string
+		changes int
+	}{
+		{"existing element", "root.tmp", 1},
+		{"existing attribute", "root.user.@id", 1},
+		{"missing element", "root.missing", 0},
+		{"missing attribute", "root.user.@role", 0},
+		{"attribute on missing element", "root.other.@id", 0},
 	}
 
-	// Verify nested structure created correctly
-	if Get(result, "root.user.name").String() != "John" {
-		t.Errorf("name not set correctly")
-	}
-	if Get(result, "root.user.address.city").String() != "NYC" {
-		t.Errorf("city not set correctly")
-	}
-	if Get(result, "root.user.address.zip").String() != "10001" {
-		t.Errorf("zip not set correctly")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, n, err := DeleteN(xml, tt.path)
+			if err != nil {
+				t.Fatalf("DeleteN() error = %v", err)
+			}
+			if n != tt.changes {
+				t.Errorf("DeleteN() changes = %d, want %d", n, tt.changes)
+			}
+			if n == 0 && result != xml {
+				t.Errorf("DeleteN() with no changes should return original XML, got %q", result)
+			}
+		})
 	}
 }
 
-// Test SetMany - Mix of creates and updates
-func TestSetMany_MixedOperations(t *testing.T) {
-	xml := `<root><user><name>John</name><age>25</age></user></root>`
-	paths := []string{"root.user.name", "root.user.age", "root.user.email"}
-	values := []interface{}{"Jane", 30, "jane@example.com"}
+func TestSetManyN(t *testing.T) {
+	xml := `<root><a>1</a><b>2</b></root>`
 
-	result, err := SetMany(xml, paths, values)
+	result, n, changed, err := SetManyN(xml, []string{"root.a", "root.b", "root.c"}, []interface{}{1, 3, "new"})
 	if err != nil {
-		t.Fatalf("SetMany() error = %v", err)
-	}
-
-	// Verify updates
-	if Get(result, "root.user.name").String() != "Jane" {
-		t.Errorf("name not updated correctly")
-	}
-	if Get(result, "root.user.age").Int() != 30 {
-		t.Errorf("age not updated correctly")
+		t.Fatalf("SetManyN() error = %v", err)
 	}
-
-	// Verify new element created
-	if Get(result, "root.user.email").String() != "jane@example.com" {
-		t.Errorf("email not created correctly")
+	if n != 2 || !changed {
+		t.Errorf("SetManyN() = %d, %v, want 2, true", n, changed)
 	}
-}
-
-// Test SetMany - Overlapping paths (later wins)
-func TestSetMany_OverlappingPaths(t *testing.T) {
-	xml := `<root><value>original</value></root>`
-	paths := []string{"root.value", "root.value", "root.value"}
-	values := []interface{}{"first", "second", "third"}
-
-	result, err := SetMany(xml, paths, values)
-	if err != nil {
-		t.Fatalf("SetMany() error = %v", err)
+	if result != `<root><a>1</a><b>3</b><c>new</c></root>` {
+		t.Errorf("SetManyN() = %q", result)
 	}
 
-	// Last operation should win
-	if Get(result, "root.value").String() != "third" {
-		t.Errorf("overlapping paths not handled correctly, got %v", Get(result, "root.value").String())
+	result, n, changed, err = SetManyN(xml, []string{"root.a", "root.b"}, []interface{}{"1", 2})
+	if err != nil || n != 0 || changed || result != xml {
+		t.Errorf("SetManyN() no-op = (%q, %d, %v, %v), want original, 0, false, nil", result, n, changed, err)
 	}
-}
-
-// Test SetMany - Error case: mismatched counts
-func TestSetMany_MismatchedCounts(t *testing.T) {
-	xml := `<root></root>`
-	paths := []string{"root.a", "root.b", "root.c"}
-	values := []interface{}{1, 2} // Only 2 values for 3 paths
 
-	_, err := SetMany(xml, paths, values)
-	if err == nil {
-		t.Fatalf("SetMany() expected error for mismatched counts, got nil")
+	// Counts are per operation: a change that a later operation undoes is
+	// counted, but the document is reported unchanged
+	result, n, changed, err = SetManyN(xml, []string{"root.a", "root.a"}, []interface{}{"z", "1"})
+	if err != nil || n != 2 || changed || result != xml {
+		t.Errorf("SetManyN() reverted = (%q, %d, %v, %v), want original, 2, false, nil", result, n, changed, err)
 	}
-}
-
-// Test SetMany - Error case: invalid path
-func TestSetMany_InvalidPath(t *testing.T) {
-	xml := `<root></root>`
-	paths := []string{"root.valid", "", "root.another"}
-	values := []interface{}{1, 2, 3}
 
-	_, err := SetMany(xml, paths, values)
-	if err == nil {
-		t.Fatalf("SetMany() expected error for invalid path, got nil")
+	if _, _, _, err := SetManyN(xml, []string{"root.a"}, nil); !errors.Is(err, ErrInvalidPath) {
+		t.Errorf("SetManyN() mismatched lengths error = %v, want ErrInvalidPath", err)
 	}
 }
 
-// Test SetMany - Empty inputs
-func TestSetMany_EmptyInputs(t *testing.T) {
-	xml := `<root><value>test</value></root>`
-	paths := []string{}
-	values := []interface{}{}
+func TestSetBytes(t *testing.T) {
+	xml := []byte(`<root><value>old</value></root>`)
+	expected := []byte(`<root><value>new</value></root>`)
 
-	result, err := SetMany(xml, paths, values)
+	result, err := SetBytes(xml, "root.value", "new")
 	if err != nil {
-		t.Fatalf("SetMany() error = %v", err)
+		t.Fatalf("SetBytes() error = %v", err)
 	}
 
-	// Should return original XML unchanged
-	if result != xml {
-		t.Errorf("SetMany() with empty inputs should return original XML")
+	if string(result) != string(expected) {
+		t.Errorf("SetBytes() = %s, want %s", result, expected)
 	}
 }
 
-// Test SetMany - Attributes
-func TestSetMany_Attributes(t *testing.T) {
-	xml := `<user id="123"><name>John</name></user>`
-	paths := []string{"user.@active", "user.@role", "user.@id"}
-	values := []interface{}{"true", "admin", "456"}
+// Test nil value as deletion
+func TestSet_NilValue(t *testing.T) {
+	xml := `<root><user><name>John</name><age>30</age></user></root>`
+	expected := `<root><user><name>John</name></user></root>`
 
-	result, err := SetMany(xml, paths, values)
+	result, err := Set(xml, "root.user.age", nil)
 	if err != nil {
-		t.Fatalf("SetMany() error = %v", err)
+		t.Fatalf("Set() with nil error = %v", err)
 	}
 
-	// Verify attributes
-	if Get(result, "user.@active").String() != "true" {
-		t.Errorf("active attribute not set correctly")
-	}
-	if Get(result, "user.@role").String() != "admin" {
-		t.Errorf("role attribute not set correctly")
-	}
-	if Get(result, "user.@id").String() != "456" {
-		t.Errorf("id attribute not updated correctly")
+	if result != expected {
+		t.Errorf("Set() with nil = %v, want %v", result, expected)
 	}
 }
 
-// Test SetMany - Array indices
-func TestSetMany_ArrayIndices(t *testing.T) {
-	xml := `<root>
-		<item>first</item>
-		<item>second</item>
-		<item>third</item>
-	</root>`
-	paths := []string{"root.item.0", "root.item.2"}
-	values := []interface{}{"updated-first", "updated-third"}
-
-	result, err := SetMany(xml, paths, values)
-	if err != nil {
-		t.Fatalf("SetMany() error = %v", err)
+// Test error cases
+func TestSet_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		xml     string
+		path    string
+		value   interface{}
+		wantErr error
+	}{
+		{
+			name:    "empty path",
+			xml:     `<root></root>`,
+			path:    "",
+			value:   "test",
+			wantErr: ErrInvalidPath,
+		},
+		{
+			name:    "document too large",
+			xml:     string(make([]byte, MaxDocumentSize+1)),
+			path:    "root.test",
+			value:   "test",
+			wantErr: ErrMalformedXML,
+		},
 	}
 
-	// Verify updates
-	if Get(result, "root.item.0").String() != "updated-first" {
-		t.Errorf("first item not updated correctly")
-	}
-	if Get(result, "root.item.1").String() != "second" {
-		t.Errorf("middle item corrupted")
-	}
-	if Get(result, "root.item.2").String() != "updated-third" {
-		t.Errorf("last item not updated correctly")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Set(tt.xml, tt.path, tt.value)
+			if err == nil {
+				t.Fatalf("Set() expected error, got nil")
+			}
+			if err != tt.wantErr {
+				t.Errorf("Set() error = %v, want %v", err, tt.wantErr)
+			}
+		})
 	}
 }
 
-// Test SetManyBytes
-func TestSetManyBytes(t *testing.T) {
-	xml := []byte(`<root><value>old</value></root>`)
-	paths := []string{"root.value", "root.new"}
-	values := []interface{}{"updated", "created"}
+// Test array index setting
+func TestSet_ArrayIndex(t *testing.T) {
+	tests := []struct {
+		name     string
+		xml      string
+		path     string
+		value    interface{}
+		expected string
+	}{
+		{
+			name: "update first element",
+			xml: `<root>
+				<item>first</item>
+				<item>second</item>
+				<item>third</item>
+			</root>`,
+			path:     "root.item.0",
+			value:    "updated",
+			expected: "updated",
+		},
+		{
+			name: "update middle element",
+			xml: `<root>
+				<item>first</item>
+				<item>second</item>
+				<item>third</item>
+			</root>`,
+			path:     "root.item.1",
+			value:    "updated",
+			expected: "updated",
+		},
+	}
 
-	result, err := SetManyBytes(xml, paths, values)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Set(tt.xml, tt.path, tt.value)
+			if err != nil {
+				t.Fatalf("Set() error = %v", err)
+			}
+			// Verify the value was set by reading it back
+			readResult := Get(result, tt.path)
+			if readResult.String() != tt.expected {
+				t.Errorf("Set() then Get() = %v, want %v", readResult.String(), tt.expected)
+			}
+		})
+	}
+}
+
+// Integration test: Set followed by Get
+func TestSet_Integration(t *testing.T) {
+	xml := `<root><user><name>John</name></user></root>`
+
+	// Set a new value
+	modified, err := Set(xml, "root.user.age", 30)
 	if err != nil {
-		t.Fatalf("SetManyBytes() error = %v", err)
+		t.Fatalf("Set() error = %v", err)
 	}
 
-	resultStr := string(result)
-	if Get(resultStr, "root.value").String() != "updated" {
-		t.Errorf("value not updated correctly")
+	// Get the value back
+	result := Get(modified, "root.user.age")
+	if result.Int() != 30 {
+		t.Errorf("Get() after Set() = %v, want 30", result.Int())
 	}
-	if Get(resultStr, "root.new").String() != "created" {
-		t.Errorf("new element not created correctly")
+
+	// Verify original value still exists
+	name := Get(modified, "root.user.name")
+	if name.String() != "John" {
+		t.Errorf("Get() original value = %v, want John", name.String())
 	}
 }
 
-// Test DeleteMany - Multiple unrelated deletes
-func TestDeleteMany_MultipleUnrelated(t *testing.T) {
-	xml := `<root>
-		<a>1</a>
-		<b>2</b>
-		<c>3</c>
-		<d>4</d>
-	</root>`
+// Helper function to compare XML with attributes (attribute order may vary)
+func attributesEqual(xml1, xml2 string) bool {
+	// Simple comparison - in production might need to normalize attribute order
+	// For now, just check if both contain the same attributes
+	return xml1 == xml2
+}
 
-	result, err := DeleteMany(xml, "root.b", "root.d")
+// Test deep nesting position tracking (bug fix verification)
+func TestSet_DeepNesting(t *testing.T) {
+	xml := `<root><level1><level2><level3><target>old</target></level3></level2></level1></root>`
+	result, err := Set(xml, "root.level1.level2.level3.target", "new")
 	if err != nil {
-		t.Fatalf("DeleteMany() error = %v", err)
+		t.Fatalf("Set failed: %v", err)
 	}
 
-	// Verify deletions
-	if Get(result, "root.b").Exists() {
-		t.Errorf("element b should be deleted")
-	}
-	if Get(result, "root.d").Exists() {
-		t.Errorf("element d should be deleted")
+	// Verify target was actually changed
+	got := Get(result, "root.level1.level2.level3.target")
+	if got.String() != "new" {
+		t.Errorf("Deep nesting set failed: got %q, want %q", got.String(), "new")
 	}
 
-	// Verify remaining elements
-	if Get(result, "root.a").String() != "1" {
-		t.Errorf("element a corrupted")
-	}
-	if Get(result, "root.c").String() != "3" {
-		t.Errorf("element c corrupted")
+	// Verify XML structure is intact
+	if !containsAll(result, []string{"<level1>", "<level2>", "<level3>", "</level1>", "</level2>", "</level3>"}) {
+		t.Error("XML structure corrupted during deep nesting set")
 	}
 }
 
-// Test DeleteMany - Parent and child deletes
-func TestDeleteMany_ParentChild(t *testing.T) {
+func TestSet_MultipleNested(t *testing.T) {
 	xml := `<root>
 		<user>
 			<name>John</name>
@@ -938,879 +1612,2244 @@ func TestDeleteMany_ParentChild(t *testing.T) {
 		</user>
 	</root>`
 
-	// Delete parent first - child path becomes invalid
-	result, err := DeleteMany(xml, "root.user", "root.user.name")
+	result, err := Set(xml, "root.user.address.city", "LA")
 	if err != nil {
-		t.Fatalf("DeleteMany() error = %v", err)
+		t.Fatalf("Set failed: %v", err)
 	}
 
-	// Verify parent deleted
-	if Get(result, "root.user").Exists() {
-		t.Errorf("user element should be deleted")
-	}
+	gotCity := Get(result, "root.user.address.city")
+	gotZip := Get(result, "root.user.address.zip")
+	gotName := Get(result, "root.user.name")
 
-	// Verify root still exists
-	if !Get(result, "root").Exists() {
-		t.Errorf("root element should still exist")
+	if gotCity.String() != "LA" {
+		t.Errorf("City not updated: got %q", gotCity.String())
+	}
+	if gotZip.String() != "10001" {
+		t.Errorf("Zip corrupted: got %q", gotZip.String())
+	}
+	if gotName.String() != "John" {
+		t.Errorf("Name corrupted: got %q", gotName.String())
 	}
 }
 
-// Test DeleteMany - Non-existent paths
-func TestDeleteMany_NonExistent(t *testing.T) {
-	xml := `<root><a>1</a><b>2</b></root>`
+func TestSet_DeepNestingWithAttributes(t *testing.T) {
+	xml := `<root><a><b id="1"><c><d>value</d></c></b></a></root>`
 
-	result, err := DeleteMany(xml, "root.nonexistent", "root.missing", "root.fake")
+	// Test modifying deeply nested element
+	result, err := Set(xml, "root.a.b.c.d", "newvalue")
 	if err != nil {
-		t.Fatalf("DeleteMany() error = %v", err)
+		t.Fatalf("Set failed: %v", err)
 	}
 
-	// Should return original XML unchanged (minus whitespace differences)
-	if Get(result, "root.a").String() != "1" {
-		t.Errorf("element a corrupted")
+	got := Get(result, "root.a.b.c.d")
+	if got.String() != "newvalue" {
+		t.Errorf("Deep nesting set failed: got %q, want %q", got.String(), "newvalue")
 	}
-	if Get(result, "root.b").String() != "2" {
-		t.Errorf("element b corrupted")
+
+	// Verify attribute wasn't corrupted
+	gotAttr := Get(result, "root.a.b.@id")
+	if gotAttr.String() != "1" {
+		t.Errorf("Attribute corrupted: got %q", gotAttr.String())
 	}
 }
 
-// Test DeleteMany - Duplicate paths
-func TestDeleteMany_DuplicatePaths(t *testing.T) {
-	xml := `<root><a>1</a><b>2</b><c>3</c></root>`
+func TestSet_NestedSiblings(t *testing.T) {
+	xml := `<root>
+		<section>
+			<item>first</item>
+			<nested>
+				<value>nested1</value>
+			</nested>
+			<item>second</item>
+		</section>
+	</root>`
 
-	result, err := DeleteMany(xml, "root.a", "root.b", "root.a", "root.b")
+	// Modify nested element
+	result, err := Set(xml, "root.section.nested.value", "modified")
 	if err != nil {
-		t.Fatalf("DeleteMany() error = %v", err)
+		t.Fatalf("Set failed: %v", err)
 	}
 
-	// Verify deletions (duplicates should be handled gracefully)
-	if Get(result, "root.a").Exists() {
-		t.Errorf("element a should be deleted")
+	// Verify the nested element was modified
+	gotNested := Get(result, "root.section.nested.value")
+	if gotNested.String() != "modified" {
+		t.Errorf("Nested value not updated: got %q", gotNested.String())
 	}
-	if Get(result, "root.b").Exists() {
-		t.Errorf("element b should be deleted")
+
+	// Verify siblings weren't affected
+	gotFirst := Get(result, "root.section.item.0")
+	gotSecond := Get(result, "root.section.item.1")
+	if gotFirst.String() != "first" || gotSecond.String() != "second" {
+		t.Error("Sibling elements were corrupted")
 	}
-	if Get(result, "root.c").String() != "3" {
-		t.Errorf("element c corrupted")
+}
+
+// Helper function to check if string contains all substrings
+func containsAll(s string, substrs []string) bool {
+	for _, substr := range substrs {
+		if !containsSubstring(s, substr) {
+			return false
+		}
 	}
+	return true
 }
 
-// Test DeleteMany - Empty inputs
-func TestDeleteMany_EmptyInputs(t *testing.T) {
-	xml := `<root><value>test</value></root>`
+func containsSubstring(s, substr string) bool {
+	return len(s) >= len(substr) && (s == substr || len(s) > len(substr) && containsSubstringHelper(s, substr))
+}
 
-	result, err := DeleteMany(xml)
-	if err != nil {
-		t.Fatalf("DeleteMany() error = %v", err)
+func containsSubstringHelper(s, substr string) bool {
+	for i := 0; i <= len(s)-len(substr); i++ {
+		if s[i:i+len(substr)] == substr {
+			return true
+		}
 	}
+	return false
+}
 
-	// Should return original XML unchanged
-	if result != xml {
-		t.Errorf("DeleteMany() with empty inputs should return original XML")
+// Benchmark Set operations
+func BenchmarkSet_SimpleElement(b *testing.B) {
+	xml := `<root><user><name>John</name></user></root>`
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = Set(xml, "root.user.name", "Jane")
 	}
 }
 
-// Test DeleteMany - Attributes
-func TestDeleteMany_Attributes(t *testing.T) {
-	xml := `<user id="123" active="true" role="admin"><name>John</name></user>`
-
-	result, err := DeleteMany(xml, "user.@active", "user.@role")
-	if err != nil {
-		t.Fatalf("DeleteMany() error = %v", err)
+func BenchmarkSet_CreateElement(b *testing.B) {
+	xml := `<root><user><name>John</name></user></root>`
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = Set(xml, "root.user.age", 30)
 	}
+}
 
-	// Verify attribute deletions
-	if Get(result, "user.@active").Exists() {
-		t.Errorf("active attribute should be deleted")
-	}
-	if Get(result, "user.@role").Exists() {
-		t.Errorf("role attribute should be deleted")
+func BenchmarkSet_Attribute(b *testing.B) {
+	xml := `<root><user id="123"><name>John</name></user></root>`
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = Set(xml, "root.user.@id", "456")
 	}
+}
 
-	// Verify remaining attribute
-	if Get(result, "user.@id").String() != "123" {
-		t.Errorf("id attribute corrupted")
-	}
-
-	// Verify element content
-	if Get(result, "user.name").String() != "John" {
-		t.Errorf("name element corrupted")
+func BenchmarkSetRaw(b *testing.B) {
+	xml := `<root></root>`
+	rawxml := "<item><name>Test</name></item>"
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = SetRaw(xml, "root.data", rawxml)
 	}
 }
 
-// Test DeleteMany - Array elements
-func TestDeleteMany_ArrayElements(t *testing.T) {
-	xml := `<root>
-		<item>first</item>
-		<item>second</item>
-		<item>third</item>
-		<item>fourth</item>
-	</root>`
+// Test SetMany - Multiple unrelated paths
+func TestSetMany_MultipleUnrelated(t *testing.T) {
+	xml := `<root><user><name>John</name></user></root>`
+	paths := []string{"root.user.age", "root.user.email", "root.user.city"}
+	values := []interface{}{30, "john@example.com", "NYC"}
 
-	// Delete indices 1 and 3 (note: after first deletion, indices shift)
-	result, err := DeleteMany(xml, "root.item.1", "root.item.2")
+	result, err := SetMany(xml, paths, values)
 	if err != nil {
-		t.Fatalf("DeleteMany() error = %v", err)
+		t.Fatalf("SetMany() error = %v", err)
 	}
 
-	// After deleting index 1 (second), the remaining items shift down
-	// After deleting index 2 of the shifted array (originally fourth), we should have first and third
-	count := Get(result, "root.item.#").Int()
-	if count != 2 {
-		t.Errorf("expected 2 remaining items, got %d", count)
+	// Verify all values were set
+	if Get(result, "root.user.age").Int() != 30 {
+		t.Errorf("age not set correctly")
+	}
+	if Get(result, "root.user.email").String() != "john@example.com" {
+		t.Errorf("email not set correctly")
+	}
+	if Get(result, "root.user.city").String() != "NYC" {
+		t.Errorf("city not set correctly")
+	}
+
+	// Verify original data preserved
+	if Get(result, "root.user.name").String() != "John" {
+		t.Errorf("original name corrupted")
 	}
 }
 
-// Test DeleteManyBytes
-func TestDeleteManyBytes(t *testing.T) {
-	xml := []byte(`<root><a>1</a><b>2</b><c>3</c></root>`)
+// Test SetMany - Multiple related paths (parent and child)
+func TestSetMany_RelatedPaths(t *testing.T) {
+	xml := `<root></root>`
+	paths := []string{"root.user.name", "root.user.address.city", "root.user.address.zip"}
+	values := []interface{}{"John", "NYC", "10001"}
 
-	result, err := DeleteManyBytes(xml, "root.b")
+	result, err := SetMany(xml, paths, values)
 	if err != nil {
-		t.Fatalf("DeleteManyBytes() error = %v", err)
+		t.Fatalf("SetMany() error = %v", err)
 	}
 
-	resultStr := string(result)
-	if Get(resultStr, "root.b").Exists() {
-		t.Errorf("element b should be deleted")
+	// Verify nested structure created correctly
+	if Get(result, "root.user.name").String() != "John" {
+		t.Errorf("name not set correctly")
 	}
-	if Get(resultStr, "root.a").String() != "1" {
-		t.Errorf("element a corrupted")
+	if Get(result, "root.user.address.city").String() != "NYC" {
+		t.Errorf("city not set correctly")
 	}
-	if Get(resultStr, "root.c").String() != "3" {
-		t.Errorf("element c corrupted")
+	if Get(result, "root.user.address.zip").String() != "10001" {
+		t.Errorf("zip not set correctly")
 	}
 }
 
-// Test DeleteMany - Error case: invalid path
-func TestDeleteMany_InvalidPath(t *testing.T) {
-	xml := `<root><a>1</a></root>`
+// Test SetMany - Mix of creates and updates
+func TestSetMany_MixedOperations(t *testing.T) {
+	xml := `<root><user><name>John</name><age>25</age></user></root>`
+	paths := []string{"root.user.name", "root.user.age", "root.user.email"}
+	values := []interface{}{"Jane", 30, "jane@example.com"}
 
-	_, err := DeleteMany(xml, "root.a", "")
-	if err == nil {
-		t.Fatalf("DeleteMany() expected error for invalid path, got nil")
+	result, err := SetMany(xml, paths, values)
+	if err != nil {
+		t.Fatalf("SetMany() error = %v", err)
 	}
-}
 
-// Test DeleteMany - Error case: document too large
-func TestDeleteMany_DocumentTooLarge(t *testing.T) {
-	xml := string(make([]byte, MaxDocumentSize+1))
+	// Verify updates
+	if Get(result, "root.user.name").String() != "Jane" {
+		t.Errorf("name not updated correctly")
+	}
+	if Get(result, "root.user.age").Int() != 30 {
+		t.Errorf("age not updated correctly")
+	}
 
-	_, err := DeleteMany(xml, "root.a")
-	if err == nil {
-		t.Fatalf("DeleteMany() expected error for document too large, got nil")
+	// Verify new element created
+	if Get(result, "root.user.email").String() != "jane@example.com" {
+		t.Errorf("email not created correctly")
 	}
 }
 
-// Integration test: SetMany and DeleteMany combination
-func TestSetDeleteMany_Integration(t *testing.T) {
-	xml := `<root><user><name>John</name></user></root>`
-
-	// Add multiple elements
-	paths := []string{"root.user.age", "root.user.email", "root.user.city"}
-	values := []interface{}{30, "john@example.com", "NYC"}
+// Test SetMany - Overlapping paths (later wins)
+func TestSetMany_OverlappingPaths(t *testing.T) {
+	xml := `<root><value>original</value></root>`
+	paths := []string{"root.value", "root.value", "root.value"}
+	values := []interface{}{"first", "second", "third"}
 
-	modified, err := SetMany(xml, paths, values)
+	result, err := SetMany(xml, paths, values)
 	if err != nil {
 		t.Fatalf("SetMany() error = %v", err)
 	}
 
-	// Verify additions
-	if Get(modified, "root.user.age").Int() != 30 {
-		t.Errorf("age not set correctly")
-	}
-	if Get(modified, "root.user.email").String() != "john@example.com" {
-		t.Errorf("email not set correctly")
-	}
-
-	// Delete some elements
-	modified2, err := DeleteMany(modified, "root.user.age", "root.user.city")
-	if err != nil {
-		t.Fatalf("DeleteMany() error = %v", err)
+	// Last operation should win
+	if Get(result, "root.value").String() != "third" {
+		t.Errorf("overlapping paths not handled correctly, got %v", Get(result, "root.value").String())
 	}
+}
 
-	// Verify deletions
-	if Get(modified2, "root.user.age").Exists() {
-		t.Errorf("age should be deleted")
-	}
-	if Get(modified2, "root.user.city").Exists() {
-		t.Errorf("city should be deleted")
-	}
+// Test SetMany - Error case: mismatched counts
+func TestSetMany_MismatchedCounts(t *testing.T) {
+	xml := `<root></root>`
+	paths := []string{"root.a", "root.b", "root.c"}
+	values := []interface{}{1, 2} // Only 2 values for 3 paths
 
-	// Verify remaining data
-	if Get(modified2, "root.user.name").String() != "John" {
-		t.Errorf("name corrupted")
-	}
-	if Get(modified2, "root.user.email").String() != "john@example.com" {
-		t.Errorf("email corrupted")
+	_, err := SetMany(xml, paths, values)
+	if err == nil {
+		t.Fatalf("SetMany() expected error for mismatched counts, got nil")
 	}
 }
 
-// Benchmark SetMany vs individual Set calls
-func BenchmarkSetMany_3Paths(b *testing.B) {
-	xml := `<root><user><name>John</name></user></root>`
-	paths := []string{"root.user.age", "root.user.email", "root.user.city"}
-	values := []interface{}{30, "john@example.com", "NYC"}
+// Test SetMany - Error case: invalid path
+func TestSetMany_InvalidPath(t *testing.T) {
+	xml := `<root></root>`
+	paths := []string{"root.valid", "", "root.another"}
+	values := []interface{}{1, 2, 3}
 
-	b.ResetTimer()
-	for i := 0; i < b.N; i++ {
-		_, _ = SetMany(xml, paths, values)
+	_, err := SetMany(xml, paths, values)
+	if err == nil {
+		t.Fatalf("SetMany() expected error for invalid path, got nil")
 	}
 }
 
-func BenchmarkSet_3Individual(b *testing.B) {
-	xml := `<root><user><name>John</name></user></root>`
+// Test SetMany - a failing operation discards all earlier ones
+func TestSetMany_Atomic(t *testing.T) {
+	xml := `<root><a>1</a></root>`
+	paths := []string{"root.a", "root.b", "root.c.@id", "root.d", "root.e"}
 
-	b.ResetTimer()
-	for i := 0; i < b.N; i++ {
-		result, _ := Set(xml, "root.user.age", 30)
-		result, _ = Set(result, "root.user.email", "john@example.com")
-		result, _ = Set(result, "root.user.city", "NYC")
-		_ = result
+	tests := []struct {
+		name    string
+		paths   []string
+		values  []interface{}
+		wantErr error
+	}{
+		{"invalid value", paths, []interface{}{2, 3, "x", "y", make(chan int)}, ErrInvalidValue},
+		{"invalid path", append(paths[:4:4], ""), []interface{}{2, 3, "x", "y", "z"}, ErrInvalidPath},
 	}
-}
 
-// Benchmark DeleteMany vs individual Delete calls
-func BenchmarkDeleteMany_3Paths(b *testing.B) {
-	xml := `<root><a>1</a><b>2</b><c>3</c><d>4</d></root>`
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := SetMany(xml, tt.paths, tt.values)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("SetMany() error = %v, want %v", err, tt.wantErr)
+			}
+			if result != xml {
+				t.Errorf("SetMany() = %q, want original %q", result, xml)
+			}
 
-	b.ResetTimer()
-	for i := 0; i < b.N; i++ {
-		_, _ = DeleteMany(xml, "root.b", "root.c", "root.d")
+			input := []byte(xml)
+			resultBytes, err := SetManyBytes(input, tt.paths, tt.values)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("SetManyBytes() error = %v, want %v", err, tt.wantErr)
+			}
+			if string(resultBytes) != xml || string(input) != xml {
+				t.Errorf("SetManyBytes() = %q (input %q), want original %q", resultBytes, input, xml)
+			}
+
+			resultN, n, changed, err := SetManyN(xml, tt.paths, tt.values)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("SetManyN() error = %v, want %v", err, tt.wantErr)
+			}
+			if resultN != xml || n != 0 || changed {
+				t.Errorf("SetManyN() = %q, %d, %v, want original %q, 0, false", resultN, n, changed, xml)
+			}
+		})
 	}
 }
 
-func BenchmarkDelete_3Individual(b *testing.B) {
-	xml := `<root><a>1</a><b>2</b><c>3</c><d>4</d></root>`
+// Test SetMany - Empty inputs
+func TestSetMany_EmptyInputs(t *testing.T) {
+	xml := `<root><value>test</value></root>`
+	paths := []string{}
+	values := []interface{}{}
 
-	b.ResetTimer()
-	for i := 0; i < b.N; i++ {
-		result, _ := Delete(xml, "root.b")
-		result, _ = Delete(result, "root.c")
-		result, _ = Delete(result, "root.d")
-		_ = result
+	result, err := SetMany(xml, paths, values)
+	if err != nil {
+		t.Fatalf("SetMany() error = %v", err)
+	}
+
+	// Should return original XML unchanged
+	if result != xml {
+		t.Errorf("SetMany() with empty inputs should return original XML")
 	}
 }
 
-// Example functions for godoc
+// Test SetMany - Attributes
+func TestSetMany_Attributes(t *testing.T) {
+	xml := `<user id="123"><name>John</name></user>`
+	paths := []string{"user.@active", "user.@role", "user.@id"}
+	values := []interface{}{"true", "admin", "456"}
 
-// ExampleSet demonstrates updating an element value
-func ExampleSet() {
-	xml := `<user>
-		<name>John Doe</name>
-		<age>30</age>
-	</user>`
+	result, err := SetMany(xml, paths, values)
+	if err != nil {
+		t.Fatalf("SetMany() error = %v", err)
+	}
 
-	// Update the age element
-	modified, _ := Set(xml, "user.age", 31)
-	result := Get(modified, "user.age")
-	fmt.Println(result.String())
-	// Output: 31
+	// Verify attributes
+	if Get(result, "user.@active").String() != "true" {
+		t.Errorf("active attribute not set correctly")
+	}
+	if Get(result, "user.@role").String() != "admin" {
+		t.Errorf("role attribute not set correctly")
+	}
+	if Get(result, "user.@id").String() != "456" {
+		t.Errorf("id attribute not updated correctly")
+	}
 }
 
-// ExampleSet_attribute demonstrates setting an attribute value
+// Test SetMany - Array indices
+func TestSetMany_ArrayIndices(t *testing.T) {
+	xml := `<root>
+		<item>first</item>
+		<item>second</item>
+		<item>third</item>
+	</root>`
+	paths := []string{"root.item.0", "root.item.2"}
+	values := []interface{}{"updated-first", "updated-third"}
+
+	result, err := SetMany(xml, paths, values)
+	if err != nil {
+		t.Fatalf("SetMany() error = %v", err)
+	}
+
+	// Verify updates
+	if Get(result, "root.item.0").String() != "updated-first" {
+		t.Errorf("first item not updated correctly")
+	}
+	if Get(result, "root.item.1").String() != "second" {
+		t.Errorf("middle item corrupted")
+	}
+	if Get(result, "root.item.2").String() != "updated-third" {
+		t.Errorf("last item not updated correctly")
+	}
+}
+
+// Test SetManyBytes
+func TestSetManyBytes(t *testing.T) {
+	xml := []byte(`<root><value>old</value></root>`)
+	paths := []string{"root.value", "root.new"}
+	values := []interface{}{"updated", "created"}
+
+	result, err := SetManyBytes(xml, paths, values)
+	if err != nil {
+		t.Fatalf("SetManyBytes() error = %v", err)
+	}
+
+	resultStr := string(result)
+	if Get(resultStr, "root.value").String() != "updated" {
+		t.Errorf("value not updated correctly")
+	}
+	if Get(resultStr, "root.new").String() != "created" {
+		t.Errorf("new element not created correctly")
+	}
+}
+
+func TestSetBytesMany(t *testing.T) {
+	xml := []byte(`<root><value>old</value></root>`)
+	paths := []string{"root.value", "root.new"}
+	values := []interface{}{"updated", 42}
+
+	expected, err := SetMany(string(xml), paths, values)
+	if err != nil {
+		t.Fatalf("SetMany() error = %v", err)
+	}
+	result, err := SetBytesMany(xml, paths, values)
+	if err != nil {
+		t.Fatalf("SetBytesMany() error = %v", err)
+	}
+	if string(result) != expected {
+		t.Errorf("SetBytesMany() = %q, want %q", result, expected)
+	}
+
+	// Errors mirror SetMany
+	if _, err := SetBytesMany(xml, paths, values[:1]); !errors.Is(err, ErrInvalidPath) {
+		t.Errorf("SetBytesMany() with mismatched lengths error = %v, want ErrInvalidPath", err)
+	}
+}
+
+// Test DeleteMany - Multiple unrelated deletes
+func TestDeleteMany_MultipleUnrelated(t *testing.T) {
+	xml := `<root>
+		<a>1</a>
+		<b>2</b>
+		<c>3</c>
+		<d>4</d>
+	</root>`
+
+	result, err := DeleteMany(xml, "root.b", "root.d")
+	if err != nil {
+		t.Fatalf("DeleteMany() error = %v", err)
+	}
+
+	// Verify deletions
+	if Get(result, "root.b").Exists() {
+		t.Errorf("element b should be deleted")
+	}
+	if Get(result, "root.d").Exists() {
+		t.Errorf("element d should be deleted")
+	}
+
+	// Verify remaining elements
+	if Get(result, "root.a").String() != "1" {
+		t.Errorf("element a corrupted")
+	}
+	if Get(result, "root.c").String() != "3" {
+		t.Errorf("element c corrupted")
+	}
+}
+
+// Test DeleteMany - Parent and child deletes
+func TestDeleteMany_ParentChild(t *testing.T) {
+	xml := `<root>
+		<user>
+			<name>John</name>
+			<address>
+				<city>NYC</city>
+				<zip>10001</zip>
+			</address>
+		</user>
+	</root>`
+
+	// Delete parent first - child path becomes invalid
+	result, err := DeleteMany(xml, "root.user", "root.user.name")
+	if err != nil {
+		t.Fatalf("DeleteMany() error = %v", err)
+	}
+
+	// Verify parent deleted
+	if Get(result, "root.user").Exists() {
+		t.Errorf("user element should be deleted")
+	}
+
+	// Verify root still exists
+	if !Get(result, "root").Exists() {
+		t.Errorf("root element should still exist")
+	}
+}
+
+// Test DeleteMany - Non-existent paths
+func TestDeleteMany_NonExistent(t *testing.T) {
+	xml := `<root><a>1</a><b>2</b></root>`
+
+	result, err := DeleteMany(xml, "root.nonexistent", "root.missing", "root.fake")
+	if err != nil {
+		t.Fatalf("DeleteMany() error = %v", err)
+	}
+
+	// Should return original XML unchanged (minus whitespace differences)
+	if Get(result, "root.a").String() != "1" {
+		t.Errorf("element a corrupted")
+	}
+	if Get(result, "root.b").String() != "2" {
+		t.Errorf("element b corrupted")
+	}
+}
+
+// Test DeleteMany - Duplicate paths
+func TestDeleteMany_DuplicatePaths(t *testing.T) {
+	xml := `<root><a>1</a><b>2</b><c>3</c></root>`
+
+	result, err := DeleteMany(xml, "root.a", "root.b", "root.a", "root.b")
+	if err != nil {
+		t.Fatalf("DeleteMany() error = %v", err)
+	}
+
+	// Verify deletions (duplicates should be handled gracefully)
+	if Get(result, "root.a").Exists() {
+		t.Errorf("element a should be deleted")
+	}
+	if Get(result, "root.b").Exists() {
+		t.Errorf("element b should be deleted")
+	}
+	if Get(result, "root.c").String() != "3" {
+		t.Errorf("element c corrupted")
+	}
+}
+
+// Test DeleteMany - Empty inputs
+func TestDeleteMany_EmptyInputs(t *testing.T) {
+	xml := `<root><value>test</value></root>`
+
+	result, err := DeleteMany(xml)
+	if err != nil {
+		t.Fatalf("DeleteMany() error = %v", err)
+	}
+
+	// Should return original XML unchanged
+	if result != xml {
+		t.Errorf("DeleteMany() with empty inputs should return original XML")
+	}
+}
+
+// Test DeleteMany - Attributes
+func TestDeleteMany_Attributes(t *testing.T) {
+	xml := `<user id="123" active="true" role="admin"><name>John</name></user>`
+
+	result, err := DeleteMany(xml, "user.@active", "user.@role")
+	if err != nil {
+		t.Fatalf("DeleteMany() error = %v", err)
+	}
+
+	// Verify attribute deletions
+	if Get(result, "user.@active").Exists() {
+		t.Errorf("active attribute should be deleted")
+	}
+	if Get(result, "user.@role").Exists() {
+		t.Errorf("role attribute should be deleted")
+	}
+
+	// Verify remaining attribute
+	if Get(result, "user.@id").String() != "123" {
+		t.Errorf("id attribute corrupted")
+	}
+
+	// Verify element content
+	if Get(result, "user.name").String() != "John" {
+		t.Errorf("name element corrupted")
+	}
+}
+
+// Test DeleteMany - Array elements
+func TestDeleteMany_ArrayElements(t *testing.T) {
+	xml := `<root>
+		<item>first</item>
+		<item>second</item>
+		<item>third</item>
+		<item>fourth</item>
+	</root>`
+
+	// Delete indices 1 and 3 (note: after first deletion, indices shift)
+	result, err := DeleteMany(xml, "root.item.1", "root.item.2")
+	if err != nil {
+		t.Fatalf("DeleteMany() error = %v", err)
+	}
+
+	// After deleting index 1 (second), the remaining items shift down
+	// After deleting index 2 of the shifted array (originally fourth), we should have first and third
+	count := Get(result, "root.item.#").Int()
+	if count != 2 {
+		t.Errorf("expected 2 remaining items, got %d", count)
+	}
+}
+
+// Test DeleteManyBytes
+func TestDeleteManyBytes(t *testing.T) {
+	xml := []byte(`<root><a>1</a><b>2</b><c>3</c></root>`)
+
+	result, err := DeleteManyBytes(xml, "root.b")
+	if err != nil {
+		t.Fatalf("DeleteManyBytes() error = %v", err)
+	}
+
+	resultStr := string(result)
+	if Get(resultStr, "root.b").Exists() {
+		t.Errorf("element b should be deleted")
+	}
+	if Get(resultStr, "root.a").String() != "1" {
+		t.Errorf("element a corrupted")
+	}
+	if Get(resultStr, "root.c").String() != "3" {
+		t.Errorf("element c corrupted")
+	}
+}
+
+// Test DeleteMany - Error case: invalid path
+func TestDeleteMany_InvalidPath(t *testing.T) {
+	xml := `<root><a>1</a></root>`
+
+	_, err := DeleteMany(xml, "root.a", "")
+	if err == nil {
+		t.Fatalf("DeleteMany() expected error for invalid path, got nil")
+	}
+}
+
+// Test DeleteMany - an invalid path discards all earlier deletions
+func TestDeleteMany_Atomic(t *testing.T) {
+	xml := `<root><a>1</a><b>2</b><c id="x"/></root>`
+
+	result, err := DeleteMany(xml, "root.a", "root.b", "root.c.@id", "")
+	if !errors.Is(err, ErrInvalidPath) {
+		t.Fatalf("DeleteMany() error = %v, want ErrInvalidPath", err)
+	}
+	if result != xml {
+		t.Errorf("DeleteMany() = %q, want original %q", result, xml)
+	}
+}
+
+// Test DeleteMany - Error case: document too large
+func TestDeleteMany_DocumentTooLarge(t *testing.T) {
+	xml := string(make([]byte, MaxDocumentSize+1))
+
+	_, err := DeleteMany(xml, "root.a")
+	if err == nil {
+		t.Fatalf("DeleteMany() expected error for document too large, got nil")
+	}
+}
+
+// Integration test: SetMany and DeleteMany combination
+func TestSetDeleteMany_Integration(t *testing.T) {
+	xml := `<root><user><name>John</name></user></root>`
+
+	// Add multiple elements
+	paths := []string{"root.user.age", "root.user.email", "root.user.city"}
+	values := []interface{}{30, "john@example.com", "NYC"}
+
+	modified, err := SetMany(xml, paths, values)
+	if err != nil {
+		t.Fatalf("SetMany() error = %v", err)
+	}
+
+	// Verify additions
+	if Get(modified, "root.user.age").Int() != 30 {
+		t.Errorf("age not set correctly")
+	}
+	if Get(modified, "root.user.email").String() != "john@example.com" {
+		t.Errorf("email not set correctly")
+	}
+
+	// Delete some elements
+	modified2, err := DeleteMany(modified, "root.user.age", "root.user.city")
+	if err != nil {
+		t.Fatalf("DeleteMany() error = %v", err)
+	}
+
+	// Verify deletions
+	if Get(modified2, "root.user.age").Exists() {
+		t.Errorf("age should be deleted")
+	}
+	if Get(modified2, "root.user.city").Exists() {
+		t.Errorf("city should be deleted")
+	}
+
+	// Verify remaining data
+	if Get(modified2, "root.user.name").String() != "John" {
+		t.Errorf("name corrupted")
+	}
+	if Get(modified2, "root.user.email").String() != "john@example.com" {
+		t.Errorf("email corrupted")
+	}
+}
+
+// Benchmark SetMany vs individual Set calls
+func BenchmarkSetMany_3Paths(b *testing.B) {
+	xml := `<root><user><name>John</name></user></root>`
+	paths := []string{"root.user.age", "root.user.email", "root.user.city"}
+	values := []interface{}{30, "john@example.com", "NYC"}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = SetMany(xml, paths, values)
+	}
+}
+
+func BenchmarkSet_3Individual(b *testing.B) {
+	xml := `<root><user><name>John</name></user></root>`
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		result, _ := Set(xml, "root.user.age", 30)
+		result, _ = Set(result, "root.user.email", "john@example.com")
+		result, _ = Set(result, "root.user.city", "NYC")
+		_ = result
+	}
+}
+
+// Benchmark DeleteMany vs individual Delete calls
+func BenchmarkDeleteMany_3Paths(b *testing.B) {
+	xml := `<root><a>1</a><b>2</b><c>3</c><d>4</d></root>`
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = DeleteMany(xml, "root.b", "root.c", "root.d")
+	}
+}
+
+func BenchmarkDelete_3Individual(b *testing.B) {
+	xml := `<root><a>1</a><b>2</b><c>3</c><d>4</d></root>`
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		result, _ := Delete(xml, "root.b")
+		result, _ = Delete(result, "root.c")
+		result, _ = Delete(result, "root.d")
+		_ = result
+	}
+}
+
+// Example functions for godoc
+
+// ExampleSet demonstrates updating an element value
+func ExampleSet() {
+	xml := `<user>
+		<name>John Doe</name>
+		<age>30</age>
+	</user>`
+
+	// Update the age element
+	modified, _ := Set(xml, "user.age", 31)
+	result := Get(modified, "user.age")
+	fmt.Println(result.String())
+	// Output: 31
+}
+
+// ExampleSet_attribute demonstrates setting an attribute value
 func ExampleSet_attribute() {
 	xml := `<user id="123">
 		<name>John Doe</name>
 	</user>`
 
-	// Update existing attribute
-	modified, _ := Set(xml, "user.@id", "456")
+	// Update existing attribute
+	modified, _ := Set(xml, "user.@id", "456")
+
+	// Add new attribute
+	modified, _ = Set(modified, "user.@active", "true")
+
+	fmt.Println(Get(modified, "user.@id").String())
+	fmt.Println(Get(modified, "user.@active").String())
+	// Output:
+	// 456
+	// true
+}
+
+// ExampleSet_createNew demonstrates creating new elements
+func ExampleSet_createNew() {
+	xml := `<user>
+		<name>John Doe</name>
+	</user>`
+
+	// Create new element that doesn't exist
+	modified, _ := Set(xml, "user.email", "john@example.com")
+
+	// Create deeply nested element
+	modified, _ = Set(modified, "user.address.city", "New York")
+
+	fmt.Println(Get(modified, "user.email").String())
+	fmt.Println(Get(modified, "user.address.city").String())
+	// Output:
+	// john@example.com
+	// New York
+}
+
+// ExampleDelete demonstrates removing an element
+func ExampleDelete() {
+	xml := `<user>
+		<name>John Doe</name>
+		<age>30</age>
+		<email>john@example.com</email>
+	</user>`
+
+	// Delete the email element
+	modified, _ := Delete(xml, "user.email")
+
+	fmt.Println("Email exists:", Get(modified, "user.email").Exists())
+	fmt.Println("Name exists:", Get(modified, "user.name").Exists())
+	// Output:
+	// Email exists: false
+	// Name exists: true
+}
+
+// ExampleSetMany demonstrates batch set operations
+func ExampleSetMany() {
+	xml := `<user>
+		<name>John Doe</name>
+	</user>`
+
+	// Set multiple paths in one call
+	paths := []string{"user.age", "user.email", "user.city"}
+	values := []interface{}{30, "john@example.com", "New York"}
+
+	modified, _ := SetMany(xml, paths, values)
+
+	fmt.Println("Age:", Get(modified, "user.age").Int())
+	fmt.Println("Email:", Get(modified, "user.email").String())
+	fmt.Println("City:", Get(modified, "user.city").String())
+	// Output:
+	// Age: 30
+	// Email: john@example.com
+	// City: New York
+}
+
+// ExampleDeleteMany demonstrates batch delete operations
+func ExampleDeleteMany() {
+	xml := `<user>
+		<name>John Doe</name>
+		<age>30</age>
+		<email>john@example.com</email>
+		<phone>555-1234</phone>
+	</user>`
+
+	// Delete multiple elements in one call
+	modified, _ := DeleteMany(xml, "user.email", "user.phone")
+
+	fmt.Println("Email exists:", Get(modified, "user.email").Exists())
+	fmt.Println("Phone exists:", Get(modified, "user.phone").Exists())
+	fmt.Println("Name exists:", Get(modified, "user.name").Exists())
+	// Output:
+	// Email exists: false
+	// Phone exists: false
+	// Name exists: true
+}
+
+// ExampleSetWithOptions demonstrates using options when setting values
+func ExampleSetWithOptions() {
+	xml := `<root><value>test</value></root>`
+
+	// Set with custom options for indentation
+	opts := Options{Indent: "  "}
+	modified, _ := SetWithOptions(xml, "root.value", "updated", &opts)
+
+	// Output shows the modified value (indentation may vary by implementation)
+	result := Get(modified, "root.value")
+	fmt.Println(result.String())
+	// Output: updated
+}
+
+// ExampleSetWithOptions_indent demonstrates pretty printing with indentation
+func ExampleSetWithOptions_indent() {
+	xml := `<root><user><name>John</name><age>30</age></user></root>`
+
+	// Set value with options
+	opts := Options{Indent: "    "}
+	modified, _ := SetWithOptions(xml, "root.user.age", 31, &opts)
+
+	// Verify the value was updated
+	result := Get(modified, "root.user.age")
+	fmt.Printf("New age: %d\n", result.Int())
+	// Output: New age: 31
+}
+
+// TestSetMalformedXMLRegression is a regression test for fuzz-discovered crash
+// Issue: Set operations on malformed XML with unclosed tags caused runtime panics
+func TestSetMalformedXMLRegression(t *testing.T) {
+	tests := []struct {
+		name         string
+		xml          string
+		path         string
+		value        interface{}
+		expectError  bool
+		errorMessage string
+	}{
+		{
+			name:         "unclosed root tag",
+			xml:          "<root>",
+			path:         "root.item",
+			value:        "value",
+			expectError:  true,
+			errorMessage: "malformed",
+		},
+		{
+			name:         "unclosed nested tag",
+			xml:          "<root><user>",
+			path:         "root.user.name",
+			value:        "John",
+			expectError:  true,
+			errorMessage: "malformed",
+		},
+		{
+			name:         "mismatched closing tag",
+			xml:          "<root><user></root>",
+			path:         "root.user",
+			value:        "value",
+			expectError:  true,
+			errorMessage: "malformed",
+		},
+		{
+			name:         "missing opening tag",
+			xml:          "<root></user></root>",
+			path:         "root.user",
+			value:        "value",
+			expectError:  true,
+			errorMessage: "malformed",
+		},
+		{
+			name:         "unclosed attribute quote",
+			xml:          `<root attr="value><item>test</item></root>`,
+			path:         "root.item",
+			value:        "new",
+			expectError:  true,
+			errorMessage: "malformed",
+		},
+		{
+			name:        "valid XML should work",
+			xml:         "<root></root>",
+			path:        "root.item",
+			value:       "value",
+			expectError: false,
+		},
+		{
+			name:        "valid XML with nested elements",
+			xml:         "<root><user><name>John</name></user></root>",
+			path:        "root.user.age",
+			value:       30,
+			expectError: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Set(tt.xml, tt.path, tt.value)
+
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("Set() expected error but got none; result = %v", result)
+				}
+				if tt.errorMessage != "" && err != nil {
+					errStr := err.Error()
+					if !containsIgnoreCase(errStr, tt.errorMessage) {
+						t.Errorf("Set() error = %v, should contain %q", err, tt.errorMessage)
+					}
+				}
+				// Error case - result should be original XML
+				if result != tt.xml {
+					t.Errorf("Set() on error should return original XML, got %v", result)
+				}
+			} else {
+				if err != nil {
+					t.Errorf("Set() unexpected error = %v", err)
+				}
+				// Success case - verify value was set
+				if !Get(result, tt.path).Exists() {
+					t.Errorf("Set() value not found at path %s in result: %s", tt.path, result)
+				}
+			}
+		})
+	}
+}
+
+// TestDeleteMalformedXMLRegression is a regression test for fuzz-discovered crash
+// Issue: Delete operations on malformed XML caused runtime panics
+func TestDeleteMalformedXMLRegression(t *testing.T) {
+	tests := []struct {
+		name         string
+		xml          string
+		path         string
+		expectError  bool
+		errorMessage string
+	}{
+		{
+			name:         "unclosed root tag",
+			xml:          "<root>",
+			path:         "root",
+			expectError:  true,
+			errorMessage: "malformed",
+		},
+		{
+			name:         "unclosed nested tag",
+			xml:          "<root><user>",
+			path:         "root.user",
+			expectError:  true,
+			errorMessage: "malformed",
+		},
+		{
+			name:         "mismatched closing tag",
+			xml:          "<root><user></root>",
+			path:         "root.user",
+			expectError:  true,
+			errorMessage: "malformed",
+		},
+		{
+			name:        "valid XML should work",
+			xml:         "<root><item>test</item></root>",
+			path:        "root.item",
+			expectError: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Delete(tt.xml, tt.path)
+
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("Delete() expected error but got none; result = %v", result)
+				}
+				if tt.errorMessage != "" && err != nil {
+					errStr := err.Error()
+					if !containsIgnoreCase(errStr, tt.errorMessage) {
+						t.Errorf("Delete() error = %v, should contain %q", err, tt.errorMessage)
+					}
+				}
+				// Error case - result should be original XML
+				if result != tt.xml {
+					t.Errorf("Delete() on error should return original XML, got %v", result)
+				}
+			} else {
+				if err != nil {
+					t.Errorf("Delete() unexpected error = %v", err)
+				}
+			}
+		})
+	}
+}
+
+// containsIgnoreCase checks if s contains substr (case-insensitive)
+func containsIgnoreCase(s, substr string) bool {
+	s = toLowerASCII(s)
+	substr = toLowerASCII(substr)
+	return len(s) >= len(substr) && findSubstring(s, substr)
+}
+
+func findSubstring(s, substr string) bool {
+	if len(substr) == 0 {
+		return true
+	}
+	if len(substr) > len(s) {
+		return false
+	}
+	for i := 0; i <= len(s)-len(substr); i++ {
+		if s[i:i+len(substr)] == substr {
+			return true
+		}
+	}
+	return false
+}
+
+// ============================================================================
+// Phase 4 Production Hardening: Wildcard/Filter Integration with Batch Ops
+// ============================================================================
+
+// TestSetMany_WithWildcards tests that SetMany accepts wildcard paths without errors
+func TestSetMany_WithWildcards(t *testing.T) {
+	t.Run("wildcard path in SetMany - basic test", func(t *testing.T) {
+		xml := `<root>
+			<user><name>Alice</name><age>25</age></user>
+			<user><name>Bob</name><age>30</age></user>
+		</root>`
+
+		paths := []string{"root.*.age"}
+		values := []interface{}{35}
+
+		result, err := SetMany(xml, paths, values)
+		if err != nil {
+			t.Fatalf("SetMany() with wildcards error = %v", err)
+		}
+
+		// Verify operation completes without error
+		if result == "" {
+			t.Errorf("SetMany() should return non-empty result")
+		}
+	})
+
+	t.Run("recursive wildcard in SetMany - basic test", func(t *testing.T) {
+		xml := `<root><user><profile><name>Alice</name></profile></user></root>`
 
-	// Add new attribute
-	modified, _ = Set(modified, "user.@active", "true")
+		paths := []string{"root.**.name"}
+		values := []interface{}{"Updated"}
 
-	fmt.Println(Get(modified, "user.@id").String())
-	fmt.Println(Get(modified, "user.@active").String())
-	// Output:
-	// 456
-	// true
+		result, err := SetMany(xml, paths, values)
+		if err != nil {
+			t.Fatalf("SetMany() with recursive wildcard error = %v", err)
+		}
+
+		// Verify operation completes without error
+		if result == "" {
+			t.Errorf("SetMany() should return non-empty result")
+		}
+	})
 }
 
-// ExampleSet_createNew demonstrates creating new elements
-func ExampleSet_createNew() {
-	xml := `<user>
-		<name>John Doe</name>
-	</user>`
+// TestSetMany_WithFilters tests that SetMany accepts filter expressions without errors
+func TestSetMany_WithFilters(t *testing.T) {
+	t.Run("filter in SetMany - basic test", func(t *testing.T) {
+		xml := `<root>
+			<user><name>Alice</name><age>25</age><status>active</status></user>
+			<user><name>Bob</name><age>30</age><status>inactive</status></user>
+		</root>`
 
-	// Create new element that doesn't exist
-	modified, _ := Set(xml, "user.email", "john@example.com")
+		paths := []string{"root.user[age>25].status"}
+		values := []interface{}{"premium"}
 
-	// Create deeply nested element
-	modified, _ = Set(modified, "user.address.city", "New York")
+		result, err := SetMany(xml, paths, values)
+		if err != nil {
+			t.Fatalf("SetMany() with filter error = %v", err)
+		}
 
-	fmt.Println(Get(modified, "user.email").String())
-	fmt.Println(Get(modified, "user.address.city").String())
-	// Output:
-	// john@example.com
-	// New York
+		// Verify operation completes without error
+		if result == "" {
+			t.Errorf("SetMany() should return non-empty result")
+		}
+	})
 }
 
-// ExampleDelete demonstrates removing an element
-func ExampleDelete() {
-	xml := `<user>
-		<name>John Doe</name>
-		<age>30</age>
-		<email>john@example.com</email>
-	</user>`
+// TestDeleteMany_WithWildcards tests that DeleteMany accepts wildcard paths without errors
+func TestDeleteMany_WithWildcards(t *testing.T) {
+	t.Run("wildcard path in DeleteMany - basic test", func(t *testing.T) {
+		xml := `<root>
+			<user><name>Alice</name><age>25</age></user>
+			<user><name>Bob</name><age>30</age></user>
+		</root>`
+
+		result, err := DeleteMany(xml, "root.*.age")
+		if err != nil {
+			t.Fatalf("DeleteMany() with wildcards error = %v", err)
+		}
+
+		// Verify operation completes without error
+		if result == "" {
+			t.Errorf("DeleteMany() should return non-empty result")
+		}
+	})
+
+	t.Run("recursive wildcard in DeleteMany - basic test", func(t *testing.T) {
+		xml := `<root><level1><value>1</value><nested><value>2</value></nested></level1></root>`
+
+		result, err := DeleteMany(xml, "root.**.value")
+		if err != nil {
+			t.Fatalf("DeleteMany() with recursive wildcard error = %v", err)
+		}
+
+		// Verify operation completes without error
+		if result == "" {
+			t.Errorf("DeleteMany() should return non-empty result")
+		}
+	})
+}
+
+// TestDeleteMany_WithFilters tests that DeleteMany accepts filter expressions without errors
+func TestDeleteMany_WithFilters(t *testing.T) {
+	t.Run("filter in DeleteMany - basic test", func(t *testing.T) {
+		xml := `<root>
+			<user><name>Alice</name><age>25</age></user>
+			<user><name>Bob</name><age>30</age></user>
+		</root>`
+
+		result, err := DeleteMany(xml, "root.user[age>=30]")
+		if err != nil {
+			t.Fatalf("DeleteMany() with filter error = %v", err)
+		}
+
+		// Verify operation completes without error
+		if result == "" {
+			t.Errorf("DeleteMany() should return non-empty result")
+		}
+	})
+}
+
+// TestBatchOps_ComplexFilterWildcardCombinations tests complex scenarios
+func TestBatchOps_ComplexFilterWildcardCombinations(t *testing.T) {
+	t.Run("SetMany with wildcard and filter combination", func(t *testing.T) {
+		xml := `<root>
+			<group><user><name>Alice</name><age>25</age></user></group>
+			<group><user><name>Bob</name><age>30</age></user></group>
+		</root>`
+
+		paths := []string{"root.*.user[age>=30].name"}
+		values := []interface{}{"Senior User"}
+
+		result, err := SetMany(xml, paths, values)
+		if err != nil {
+			t.Fatalf("SetMany() with wildcard+filter error = %v", err)
+		}
+
+		// Verify operation completes without error
+		if result == "" {
+			t.Errorf("SetMany() should return non-empty result")
+		}
+	})
+
+	t.Run("DeleteMany with multiple filter operations", func(t *testing.T) {
+		t.Skip("DeleteMany with GJSON filter syntax not yet implemented - filters return result arrays, not element paths")
+
+		xml := `<root>
+			<user><name>Alice</name><age>25</age><role>admin</role></user>
+			<user><name>Bob</name><age>30</age><role>user</role></user>
+			<user><name>Charlie</name><age>35</age><role>admin</role></user>
+		</root>`
+
+		result, err := DeleteMany(xml, "root.user.#(role==user)#", "root.user.#(age<30)#")
+		if err != nil {
+			t.Fatalf("DeleteMany() with multiple filters error = %v", err)
+		}
+
+		// Verify Charlie remains (age>=30 AND role==admin)
+		count := Get(result, "root.user.#")
+		if count.Int() != 1 {
+			t.Errorf("DeleteMany() count = %v, want 1", count.Int())
+		}
+
+		name0 := Get(result, "root.user.0.name")
+		if name0.String() != "Charlie" {
+			t.Errorf("Only Charlie should remain, got %v", name0.String())
+		}
+	})
+}
+
+// ============================================================================
+// Phase 4 Production Hardening: MaxDocumentSize Tests for Batch Operations
+// ============================================================================
+
+// TestSetMany_MaxDocumentSize tests that SetMany respects MaxDocumentSize limit
+func TestSetMany_MaxDocumentSize(t *testing.T) {
+	t.Run("SetMany rejects document exceeding MaxDocumentSize", func(t *testing.T) {
+		// Create XML document larger than MaxDocumentSize (10MB)
+		largeXML := string(make([]byte, MaxDocumentSize+1))
+		paths := []string{"root.test"}
+		values := []interface{}{"value"}
+
+		_, err := SetMany(largeXML, paths, values)
+		if err == nil {
+			t.Fatalf("SetMany() should reject documents exceeding MaxDocumentSize")
+		}
+
+		if err != ErrMalformedXML {
+			t.Errorf("SetMany() error = %v, want ErrMalformedXML", err)
+		}
+	})
+
+	t.Run("SetMany accepts document at MaxDocumentSize limit", func(_ *testing.T) {
+		// Create valid XML document at exactly MaxDocumentSize
+		xmlSize := MaxDocumentSize - 100 // Leave room for tags
+		content := string(make([]byte, xmlSize))
+		xml := "<root>" + content + "</root>"
+
+		paths := []string{"root.test"}
+		values := []interface{}{"value"}
+
+		_, err := SetMany(xml, paths, values)
+		// May fail due to malformed XML (just random bytes), but should not be
+		// rejected for size reasons. We just verify it doesn't panic.
+		_ = err
+	})
+
+	t.Run("SetMany with multiple operations on large document", func(_ *testing.T) {
+		// Create document approaching MaxDocumentSize
+		xmlSize := MaxDocumentSize/2 - 1000
+		largeContent := string(make([]byte, xmlSize))
+		xml := "<root><data>" + largeContent + "</data></root>"
+
+		paths := []string{"root.field1", "root.field2", "root.field3"}
+		values := []interface{}{"val1", "val2", "val3"}
+
+		_, err := SetMany(xml, paths, values)
+		// May fail due to malformed XML, but should not panic
+		_ = err
+	})
+}
+
+// TestSetManyBytes_MaxDocumentSize tests that SetManyBytes respects MaxDocumentSize
+func TestSetManyBytes_MaxDocumentSize(t *testing.T) {
+	t.Run("SetManyBytes rejects document exceeding MaxDocumentSize", func(t *testing.T) {
+		largeXML := make([]byte, MaxDocumentSize+1)
+		paths := []string{"root.test"}
+		values := []interface{}{"value"}
+
+		_, err := SetManyBytes(largeXML, paths, values)
+		if err == nil {
+			t.Fatalf("SetManyBytes() should reject documents exceeding MaxDocumentSize")
+		}
+
+		if err != ErrMalformedXML {
+			t.Errorf("SetManyBytes() error = %v, want ErrMalformedXML", err)
+		}
+	})
+}
+
+// TestDeleteMany_MaxDocumentSize tests that DeleteMany respects MaxDocumentSize limit
+func TestDeleteMany_MaxDocumentSize(t *testing.T) {
+	t.Run("DeleteMany rejects document exceeding MaxDocumentSize", func(t *testing.T) {
+		// Create XML document larger than MaxDocumentSize (10MB)
+		largeXML := string(make([]byte, MaxDocumentSize+1))
+
+		_, err := DeleteMany(largeXML, "root.test")
+		if err == nil {
+			t.Fatalf("DeleteMany() should reject documents exceeding MaxDocumentSize")
+		}
+
+		if err != ErrMalformedXML {
+			t.Errorf("DeleteMany() error = %v, want ErrMalformedXML", err)
+		}
+	})
+
+	t.Run("DeleteMany accepts document at MaxDocumentSize limit", func(_ *testing.T) {
+		// Create valid XML document at exactly MaxDocumentSize
+		xmlSize := MaxDocumentSize - 100 // Leave room for tags
+		content := string(make([]byte, xmlSize))
+		xml := "<root>" + content + "</root>"
 
-	// Delete the email element
-	modified, _ := Delete(xml, "user.email")
+		_, err := DeleteMany(xml, "root.test")
+		// May fail due to malformed XML (just random bytes), but should not be
+		// rejected for size reasons. We just verify it doesn't panic.
+		_ = err
+	})
 
-	fmt.Println("Email exists:", Get(modified, "user.email").Exists())
-	fmt.Println("Name exists:", Get(modified, "user.name").Exists())
-	// Output:
-	// Email exists: false
-	// Name exists: true
-}
+	t.Run("DeleteMany with multiple operations on large document", func(_ *testing.T) {
+		// Create document approaching MaxDocumentSize
+		xmlSize := MaxDocumentSize/2 - 1000
+		largeContent := string(make([]byte, xmlSize))
+		xml := "<root><data>" + largeContent + "</data><field1>1</field1><field2>2</field2></root>"
 
-// ExampleSetMany demonstrates batch set operations
-func ExampleSetMany() {
-	xml := `<user>
-		<name>John Doe</name>
-	</user>`
+		_, err := DeleteMany(xml, "root.field1", "root.field2")
+		// May fail due to malformed XML, but should not panic
+		_ = err
+	})
+}
 
-	// Set multiple paths in one call
-	paths := []string{"user.age", "user.email", "user.city"}
-	values := []interface{}{30, "john@example.com", "New York"}
+// TestDeleteManyBytes_MaxDocumentSize tests that DeleteManyBytes respects MaxDocumentSize
+func TestDeleteManyBytes_MaxDocumentSize(t *testing.T) {
+	t.Run("DeleteManyBytes rejects document exceeding MaxDocumentSize", func(t *testing.T) {
+		largeXML := make([]byte, MaxDocumentSize+1)
 
-	modified, _ := SetMany(xml, paths, values)
+		_, err := DeleteManyBytes(largeXML, "root.test")
+		if err == nil {
+			t.Fatalf("DeleteManyBytes() should reject documents exceeding MaxDocumentSize")
+		}
 
-	fmt.Println("Age:", Get(modified, "user.age").Int())
-	fmt.Println("Email:", Get(modified, "user.email").String())
-	fmt.Println("City:", Get(modified, "user.city").String())
-	// Output:
-	// Age: 30
-	// Email: john@example.com
-	// City: New York
+		if err != ErrMalformedXML {
+			t.Errorf("DeleteManyBytes() error = %v, want ErrMalformedXML", err)
+		}
+	})
 }
 
-// ExampleDeleteMany demonstrates batch delete operations
-func ExampleDeleteMany() {
-	xml := `<user>
-		<name>John Doe</name>
-		<age>30</age>
-		<email>john@example.com</email>
-		<phone>555-1234</phone>
-	</user>`
+func TestMove(t *testing.T) {
+	list := "<list>\n  <item>a</item>\n  <item>b</item>\n  <item>c</item>\n</list>"
 
-	// Delete multiple elements in one call
-	modified, _ := DeleteMany(xml, "user.email", "user.phone")
+	tests := []struct {
+		name     string
+		xml      string
+		from     string
+		to       string
+		expected string
+	}{
+		{
+			name:     "relocate into existing parent",
+			xml:      `<config><old><db>x</db></old><new></new></config>`,
+			from:     "config.old.db",
+			to:       "config.new.db",
+			expected: `<config><old></old><new><db>x</db></new></config>`,
+		},
+		{
+			name:     "create missing parents",
+			xml:      `<config><db port="1">x</db></config>`,
+			from:     "config.db",
+			to:       "config.storage.primary.db",
+			expected: `<config><storage><primary><db port="1">x</db></primary></storage></config>`,
+		},
+		{
+			name:     "forward reorder uses pre-move indices",
+			xml:      list,
+			from:     "list.item.0",
+			to:       "list.item.2",
+			expected: "<list>\n  <item>b</item>\n  <item>a</item>\n  <item>c</item>\n</list>",
+		},
+		{
+			name:     "backward reorder",
+			xml:      list,
+			from:     "list.item.2",
+			to:       "list.item.0",
+			expected: "<list>\n  <item>c</item>\n  <item>a</item>\n  <item>b</item>\n</list>",
+		},
+		{
+			name:     "append with count",
+			xml:      list,
+			from:     "list.item.0",
+			to:       "list.item.#",
+			expected: "<list>\n  <item>b</item>\n  <item>c</item>\n  <item>a</item>\n</list>",
+		},
+		{
+			name:     "append with -1",
+			xml:      list,
+			from:     "list.item.1",
+			to:       "list.item.-1",
+			expected: "<list>\n  <item>a</item>\n  <item>c</item>\n  <item>b</item>\n</list>",
+		},
+		{
+			name:     "rename to destination name",
+			xml:      list,
+			from:     "list.item.1",
+			to:       "list.entry",
+			expected: "<list>\n  <item>a</item>\n  <item>c</item>\n  <entry>b</entry>\n</list>",
+		},
+		{
+			name:     "replace existing destination",
+			xml:      `<r><a><v>1</v></a><b><v>2</v></b></r>`,
+			from:     "r.a.v",
+			to:       "r.b.v",
+			expected: `<r><a></a><b><v>1</v></b></r>`,
+		},
+		{
+			name:     "onto itself is a no-op",
+			xml:      list,
+			from:     "list.item.1",
+			to:       "list.item.1",
+			expected: list,
+		},
+		{
+			name:     "self-closing with attributes",
+			xml:      `<r><a><x id="1" k="v"/></a><b/></r>`,
+			from:     "r.a.x",
+			to:       "r.b.x",
+			expected: `<r><a></a><b><x id="1" k="v"/></b></r>`,
+		},
+		{
+			name:     "reindent nested subtree",
+			xml:      "<r>\n  <a>\n    <s>\n      <p>1</p>\n    </s>\n  </a>\n  <b>\n    <c>\n      <x/>\n    </c>\n  </b>\n</r>",
+			from:     "r.a.s",
+			to:       "r.b.c.s.-1",
+			expected: "<r>\n  <a>\n  </a>\n  <b>\n    <c>\n      <x/>\n      <s>\n        <p>1</p>\n      </s>\n    </c>\n  </b>\n</r>",
+		},
+	}
 
-	fmt.Println("Email exists:", Get(modified, "user.email").Exists())
-	fmt.Println("Phone exists:", Get(modified, "user.phone").Exists())
-	fmt.Println("Name exists:", Get(modified, "user.name").Exists())
-	// Output:
-	// Email exists: false
-	// Phone exists: false
-	// Name exists: true
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Move(tt.xml, tt.from, tt.to)
+			if err != nil {
+				t.Fatalf("Move() error = %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Move() =\n%s\nexpected\n%s", result, tt.expected)
+			}
+		})
+	}
 }
 
-// ExampleSetWithOptions demonstrates using options when setting values
-func ExampleSetWithOptions() {
-	xml := `<root><value>test</value></root>`
+func TestMove_Errors(t *testing.T) {
+	xml := `<root><a><b>1</b></a><c/></root>`
 
-	// Set with custom options for indentation
-	opts := Options{Indent: "  "}
-	modified, _ := SetWithOptions(xml, "root.value", "updated", &opts)
+	tests := []struct {
+		name    string
+		xml     string
+		from    string
+		to      string
+		wantErr error
+	}{
+		{"malformed xml", `<root><a></root>`, "root.a", "root.b", ErrMalformedXML},
+		{"missing source", xml, "root.missing", "root.c", ErrInvalidPath},
+		{"into itself", xml, "root.a", "root.a.b.x", ErrInvalidPath},
+		{"onto own child", xml, "root.a", "root.a.b", ErrInvalidPath},
+		{"before own child", xml, "root.a", "root.a.b.0", ErrInvalidPath},
+		{"append into itself", xml, "root.a", "root.a.b.-1", ErrInvalidPath},
+		{"empty from", xml, "", "root.c", ErrInvalidPath},
+		{"empty to", xml, "root.a", "", ErrInvalidPath},
+		{"attribute path", xml, "root.a.@id", "root.c", ErrInvalidPath},
+		{"wildcard path", xml, "root.*", "root.c", ErrInvalidPath},
+		{"filter path", xml, "root.a", "root.#(b==1)", ErrInvalidPath},
+		{"append index in source", xml, "root.a.-1", "root.c", ErrInvalidPath},
+		{"modifier", xml, "root.a|@reverse", "root.c", ErrInvalidPath},
+	}
 
-	// Output shows the modified value (indentation may vary by implementation)
-	result := Get(modified, "root.value")
-	fmt.Println(result.String())
-	// Output: updated
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Move(tt.xml, tt.from, tt.to)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Move() error = %v, expected %v", err, tt.wantErr)
+			}
+			if result != tt.xml {
+				t.Errorf("Move() should return original XML on error, got %q", result)
+			}
+		})
+	}
 }
 
-// ExampleSetWithOptions_indent demonstrates pretty printing with indentation
-func ExampleSetWithOptions_indent() {
-	xml := `<root><user><name>John</name><age>30</age></user></root>`
+// TestMove_MarkerLikeContent tests that Move and Copy locate the source and
+// destination by offset, so text that looks like internal bookkeeping is
+// copied like any other content
+func TestMove_MarkerLikeContent(t *testing.T) {
+	xml := `<root><a xmldot-transplant-source="">xmldot-transplant-placeholder</a><b>xmldot-transplant-placeholder</b></root>`
 
-	// Set value with options
-	opts := Options{Indent: "    "}
-	modified, _ := SetWithOptions(xml, "root.user.age", 31, &opts)
+	tests := []struct {
+		name     string
+		op       func(string, string, string) (string, error)
+		from, to string
+		expected string
+	}{
+		{"move to new element", Move, "root.a", "root.c.a",
+			`<root><b>xmldot-transplant-placeholder</b><c><a xmldot-transplant-source="">xmldot-transplant-placeholder</a></c></root>`},
+		{"move appended", Move, "root.a", "root.b.-1",
+			`<root><b>xmldot-transplant-placeholder</b><b xmldot-transplant-source="">xmldot-transplant-placeholder</b></root>`},
+		{"move replacing", Move, "root.b", "root.a",
+			`<root><a>xmldot-transplant-placeholder</a></root>`},
+		{"copy before", Copy, "root.b", "root.a.0",
+			`<root><a>xmldot-transplant-placeholder</a><a xmldot-transplant-source="">xmldot-transplant-placeholder</a><b>xmldot-transplant-placeholder</b></root>`},
+	}
 
-	// Verify the value was updated
-	result := Get(modified, "root.user.age")
-	fmt.Printf("New age: %d\n", result.Int())
-	// Output: New age: 31
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := tt.op(xml, tt.from, tt.to)
+			if err != nil {
+				t.Fatalf("error = %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("got\n%s\nwant\n%s", result, tt.expected)
+			}
+		})
+	}
 }
 
-// TestSetMalformedXMLRegression is a regression test for fuzz-discovered crash
-// Issue: Set operations on malformed XML with unclosed tags caused runtime panics
-func TestSetMalformedXMLRegression(t *testing.T) {
+func TestMoveBytes(t *testing.T) {
+	result, err := MoveBytes([]byte(`<r><a>1</a><b/></r>`), "r.a", "r.b.a")
+	if err != nil {
+		t.Fatalf("MoveBytes() error = %v", err)
+	}
+	if string(result) != `<r><b><a>1</a></b></r>` {
+		t.Errorf("MoveBytes() = %q", result)
+	}
+}
+
+func TestPrettySubtree(t *testing.T) {
+	compact := `<config><db a=">"><host>x</host><!--c--><opts><o k="1"/><o>mixed <b>t</b> text</o></opts><p:q xmlns:p="u"><p:r/></p:q></db><log><x/></log></config>`
+	indented := "<root>\n\t<section>\n\t\t<a><b>1</b><c/></a>\n\t</section>\n\t<other><z/></other>\n</root>"
+
 	tests := []struct {
-		name         string
-		xml          string
-		path         string
-		value        interface{}
-		expectError  bool
-		errorMessage string
+		name     string
+		xml      string
+		path     string
+		indent   string
+		expected string
 	}{
 		{
-			name:         "unclosed root tag",
-			xml:          "<root>",
-			path:         "root.item",
-			value:        "value",
-			expectError:  true,
-			errorMessage: "malformed",
-		},
-		{
-			name:         "unclosed nested tag",
-			xml:          "<root><user>",
-			path:         "root.user.name",
-			value:        "John",
-			expectError:  true,
-			errorMessage: "malformed",
-		},
-		{
-			name:         "mismatched closing tag",
-			xml:          "<root><user></root>",
-			path:         "root.user",
-			value:        "value",
-			expectError:  true,
-			errorMessage: "malformed",
-		},
-		{
-			name:         "missing opening tag",
-			xml:          "<root></user></root>",
-			path:         "root.user",
-			value:        "value",
-			expectError:  true,
-			errorMessage: "malformed",
+			name:   "compact document",
+			xml:    compact,
+			path:   "config.db",
+			indent: "  ",
+			expected: "<config><db a=\">\">\n" +
+				"  <host>x</host>\n" +
+				"  <!--c-->\n" +
+				"  <opts>\n" +
+				"    <o k=\"1\"/>\n" +
+				"    <o>mixed <b>t</b> text</o>\n" +
+				"  </opts>\n" +
+				"  <p:q xmlns:p=\"u\">\n" +
+				"    <p:r/>\n" +
+				"  </p:q>\n" +
+				"</db><log><x/></log></config>",
 		},
 		{
-			name:         "unclosed attribute quote",
-			xml:          `<root attr="value><item>test</item></root>`,
-			path:         "root.item",
-			value:        "new",
-			expectError:  true,
-			errorMessage: "malformed",
+			name:     "aligned with its line",
+			xml:      indented,
+			path:     "root.section.a",
+			indent:   "\t",
+			expected: "<root>\n\t<section>\n\t\t<a>\n\t\t\t<b>1</b>\n\t\t\t<c/>\n\t\t</a>\n\t</section>\n\t<other><z/></other>\n</root>",
 		},
 		{
-			name:        "valid XML should work",
-			xml:         "<root></root>",
-			path:        "root.item",
-			value:       "value",
-			expectError: false,
+			name:     "indexed element",
+			xml:      `<a><b><c/></b><b><d/></b></a>`,
+			path:     "a.b.1",
+			indent:   " ",
+			expected: "<a><b><c/></b><b>\n <d/>\n</b></a>",
 		},
 		{
-			name:        "valid XML with nested elements",
-			xml:         "<root><user><name>John</name></user></root>",
-			path:        "root.user.age",
-			value:       30,
-			expectError: false,
+			name:     "text element unchanged",
+			xml:      compact,
+			path:     "config.db.host",
+			indent:   "  ",
+			expected: compact,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result, err := Set(tt.xml, tt.path, tt.value)
+			result, err := PrettySubtree(tt.xml, tt.path, tt.indent)
+			if err != nil {
+				t.Fatalf("PrettySubtree() error = %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("PrettySubtree() = %q, want %q", result, tt.expected)
+			}
+			if !Valid(result) {
+				t.Errorf("PrettySubtree() produced invalid XML: %q", result)
+			}
+		})
+	}
+}
 
-			if tt.expectError {
-				if err == nil {
-					t.Errorf("Set() expected error but got none; result = %v", result)
-				}
-				if tt.errorMessage != "" && err != nil {
-					errStr := err.Error()
-					if !containsIgnoreCase(errStr, tt.errorMessage) {
-						t.Errorf("Set() error = %v, should contain %q", err, tt.errorMessage)
-					}
-				}
-				// Error case - result should be original XML
-				if result != tt.xml {
-					t.Errorf("Set() on error should return original XML, got %v", result)
-				}
-			} else {
-				if err != nil {
-					t.Errorf("Set() unexpected error = %v", err)
-				}
-				// Success case - verify value was set
-				if !Get(result, tt.path).Exists() {
-					t.Errorf("Set() value not found at path %s in result: %s", tt.path, result)
-				}
+func TestPrettySubtree_Errors(t *testing.T) {
+	xml := `<root><a><b>1</b></a></root>`
+
+	tests := []struct {
+		name    string
+		xml     string
+		path    string
+		indent  string
+		wantErr error
+	}{
+		{"malformed xml", `<root><a></root>`, "root.a", "  ", ErrMalformedXML},
+		{"missing element", xml, "root.missing", "  ", ErrInvalidPath},
+		{"attribute path", xml, "root.a.@id", "  ", ErrInvalidPath},
+		{"modifier", xml, "root.a|@reverse", "  ", ErrInvalidPath},
+		{"invalid indent", xml, "root.a", "--", ErrInvalidValue},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := PrettySubtree(tt.xml, tt.path, tt.indent)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("PrettySubtree() error = %v, expected %v", err, tt.wantErr)
+			}
+			if result != tt.xml {
+				t.Errorf("PrettySubtree() should return original XML on error, got %q", result)
 			}
 		})
 	}
 }
 
-// TestDeleteMalformedXMLRegression is a regression test for fuzz-discovered crash
-// Issue: Delete operations on malformed XML caused runtime panics
-func TestDeleteMalformedXMLRegression(t *testing.T) {
+func TestRenameAttribute(t *testing.T) {
+	manifest := `<manifest><application><activity android:label="a" x='1'/><activity  android:label = "b">text</activity><service android:label="s"/></application></manifest>`
+
 	tests := []struct {
-		name         string
-		xml          string
-		path         string
-		expectError  bool
-		errorMessage string
+		name     string
+		xml      string
+		path     string
+		opts     *Options
+		expected string
 	}{
 		{
-			name:         "unclosed root tag",
-			xml:          "<root>",
-			path:         "root",
-			expectError:  true,
-			errorMessage: "malformed",
+			name:     "first match of a plain path",
+			xml:      manifest,
+			path:     "manifest.application.activity",
+			expected: `<manifest><application><activity android:title="a" x='1'/><activity  android:label = "b">text</activity><service android:label="s"/></application></manifest>`,
 		},
 		{
-			name:         "unclosed nested tag",
-			xml:          "<root><user>",
-			path:         "root.user",
-			expectError:  true,
-			errorMessage: "malformed",
+			name:     "indexed element keeps its spacing",
+			xml:      manifest,
+			path:     "manifest.application.activity.1",
+			expected: `<manifest><application><activity android:label="a" x='1'/><activity  android:title = "b">text</activity><service android:label="s"/></application></manifest>`,
 		},
 		{
-			name:         "mismatched closing tag",
-			xml:          "<root><user></root>",
-			path:         "root.user",
-			expectError:  true,
-			errorMessage: "malformed",
+			name:     "all siblings with trailing #",
+			xml:      manifest,
+			path:     "manifest.application.activity.#",
+			expected: `<manifest><application><activity android:title="a" x='1'/><activity  android:title = "b">text</activity><service android:label="s"/></application></manifest>`,
 		},
 		{
-			name:        "valid XML should work",
-			xml:         "<root><item>test</item></root>",
-			path:        "root.item",
-			expectError: false,
+			name:     "wildcard",
+			xml:      manifest,
+			path:     "manifest.application.*",
+			expected: `<manifest><application><activity android:title="a" x='1'/><activity  android:title = "b">text</activity><service android:title="s"/></application></manifest>`,
+		},
+		{
+			name:     "filter",
+			xml:      manifest,
+			path:     "manifest.application.activity.#(@x==1)#",
+			expected: `<manifest><application><activity android:title="a" x='1'/><activity  android:label = "b">text</activity><service android:label="s"/></application></manifest>`,
+		},
+		{
+			name:     "field extraction",
+			xml:      `<r><a><b k="1"/></a><a><b k="2"/></a></r>`,
+			path:     "r.a.#.b",
+			expected: `<r><a><b key="1"/></a><a><b key="2"/></a></r>`,
+		},
+		{
+			name:     "order of other attributes kept",
+			xml:      `<r><a z="1" k="2" b="3"/></r>`,
+			path:     "r.a",
+			expected: `<r><a z="1" key="2" b="3"/></r>`,
+		},
+		{
+			name:     "overwrite existing attribute",
+			xml:      `<r><a k="new" key="old"/></r>`,
+			path:     "r.a",
+			opts:     &Options{CaseSensitive: true, RenameOverwrite: true},
+			expected: `<r><a key="new"/></r>`,
+		},
+		{
+			name:     "case-insensitive names",
+			xml:      `<R><A K="1"/></R>`,
+			path:     "r.a",
+			opts:     &Options{CaseSensitive: false},
+			expected: `<R><A key="1"/></R>`,
+		},
+		{
+			name:     "attribute missing",
+			xml:      `<r><a other="1"/></r>`,
+			path:     "r.a",
+			expected: `<r><a other="1"/></r>`,
+		},
+		{
+			name:     "element missing",
+			xml:      `<r><a k="1"/></r>`,
+			path:     "r.b",
+			expected: `<r><a k="1"/></r>`,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result, err := Delete(tt.xml, tt.path)
-
-			if tt.expectError {
-				if err == nil {
-					t.Errorf("Delete() expected error but got none; result = %v", result)
-				}
-				if tt.errorMessage != "" && err != nil {
-					errStr := err.Error()
-					if !containsIgnoreCase(errStr, tt.errorMessage) {
-						t.Errorf("Delete() error = %v, should contain %q", err, tt.errorMessage)
-					}
-				}
-				// Error case - result should be original XML
-				if result != tt.xml {
-					t.Errorf("Delete() on error should return original XML, got %v", result)
-				}
-			} else {
-				if err != nil {
-					t.Errorf("Delete() unexpected error = %v", err)
-				}
+			oldName, newName := "k", "key"
+			if tt.xml == manifest {
+				oldName, newName = "android:label", "android:title"
+			}
+			result, err := RenameAttributeWithOptions(tt.xml, tt.path, oldName, newName, tt.opts)
+			if err != nil {
+				t.Fatalf("RenameAttributeWithOptions() error = %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("RenameAttributeWithOptions() = %q, expected %q", result, tt.expected)
 			}
 		})
 	}
-}
 
-// containsIgnoreCase checks if s contains substr (case-insensitive)
-func containsIgnoreCase(s, substr string) bool {
-	s = toLowerASCII(s)
-	substr = toLowerASCII(substr)
-	return len(s) >= len(substr) && findSubstring(s, substr)
+	result, err := RenameAttributeBytes([]byte(`<r><a k="1"/></r>`), "r.a", "k", "key")
+	if err != nil || string(result) != `<r><a key="1"/></r>` {
+		t.Errorf("RenameAttributeBytes() = %q, %v", result, err)
+	}
 }
 
-func findSubstring(s, substr string) bool {
-	if len(substr) == 0 {
-		return true
-	}
-	if len(substr) > len(s) {
-		return false
+func TestRenameAttribute_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		xml     string
+		path    string
+		newName string
+		wantErr error
+	}{
+		{"new name exists", `<r><a k="1" key="2"/></r>`, "r.a", "key", ErrInvalidValue},
+		{"new name exists on one match", `<r><a k="1"/><a k="2" key="3"/></r>`, "r.a.#", "key", ErrInvalidValue},
+		{"invalid new name", `<r><a k="1"/></r>`, "r.a", "1key", ErrInvalidValue},
+		{"attribute path", `<r><a k="1"/></r>`, "r.a.@k", "key", ErrInvalidPath},
+		{"recursive wildcard", `<r><a k="1"/></r>`, "r.**.a", "key", ErrInvalidPath},
+		{"modifier", `<r><a k="1"/></r>`, "r.a|@this", "key", ErrInvalidPath},
+		{"malformed XML", `<r><a k="1"></r>`, "r.a", "key", ErrMalformedXML},
 	}
-	for i := 0; i <= len(s)-len(substr); i++ {
-		if s[i:i+len(substr)] == substr {
-			return true
-		}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := RenameAttribute(tt.xml, tt.path, "k", tt.newName)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("RenameAttribute() error = %v, expected %v", err, tt.wantErr)
+			}
+			if result != tt.xml {
+				t.Errorf("RenameAttribute() should return original XML on error, got %q", result)
+			}
+		})
 	}
-	return false
 }
 
-// ============================================================================
-// Phase 4 Production Hardening: Wildcard/Filter Integration with Batch Ops
-// ============================================================================
-
-// TestSetMany_WithWildcards tests that SetMany accepts wildcard paths without errors
-func TestSetMany_WithWildcards(t *testing.T) {
-	t.Run("wildcard path in SetMany - basic test", func(t *testing.T) {
-		xml := `<root>
-			<user><name>Alice</name><age>25</age></user>
-			<user><name>Bob</name><age>30</age></user>
-		</root>`
-
-		paths := []string{"root.*.age"}
-		values := []interface{}{35}
-
-		result, err := SetMany(xml, paths, values)
-		if err != nil {
-			t.Fatalf("SetMany() with wildcards error = %v", err)
-		}
-
-		// Verify operation completes without error
-		if result == "" {
-			t.Errorf("SetMany() should return non-empty result")
-		}
-	})
-
-	t.Run("recursive wildcard in SetMany - basic test", func(t *testing.T) {
-		xml := `<root><user><profile><name>Alice</name></profile></user></root>`
-
-		paths := []string{"root.**.name"}
-		values := []interface{}{"Updated"}
-
-		result, err := SetMany(xml, paths, values)
-		if err != nil {
-			t.Fatalf("SetMany() with recursive wildcard error = %v", err)
-		}
-
-		// Verify operation completes without error
-		if result == "" {
-			t.Errorf("SetMany() should return non-empty result")
+func TestEditEach(t *testing.T) {
+	upperOrDrop := func(r Result) (string, bool) {
+		if r.String() == "" {
+			return "", false
 		}
-	})
-}
-
-// TestSetMany_WithFilters tests that SetMany accepts filter expressions without errors
-func TestSetMany_WithFilters(t *testing.T) {
-	t.Run("filter in SetMany - basic test", func(t *testing.T) {
-		xml := `<root>
-			<user><name>Alice</name><age>25</age><status>active</status></user>
-			<user><name>Bob</name><age>30</age><status>inactive</status></user>
-		</root>`
+		return strings.ToUpper(r.String()), true
+	}
 
-		paths := []string{"root.user[age>25].status"}
-		values := []interface{}{"premium"}
+	tests := []struct {
+		name     string
+		xml      string
+		path     string
+		expected string
+	}{
+		{"all siblings", `<tags><tag>go</tag><tag></tag><tag>xml</tag></tags>`, "tags.tag",
+			`<tags><tag>GO</tag><tag>XML</tag></tags>`},
+		{"trailing #", `<tags><tag>go</tag><tag/><tag>xml</tag></tags>`, "tags.tag.#",
+			`<tags><tag>GO</tag><tag>XML</tag></tags>`},
+		{"attributes kept and text escaped", `<tags><tag a="1">x &amp; y</tag></tags>`, "tags.tag",
+			`<tags><tag a="1">X &amp; Y</tag></tags>`},
+		{"children of each match", `<r><a><b>1</b></a><a><b></b></a><a><b>x</b></a></r>`, "r.a.#.b",
+			`<r><a><b>1</b></a><a></a><a><b>X</b></a></r>`},
+		{"wildcard", `<r><a>x</a><b>y</b></r>`, "r.*", `<r><a>X</a><b>Y</b></r>`},
+		{"filter", `<r><a k="1">x</a><a>y</a><a k="1"></a></r>`, "r.a.#(@k==1)#", `<r><a k="1">X</a><a>y</a></r>`},
+		{"no match", `<r><a>x</a></r>`, "r.b", `<r><a>x</a></r>`},
+		{"unchanged text keeps children", `<r><a><c>KEEP</c></a><a>x</a></r>`, "r.a", `<r><a><c>KEEP</c></a><a>X</a></r>`},
+	}
 
-		result, err := SetMany(xml, paths, values)
-		if err != nil {
-			t.Fatalf("SetMany() with filter error = %v", err)
-		}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := EditEach(tt.xml, tt.path, upperOrDrop)
+			if err != nil {
+				t.Fatalf("EditEach() error = %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("EditEach() = %q, want %q", result, tt.expected)
+			}
+			resultBytes, err := EditEachBytes([]byte(tt.xml), tt.path, upperOrDrop)
+			if err != nil || string(resultBytes) != tt.expected {
+				t.Errorf("EditEachBytes() = %q, %v, want %q", resultBytes, err, tt.expected)
+			}
+		})
+	}
 
-		// Verify operation completes without error
-		if result == "" {
-			t.Errorf("SetMany() should return non-empty result")
-		}
+	// The callback sees each element of the original document in order
+	xml := `<r><i n="1">a</i><i n="2">b</i><i n="3">c</i></r>`
+	var seen []string
+	result, err := EditEach(xml, "r.i", func(r Result) (string, bool) {
+		seen = append(seen, r.Name()+"="+r.String())
+		return "x", r.String() != "a"
 	})
+	if err != nil {
+		t.Fatalf("EditEach() error = %v", err)
+	}
+	if strings.Join(seen, ",") != "i=a,i=b,i=c" {
+		t.Errorf("EditEach() visited %v, want i=a,i=b,i=c", seen)
+	}
+	if result != `<r><i n="2">x</i><i n="3">x</i></r>` {
+		t.Errorf("EditEach() = %q", result)
+	}
 }
 
-// TestDeleteMany_WithWildcards tests that DeleteMany accepts wildcard paths without errors
-func TestDeleteMany_WithWildcards(t *testing.T) {
-	t.Run("wildcard path in DeleteMany - basic test", func(t *testing.T) {
-		xml := `<root>
-			<user><name>Alice</name><age>25</age></user>
-			<user><name>Bob</name><age>30</age></user>
-		</root>`
-
-		result, err := DeleteMany(xml, "root.*.age")
-		if err != nil {
-			t.Fatalf("DeleteMany() with wildcards error = %v", err)
-		}
-
-		// Verify operation completes without error
-		if result == "" {
-			t.Errorf("DeleteMany() should return non-empty result")
-		}
-	})
-
-	t.Run("recursive wildcard in DeleteMany - basic test", func(t *testing.T) {
-		xml := `<root><level1><value>1</value><nested><value>2</value></nested></level1></root>`
-
-		result, err := DeleteMany(xml, "root.**.value")
-		if err != nil {
-			t.Fatalf("DeleteMany() with recursive wildcard error = %v", err)
-		}
+func TestEditEach_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		xml     string
+		path    string
+		wantErr error
+	}{
+		{"remove root", `<r><a>x</a></r>`, "r", ErrInvalidPath},
+		{"attribute path", `<r><a k="1"/></r>`, "r.a.@k", ErrInvalidPath},
+		{"recursive wildcard", `<r><a>x</a></r>`, "r.**.a", ErrInvalidPath},
+		{"modifier", `<r><a>x</a></r>`, "r.a|@this", ErrInvalidPath},
+		{"empty path", `<r><a>x</a></r>`, "", ErrInvalidPath},
+		{"malformed XML", `<r><a>x</r>`, "r.a", ErrMalformedXML},
+	}
 
-		// Verify operation completes without error
-		if result == "" {
-			t.Errorf("DeleteMany() should return non-empty result")
-		}
-	})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := EditEach(tt.xml, tt.path, func(Result) (string, bool) { return "", false })
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("EditEach() error = %v, expected %v", err, tt.wantErr)
+			}
+			if result != tt.xml {
+				t.Errorf("EditEach() should return original XML on error, got %q", result)
+			}
+		})
+	}
 }
 
-// TestDeleteMany_WithFilters tests that DeleteMany accepts filter expressions without errors
-func TestDeleteMany_WithFilters(t *testing.T) {
-	t.Run("filter in DeleteMany - basic test", func(t *testing.T) {
-		xml := `<root>
-			<user><name>Alice</name><age>25</age></user>
-			<user><name>Bob</name><age>30</age></user>
-		</root>`
-
-		result, err := DeleteMany(xml, "root.user[age>=30]")
-		if err != nil {
-			t.Fatalf("DeleteMany() with filter error = %v", err)
-		}
+func TestCopy(t *testing.T) {
+	tests := []struct {
+		name     string
+		xml      string
+		from     string
+		to       string
+		expected string
+	}{
+		{
+			name:     "append copy of prototype",
+			xml:      `<servers><server name="proto"><port>80</port></server></servers>`,
+			from:     "servers.server",
+			to:       "servers.server.-1",
+			expected: `<servers><server name="proto"><port>80</port></server><server name="proto"><port>80</port></server></servers>`,
+		},
+		{
+			name:     "copy to new location",
+			xml:      `<r><defaults><timeout unit="s">30</timeout></defaults><svc/></r>`,
+			from:     "r.defaults.timeout",
+			to:       "r.svc.timeout",
+			expected: `<r><defaults><timeout unit="s">30</timeout></defaults><svc><timeout unit="s">30</timeout></svc></r>`,
+		},
+		{
+			name:     "copy into own subtree",
+			xml:      `<a><b>1</b></a>`,
+			from:     "a.b",
+			to:       "a.b.b",
+			expected: `<a><b>1<b>1</b></b></a>`,
+		},
+		{
+			name:     "copy with rename",
+			xml:      `<r><primary host="a"/></r>`,
+			from:     "r.primary",
+			to:       "r.backup",
+			expected: `<r><primary host="a"/><backup host="a"/></r>`,
+		},
+		{
+			name:     "insert before index",
+			xml:      "<list>\n  <item>a</item>\n  <item>b</item>\n</list>",
+			from:     "list.item.1",
+			to:       "list.item.0",
+			expected: "<list>\n  <item>b</item>\n  <item>a</item>\n  <item>b</item>\n</list>",
+		},
+		{
+			name:     "reindent to destination",
+			xml:      "<r>\n  <proto>\n    <server>\n      <port>80</port>\n    </server>\n  </proto>\n  <servers>\n    <server>\n      <port>443</port>\n    </server>\n  </servers>\n</r>",
+			from:     "r.proto.server",
+			to:       "r.servers.server.#",
+			expected: "<r>\n  <proto>\n    <server>\n      <port>80</port>\n    </server>\n  </proto>\n  <servers>\n    <server>\n      <port>443</port>\n    </server>\n    <server>\n      <port>80</port>\n    </server>\n  </servers>\n</r>",
+		},
+	}
 
-		// Verify operation completes without error
-		if result == "" {
-			t.Errorf("DeleteMany() should return non-empty result")
-		}
-	})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Copy(tt.xml, tt.from, tt.to)
+			if err != nil {
+				t.Fatalf("Copy() error = %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Copy() =\n%s\nexpected\n%s", result, tt.expected)
+			}
+			if !Valid(result) {
+				t.Errorf("Copy() produced invalid XML: %q", result)
+			}
+		})
+	}
 }
 
-// TestBatchOps_ComplexFilterWildcardCombinations tests complex scenarios
-func TestBatchOps_ComplexFilterWildcardCombinations(t *testing.T) {
-	t.Run("SetMany with wildcard and filter combination", func(t *testing.T) {
-		xml := `<root>
-			<group><user><name>Alice</name><age>25</age></user></group>
-			<group><user><name>Bob</name><age>30</age></user></group>
-		</root>`
-
-		paths := []string{"root.*.user[age>=30].name"}
-		values := []interface{}{"Senior User"}
-
-		result, err := SetMany(xml, paths, values)
-		if err != nil {
-			t.Fatalf("SetMany() with wildcard+filter error = %v", err)
+func TestCopy_Errors(t *testing.T) {
+	xml := `<root><a><b>1</b></a></root>`
+	for _, tc := range []struct{ from, to string }{
+		{"root.missing", "root.c"},
+		{"root.a.@id", "root.c"},
+		{"root.a", "root.*"},
+		{"root.a", "root.a.@id"},
+	} {
+		result, err := Copy(xml, tc.from, tc.to)
+		if !errors.Is(err, ErrInvalidPath) {
+			t.Errorf("Copy(%q, %q) error = %v, expected ErrInvalidPath", tc.from, tc.to, err)
 		}
-
-		// Verify operation completes without error
-		if result == "" {
-			t.Errorf("SetMany() should return non-empty result")
+		if result != xml {
+			t.Errorf("Copy(%q, %q) should return original XML on error, got %q", tc.from, tc.to, result)
 		}
-	})
+	}
 
-	t.Run("DeleteMany with multiple filter operations", func(t *testing.T) {
-		t.Skip("DeleteMany with GJSON filter syntax not yet implemented - filters return result arrays, not element paths")
+	if _, err := CopyBytes([]byte(`<root><a></root>`), "root.a", "root.b"); !errors.Is(err, ErrMalformedXML) {
+		t.Errorf("CopyBytes() error = %v, expected ErrMalformedXML", err)
+	}
+}
 
-		xml := `<root>
-			<user><name>Alice</name><age>25</age><role>admin</role></user>
-			<user><name>Bob</name><age>30</age><role>user</role></user>
-			<user><name>Charlie</name><age>35</age><role>admin</role></user>
-		</root>`
+// TestSetFanOut tests that wildcard, #.field and filter write paths update
+// every matched element in a single pass.
+func TestSetFanOut(t *testing.T) {
+	catalog := `<catalog><product><name>A</name><currency>EUR</currency></product>` +
+		`<product><name>B</name></product>` +
+		`<product><name>C</name><stock>0</stock></product></catalog>`
 
-		result, err := DeleteMany(xml, "root.user.#(role==user)#", "root.user.#(age<30)#")
-		if err != nil {
-			t.Fatalf("DeleteMany() with multiple filters error = %v", err)
-		}
+	tests := []struct {
+		name     string
+		xml      string
+		path     string
+		value    interface{}
+		expected string
+		changed  int
+	}{
+		{
+			name:  "field extraction updates or creates per match",
+			xml:   catalog,
+			path:  "catalog.product.#.currency",
+			value: "USD",
+			expected: `<catalog><product><name>A</name><currency>USD</currency></product>` +
+				`<product><name>B</name><currency>USD</currency></product>` +
+				`<product><name>C</name><stock>0</stock><currency>USD</currency></product></catalog>`,
+			changed: 3,
+		},
+		{
+			name:  "wildcard updates existing children",
+			xml:   catalog,
+			path:  "catalog.*.name",
+			value: "X",
+			expected: `<catalog><product><name>X</name><currency>EUR</currency></product>` +
+				`<product><name>X</name></product>` +
+				`<product><name>X</name><stock>0</stock></product></catalog>`,
+			changed: 3,
+		},
+		{
+			name:  "filter all",
+			xml:   catalog,
+			path:  "catalog.product.#(stock==0)#.available",
+			value: false,
+			expected: `<catalog><product><name>A</name><currency>EUR</currency></product>` +
+				`<product><name>B</name></product>` +
+				`<product><name>C</name><stock>0</stock><available>false</available></product></catalog>`,
+			changed: 1,
+		},
+		{
+			name:     "attribute field",
+			xml:      `<list><item id="1"/><item/><other/></list>`,
+			path:     "list.item.#.@id",
+			value:    1,
+			expected: `<list><item id="1"/><item id="1"/><other/></list>`,
+			changed:  1,
+		},
+		{
+			name:     "first filter match only",
+			xml:      `<r><v n="1"/><v n="1"/></r>`,
+			path:     "r.v.#(@n==1).@seen",
+			value:    "yes",
+			expected: `<r><v n="1" seen="yes"/><v n="1"/></r>`,
+			changed:  1,
+		},
+		{
+			name:     "nested fan-out",
+			xml:      `<r><g><i/><i/></g><g><i/></g></r>`,
+			path:     "r.g.#.i.#.@x",
+			value:    "1",
+			expected: `<r><g><i x="1"/><i x="1"/></g><g><i x="1"/></g></r>`,
+			changed:  3,
+		},
+		{
+			name:     "no match leaves document unchanged",
+			xml:      catalog,
+			path:     "catalog.product.#(stock==9)#.available",
+			value:    true,
+			expected: catalog,
+			changed:  0,
+		},
+		{
+			name:     "delete fan-out",
+			xml:      `<r><a><x/><y/></a><b><x/></b></r>`,
+			path:     "r.*.x",
+			value:    nil,
+			expected: `<r><a><y/></a><b></b></r>`,
+			changed:  2,
+		},
+		{
+			name:     "siblings after a tag with spaces around =",
+			xml:      `<m><app><activity l = "b">text</activity><service l="s"/></app></m>`,
+			path:     "m.app.*.@z",
+			value:    "1",
+			expected: `<m><app><activity l="b" z="1">text</activity><service l="s" z="1"/></app></m>`,
+			changed:  2,
+		},
+	}
 
-		// Verify Charlie remains (age>=30 AND role==admin)
-		count := Get(result, "root.user.#")
-		if count.Int() != 1 {
-			t.Errorf("DeleteMany() count = %v, want 1", count.Int())
-		}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Set(tt.xml, tt.path, tt.value)
+			if err != nil {
+				t.Fatalf("Set() error = %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Set() = %q, want %q", result, tt.expected)
+			}
 
-		name0 := Get(result, "root.user.0.name")
-		if name0.String() != "Charlie" {
-			t.Errorf("Only Charlie should remain, got %v", name0.String())
-		}
-	})
+			_, n, err := SetN(tt.xml, tt.path, tt.value)
+			if err != nil {
+				t.Fatalf("SetN() error = %v", err)
+			}
+			if n != tt.changed {
+				t.Errorf("SetN() changed = %d, want %d", n, tt.changed)
+			}
+		})
+	}
 }
 
-// ============================================================================
-// Phase 4 Production Hardening: MaxDocumentSize Tests for Batch Operations
-// ============================================================================
-
-// TestSetMany_MaxDocumentSize tests that SetMany respects MaxDocumentSize limit
-func TestSetMany_MaxDocumentSize(t *testing.T) {
-	t.Run("SetMany rejects document exceeding MaxDocumentSize", func(t *testing.T) {
-		// Create XML document larger than MaxDocumentSize (10MB)
-		largeXML := string(make([]byte, MaxDocumentSize+1))
-		paths := []string{"root.test"}
-		values := []interface{}{"value"}
+// TestSetFanOut_Errors tests invalid fan-out write paths.
+func TestSetFanOut_Errors(t *testing.T) {
+	xml := `<r><a><x>1</x></a></r>`
 
-		_, err := SetMany(largeXML, paths, values)
-		if err == nil {
-			t.Fatalf("SetMany() should reject documents exceeding MaxDocumentSize")
+	for _, path := range []string{"r.a.#.%", "r.*.x|@reverse"} {
+		if _, err := Set(xml, path, "v"); !errors.Is(err, ErrInvalidPath) {
+			t.Errorf("Set(%q) error = %v, want ErrInvalidPath", path, err)
 		}
-
-		if err != ErrMalformedXML {
-			t.Errorf("SetMany() error = %v, want ErrMalformedXML", err)
+		if err := CanSet(xml, path, "v"); !errors.Is(err, ErrInvalidPath) {
+			t.Errorf("CanSet(%q) error = %v, want ErrInvalidPath", path, err)
 		}
-	})
-
-	t.Run("SetMany accepts document at MaxDocumentSize limit", func(_ *testing.T) {
-		// Create valid XML document at exactly MaxDocumentSize
-		xmlSize := MaxDocumentSize - 100 // Leave room for tags
-		content := string(make([]byte, xmlSize))
-		xml := "<root>" + content + "</root>"
-
-		paths := []string{"root.test"}
-		values := []interface{}{"value"}
+	}
 
-		_, err := SetMany(xml, paths, values)
-		// May fail due to malformed XML (just random bytes), but should not be
-		// rejected for size reasons. We just verify it doesn't panic.
-		_ = err
-	})
+	// A * wildcard never creates elements inside the children it matches
+	catalog := `<catalog><product><name>A</name><currency>EUR</currency></product>` +
+		`<product><name>B</name></product></catalog>`
+	for _, path := range []string{"catalog.product.*.currency", "catalog.*.currency", "catalog.*.price.@unit"} {
+		result, err := Set(catalog, path, "USD")
+		if !errors.Is(err, ErrInvalidPath) {
+			t.Errorf("Set(%q) error = %v, want ErrInvalidPath", path, err)
+		}
+		if result != catalog {
+			t.Errorf("Set(%q) modified the document: %s", path, result)
+		}
+	}
+}
 
-	t.Run("SetMany with multiple operations on large document", func(_ *testing.T) {
-		// Create document approaching MaxDocumentSize
-		xmlSize := MaxDocumentSize/2 - 1000
-		largeContent := string(make([]byte, xmlSize))
-		xml := "<root><data>" + largeContent + "</data></root>"
+// TestDeleteFanOut tests that Delete removes every element a fan-out path
+// selects, or the rest of the path inside each of them.
+func TestDeleteFanOut(t *testing.T) {
+	xml := `<root><item id="1"><n>a</n></item><item id="2"><n>b</n></item><item id="3"/><other/></root>`
 
-		paths := []string{"root.field1", "root.field2", "root.field3"}
-		values := []interface{}{"val1", "val2", "val3"}
+	tests := []struct {
+		name     string
+		path     string
+		expected string
+		removed  int
+	}{
+		{"filter all", "root.item.#(@id>1)#", `<root><item id="1"><n>a</n></item><other/></root>`, 2},
+		{"first filter match", "root.item.#(@id==2)", `<root><item id="1"><n>a</n></item><item id="3"/><other/></root>`, 1},
+		{"limited filter", "root.item.#(@id>0)#:2", `<root><item id="3"/><other/></root>`, 2},
+		{"wildcard", "root.*", `<root></root>`, 4},
+		{"child of every match", "root.item.#.n", `<root><item id="1"></item><item id="2"></item><item id="3"/><other/></root>`, 2},
+		{"attribute of every match", "root.*.@id", `<root><item><n>a</n></item><item><n>b</n></item><item/><other/></root>`, 3},
+		{"no match", "root.item.#(@id>9)#", xml, 0},
+	}
 
-		_, err := SetMany(xml, paths, values)
-		// May fail due to malformed XML, but should not panic
-		_ = err
-	})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Delete(xml, tt.path)
+			if err != nil {
+				t.Fatalf("Delete(%q) error = %v", tt.path, err)
+			}
+			if result != tt.expected {
+				t.Errorf("Delete(%q) = %s, want %s", tt.path, result, tt.expected)
+			}
+			_, n, err := DeleteN(xml, tt.path)
+			if err != nil || n != tt.removed {
+				t.Errorf("DeleteN(%q) = %d, %v, want %d", tt.path, n, err, tt.removed)
+			}
+			// Set with a path ending in the fan-out selects the same elements
+			if last := parsePath(tt.path); last[len(last)-1].Type == SegmentFilter || last[len(last)-1].Type == SegmentWildcard {
+				if _, n, _ := SetN(xml, tt.path, "x"); n != tt.removed {
+					t.Errorf("SetN(%q) = %d, want %d like DeleteN", tt.path, n, tt.removed)
+				}
+			}
+		})
+	}
 }
 
-// TestSetManyBytes_MaxDocumentSize tests that SetManyBytes respects MaxDocumentSize
-func TestSetManyBytes_MaxDocumentSize(t *testing.T) {
-	t.Run("SetManyBytes rejects document exceeding MaxDocumentSize", func(t *testing.T) {
-		largeXML := make([]byte, MaxDocumentSize+1)
-		paths := []string{"root.test"}
-		values := []interface{}{"value"}
+func TestSetFanOut_LimitExceeded(t *testing.T) {
+	var sb strings.Builder
+	sb.WriteString("<catalog>")
+	for i := 0; i < MaxWildcardResults+1; i++ {
+		sb.WriteString("<product><price>1</price></product>")
+	}
+	sb.WriteString("</catalog>")
+	xml := sb.String()
 
-		_, err := SetManyBytes(largeXML, paths, values)
-		if err == nil {
-			t.Fatalf("SetManyBytes() should reject documents exceeding MaxDocumentSize")
+	for _, path := range []string{"catalog.product.#.price", "catalog.*.price", "catalog.product.#(price==1)#.price"} {
+		result, err := Set(xml, path, "2")
+		if !errors.Is(err, ErrLimitExceeded) {
+			t.Errorf("Set(%q) error = %v, want ErrLimitExceeded", path, err)
 		}
-
-		if err != ErrMalformedXML {
-			t.Errorf("SetManyBytes() error = %v, want ErrMalformedXML", err)
+		if result != xml {
+			t.Errorf("Set(%q) modified the document", path)
 		}
-	})
-}
-
-// TestDeleteMany_MaxDocumentSize tests that DeleteMany respects MaxDocumentSize limit
-func TestDeleteMany_MaxDocumentSize(t *testing.T) {
-	t.Run("DeleteMany rejects document exceeding MaxDocumentSize", func(t *testing.T) {
-		// Create XML document larger than MaxDocumentSize (10MB)
-		largeXML := string(make([]byte, MaxDocumentSize+1))
-
-		_, err := DeleteMany(largeXML, "root.test")
-		if err == nil {
-			t.Fatalf("DeleteMany() should reject documents exceeding MaxDocumentSize")
+		if _, n, err := SetN(xml, path, "2"); !errors.Is(err, ErrLimitExceeded) || n != 0 {
+			t.Errorf("SetN(%q) = %d, %v, want 0, ErrLimitExceeded", path, n, err)
 		}
+	}
+	if _, err := Delete(xml, "catalog.product.#.price"); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("Delete() error = %v, want ErrLimitExceeded", err)
+	}
 
-		if err != ErrMalformedXML {
-			t.Errorf("DeleteMany() error = %v, want ErrMalformedXML", err)
-		}
-	})
+	// Exactly MaxWildcardResults targets are still written
+	xml = strings.Replace(xml, "<product><price>1</price></product>", "", 1)
+	_, n, err := SetN(xml, "catalog.product.#.price", "2")
+	if err != nil || n != MaxWildcardResults {
+		t.Errorf("SetN() at the limit = %d, %v, want %d, nil", n, err, MaxWildcardResults)
+	}
+}
 
-	t.Run("DeleteMany accepts document at MaxDocumentSize limit", func(_ *testing.T) {
-		// Create valid XML document at exactly MaxDocumentSize
-		xmlSize := MaxDocumentSize - 100 // Leave room for tags
-		content := string(make([]byte, xmlSize))
-		xml := "<root>" + content + "</root>"
+func TestUpdate(t *testing.T) {
+	xml := `<manifest xmlns:android="http://schemas.android.com/apk/res/android" android:versionCode="41" package="app">` +
+		`<name>Demo</name><count>9</count><empty/></manifest>`
+	increment := func(old Result) string {
+		return strconv.FormatInt(old.Int()+1, 10)
+	}
 
-		_, err := DeleteMany(xml, "root.test")
-		// May fail due to malformed XML (just random bytes), but should not be
-		// rejected for size reasons. We just verify it doesn't panic.
-		_ = err
-	})
+	tests := []struct {
+		name string
+		path string
+		fn   func(Result) string
+		want string // value read back at path
+	}{
+		{"attribute", "manifest.@android:versionCode", increment, "42"},
+		{"element", "manifest.count", increment, "10"},
+		{"self-closing element", "manifest.empty", func(old Result) string { return old.String() + "x" }, "x"},
+		{"escaped text", "manifest.name", func(old Result) string { return old.String() + " & <more>" }, "Demo & <more>"},
+		{"missing attribute", "manifest.@debug", func(old Result) string {
+			if old.Exists() {
+				return "unexpected"
+			}
+			return "true"
+		}, "true"},
+		{"missing element", "manifest.build.number", increment, "1"},
+	}
 
-	t.Run("DeleteMany with multiple operations on large document", func(_ *testing.T) {
-		// Create document approaching MaxDocumentSize
-		xmlSize := MaxDocumentSize/2 - 1000
-		largeContent := string(make([]byte, xmlSize))
-		xml := "<root><data>" + largeContent + "</data><field1>1</field1><field2>2</field2></root>"
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var seen Result
+			modified, err := Update(xml, tt.path, func(old Result) string {
+				seen = old
+				return tt.fn(old)
+			})
+			if err != nil {
+				t.Fatalf("Update() error = %v", err)
+			}
+			if want := Get(xml, tt.path); seen.Exists() != want.Exists() || seen.String() != want.String() {
+				t.Errorf("fn received %q, want the current value %q", seen.String(), want.String())
+			}
+			if got := Get(modified, tt.path).String(); got != tt.want {
+				t.Errorf("after Update() %s = %q, want %q\n%s", tt.path, got, tt.want, modified)
+			}
+			if !Valid(modified) {
+				t.Errorf("Update() produced invalid XML: %s", modified)
+			}
+		})
+	}
 
-		_, err := DeleteMany(xml, "root.field1", "root.field2")
-		// May fail due to malformed XML, but should not panic
-		_ = err
-	})
+	// The rest of the document is untouched
+	modified, _ := Update(xml, "manifest.@android:versionCode", increment)
+	if want := strings.Replace(xml, `"41"`, `"42"`, 1); modified != want {
+		t.Errorf("Update() = %s, want %s", modified, want)
+	}
+	bytesModified, err := UpdateBytes([]byte(xml), "manifest.@android:versionCode", increment)
+	if err != nil || string(bytesModified) != modified {
+		t.Errorf("UpdateBytes() = %s, %v", bytesModified, err)
+	}
 }
 
-// TestDeleteManyBytes_MaxDocumentSize tests that DeleteManyBytes respects MaxDocumentSize
-func TestDeleteManyBytes_MaxDocumentSize(t *testing.T) {
-	t.Run("DeleteManyBytes rejects document exceeding MaxDocumentSize", func(t *testing.T) {
-		largeXML := make([]byte, MaxDocumentSize+1)
+func TestUpdate_Errors(t *testing.T) {
+	xml := `<root><item>1</item><item>2</item></root>`
+	called := false
+	fn := func(Result) string { called = true; return "x" }
 
-		_, err := DeleteManyBytes(largeXML, "root.test")
-		if err == nil {
-			t.Fatalf("DeleteManyBytes() should reject documents exceeding MaxDocumentSize")
-		}
+	tests := []struct {
+		name    string
+		xml     string
+		path    string
+		wantErr error
+	}{
+		{"malformed", `<root><item></root>`, "root.item", ErrMalformedXML},
+		{"empty path", xml, "", ErrInvalidPath},
+		{"wildcard", xml, "root.*", ErrInvalidPath},
+		{"all matches", xml, "root.item.#(%>0)#", ErrInvalidPath},
+		{"root accessor", xml, "#root.0", ErrInvalidPath},
+		{"positional attribute", xml, "root.item.@0", ErrInvalidPath},
+	}
 
-		if err != ErrMalformedXML {
-			t.Errorf("DeleteManyBytes() error = %v, want ErrMalformedXML", err)
-		}
-	})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called = false
+			modified, err := Update(tt.xml, tt.path, fn)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Update() error = %v, want %v", err, tt.wantErr)
+			}
+			if modified != tt.xml {
+				t.Errorf("Update() modified the document on error: %s", modified)
+			}
+			if called {
+				t.Errorf("Update() called fn on error")
+			}
+		})
+	}
 }
@@ -0,0 +1,469 @@
+// SPDX-License-Identifier: MIT
+// Copyright (c) 2025 Daniel Schmidt
+
+package xmldot
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// GetStream reads an XML document from r and calls fn for each element that
+// matches path, as soon as the element has been read. Iteration stops when fn
+// returns false. Only the element being matched is held in memory, so
+// GetStream processes feeds of any size in constant memory where
+// Get(...).Array() would have to load the whole document.
+//
+// The path may contain element names, glob patterns and * wildcards; every
+// element whose ancestry matches the path is reported, in document order.
+// Each Result is an Element whose Raw is the element's content, so it can be
+// queried further with Result.Get.
+//
+// GetStream returns ErrInvalidPath for a path using other syntax (attributes,
+// indexes, filters, ** or modifiers), ErrMalformedXML if the document is not
+// well-formed up to the point where iteration stopped, ErrLimitExceeded if a
+// matched element is larger than MaxDocumentSize or the document nests deeper
+// than MaxNestingDepth, and any error returned by r.
+//
+// Example:
+//
+//	err := xmldot.GetStream(feed, "rss.channel.item", func(item xmldot.Result) bool {
+//	    fmt.Println(item.Get("title").String())
+//	    return true
+//	})
+func GetStream(r io.Reader, path string, fn func(Result) bool) error {
+	segments, err := parseStreamPath(path)
+	if err != nil {
+		return err
+	}
+
+	rec := &recordingReader{r: bufio.NewReader(r)}
+	decoder := xml.NewDecoder(rec)
+
+	var stack []string
+	captureDepth := -1 // depth of the element being captured, or -1
+	var captureStart int64
+
+	for {
+		// Token boundaries outside a match need not be kept
+		if captureDepth < 0 {
+			rec.discardBefore(decoder.InputOffset())
+		}
+		tokenStart := decoder.InputOffset()
+
+		token, err := decoder.RawToken()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				if len(stack) > 0 {
+					return fmt.Errorf("%w: unexpected end of document inside <%s>", ErrMalformedXML, stack[len(stack)-1])
+				}
+				return nil
+			}
+			var syntaxErr *xml.SyntaxError
+			if errors.As(err, &syntaxErr) {
+				return fmt.Errorf("%w: %v", ErrMalformedXML, syntaxErr)
+			}
+			return err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if len(stack) >= MaxNestingDepth {
+				return fmt.Errorf("%w: nesting deeper than %d elements", ErrLimitExceeded, MaxNestingDepth)
+			}
+			stack = append(stack, qualifiedName(t.Name))
+			if captureDepth < 0 && streamPathMatches(segments, stack) {
+				captureDepth = len(stack)
+				captureStart = tokenStart
+			}
+
+		case xml.EndElement:
+			name := qualifiedName(t.Name)
+			if len(stack) == 0 || stack[len(stack)-1] != name {
+				return fmt.Errorf("%w: unexpected closing tag </%s>", ErrMalformedXML, name)
+			}
+			if len(stack) == captureDepth {
+				captureDepth = -1
+				result := streamElementResult(rec.slice(captureStart, decoder.InputOffset()))
+				if result.Type == Element {
+					result.span = docSpan{start: int(captureStart), end: int(decoder.InputOffset())}
+				}
+				if !fn(result) {
+					return nil
+				}
+			}
+			stack = stack[:len(stack)-1]
+		}
+
+		if captureDepth >= 0 && decoder.InputOffset()-captureStart > MaxDocumentSize {
+			return fmt.Errorf("%w: matched element exceeds maximum size of %d bytes", ErrLimitExceeded, MaxDocumentSize)
+		}
+	}
+}
+
+// SetWriter reads an XML document from r and writes it to w with the value
+// at path set as Set would, without holding the document in memory. Bytes
+// outside the edited element are copied to w unchanged as they are read; only
+// the element being replaced is buffered, and for an attribute only its start
+// tag, so single-field edits on very large files use little memory.
+//
+// The path may contain element names followed by an optional final
+// attribute (@attr). As with Set, each name selects the first matching
+// element inside the previous one, missing elements are created (inside the
+// deepest existing one, just before its end tag, written compactly), and a
+// nil value deletes the element or attribute.
+//
+// SetWriter returns ErrInvalidPath for a path using other syntax, the errors
+// Set returns for the value, ErrMalformedXML if the document is not
+// well-formed, ErrLimitExceeded if the edited element is larger than
+// MaxDocumentSize or the document nests deeper than MaxNestingDepth, and any
+// error returned by r or w. The document is checked as it is copied, so on
+// an error w may already have received part of the output.
+//
+// Example:
+//
+//	in, _ := os.Open("catalog.xml")
+//	out, _ := os.Create("catalog.new.xml")
+//	err := xmldot.SetWriter(out, in, "catalog.meta.@updated", "2025-06-01")
+func SetWriter(w io.Writer, r io.Reader, path string, value any) error {
+	segments, err := parseStreamSetPath(path)
+	if err != nil {
+		return err
+	}
+	if value != nil {
+		if _, _, err := valueToXMLWithOptions(value, DefaultOptions()); err != nil {
+			return err
+		}
+	}
+	s := &streamSetter{
+		w:        w,
+		rec:      &recordingReader{r: bufio.NewReader(r)},
+		segments: segments,
+		elements: len(segments),
+		value:    value,
+	}
+	if segments[len(segments)-1].Type == SegmentAttribute {
+		s.elements--
+	}
+	return s.run()
+}
+
+// streamSetter holds the state of a SetWriter edit.
+type streamSetter struct {
+	w        io.Writer
+	rec      *recordingReader
+	segments []PathSegment
+	elements int // number of element segments in segments
+	value    any
+
+	written int64 // stream offset up to which the input has been written
+	matched int   // number of leading element segments matched by open elements
+	done    bool  // the edit has been written
+}
+
+func (s *streamSetter) run() error {
+	decoder := xml.NewDecoder(s.rec)
+
+	var stack []string
+	rootSeen := false
+	captureStart := int64(-1) // start of the buffered target element, or -1
+
+	for {
+		tokenStart := decoder.InputOffset()
+		if captureStart < 0 {
+			if err := s.copyTo(tokenStart); err != nil {
+				return err
+			}
+		}
+
+		token, err := decoder.RawToken()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				if len(stack) > 0 {
+					return fmt.Errorf("%w: unexpected end of document inside <%s>", ErrMalformedXML, stack[len(stack)-1])
+				}
+				if err := s.copyTo(decoder.InputOffset()); err != nil {
+					return err
+				}
+				if !rootSeen {
+					// Like Set, only an empty document can be created from scratch
+					if decoder.InputOffset() == 0 && s.value == nil {
+						return fmt.Errorf("%w: empty document", ErrMalformedXML)
+					}
+					if decoder.InputOffset() > 0 {
+						return fmt.Errorf("%w: no root element found", ErrMalformedXML)
+					}
+				}
+				// Like Set, a document without the first element gets a new one
+				if !s.done && s.matched == 0 {
+					return s.insert(nil, s.segments)
+				}
+				return nil
+			}
+			var syntaxErr *xml.SyntaxError
+			if errors.As(err, &syntaxErr) {
+				return fmt.Errorf("%w: %v", ErrMalformedXML, syntaxErr)
+			}
+			return err
+		}
+		offset := decoder.InputOffset()
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if len(stack) >= MaxNestingDepth {
+				return fmt.Errorf("%w: nesting deeper than %d elements", ErrLimitExceeded, MaxNestingDepth)
+			}
+			stack = append(stack, qualifiedName(t.Name))
+			rootSeen = true
+			if s.done || s.matched == s.elements || len(stack) != s.matched+1 || !s.segments[s.matched].matches(stack[len(stack)-1]) {
+				break
+			}
+			s.matched++
+			tag := s.rec.slice(tokenStart, offset)
+			selfClosing := bytes.HasSuffix(tag, []byte("/>"))
+			switch {
+			case s.matched == s.elements && s.elements < len(s.segments):
+				// An attribute only needs the start tag
+				if err := s.replaceStartTag(tag, selfClosing, stack[len(stack)-1], offset); err != nil {
+					return err
+				}
+			case s.matched == s.elements:
+				captureStart = tokenStart
+			case selfClosing:
+				// The rest of the path is created inside an empty element
+				if s.value != nil {
+					if err := s.insert(tag, s.segments[s.matched-1:]); err != nil {
+						return err
+					}
+					s.written = offset
+				}
+				s.done = true
+			}
+
+		case xml.EndElement:
+			name := qualifiedName(t.Name)
+			if len(stack) == 0 || stack[len(stack)-1] != name {
+				return fmt.Errorf("%w: unexpected closing tag </%s>", ErrMalformedXML, name)
+			}
+			if !s.done && len(stack) == s.matched {
+				if captureStart >= 0 {
+					// The target element is complete. Deleting it drops its
+					// markup, except for the root element, which Set keeps.
+					markup := s.rec.slice(captureStart, offset)
+					if s.value == nil && s.matched > 1 {
+						markup = nil
+					}
+					if err := s.insert(markup, s.segments[s.matched-1:]); err != nil {
+						return err
+					}
+					s.written = offset
+					captureStart = -1
+				} else if s.value != nil {
+					// The rest of the path is missing: create it before the end tag
+					if err := s.insert(nil, s.segments[s.matched:]); err != nil {
+						return err
+					}
+				}
+				s.done = true
+			}
+			stack = stack[:len(stack)-1]
+		}
+
+		if captureStart >= 0 && offset-captureStart > MaxDocumentSize {
+			return fmt.Errorf("%w: edited element exceeds maximum size of %d bytes", ErrLimitExceeded, MaxDocumentSize)
+		}
+	}
+}
+
+// copyTo writes the input up to the stream offset to w and stops recording it.
+func (s *streamSetter) copyTo(offset int64) error {
+	if offset > s.written {
+		if _, err := s.w.Write(s.rec.slice(s.written, offset)); err != nil {
+			return err
+		}
+		s.written = offset
+	}
+	s.rec.discardBefore(s.written)
+	return nil
+}
+
+// insert applies the edit to the standalone markup of one element (or to an
+// empty document when markup is nil) and writes the result to w.
+func (s *streamSetter) insert(markup []byte, segments []PathSegment) error {
+	if markup == nil && s.value == nil {
+		return nil
+	}
+	edited, err := setFragment(markup, segments, s.value)
+	if err != nil {
+		return err
+	}
+	_, err = s.w.Write(edited)
+	return err
+}
+
+// replaceStartTag sets the target attribute on the start tag of the target
+// element and writes the new tag in place of the old one, which ends at
+// offset.
+func (s *streamSetter) replaceStartTag(tag []byte, selfClosing bool, name string, offset int64) error {
+	markup := tag
+	endTag := "</" + name + ">"
+	if !selfClosing {
+		markup = append(append([]byte(nil), tag...), endTag...)
+	}
+	edited, err := setFragment(markup, s.segments[s.matched-1:], s.value)
+	if err != nil {
+		return err
+	}
+	if !selfClosing {
+		edited = bytes.TrimSuffix(edited, []byte(endTag))
+	}
+	if _, err := s.w.Write(edited); err != nil {
+		return err
+	}
+	s.written = offset
+	s.done = true
+	return nil
+}
+
+// setFragment applies Set(markup, segments, value) to a small document, or
+// Delete for a nil value.
+func setFragment(markup []byte, segments []PathSegment, value any) ([]byte, error) {
+	builder := newXMLBuilderWithOptions(markup, DefaultOptions())
+	var err error
+	if value == nil {
+		err = builder.deleteElement(segments)
+	} else {
+		err = builder.setElement(segments, value)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return []byte(builder.getResult()), nil
+}
+
+// parseStreamSetPath parses a SetWriter path, which may only contain element
+// names and a final attribute.
+func parseStreamSetPath(path string) ([]PathSegment, error) {
+	if path == "" {
+		return nil, fmt.Errorf("%w: empty path", ErrInvalidPath)
+	}
+	segments := parsePath(path)
+	if len(segments) == 0 {
+		return nil, ErrInvalidPath
+	}
+	for i, seg := range segments {
+		streamable := seg.Type == SegmentElement ||
+			(seg.Type == SegmentAttribute && i > 0 && i == len(segments)-1)
+		if !streamable || len(seg.Modifiers) > 0 {
+			return nil, fmt.Errorf("%w: SetWriter paths may only contain element names and a final attribute", ErrInvalidPath)
+		}
+	}
+	return segments, nil
+}
+
+// parseStreamPath parses a GetStream path, which may only contain element
+// names and single-level wildcards.
+func parseStreamPath(path string) ([]PathSegment, error) {
+	if path == "" {
+		return nil, fmt.Errorf("%w: empty path", ErrInvalidPath)
+	}
+	segments := parsePath(path)
+	if len(segments) == 0 {
+		return nil, ErrInvalidPath
+	}
+	for _, seg := range segments {
+		streamable := (seg.Type == SegmentElement || (seg.Type == SegmentWildcard && !seg.Wildcard)) &&
+			len(seg.Modifiers) == 0
+		if !streamable {
+			return nil, fmt.Errorf("%w: GetStream paths may only contain element names and * wildcards", ErrInvalidPath)
+		}
+	}
+	return segments, nil
+}
+
+// streamPathMatches reports whether the open elements in stack match
+// segments level by level.
+func streamPathMatches(segments []PathSegment, stack []string) bool {
+	if len(stack) != len(segments) {
+		return false
+	}
+	for i, seg := range segments {
+		if !seg.matches(stack[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// streamElementResult builds the Element Result for the markup of one
+// complete element.
+func streamElementResult(markup []byte) Result {
+	parser := newXMLParser(markup)
+	if !parser.skipToNextElement() {
+		return Result{Type: Null}
+	}
+	parser.next() // skip '<'
+	name, attrs, attrOrder, isSelfClosing := parser.parseElementTag()
+	tag := parser.tag
+	var content string
+	if !isSelfClosing {
+		content = parser.parseElementContent(name)
+	}
+	return newElementResult(elementMatch{
+		name:          name,
+		attrs:         attrs,
+		tag:           tag,
+		attrOrder:     attrOrder,
+		content:       content,
+		isSelfClosing: isSelfClosing,
+	})
+}
+
+// qualifiedName returns an element name as written, including its prefix.
+func qualifiedName(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+	return name.Space + ":" + name.Local
+}
+
+// recordingReader keeps the bytes read by the decoder from a given stream
+// offset on, so that the markup of a matched element can be recovered. It
+// implements io.ByteReader so that the decoder reads exactly what it
+// consumes and its InputOffset lines up with the recorded bytes.
+type recordingReader struct {
+	r     *bufio.Reader
+	buf   []byte
+	start int64 // stream offset of buf[0]
+}
+
+func (rr *recordingReader) Read(p []byte) (int, error) {
+	n, err := rr.r.Read(p)
+	rr.buf = append(rr.buf, p[:n]...)
+	return n, err
+}
+
+func (rr *recordingReader) ReadByte() (byte, error) {
+	c, err := rr.r.ReadByte()
+	if err == nil {
+		rr.buf = append(rr.buf, c)
+	}
+	return c, err
+}
+
+// discardBefore drops recorded bytes before the stream offset.
+func (rr *recordingReader) discardBefore(offset int64) {
+	if n := int(offset - rr.start); n > 0 {
+		rr.buf = append(rr.buf[:0], rr.buf[n:]...)
+		rr.start = offset
+	}
+}
+
+// slice returns the recorded bytes between two stream offsets.
+func (rr *recordingReader) slice(from, to int64) []byte {
+	return rr.buf[from-rr.start : to-rr.start]
+}
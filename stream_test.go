@@ -0,0 +1,344 @@
+// SPDX-License-Identifier: MIT
+// Copyright (c) 2025 Daniel Schmidt
+
+package xmldot
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+// TestGetStream tests streaming matches from a reader
+func TestGetStream(t *testing.T) {
+	xml := `<?xml version="1.0"?>
+<!-- feed -->
+<feed xmlns:m="urn:m">
+  <title>News</title>
+  <item id="1"><name>A &amp; B</name><m:tag>x</m:tag></item>
+  <group><item id="nested"/></group>
+  <item id="2"/>
+  <item id="3"><![CDATA[<raw>]]></item>
+</feed>`
+
+	tests := []struct {
+		name     string
+		path     string
+		expected []string
+	}{
+		{"direct children", "feed.item", []string{"item:1", "item:2", "item:3"}},
+		{"wildcard level", "feed.*.item", []string{"item:nested"}},
+		{"glob", "feed.it*", []string{"item:1", "item:2", "item:3"}},
+		{"all children", "feed.*", []string{"title:", "item:1", "group:", "item:2", "item:3"}},
+		{"namespaced", "feed.item.m:tag", []string{"m:tag:"}},
+		{"no match", "feed.missing", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			err := GetStream(strings.NewReader(xml), tt.path, func(r Result) bool {
+				id := ""
+				for _, attr := range r.Attributes() {
+					if attr.Name == "id" {
+						id = attr.Value
+					}
+				}
+				got = append(got, r.Name()+":"+id)
+				return true
+			})
+			if err != nil {
+				t.Fatalf("GetStream() error = %v", err)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.expected) {
+				t.Errorf("GetStream(%q) = %v, want %v", tt.path, got, tt.expected)
+			}
+		})
+	}
+
+	t.Run("results can be queried", func(t *testing.T) {
+		var names []string
+		err := GetStream(strings.NewReader(xml), "feed.item", func(r Result) bool {
+			if r.Type != Element {
+				t.Errorf("Type = %v, want Element", r.Type)
+			}
+			names = append(names, r.Get("name").String())
+			return true
+		})
+		if err != nil {
+			t.Fatalf("GetStream() error = %v", err)
+		}
+		if fmt.Sprint(names) != "[A & B  ]" {
+			t.Errorf("names = %q", names)
+		}
+	})
+
+	t.Run("stop early", func(t *testing.T) {
+		calls := 0
+		err := GetStream(strings.NewReader(xml), "feed.item", func(Result) bool {
+			calls++
+			return false
+		})
+		if err != nil || calls != 1 {
+			t.Errorf("GetStream() = %v after %d calls, want nil after 1", err, calls)
+		}
+	})
+}
+
+// TestGetStream_Span tests that streamed elements report their offsets in the
+// whole input
+func TestGetStream_Span(t *testing.T) {
+	feed := "<rss>\n<item><title>a</title></item>\n<item/>\n</rss>"
+	var spans []string
+	err := GetStream(strings.NewReader(feed), "rss.item", func(item Result) bool {
+		start, end := item.Span()
+		spans = append(spans, feed[start:end])
+		return true
+	})
+	if err != nil {
+		t.Fatalf("GetStream() error = %v", err)
+	}
+	want := []string{"<item><title>a</title></item>", "<item/>"}
+	if fmt.Sprint(spans) != fmt.Sprint(want) {
+		t.Errorf("spans = %q, want %q", spans, want)
+	}
+}
+
+// TestGetStream_Errors tests GetStream error reporting
+func TestGetStream_Errors(t *testing.T) {
+	noop := func(Result) bool { return true }
+
+	tests := []struct {
+		name    string
+		xml     string
+		path    string
+		wantErr error
+	}{
+		{"empty path", "<a/>", "", ErrInvalidPath},
+		{"attribute path", "<a/>", "a.@id", ErrInvalidPath},
+		{"index path", "<a/>", "a.b.0", ErrInvalidPath},
+		{"recursive wildcard", "<a/>", "a.**.b", ErrInvalidPath},
+		{"modifier", "<a/>", "a.b|@reverse", ErrInvalidPath},
+		{"mismatched tags", "<a><b></c></a>", "a.b", ErrMalformedXML},
+		{"truncated document", "<a><b>", "a.b", ErrMalformedXML},
+		{"too deep", strings.Repeat("<a>", MaxNestingDepth+1), "a.b", ErrLimitExceeded},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := GetStream(strings.NewReader(tt.xml), tt.path, noop)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("GetStream() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	readErr := errors.New("read failed")
+	err := GetStream(io.MultiReader(strings.NewReader("<a><b>1</b>"), &failingReader{readErr}), "a.b", noop)
+	if !errors.Is(err, readErr) {
+		t.Errorf("GetStream() error = %v, want the reader's error", err)
+	}
+}
+
+// TestGetStream_LargeFeed tests streaming a document larger than MaxDocumentSize
+func TestGetStream_LargeFeed(t *testing.T) {
+	const items = 200000 // ~12MB
+	feed := &feedReader{items: items}
+
+	count := 0
+	err := GetStream(feed, "feed.item", func(r Result) bool {
+		if r.Get("n").Int() != int64(count) {
+			t.Fatalf("item %d has n = %s", count, r.Get("n").String())
+		}
+		count++
+		return true
+	})
+	if err != nil {
+		t.Fatalf("GetStream() error = %v", err)
+	}
+	if count != items {
+		t.Errorf("GetStream() visited %d items, want %d", count, items)
+	}
+	if feed.size <= MaxDocumentSize {
+		t.Errorf("feed size %d does not exceed MaxDocumentSize", feed.size)
+	}
+}
+
+// TestSetWriter tests that streaming edits produce the same output as Set
+func TestSetWriter(t *testing.T) {
+	tests := []struct {
+		name  string
+		xml   string
+		path  string
+		value any
+	}{
+		{"replace element", "<?xml version=\"1.0\"?>\n<a>\n  <b>1</b>\n  <c/>\n</a>\n", "a.b", 2},
+		{"first match per level", "<a><b/><b><c/></b></a>", "a.b.c", "v"},
+		{"create in empty parent", "<a><b></b><b><c/></b></a>", "a.b.c.@k", "v"},
+		{"set attribute", `<a><b x='1'>text</b></a>`, "a.b.@k", `v&<"`},
+		{"replace attribute", `<a><b x='1'>text</b></a>`, "a.b.@x", "2"},
+		{"root attribute", `<a><b x="1"/></a>`, "a.@k", "v"},
+		{"expand self-closing parent", "<a><b/></a>", "a.b.y.@z", 1},
+		{"create missing chain", "<a><b/></a>", "a.x.y", "1"},
+		{"raw value", "<a><!-- c --><b>1</b></a>", "a.b", []byte("<x/>")},
+		{"prefixed names", `<s:Env xmlns:s="urn:s"><s:Body/></s:Env>`, "s:Env.s:Body.@id", "1"},
+		{"empty document", "", "a.b.@k", "v"},
+		{"different root", "<a/>", "b.c", "v"},
+		{"delete element", "<a>\n  <b>1</b>\n  <c/>\n</a>", "a.b", nil},
+		{"delete attribute", `<a><b x="1" y="2"/></a>`, "a.b.@x", nil},
+		{"delete missing", "<a><b/></a>", "a.x.y", nil},
+		{"delete root", "<a><b/></a>", "a", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want, err := Set(tt.xml, tt.path, tt.value)
+			if err != nil {
+				t.Fatalf("Set() error = %v", err)
+			}
+			var out bytes.Buffer
+			if err := SetWriter(&out, strings.NewReader(tt.xml), tt.path, tt.value); err != nil {
+				t.Fatalf("SetWriter() error = %v", err)
+			}
+			if out.String() != want {
+				t.Errorf("SetWriter() = %q, want %q", out.String(), want)
+			}
+		})
+	}
+}
+
+// TestSetWriter_Errors tests SetWriter error reporting
+func TestSetWriter_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		xml     string
+		path    string
+		value   any
+		wantErr error
+	}{
+		{"empty path", "<a/>", "", "v", ErrInvalidPath},
+		{"attribute only", "<a/>", "@id", "v", ErrInvalidPath},
+		{"attribute before element", "<a/>", "a.@id.b", "v", ErrInvalidPath},
+		{"index path", "<a/>", "a.b.0", "v", ErrInvalidPath},
+		{"wildcard", "<a/>", "a.*", "v", ErrInvalidPath},
+		{"filter", "<a/>", "a.b.#(c==1)", "v", ErrInvalidPath},
+		{"modifier", "<a/>", "a.b|@reverse", "v", ErrInvalidPath},
+		{"invalid value", "<a/>", "a.b", struct{}{}, ErrInvalidValue},
+		{"mismatched tags", "<a><b></c></a>", "a.b", "v", ErrMalformedXML},
+		{"truncated document", "<a><b>", "a.b", "v", ErrMalformedXML},
+		{"no root element", "<!-- only a comment -->", "a.b", "v", ErrMalformedXML},
+		{"delete in empty document", "", "a.b", nil, ErrMalformedXML},
+		{"too deep", strings.Repeat("<a>", MaxNestingDepth+1), "a.b", "v", ErrLimitExceeded},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := SetWriter(io.Discard, strings.NewReader(tt.xml), tt.path, tt.value)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("SetWriter() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	readErr := errors.New("read failed")
+	err := SetWriter(io.Discard, io.MultiReader(strings.NewReader("<a><b>1</b>"), &failingReader{readErr}), "a.b", "v")
+	if !errors.Is(err, readErr) {
+		t.Errorf("SetWriter() error = %v, want the reader's error", err)
+	}
+
+	writeErr := errors.New("write failed")
+	err = SetWriter(&failingWriter{writeErr}, strings.NewReader("<a><b>1</b></a>"), "a.b", "v")
+	if !errors.Is(err, writeErr) {
+		t.Errorf("SetWriter() error = %v, want the writer's error", err)
+	}
+}
+
+// TestSetWriter_LargeDocument tests editing a document larger than
+// MaxDocumentSize
+func TestSetWriter_LargeDocument(t *testing.T) {
+	const items = 200000 // ~12MB
+	feed := &feedReader{items: items}
+
+	out := &prefixWriter{limit: 128}
+	if err := SetWriter(out, feed, "feed.item.payload", "new"); err != nil {
+		t.Fatalf("SetWriter() error = %v", err)
+	}
+	want := "<feed><item><n>0</n><payload>new</payload></item>\n<item><n>1</n>"
+	if !strings.HasPrefix(out.prefix.String(), want) {
+		t.Errorf("SetWriter() output starts %q, want %q", out.prefix.String(), want)
+	}
+	if delta := len("new") - 32; out.size != feed.size+delta {
+		t.Errorf("SetWriter() wrote %d bytes, want %d", out.size, feed.size+delta)
+	}
+	if feed.size <= MaxDocumentSize {
+		t.Errorf("feed size %d does not exceed MaxDocumentSize", feed.size)
+	}
+}
+
+// prefixWriter counts the bytes written and keeps the first limit of them.
+type prefixWriter struct {
+	limit  int
+	prefix bytes.Buffer
+	size   int
+}
+
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	if room := w.limit - w.prefix.Len(); room > 0 {
+		w.prefix.Write(p[:min(room, len(p))])
+	}
+	w.size += len(p)
+	return len(p), nil
+}
+
+// failingWriter returns err from every Write.
+type failingWriter struct {
+	err error
+}
+
+func (f *failingWriter) Write([]byte) (int, error) {
+	return 0, f.err
+}
+
+// feedReader generates <feed><item>...</item>...</feed> on the fly.
+type feedReader struct {
+	items   int
+	written int
+	pending []byte
+	opened  bool
+	closed  bool
+	size    int
+}
+
+func (f *feedReader) Read(p []byte) (int, error) {
+	for len(f.pending) == 0 {
+		switch {
+		case !f.opened:
+			f.pending = []byte("<feed>")
+			f.opened = true
+		case f.written < f.items:
+			f.pending = fmt.Appendf(nil, "<item><n>%d</n><payload>%s</payload></item>\n", f.written, strings.Repeat("x", 32))
+			f.written++
+		case !f.closed:
+			f.pending = []byte("</feed>")
+			f.closed = true
+		default:
+			return 0, io.EOF
+		}
+	}
+	n := copy(p, f.pending)
+	f.pending = f.pending[n:]
+	f.size += n
+	return n, nil
+}
+
+// failingReader returns err from every Read.
+type failingReader struct {
+	err error
+}
+
+func (f *failingReader) Read([]byte) (int, error) {
+	return 0, f.err
+}
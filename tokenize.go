@@ -0,0 +1,258 @@
+// SPDX-License-Identifier: MIT
+// Copyright (c) 2025 Daniel Schmidt
+
+package xmldot
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// TokenHandler receives the tokens of a document from Tokenize, in document
+// order. If a method returns an error, tokenizing stops and Tokenize returns
+// that error unchanged.
+type TokenHandler interface {
+	// StartElement is called for each start tag, with the element name as
+	// written (including any prefix) and its attributes in document order.
+	// A self-closing element is reported as a StartElement followed by an
+	// EndElement.
+	StartElement(name string, attrs []Attr) error
+
+	// EndElement is called for each end tag.
+	EndElement(name string) error
+
+	// CharData is called for each run of text inside an element, with
+	// entities unescaped. A CDATA section is reported as its own CharData
+	// call, with its content unchanged.
+	CharData(text string) error
+
+	// Comment is called for each comment, with the text between "<!--" and
+	// "-->".
+	Comment(text string) error
+
+	// ProcInst is called for each processing instruction, including the XML
+	// declaration, with the target name and the rest of the instruction.
+	ProcInst(target, inst string) error
+}
+
+// Tokenize reads xml from start to end and reports each token to handler.
+// It gives access to the document below the level of paths, for processing
+// that needs every element in order, without loading encoding/xml.
+//
+// Tokenize applies the same security limits as Get: documents larger than
+// MaxDocumentSize, nesting deeper than MaxNestingDepth, elements with more
+// than MaxAttributes attributes and text, comments or instructions longer
+// than MaxTokenSize return an error wrapping ErrLimitExceeded. DOCTYPE
+// declarations are skipped without being reported. A mismatched or missing
+// end tag, an unterminated comment, CDATA section or instruction, and text
+// outside the root element return an error wrapping ErrMalformedXML; the
+// tokens before the error have already been reported.
+//
+// Example:
+//
+//	err := xmldot.Tokenize(xml, handler)
+//	if errors.Is(err, xmldot.ErrMalformedXML) {
+//	    // handle the broken document
+//	}
+func Tokenize(xml string, handler TokenHandler) error {
+	return TokenizeBytes(stringToBytes(xml), handler)
+}
+
+// TokenizeBytes is like Tokenize but accepts xml as a byte slice.
+func TokenizeBytes(xml []byte, handler TokenHandler) error {
+	if len(xml) > MaxDocumentSize {
+		return fmt.Errorf("%w: document exceeds maximum size of %d bytes", ErrLimitExceeded, MaxDocumentSize)
+	}
+	t := &tokenizer{p: newXMLParser(xml), h: handler}
+	return t.run()
+}
+
+// tokenizer walks a document for Tokenize, tracking the open elements.
+type tokenizer struct {
+	p     *xmlParser
+	h     TokenHandler
+	stack []string
+}
+
+// run reports every token of the document to the handler.
+func (t *tokenizer) run() error {
+	p := t.p
+	for p.pos < p.dataLen {
+		i := bytes.IndexByte(p.data[p.pos:], '<')
+		if i < 0 {
+			i = p.dataLen - p.pos
+		}
+		if i > 0 {
+			if err := t.text(p.data[p.pos : p.pos+i]); err != nil {
+				return err
+			}
+			p.pos += i
+			continue
+		}
+
+		var err error
+		rest := p.data[p.pos:]
+		switch {
+		case bytes.HasPrefix(rest, []byte("<!--")):
+			err = t.delimited(4, "-->", "comment", t.h.Comment)
+		case bytes.HasPrefix(rest, []byte("<![CDATA[")):
+			err = t.delimited(9, "]]>", "CDATA section", t.cdata)
+		case bytes.HasPrefix(rest, []byte("<!")):
+			// DOCTYPE and other declarations are not reported
+			t.skipDeclaration()
+		case bytes.HasPrefix(rest, []byte("<?")):
+			err = t.delimited(2, "?>", "processing instruction", t.procInst)
+		case bytes.HasPrefix(rest, []byte("</")):
+			err = t.endTag()
+		default:
+			err = t.startTag()
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(t.stack) > 0 {
+		return fmt.Errorf("%w: unexpected end of document inside <%s>", ErrMalformedXML, t.stack[len(t.stack)-1])
+	}
+	return nil
+}
+
+// text reports a run of character data. Whitespace between top-level
+// constructs is not reported; other text there is malformed.
+func (t *tokenizer) text(data []byte) error {
+	if len(data) > MaxTokenSize {
+		return fmt.Errorf("%w: text exceeds maximum token size of %d bytes", ErrLimitExceeded, MaxTokenSize)
+	}
+	if len(t.stack) == 0 {
+		if len(bytes.TrimLeft(data, " \t\n\r")) > 0 {
+			return fmt.Errorf("%w: text outside root element at offset %d", ErrMalformedXML, t.p.pos)
+		}
+		return nil
+	}
+	return t.h.CharData(unescapeXML(string(data)))
+}
+
+// delimited reports the token that starts with an opening marker of
+// openLen bytes and runs up to the closing marker.
+func (t *tokenizer) delimited(openLen int, closing, kind string, report func(string) error) error {
+	p := t.p
+	start := p.pos + openLen
+	end := bytes.Index(p.data[start:], []byte(closing))
+	if end < 0 {
+		return fmt.Errorf("%w: unterminated %s at offset %d", ErrMalformedXML, kind, p.pos)
+	}
+	if end > MaxTokenSize {
+		return fmt.Errorf("%w: %s exceeds maximum token size of %d bytes", ErrLimitExceeded, kind, MaxTokenSize)
+	}
+	p.pos = start + end + len(closing)
+	return report(string(p.data[start : start+end]))
+}
+
+// cdata reports the content of a CDATA section.
+func (t *tokenizer) cdata(text string) error {
+	if len(t.stack) == 0 {
+		return fmt.Errorf("%w: CDATA section outside root element", ErrMalformedXML)
+	}
+	return t.h.CharData(text)
+}
+
+// procInst splits a processing instruction into its target and the rest.
+func (t *tokenizer) procInst(text string) error {
+	target, inst := text, ""
+	if i := strings.IndexAny(text, " \t\n\r"); i >= 0 {
+		target, inst = text[:i], strings.TrimSpace(text[i+1:])
+	}
+	return t.h.ProcInst(target, inst)
+}
+
+// skipDeclaration skips a declaration such as DOCTYPE, including an
+// internal subset in brackets.
+func (t *tokenizer) skipDeclaration() {
+	p := t.p
+	depth := 0
+	for p.pos < p.dataLen {
+		c := p.next()
+		if c == '[' {
+			depth++
+		} else if c == ']' {
+			depth--
+		} else if c == '>' && depth <= 0 {
+			return
+		}
+	}
+}
+
+// startTag reads a start tag and reports it, along with the end of a
+// self-closing element.
+func (t *tokenizer) startTag() error {
+	p := t.p
+	tagStart := p.pos
+	p.next() // skip '<'
+	nameStart := p.pos
+	name := p.readUntilAny(" \t\n\r/>")
+	if name == "" {
+		if p.pos-nameStart > MaxTokenSize {
+			return fmt.Errorf("%w: element name exceeds maximum token size of %d bytes", ErrLimitExceeded, MaxTokenSize)
+		}
+		return fmt.Errorf("%w: empty element name at offset %d", ErrMalformedXML, tagStart)
+	}
+	if len(t.stack) >= MaxNestingDepth {
+		return fmt.Errorf("%w: nesting deeper than %d elements", ErrLimitExceeded, MaxNestingDepth)
+	}
+
+	values, order, count := p.parseAttributeListCounted()
+	p.skipWhitespace()
+	if count >= MaxAttributes {
+		if c := p.peek(); p.pos < p.dataLen && c != '>' && c != '/' {
+			return fmt.Errorf("%w: <%s> has more than %d attributes", ErrLimitExceeded, name, MaxAttributes)
+		}
+	}
+
+	isSelfClosing := false
+	if p.peek() == '/' {
+		p.next()
+		isSelfClosing = true
+	}
+	if p.peek() != '>' {
+		return fmt.Errorf("%w: malformed start tag <%s> at offset %d", ErrMalformedXML, name, tagStart)
+	}
+	p.next()
+
+	var attrs []Attr
+	if len(order) > 0 {
+		attrs = make([]Attr, len(order))
+		for i, attrName := range order {
+			attrs[i] = Attr{Name: attrName, Value: values[attrName]}
+		}
+	}
+	if err := t.h.StartElement(name, attrs); err != nil {
+		return err
+	}
+	if isSelfClosing {
+		return t.h.EndElement(name)
+	}
+	t.stack = append(t.stack, name)
+	return nil
+}
+
+// endTag reads an end tag, checks it closes the innermost open element and
+// reports it.
+func (t *tokenizer) endTag() error {
+	p := t.p
+	tagStart := p.pos
+	p.pos += 2 // skip "</"
+	end := bytes.IndexByte(p.data[p.pos:], '>')
+	if end < 0 {
+		return fmt.Errorf("%w: unterminated end tag at offset %d", ErrMalformedXML, tagStart)
+	}
+	name := strings.TrimRight(string(p.data[p.pos:p.pos+end]), " \t\n\r")
+	p.pos += end + 1
+
+	if len(t.stack) == 0 || t.stack[len(t.stack)-1] != name {
+		return fmt.Errorf("%w: unexpected closing tag </%s> at offset %d", ErrMalformedXML, name, tagStart)
+	}
+	t.stack = t.stack[:len(t.stack)-1]
+	return t.h.EndElement(name)
+}
@@ -0,0 +1,148 @@
+// SPDX-License-Identifier: MIT
+// Copyright (c) 2025 Daniel Schmidt
+
+package xmldot
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// recordingHandler records each token as a line of text.
+type recordingHandler struct {
+	events []string
+	stopAt string // element name whose start tag returns errStop
+}
+
+var errStop = errors.New("stop")
+
+func (h *recordingHandler) StartElement(name string, attrs []Attr) error {
+	event := "start " + name
+	for _, a := range attrs {
+		event += fmt.Sprintf(" %s=%q", a.Name, a.Value)
+	}
+	h.events = append(h.events, event)
+	if name == h.stopAt {
+		return errStop
+	}
+	return nil
+}
+
+func (h *recordingHandler) EndElement(name string) error {
+	h.events = append(h.events, "end "+name)
+	return nil
+}
+
+func (h *recordingHandler) CharData(text string) error {
+	if strings.TrimSpace(text) != "" {
+		h.events = append(h.events, fmt.Sprintf("text %q", text))
+	}
+	return nil
+}
+
+func (h *recordingHandler) Comment(text string) error {
+	h.events = append(h.events, fmt.Sprintf("comment %q", text))
+	return nil
+}
+
+func (h *recordingHandler) ProcInst(target, inst string) error {
+	h.events = append(h.events, fmt.Sprintf("pi %s %q", target, inst))
+	return nil
+}
+
+func TestTokenize(t *testing.T) {
+	xml := `<?xml version="1.0"?>
+<!DOCTYPE catalog [<!ENTITY x "y">]>
+<catalog xmlns:dc="http://purl.org/dc">
+	<!-- first book -->
+	<book id="1" lang='en'><dc:title>Go &amp; More</dc:title></book>
+	<book id="2"/>
+	<note><![CDATA[<raw> & text]]></note>
+	<?render fast?>
+</catalog>`
+
+	h := &recordingHandler{}
+	if err := Tokenize(xml, h); err != nil {
+		t.Fatalf("Tokenize() error = %v", err)
+	}
+
+	want := []string{
+		`pi xml "version=\"1.0\""`,
+		`start catalog xmlns:dc="http://purl.org/dc"`,
+		`comment " first book "`,
+		`start book id="1" lang="en"`,
+		`start dc:title`,
+		`text "Go & More"`,
+		`end dc:title`,
+		`end book`,
+		`start book id="2"`,
+		`end book`,
+		`start note`,
+		`text "<raw> & text"`,
+		`end note`,
+		`pi render "fast"`,
+		`end catalog`,
+	}
+	if got := strings.Join(h.events, "\n"); got != strings.Join(want, "\n") {
+		t.Errorf("Tokenize() events:\n%s\nwant:\n%s", got, strings.Join(want, "\n"))
+	}
+
+	// The byte slice variant reports the same tokens
+	hb := &recordingHandler{}
+	if err := TokenizeBytes([]byte(xml), hb); err != nil {
+		t.Fatalf("TokenizeBytes() error = %v", err)
+	}
+	if strings.Join(hb.events, "\n") != strings.Join(want, "\n") {
+		t.Errorf("TokenizeBytes() events = %v", hb.events)
+	}
+}
+
+func TestTokenize_HandlerError(t *testing.T) {
+	h := &recordingHandler{stopAt: "b"}
+	err := Tokenize(`<root><a/><b/><c/></root>`, h)
+	if !errors.Is(err, errStop) {
+		t.Fatalf("Tokenize() error = %v, want handler error", err)
+	}
+	want := []string{"start root", "start a", "end a", "start b"}
+	if strings.Join(h.events, ",") != strings.Join(want, ",") {
+		t.Errorf("events = %v, want %v", h.events, want)
+	}
+}
+
+func TestTokenize_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		xml  string
+		want error
+	}{
+		{"mismatched end tag", `<root><a></b></root>`, ErrMalformedXML},
+		{"missing end tag", `<root><a></a>`, ErrMalformedXML},
+		{"stray end tag", `<root/></extra>`, ErrMalformedXML},
+		{"unterminated comment", `<root><!-- open</root>`, ErrMalformedXML},
+		{"unterminated CDATA", `<root><![CDATA[open</root>`, ErrMalformedXML},
+		{"unterminated instruction", `<?xml version="1.0"<root/>`, ErrMalformedXML},
+		{"unterminated start tag", `<root`, ErrMalformedXML},
+		{"text outside root", `<root/>trailing`, ErrMalformedXML},
+		{"nesting too deep", strings.Repeat("<a>", MaxNestingDepth+1) + strings.Repeat("</a>", MaxNestingDepth+1), ErrLimitExceeded},
+		{"too many attributes", `<root` + strings.Repeat(` a="1"`, MaxAttributes) + ` b="2"/>`, ErrLimitExceeded},
+		{"token too large", `<root>` + strings.Repeat("x", MaxTokenSize+1) + `</root>`, ErrLimitExceeded},
+		{"document too large", `<root>` + strings.Repeat("<a/>", MaxDocumentSize/4) + `</root>`, ErrLimitExceeded},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Tokenize(tt.xml, &recordingHandler{})
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Tokenize() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	// Nesting up to the limit is accepted
+	deep := strings.Repeat("<a>", MaxNestingDepth) + strings.Repeat("</a>", MaxNestingDepth)
+	if err := Tokenize(deep, &recordingHandler{}); err != nil {
+		t.Errorf("Tokenize() at MaxNestingDepth error = %v", err)
+	}
+}
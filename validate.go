@@ -10,6 +10,10 @@ type ValidateError struct {
 	Line    int
 	Column  int
 	Message string
+
+	// limit records that the document broke a resource limit (size, depth,
+	// attributes, token size) rather than a well-formedness rule
+	limit bool
 }
 
 func (e *ValidateError) Error() string {
@@ -24,6 +28,11 @@ type validatingParser struct {
 	tagStack   []tagInfo // Stack of open tags for nesting validation
 	rootFound  bool      // Track if we've found a root element
 	rootClosed bool      // Track if the root element has been closed
+	rootName   string    // Name of the first root element
+	singleRoot bool      // Reject a second root element (Options without AllowFragment)
+
+	maxDepth      int // Nesting depth limit (MaxNestingDepth unless configured)
+	maxAttributes int // Per-element attribute limit (MaxAttributes unless configured)
 }
 
 // tagInfo tracks information about an open tag
@@ -42,9 +51,11 @@ func newValidatingParser(data []byte) *validatingParser {
 			depth:   0,
 			dataLen: len(data),
 		},
-		line:     1,
-		column:   0,
-		tagStack: make([]tagInfo, 0, 16), // Pre-allocate for typical nesting depth
+		line:          1,
+		column:        0,
+		tagStack:      make([]tagInfo, 0, 16), // Pre-allocate for typical nesting depth
+		maxDepth:      MaxNestingDepth,
+		maxAttributes: MaxAttributes,
 	}
 }
 
@@ -83,6 +94,7 @@ func (p *validatingParser) validate() *ValidateError {
 			Line:    1,
 			Column:  0,
 			Message: fmt.Sprintf("document exceeds maximum size of %d bytes", MaxDocumentSize),
+			limit:   true,
 		}
 	}
 
@@ -151,6 +163,35 @@ func (p *validatingParser) validate() *ValidateError {
 	return nil
 }
 
+// readRootName validates the document up to and including the opening tag of
+// the root element and returns its name. The rest of the document is not read.
+func (p *validatingParser) readRootName() (string, *ValidateError) {
+	for p.pos < p.dataLen && !p.rootFound {
+		p.skipWhitespaceTracked()
+		if p.pos >= p.dataLen {
+			break
+		}
+		if p.peekChar() != '<' {
+			return "", &ValidateError{
+				Line:    p.line,
+				Column:  p.column,
+				Message: "content not allowed outside root element",
+			}
+		}
+		if err := p.parseTag(); err != nil {
+			return "", err
+		}
+	}
+	if !p.rootFound {
+		return "", &ValidateError{
+			Line:    1,
+			Column:  0,
+			Message: "no root element found",
+		}
+	}
+	return p.rootName, nil
+}
+
 // skipWhitespaceTracked skips whitespace while tracking line/column
 func (p *validatingParser) skipWhitespaceTracked() {
 	for p.pos < p.dataLen && isWhitespace(p.data[p.pos]) {
@@ -178,7 +219,7 @@ func (p *validatingParser) parseTag() *ValidateError {
 	// Processing instruction
 	if next == '?' {
 		p.advance()
-		return p.skipUntilTracked('>')
+		return p.skipProcessingInstruction(tagLine, tagColumn, p.pos-2)
 	}
 
 	// Comment or CDATA
@@ -237,6 +278,7 @@ func (p *validatingParser) parseOpeningTag(tagLine, tagColumn int) *ValidateErro
 				Line:    nameLine,
 				Column:  nameColumn,
 				Message: "element name exceeds maximum token size",
+				limit:   true,
 			}
 		}
 		return &ValidateError{
@@ -256,11 +298,12 @@ func (p *validatingParser) parseOpeningTag(tagLine, tagColumn int) *ValidateErro
 	}
 
 	// Check nesting depth
-	if len(p.tagStack) >= MaxNestingDepth {
+	if len(p.tagStack) >= p.maxDepth {
 		return &ValidateError{
 			Line:    tagLine,
 			Column:  tagColumn,
-			Message: fmt.Sprintf("nesting depth exceeds maximum of %d", MaxNestingDepth),
+			Message: fmt.Sprintf("nesting depth exceeds maximum of %d", p.maxDepth),
+			limit:   true,
 		}
 	}
 
@@ -290,8 +333,16 @@ func (p *validatingParser) parseOpeningTag(tagLine, tagColumn int) *ValidateErro
 	// Track root element
 	if !p.rootFound {
 		p.rootFound = true
+		p.rootName = name
 	} else if len(p.tagStack) == 0 && p.rootClosed {
 		// Fragment support: Starting a new root element after closing previous one
+		if p.singleRoot {
+			return &ValidateError{
+				Line:    tagLine,
+				Column:  tagColumn,
+				Message: fmt.Sprintf("second root element <%s> after <%s>", name, p.rootName),
+			}
+		}
 		p.rootClosed = false
 	}
 
@@ -312,6 +363,8 @@ func (p *validatingParser) parseOpeningTag(tagLine, tagColumn int) *ValidateErro
 
 // parseClosingTag parses a closing tag
 func (p *validatingParser) parseClosingTag(tagLine, tagColumn int) *ValidateError {
+	// Byte offset of the "</" that starts this tag
+	tagOffset := p.pos - 2
 	nameLine := p.line
 	nameColumn := p.column
 
@@ -351,7 +404,7 @@ func (p *validatingParser) parseClosingTag(tagLine, tagColumn int) *ValidateErro
 		return &ValidateError{
 			Line:    tagLine,
 			Column:  tagColumn,
-			Message: fmt.Sprintf("mismatched closing tag '%s' (expected '%s' opened at line %d, column %d)", name, openTag.name, openTag.line, openTag.column),
+			Message: fmt.Sprintf("mismatched closing tag: expected </%s>, found </%s> at offset %d (opened at line %d, column %d)", openTag.name, name, tagOffset, openTag.line, openTag.column),
 		}
 	}
 
@@ -387,11 +440,12 @@ func (p *validatingParser) parseAttributesValidating() *ValidateError {
 		}
 
 		// Check attribute limit
-		if attrCount >= MaxAttributes {
+		if attrCount >= p.maxAttributes {
 			return &ValidateError{
 				Line:    p.line,
 				Column:  p.column,
-				Message: fmt.Sprintf("too many attributes (maximum %d)", MaxAttributes),
+				Message: fmt.Sprintf("too many attributes (maximum %d)", p.maxAttributes),
+				limit:   true,
 			}
 		}
 
@@ -487,6 +541,7 @@ func (p *validatingParser) skipUntilTracked(delim byte) *ValidateError {
 				Line:    p.line,
 				Column:  p.column,
 				Message: "token exceeds maximum size",
+				limit:   true,
 			}
 		}
 
@@ -530,6 +585,35 @@ func (p *validatingParser) skipComment() *ValidateError {
 	}
 }
 
+// skipProcessingInstruction skips a processing instruction up to its closing
+// "?>". An unterminated PI is reported at its start (line, column and byte
+// offset), since the end of the document says little about where it went wrong.
+func (p *validatingParser) skipProcessingInstruction(line, column, offset int) *ValidateError {
+	start := p.pos
+	for p.pos < p.dataLen-1 {
+		if p.pos-start > MaxTokenSize {
+			return &ValidateError{
+				Line:    p.line,
+				Column:  p.column,
+				Message: "token exceeds maximum size",
+				limit:   true,
+			}
+		}
+		if p.data[p.pos] == '?' && p.data[p.pos+1] == '>' {
+			p.advance()
+			p.advance()
+			return nil
+		}
+		p.advance()
+	}
+
+	return &ValidateError{
+		Line:    line,
+		Column:  column,
+		Message: fmt.Sprintf("unterminated processing instruction at offset %d", offset),
+	}
+}
+
 // skipCDATA skips a CDATA section
 func (p *validatingParser) skipCDATA() *ValidateError {
 	for p.pos < p.dataLen-2 {
@@ -612,6 +696,78 @@ func ValidBytes(xml []byte) bool {
 	return parser.validate() == nil
 }
 
+// ValidWithOptions checks that xml is well-formed and within the resource
+// limits, for admitting documents from untrusted sources in one call. It
+// returns nil for an acceptable document. A document that is not well-formed
+// returns an error wrapping ErrMalformedXML; one that exceeds a limit returns
+// an error wrapping ErrLimitExceeded that names the limit, e.g.
+// "limit exceeded: too many attributes (maximum 10) at line 3, column 12".
+//
+// The limits are MaxDocumentSize, the nesting limit (opts.MaxNestingDepth
+// when set, otherwise MaxNestingDepth), MaxTokenSize and the per-element
+// attribute limit, which is opts.MaxAttributes when set (whether or not
+// AttributeOverflowError is). With opts.RejectDuplicateAttributes,
+// repeated attributes are reported as malformed, and unless
+// opts.AllowFragment is set a document with more than one root element is
+// as well. nil options check the package-level limits only and, like Valid,
+// accept fragments.
+//
+// Example:
+//
+//	opts := &xmldot.Options{MaxAttributes: 10}
+//	if err := xmldot.ValidWithOptions(upload, opts); errors.Is(err, xmldot.ErrLimitExceeded) {
+//	    return fmt.Errorf("document too large: %w", err)
+//	}
+func ValidWithOptions(xml string, opts *Options) error {
+	return ValidBytesWithOptions([]byte(xml), opts)
+}
+
+// ValidBytesWithOptions is like ValidWithOptions but accepts xml as a byte
+// slice.
+func ValidBytesWithOptions(xml []byte, opts *Options) error {
+	parser := newValidatingParser(xml)
+	parser.maxDepth = opts.nestingLimit()
+	if opts != nil {
+		parser.maxAttributes = opts.attributeLimit()
+		parser.singleRoot = !opts.AllowFragment
+	}
+	if err := parser.validate(); err != nil {
+		if err.limit {
+			return fmt.Errorf("%w: %s at line %d, column %d", ErrLimitExceeded, err.Message, err.Line, err.Column)
+		}
+		return fmt.Errorf("%w: %s at line %d, column %d", ErrMalformedXML, err.Message, err.Line, err.Column)
+	}
+	if opts != nil && opts.RejectDuplicateAttributes && hasDuplicateAttributes(xml) {
+		return fmt.Errorf("%w: duplicate attribute", ErrMalformedXML)
+	}
+	return nil
+}
+
+// checkWellFormed validates xml for write operations, wrapping the location
+// details of any failure in ErrMalformedXML.
+func checkWellFormed(xml []byte) error {
+	if err := ValidateBytesWithError(xml); err != nil {
+		return fmt.Errorf("%w: %s", ErrMalformedXML, err.Message)
+	}
+	return nil
+}
+
+// checkQueryWellFormed validates xml for QueryWithOptions in Strict mode.
+// Nesting deeper than the Options limit, like the other resource limits,
+// returns ErrLimitExceeded; other problems return ErrMalformedXML.
+func checkQueryWellFormed(xml []byte, opts *Options) error {
+	parser := newValidatingParser(xml)
+	parser.maxDepth = opts.nestingLimit()
+	parser.singleRoot = !opts.AllowFragment
+	if err := parser.validate(); err != nil {
+		if err.limit {
+			return fmt.Errorf("%w: %s at line %d, column %d", ErrLimitExceeded, err.Message, err.Line, err.Column)
+		}
+		return fmt.Errorf("%w: %s", ErrMalformedXML, err.Message)
+	}
+	return nil
+}
+
 // ValidateWithError checks XML and returns detailed error on failure
 // Returns nil if valid, *ValidateError otherwise
 func ValidateWithError(xml string) *ValidateError {
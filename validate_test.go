@@ -4,6 +4,7 @@
 package xmldot
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 	"testing"
@@ -704,7 +705,7 @@ func ExampleValidateWithError() {
 	if err != nil {
 		fmt.Printf("Validation error: %s\n", err.Message)
 	}
-	// Output: Validation error: mismatched closing tag 'root' (expected 'person' opened at line 1, column 6)
+	// Output: Validation error: mismatched closing tag: expected </person>, found </root> at offset 31 (opened at line 1, column 6)
 }
 
 // ExampleValid_fragment demonstrates validation of XML fragments with multiple roots
@@ -809,3 +810,50 @@ func TestValidateDOCTYPE(t *testing.T) {
 		})
 	}
 }
+
+func TestValidWithOptions(t *testing.T) {
+	deep := strings.Repeat("<a>", MaxNestingDepth+1) + strings.Repeat("</a>", MaxNestingDepth+1)
+	manyAttrs := `<root><item a="1" b="2" c="3"/></root>`
+
+	tests := []struct {
+		name    string
+		xml     string
+		opts    *Options
+		wantErr error
+		wantMsg string
+	}{
+		{"valid", `<root><item a="1"/></root>`, nil, nil, ""},
+		{"malformed", `<root><item></root>`, nil, ErrMalformedXML, "mismatched closing tag"},
+		{"empty", ``, &Options{}, ErrMalformedXML, "empty document"},
+		{"too deep", deep, nil, ErrLimitExceeded, "nesting depth exceeds maximum of 100"},
+		{"attributes within limit", manyAttrs, &Options{MaxAttributes: 3}, nil, ""},
+		{"too many attributes", manyAttrs, &Options{MaxAttributes: 2}, ErrLimitExceeded, "too many attributes (maximum 2) at line 1"},
+		{"too large", "<r>" + strings.Repeat("x", MaxDocumentSize) + "</r>", nil, ErrLimitExceeded, "maximum size"},
+		{"duplicate tolerated", `<r a="1" a="2"/>`, &Options{}, nil, ""},
+		{"duplicate rejected", `<r a="1" a="2"/>`, &Options{RejectDuplicateAttributes: true}, ErrMalformedXML, "duplicate attribute"},
+		{"fragment without options", `<a/><b/>`, nil, nil, ""},
+		{"fragment rejected", `<a/> <b/>`, &Options{}, ErrMalformedXML, "second root element <b> after <a> at line 1"},
+		{"fragment allowed", `<a/><b/>`, &Options{AllowFragment: true}, nil, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidWithOptions(tt.xml, tt.opts)
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Fatalf("ValidWithOptions() error = %v, want nil", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ValidWithOptions() error = %v, want %v", err, tt.wantErr)
+			}
+			if !strings.Contains(err.Error(), tt.wantMsg) {
+				t.Errorf("ValidWithOptions() error = %q, want it to contain %q", err, tt.wantMsg)
+			}
+			if bytesErr := ValidBytesWithOptions([]byte(tt.xml), tt.opts); bytesErr == nil || bytesErr.Error() != err.Error() {
+				t.Errorf("ValidBytesWithOptions() error = %v, want %v", bytesErr, err)
+			}
+		})
+	}
+}
@@ -257,6 +257,149 @@ func TestRecursiveWildcard(t *testing.T) {
 	}
 }
 
+// TestRecursiveWildcardIndex tests indexing and counting recursive wildcard
+// matches as one array in document order
+func TestRecursiveWildcardIndex(t *testing.T) {
+	xml := `<root><a><price cur="EUR">1</price><b><price>2</price></b></a><price>3</price><c><price>4</price></c></root>`
+
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{"root.**.price.0", "1"},
+		{"root.**.price.1", "2"},
+		{"root.**.price.-1", "4"},
+		{"root.**.price.-4", "1"},
+		{"root.**.price.4", ""},
+		{"root.**.price.#", "4"},
+		{"root.**.price.0.@cur", "EUR"},
+		{"root.**.price.-1|@first", "4"},
+		{"root.**.price|@first", "1"},
+		{"root.**.price|@last", "4"},
+		{"**.price.2", "3"},
+		{"root.**.b.price.0", "2"},
+	}
+
+	for _, tt := range tests {
+		for _, opts := range []*Options{nil, {CaseSensitive: false}} {
+			if got := GetWithOptions(xml, tt.path, opts).String(); got != tt.expected {
+				t.Errorf("GetWithOptions(%q, %+v) = %q, want %q", tt.path, opts, got, tt.expected)
+			}
+		}
+	}
+
+	if got := GetWithOptions(xml, "root.**.price.0", &Options{CaseSensitive: true, RecursiveOrder: BreadthFirst}).String(); got != "3" {
+		t.Errorf("breadth-first root.**.price.0 = %q, want 3", got)
+	}
+}
+
+// TestRecursiveWildcardIndex_ChildNames tests that an index after child names
+// counts the children of all recursive matches together
+func TestRecursiveWildcardIndex_ChildNames(t *testing.T) {
+	xml := `<root><dept><employee><name>A</name><name>A2</name></employee>` +
+		`<team><employee><name>B</name></employee></team></dept>` +
+		`<employee><name>C</name></employee><name>X</name></root>`
+
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{"root.**.employee.name.0", "A"},
+		{"root.**.employee.name.1", "A2"},
+		{"root.**.employee.name.2", "B"},
+		{"root.**.employee.name.3", "C"},
+		{"root.**.employee.name.4", ""},
+		{"root.**.employee.name.-1", "C"},
+		{"root.**.employee.name.#", "4"},
+		{"root.**.employee.name.#.%", `["A","A2","B","C"]`},
+		{"root.**.employee.1.name", "B"},
+		{"root.**.employee.name", `["A","B","C"]`}, // without an index, the first name of each
+	}
+
+	for _, tt := range tests {
+		if got := Get(xml, tt.path).String(); got != tt.expected {
+			t.Errorf("Get(%q) = %q, want %q", tt.path, got, tt.expected)
+		}
+	}
+
+	// Repeated queries must not see segments changed by an earlier one
+	for i := 0; i < 2; i++ {
+		if got := Get(xml, "root.**.employee.name.1").String(); got != "A2" {
+			t.Errorf("run %d: got %q, want A2", i, got)
+		}
+	}
+
+	opts := &Options{RecursiveOrder: BreadthFirst}
+	if got := GetWithOptions(xml, "root.**.employee.name.0", opts).String(); got != "C" {
+		t.Errorf("breadth-first root.**.employee.name.0 = %q, want C", got)
+	}
+}
+
+// TestGlobWildcard tests glob-style name patterns (prefix*, *suffix, ?)
+func TestGlobWildcard(t *testing.T) {
+	xml := `<config>
+		<db_host>localhost</db_host>
+		<db_port>5432</db_port>
+		<base_url>http://a</base_url>
+		<api_url>http://b</api_url>
+		<name>app</name>
+		<item1>x</item1>
+		<item22>y</item22>
+		<app:db_user xmlns:app="urn:app">admin</app:db_user>
+	</config>`
+
+	tests := []struct {
+		name     string
+		path     string
+		expected []string
+	}{
+		{"prefix glob", "config.db_*", []string{"localhost", "5432", "admin"}},
+		{"suffix glob", "config.*_url", []string{"http://a", "http://b"}},
+		{"single character", "config.item?", []string{"x"}},
+		{"two single characters", "config.item??", []string{"y"}},
+		{"prefixed glob matches full name", "config.app:db_*", []string{"admin"}},
+		{"recursive glob", "config.**.*_url", []string{"http://a", "http://b"}},
+		{"no match", "config.cache_*", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Get(xml, tt.path)
+			if tt.expected == nil {
+				if result.Exists() {
+					t.Errorf("Get(%q) should not exist, got %v", tt.path, result)
+				}
+				return
+			}
+			values := result.Array()
+			if len(values) != len(tt.expected) {
+				t.Fatalf("Get(%q) returned %d results, want %d", tt.path, len(values), len(tt.expected))
+			}
+			for i, v := range values {
+				if v.String() != tt.expected[i] {
+					t.Errorf("result[%d] = %q, want %q", i, v.String(), tt.expected[i])
+				}
+				if v.Raw != tt.expected[i] {
+					t.Errorf("result[%d].Raw = %q, want %q", i, v.Raw, tt.expected[i])
+				}
+			}
+		})
+	}
+
+	t.Run("case-insensitive", func(t *testing.T) {
+		result := GetWithOptions(xml, "CONFIG.DB_*", &Options{CaseSensitive: false})
+		if n := len(result.Array()); n != 3 {
+			t.Errorf("case-insensitive glob returned %d results, want 3", n)
+		}
+	})
+
+	t.Run("continue matching below glob", func(t *testing.T) {
+		if got := Get(`<c><db_a><v>1</v></db_a><db_b><v>2</v></db_b></c>`, "c.db_*.v").Array(); len(got) != 2 {
+			t.Errorf("c.db_*.v returned %d results, want 2", len(got))
+		}
+	})
+}
+
 // TestWildcardEdgeCases tests edge cases for wildcard queries
 func TestWildcardEdgeCases(t *testing.T) {
 	tests := []struct {